@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"interviewai.wkv.local/contentscraper/internal/health"
+
+	"cloud.google.com/go/firestore"
+	"cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
+	firebaseauth "firebase.google.com/go/v4/auth"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// healthCacheTTL lets uptime checks poll freely without each poll hitting every dependency
+const healthCacheTTL = 10 * time.Second
+
+// healthProbeUID is looked up in Firebase Auth to prove the service answers; it never exists
+const healthProbeUID = "health-probe"
+
+// healthProbeSecret is read from Secret Manager to prove the service answers; it need not exist
+const healthProbeSecret = "health-probe"
+
+// newHealthChecker describes the scraper's dependencies. Scraped content is stored in
+// Firestore and every request is authenticated, so both are critical. Secret Manager
+// holds API keys that only YouTube scraping and embeddings need.
+func newHealthChecker() *health.Checker {
+	return health.NewChecker("contentscraper", healthCacheTTL,
+		health.Check{Name: "firebase_auth", Critical: true, Probe: probeFirebaseAuth},
+		health.Check{Name: "firestore", Critical: true, Probe: probeFirestore(firestoreClient)},
+		health.Check{Name: "secret_manager", Probe: probeSecretManager},
+	)
+}
+
+// probeFirebaseAuth looks up a user that does not exist; a not-found answer proves
+// the Auth API is reachable with our credentials
+func probeFirebaseAuth(ctx context.Context) error {
+	client, err := firebaseAppSingleton.Auth(ctx)
+	if err != nil {
+		return err
+	}
+	if _, err := client.GetUser(ctx, healthProbeUID); err != nil && !firebaseauth.IsUserNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// probeFirestore reads a document that need not exist
+func probeFirestore(client *firestore.Client) health.ProbeFunc {
+	return func(ctx context.Context) error {
+		_, err := client.Collection("health").Doc("probe").Get(ctx)
+		if err != nil && status.Code(err) != codes.NotFound {
+			return err
+		}
+		return nil
+	}
+}
+
+// probeSecretManager reads the latest version of a secret that need not exist. A
+// not-found answer still proves Secret Manager is reachable.
+func probeSecretManager(ctx context.Context) error {
+	if secretClientSingleton == nil {
+		return errors.New("Secret Manager client not initialized")
+	}
+	name := fmt.Sprintf("projects/%s/secrets/%s/versions/latest", gcpProjectIDEnv, healthProbeSecret)
+	_, err := secretClientSingleton.AccessSecretVersion(ctx, &secretmanagerpb.AccessSecretVersionRequest{Name: name})
+	if err != nil && status.Code(err) != codes.NotFound {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps counters in process memory.
+// It is intended for local development and tests; limits are per instance and
+// reset when the function restarts.
+type MemoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	daily   map[string]*dailyCounter
+}
+
+type bucket struct {
+	tokens     float64
+	lastRefill time.Time
+}
+
+type dailyCounter struct {
+	count     int
+	expiresAt time.Time
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets: make(map[string]*bucket),
+		daily:   make(map[string]*dailyCounter),
+	}
+}
+
+// TakeToken refills the bucket for key and takes one token if available
+func (s *MemoryStore) TakeToken(ctx context.Context, key string, capacity int, refillPerSecond float64, now time.Time) (float64, time.Duration, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(capacity), lastRefill: now}
+		s.buckets[key] = b
+	}
+
+	tokens, retryAfter, allowed := takeToken(b.tokens, b.lastRefill, capacity, refillPerSecond, now)
+	b.tokens = tokens
+	b.lastRefill = now
+	return tokens, retryAfter, allowed, nil
+}
+
+// IncrementDaily counts one use of key unless quota has been reached
+func (s *MemoryStore) IncrementDaily(ctx context.Context, key string, quota int, expiresAt time.Time) (int, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for k, c := range s.daily {
+		if now.After(c.expiresAt) {
+			delete(s.daily, k)
+		}
+	}
+
+	c, ok := s.daily[key]
+	if !ok {
+		c = &dailyCounter{expiresAt: expiresAt}
+		s.daily[key] = c
+	}
+	if c.count >= quota {
+		return c.count, false, nil
+	}
+	c.count++
+	return c.count, true, nil
+}
+
+// takeToken applies the token bucket algorithm to a stored bucket state.
+// It returns the new token count, the wait until the next token when denied, and
+// whether a token was taken.
+func takeToken(tokens float64, lastRefill time.Time, capacity int, refillPerSecond float64, now time.Time) (float64, time.Duration, bool) {
+	if elapsed := now.Sub(lastRefill).Seconds(); elapsed > 0 {
+		tokens = math.Min(float64(capacity), tokens+elapsed*refillPerSecond)
+	}
+	if tokens >= 1 {
+		return tokens - 1, 0, true
+	}
+	if refillPerSecond <= 0 {
+		return tokens, 24 * time.Hour, false
+	}
+	wait := time.Duration((1 - tokens) / refillPerSecond * float64(time.Second))
+	return tokens, wait, false
+}
@@ -11,7 +11,9 @@ import (
 	"time"
 
 	"interviewai.wkv.local/contentscraper/internal/auth"
+	"interviewai.wkv.local/contentscraper/internal/health"
 	"interviewai.wkv.local/contentscraper/internal/httputils"
+	"interviewai.wkv.local/contentscraper/internal/ratelimit"
 	"interviewai.wkv.local/contentscraper/internal/secrets"
 	"interviewai.wkv.local/contentscraper/models"
 	"interviewai.wkv.local/contentscraper/processors"
@@ -29,6 +31,8 @@ var (
 	secretClientSingleton *secretmanager.Client
 	firestoreClient       *firestore.Client
 	gcpProjectIDEnv       string
+	rateLimiter           *ratelimit.Limiter
+	healthChecker         *health.Checker
 )
 
 // init runs during cold start or new instance creation, initializing shared clients.
@@ -66,6 +70,18 @@ func init() {
 		log.Fatalf("firestore.NewClient in init: %v", err)
 	}
 
+	// Per-user scrape limits; RATE_LIMIT_STORE=memory keeps counters in process for local runs
+	switch os.Getenv("RATE_LIMIT_STORE") {
+	case "memory":
+		rateLimiter = ratelimit.NewLimiter(ratelimit.NewMemoryStore(), nil)
+	case "", "firestore":
+		rateLimiter = ratelimit.NewLimiter(ratelimit.NewFirestoreStore(firestoreClient, "rateLimits"), nil)
+	default:
+		log.Fatalf("Unknown RATE_LIMIT_STORE %q", os.Getenv("RATE_LIMIT_STORE"))
+	}
+
+	healthChecker = newHealthChecker()
+
 	log.Println("ContentScraper: Firebase App, Secret Manager, and Firestore clients initialized.")
 }
 
@@ -102,6 +118,8 @@ func ScrapeContentGCF(w http.ResponseWriter, r *http.Request) {
 	// Handle different endpoints based on path
 	path := r.URL.Path
 	switch {
+	case health.IsHealthPath(path):
+		healthChecker.ServeHTTP(w, r)
 	case strings.HasSuffix(path, "/scrape"):
 		handleScrapeContent(w, r)
 	case strings.HasSuffix(path, "/search"):
@@ -143,6 +161,21 @@ func handleScrapeContent(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Scraping calls paid APIs; users with their own key get a larger daily quota
+	userAPIKey, _ := secrets.GetUserAPIKey(r.Context(), secretClientSingleton, gcpProjectIDEnv, authedUser.UID)
+	decision, err := rateLimiter.Allow(r.Context(), authedUser.UID, ratelimit.ClassScrape, userAPIKey != "")
+	if err != nil {
+		// Fail open so a counter store outage does not block scraping
+		log.Printf("Rate limit check failed for user %s: %v", authedUser.UID, err)
+	} else {
+		decision.WriteHeaders(w)
+		if !decision.Allowed {
+			log.Printf("User %s denied scrape request (%s)", authedUser.UID, decision.Reason)
+			httputils.ErrorJSON(w, decision.Message(), http.StatusTooManyRequests)
+			return
+		}
+	}
+
 	// Scrape content based on type
 	var scrapedContent *models.ScrapedContent
 
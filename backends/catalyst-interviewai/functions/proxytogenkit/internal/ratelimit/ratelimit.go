@@ -0,0 +1,200 @@
+package ratelimit
+
+// Per-user rate limiting and daily quotas for GCF handlers
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Class groups endpoints that share a limit
+type Class string
+
+const (
+	// ClassAIGeneration covers Genkit flows proxied through ProxyToGenkitGCF
+	ClassAIGeneration Class = "ai_generation"
+	// ClassInterview covers starting agent interviews
+	ClassInterview Class = "interview"
+	// ClassScrape covers content scraping
+	ClassScrape Class = "scrape"
+	// ClassSearch covers vector search
+	ClassSearch Class = "search"
+	// ClassTranscription covers transcribing spoken answers
+	ClassTranscription Class = "transcription"
+)
+
+// Limit configures the token bucket and daily quota for a class
+type Limit struct {
+	// Burst is the bucket capacity
+	Burst int
+	// RefillPerMinute is how many tokens are added back per minute
+	RefillPerMinute float64
+	// DailyQuota applies to users running on the platform's default key (0 = unlimited)
+	DailyQuota int
+	// DailyQuotaBYOK applies to users who brought their own key (0 = unlimited)
+	DailyQuotaBYOK int
+}
+
+// DefaultLimits are used for any class missing from a Limiter's configuration
+var DefaultLimits = map[Class]Limit{
+	ClassAIGeneration: {Burst: 10, RefillPerMinute: 10, DailyQuota: 100, DailyQuotaBYOK: 1000},
+	ClassInterview:    {Burst: 3, RefillPerMinute: 1, DailyQuota: 10, DailyQuotaBYOK: 50},
+	ClassScrape:       {Burst: 5, RefillPerMinute: 2, DailyQuota: 25, DailyQuotaBYOK: 200},
+	ClassSearch:       {Burst: 30, RefillPerMinute: 60, DailyQuota: 1000, DailyQuotaBYOK: 5000},
+	// Speech-to-text is paid by the platform whatever key the user brings
+	ClassTranscription: {Burst: 5, RefillPerMinute: 4, DailyQuota: 150, DailyQuotaBYOK: 150},
+}
+
+// Store holds bucket and quota counters
+type Store interface {
+	// TakeToken refills the bucket for key and takes one token if available.
+	// It returns the tokens left and, when denied, how long until a token is available.
+	TakeToken(ctx context.Context, key string, capacity int, refillPerSecond float64, now time.Time) (remaining float64, retryAfter time.Duration, allowed bool, err error)
+	// IncrementDaily counts one use of key unless quota has been reached.
+	// It returns the count after the call.
+	IncrementDaily(ctx context.Context, key string, quota int, expiresAt time.Time) (count int, allowed bool, err error)
+}
+
+// Decision is the outcome of a rate limit check
+type Decision struct {
+	Allowed    bool
+	Class      Class
+	Reason     string // "rate_limited" or "daily_quota_exceeded" when denied
+	Limit      int
+	Remaining  int
+	Reset      time.Duration
+	RetryAfter time.Duration
+	Policy     string
+}
+
+// Message returns a user facing explanation of a denial
+func (d *Decision) Message() string {
+	switch d.Reason {
+	case "daily_quota_exceeded":
+		return fmt.Sprintf("Daily limit reached for %s requests. Add your own API key for a higher limit or try again tomorrow.", d.Class)
+	case "rate_limited":
+		return fmt.Sprintf("Too many %s requests. Retry in %d seconds.", d.Class, ceilSeconds(d.RetryAfter))
+	}
+	return ""
+}
+
+// WriteHeaders sets the standard RateLimit-* headers, plus Retry-After when denied
+func (d *Decision) WriteHeaders(w http.ResponseWriter) {
+	w.Header().Set("RateLimit-Limit", strconv.Itoa(d.Limit))
+	w.Header().Set("RateLimit-Remaining", strconv.Itoa(d.Remaining))
+	w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(d.Reset)))
+	if d.Policy != "" {
+		w.Header().Set("RateLimit-Policy", d.Policy)
+	}
+	if !d.Allowed {
+		w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(d.RetryAfter)))
+	}
+}
+
+// Limiter enforces per-user limits for each endpoint class
+type Limiter struct {
+	store  Store
+	limits map[Class]Limit
+	now    func() time.Time
+}
+
+// NewLimiter creates a limiter. Classes missing from limits fall back to DefaultLimits.
+func NewLimiter(store Store, limits map[Class]Limit) *Limiter {
+	merged := make(map[Class]Limit, len(DefaultLimits))
+	for class, limit := range DefaultLimits {
+		merged[class] = limit
+	}
+	for class, limit := range limits {
+		merged[class] = limit
+	}
+	return &Limiter{store: store, limits: merged, now: time.Now}
+}
+
+// Allow checks and consumes one request for userID in class.
+// byok reports whether the user supplies their own API key.
+func (l *Limiter) Allow(ctx context.Context, userID string, class Class, byok bool) (*Decision, error) {
+	limit, ok := l.limits[class]
+	if !ok {
+		return nil, fmt.Errorf("no rate limit configured for class %s", class)
+	}
+
+	now := l.now().UTC()
+	refillPerSecond := limit.RefillPerMinute / 60
+	decision := &Decision{
+		Allowed: true,
+		Class:   class,
+		Limit:   limit.Burst,
+	}
+
+	quota := limit.DailyQuota
+	if byok {
+		quota = limit.DailyQuotaBYOK
+	}
+	decision.Policy = fmt.Sprintf("%d;w=%d", limit.Burst, int(math.Round(float64(limit.Burst)/math.Max(refillPerSecond, 1e-9))))
+	if quota > 0 {
+		decision.Policy += fmt.Sprintf(", %d;w=86400", quota)
+	}
+
+	// Token bucket
+	bucketKey := fmt.Sprintf("bucket_%s_%s", class, userID)
+	remaining, retryAfter, allowed, err := l.store.TakeToken(ctx, bucketKey, limit.Burst, refillPerSecond, now)
+	if err != nil {
+		return nil, fmt.Errorf("rate limit check failed: %w", err)
+	}
+	decision.Remaining = int(math.Floor(remaining))
+	if refillPerSecond > 0 {
+		decision.Reset = time.Duration((float64(limit.Burst) - remaining) / refillPerSecond * float64(time.Second))
+	}
+	if !allowed {
+		decision.Allowed = false
+		decision.Reason = "rate_limited"
+		decision.RetryAfter = retryAfter
+		decision.Reset = retryAfter
+		return decision, nil
+	}
+
+	// Daily quota
+	if quota <= 0 {
+		return decision, nil
+	}
+	day := now.Format("2006-01-02")
+	endOfDay := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
+	dailyKey := fmt.Sprintf("daily_%s_%s_%s", class, userID, day)
+
+	count, allowed, err := l.store.IncrementDaily(ctx, dailyKey, quota, endOfDay)
+	if err != nil {
+		return nil, fmt.Errorf("daily quota check failed: %w", err)
+	}
+
+	// Report whichever window is tighter
+	if dailyRemaining := quota - count; dailyRemaining < decision.Remaining || !allowed {
+		decision.Limit = quota
+		decision.Remaining = maxInt(dailyRemaining, 0)
+		decision.Reset = endOfDay.Sub(now)
+	}
+	if !allowed {
+		decision.Allowed = false
+		decision.Reason = "daily_quota_exceeded"
+		decision.RetryAfter = endOfDay.Sub(now)
+	}
+
+	return decision, nil
+}
+
+func ceilSeconds(d time.Duration) int {
+	if d <= 0 {
+		return 0
+	}
+	return int(math.Ceil(d.Seconds()))
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -13,11 +13,17 @@ import (
 	"time"
 
 	"interviewai.wkv.local/proxytogenkit/internal/auth"
+	"interviewai.wkv.local/proxytogenkit/internal/health"
 	"interviewai.wkv.local/proxytogenkit/internal/httputils"
+	"interviewai.wkv.local/proxytogenkit/internal/ratelimit"
 	"interviewai.wkv.local/proxytogenkit/internal/secrets"
+	"interviewai.wkv.local/proxytogenkit/internal/tracing"
 
+	"cloud.google.com/go/firestore"
 	secretmanager "cloud.google.com/go/secretmanager/apiv1"
 	firebase "firebase.google.com/go/v4"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/api/option"
 )
 
@@ -27,6 +33,8 @@ var (
 	gcpProjectIDEnv       string
 	nextjsBaseURLEnv      string
 	defaultAPIKeyEnv      string
+	rateLimiter           *ratelimit.Limiter
+	healthChecker         *health.Checker
 )
 
 func init() {
@@ -39,6 +47,10 @@ func init() {
 	if nextjsBaseURLEnv == "" {
 		log.Fatal("NEXTJS_BASE_URL environment variable not set.")
 	}
+	// A broken trace exporter costs us traces, not the function
+	if _, err := tracing.Setup(ctx, tracing.ConfigFromEnv("proxytogenkit", gcpProjectIDEnv)); err != nil {
+		log.Printf("Warning: Failed to set up tracing: %v", err)
+	}
 	defaultAPIKeyEnv = os.Getenv("DEFAULT_GEMINI_API_KEY") // Can be empty if not all flows require a default
 	if defaultAPIKeyEnv == "" {
 		log.Println("Warning: DEFAULT_GEMINI_API_KEY environment variable not set. Flows requiring a default key may fail.")
@@ -61,17 +73,43 @@ func init() {
 	if err != nil {
 		log.Fatalf("secretmanager.NewClient in init: %v", err)
 	}
+
+	// RATE_LIMIT_STORE selects where counters live: "firestore" (default) shares limits
+	// across instances, "memory" is for local development
+	var firestoreClient *firestore.Client
+	switch os.Getenv("RATE_LIMIT_STORE") {
+	case "memory":
+		rateLimiter = ratelimit.NewLimiter(ratelimit.NewMemoryStore(), nil)
+	case "", "firestore":
+		firestoreClient, err = firebaseAppSingleton.Firestore(ctx)
+		if err != nil {
+			log.Fatalf("firebaseApp.Firestore in init: %v", err)
+		}
+		rateLimiter = ratelimit.NewLimiter(ratelimit.NewFirestoreStore(firestoreClient, "rateLimits"), nil)
+	default:
+		log.Fatalf("Unknown RATE_LIMIT_STORE %q", os.Getenv("RATE_LIMIT_STORE"))
+	}
+	healthChecker = newHealthChecker(firestoreClient)
 	log.Println("ProxyToGenkit: Firebase App and Secret Manager Client initialized.")
 }
 
 // ProxyToGenkitGCF is the HTTP Cloud Function for proxying requests to Genkit flows.
 func ProxyToGenkitGCF(w http.ResponseWriter, r *http.Request) {
+	w, r, end := tracing.StartServer(w, r, "ProxyToGenkit")
+	defer end()
+
 	httputils.SetCORSHeaders(w, r)
 	if r.Method == http.MethodOptions {
 		w.WriteHeader(http.StatusNoContent)
 		return
 	}
 
+	// Uptime checks probe GET .../health, .../health/live and .../health/ready without credentials
+	if r.Method == http.MethodGet && health.IsHealthPath(r.URL.Path) {
+		healthChecker.ServeHTTP(w, r)
+		return
+	}
+
 	if r.Method != http.MethodPost {
 		httputils.ErrorJSON(w, "Only POST method is allowed", http.StatusMethodNotAllowed)
 		return
@@ -115,6 +153,7 @@ func ProxyToGenkitGCF(w http.ResponseWriter, r *http.Request) {
 	}
 
 	log.Printf("ProxyToGenkitGCF: User %s, attempting flow %s", userID, flowName)
+	trace.SpanFromContext(r.Context()).SetAttributes(attribute.String("genkit.flow", flowName))
 
 	userAPIKey, err := secrets.GetUserAPIKey(r.Context(), secretClientSingleton, gcpProjectIDEnv, userID)
 	apiKeyToUse := ""
@@ -136,6 +175,20 @@ func ProxyToGenkitGCF(w http.ResponseWriter, r *http.Request) {
 		log.Printf("ProxyToGenkitGCF: Using default API key for user %s for flow %s", userID, flowName)
 	}
 
+	// Users on their own key get a larger daily quota
+	decision, err := rateLimiter.Allow(r.Context(), userID, ratelimit.ClassAIGeneration, apiKeySource == "user")
+	if err != nil {
+		// Fail open so a counter store outage does not take the AI features down
+		log.Printf("ProxyToGenkitGCF: Rate limit check failed for user %s: %v", userID, err)
+	} else {
+		decision.WriteHeaders(w)
+		if !decision.Allowed {
+			log.Printf("ProxyToGenkitGCF: User %s denied flow %s (%s)", userID, flowName, decision.Reason)
+			httputils.ErrorJSON(w, decision.Message(), http.StatusTooManyRequests)
+			return
+		}
+	}
+
 	requestBodyBytes, err := io.ReadAll(r.Body)
 	if err != nil {
 		httputils.ErrorJSON(w, "Failed to read request body", http.StatusInternalServerError)
@@ -155,12 +208,12 @@ func ProxyToGenkitGCF(w http.ResponseWriter, r *http.Request) {
 	proxyReq.Header.Set("Content-Type", "application/json")
 	proxyReq.Header.Set("X-Internal-API-Key", apiKeyToUse)
 	proxyReq.Header.Set("X-API-Key-Source", apiKeySource)
-	// Potentially forward other relevant headers if needed, e.g., X-Cloud-Trace-Context for tracing
-	if traceHeader := r.Header.Get("X-Cloud-Trace-Context"); traceHeader != "" {
-		proxyReq.Header.Set("X-Cloud-Trace-Context", traceHeader)
-	}
+	// The tracing transport adds traceparent (and X-Cloud-Trace-Context) for this request's span
 
-	httpClient := &http.Client{Timeout: 90 * time.Second} // Increased timeout for potentially long AI calls
+	httpClient := &http.Client{
+		Timeout:   90 * time.Second, // Increased timeout for potentially long AI calls
+		Transport: tracing.Transport(http.DefaultTransport),
+	}
 	resp, err := httpClient.Do(proxyReq)
 	if err != nil {
 		log.Printf("ProxyToGenkitGCF: Error proxying request to %s for flow %s, user %s: %v", targetURLStr, flowName, userID, err)
@@ -0,0 +1,489 @@
+package pythonagentgateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"interviewai.wkv.local/pythonagentgateway/internal/agents"
+	"interviewai.wkv.local/pythonagentgateway/internal/audit"
+	"interviewai.wkv.local/pythonagentgateway/internal/auth"
+	"interviewai.wkv.local/pythonagentgateway/internal/httputils"
+	"interviewai.wkv.local/pythonagentgateway/internal/schema"
+	"interviewai.wkv.local/pythonagentgateway/internal/sessions"
+	"interviewai.wkv.local/pythonagentgateway/internal/tracing"
+)
+
+// Active session listing limits for the admin API
+const (
+	adminListDefaultLimit = 50
+	adminListMaxLimit     = 500
+)
+
+// adminActor is the staff member calling an admin endpoint
+type adminActor struct {
+	UID   string
+	Email string
+}
+
+// adminSessionSummary is one active session in the admin listing
+type adminSessionSummary struct {
+	SessionID      string    `json:"sessionId"`
+	UserID         string    `json:"userId"`
+	InterviewType  string    `json:"interviewType"`
+	Backend        string    `json:"backend"`
+	CreatedAt      time.Time `json:"createdAt"`
+	AgeSeconds     int64     `json:"ageSeconds"`
+	LastActivityAt time.Time `json:"lastActivityAt"`
+	IdleSeconds    int64     `json:"idleSeconds"`
+	TurnCount      int       `json:"turnCount"`
+	ResponseCount  int       `json:"responseCount"`
+}
+
+// AdminListSessionsGCF handles GET /api/admin/sessions. It lists active sessions of
+// every user, most recent first; ?limit= caps how many.
+func AdminListSessionsGCF(w http.ResponseWriter, r *http.Request) {
+	w, r, end := tracing.StartServer(w, r, "AdminListSessions")
+	defer end()
+
+	httputils.SetCORSHeaders(w, r)
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		httputils.ErrorJSON(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	entry := &audit.Entry{Action: audit.ActionListSessions}
+	actor, ok := authorizeAdmin(w, r, entry)
+	if !ok {
+		return
+	}
+
+	limit := adminListDefaultLimit
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		parsedLimit, err := strconv.Atoi(limitStr)
+		if err != nil || parsedLimit <= 0 || parsedLimit > adminListMaxLimit {
+			httputils.ErrorJSON(w, fmt.Sprintf("limit must be between 1 and %d", adminListMaxLimit), http.StatusBadRequest)
+			return
+		}
+		limit = parsedLimit
+	}
+	entry.Details = map[string]interface{}{"limit": limit}
+
+	active, err := sessionRegistry.Active(r.Context(), limit)
+	if err != nil {
+		log.Printf("AdminListSessions: %v", err)
+		finishAdminAction(actor, entry, http.StatusInternalServerError, err)
+		httputils.ErrorJSON(w, "Failed to list sessions", http.StatusInternalServerError)
+		return
+	}
+
+	now := time.Now().UTC()
+	summaries := make([]adminSessionSummary, 0, len(active))
+	for _, session := range active {
+		summaries = append(summaries, adminSessionSummary{
+			SessionID:      session.SessionID,
+			UserID:         session.UserID,
+			InterviewType:  session.InterviewType,
+			Backend:        sessionBackendName(session),
+			CreatedAt:      session.CreatedAt,
+			AgeSeconds:     int64(now.Sub(session.CreatedAt).Seconds()),
+			LastActivityAt: session.LastActivityAt,
+			IdleSeconds:    int64(now.Sub(session.LastActivityAt).Seconds()),
+			TurnCount:      session.TurnCount,
+			ResponseCount:  session.ResponseCount,
+		})
+	}
+	entry.Details["returned"] = len(summaries)
+	finishAdminAction(actor, entry, http.StatusOK, nil)
+
+	httputils.ResponseJSON(w, map[string]interface{}{
+		"sessions": summaries,
+		"total":    len(summaries),
+	}, http.StatusOK)
+}
+
+// AdminSessionGCF handles GET /api/admin/sessions/{sessionId}. It returns the
+// session's registry entry, what its agent reports and the full transcript.
+func AdminSessionGCF(w http.ResponseWriter, r *http.Request) {
+	w, r, end := tracing.StartServer(w, r, "AdminSession")
+	defer end()
+
+	httputils.SetCORSHeaders(w, r)
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		httputils.ErrorJSON(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	entry := &audit.Entry{Action: audit.ActionViewSession}
+	actor, ok := authorizeAdmin(w, r, entry)
+	if !ok {
+		return
+	}
+
+	session, ok := findAdminSession(w, r, actor, entry)
+	if !ok {
+		return
+	}
+
+	turns, err := sessionRegistry.Transcript(r.Context(), session.UserID, session.SessionID)
+	if err != nil {
+		log.Printf("AdminSession: %v", err)
+		finishAdminAction(actor, entry, http.StatusInternalServerError, err)
+		httputils.ErrorJSON(w, "Failed to load transcript", http.StatusInternalServerError)
+		return
+	}
+	if turns == nil {
+		turns = []*sessions.Turn{}
+	}
+
+	result := map[string]interface{}{
+		"session": session,
+		"backend": sessionBackendName(session),
+		"turns":   turns,
+	}
+
+	// Only live sessions are asked about; an admin look must not resume a lost one
+	if session.Status == sessions.StatusActive {
+		agentStatus, err := agentForSession(session).GetSessionStatus(r.Context(), session.SessionID, session.UserID)
+		if err != nil {
+			result["agentError"] = err.Error()
+		} else {
+			result["agentStatus"] = agentStatus
+		}
+	}
+	finishAdminAction(actor, entry, http.StatusOK, nil)
+
+	httputils.ResponseJSON(w, result, http.StatusOK)
+}
+
+// AdminEndSessionGCF handles POST /api/admin/sessions/{sessionId}/end with
+// {"reason": "..."}. The session is recorded as terminated even when its agent
+// cannot be reached, so a stuck session can always be closed.
+func AdminEndSessionGCF(w http.ResponseWriter, r *http.Request) {
+	w, r, end := tracing.StartServer(w, r, "AdminEndSession")
+	defer end()
+
+	httputils.SetCORSHeaders(w, r)
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		httputils.ErrorJSON(w, "Only POST method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	entry := &audit.Entry{Action: audit.ActionEndSession}
+	actor, ok := authorizeAdmin(w, r, entry)
+	if !ok {
+		return
+	}
+
+	action, ok := decodeAdminAction(w, r)
+	if !ok {
+		return
+	}
+	entry.Reason = action.Reason
+
+	session, ok := findAdminSession(w, r, actor, entry)
+	if !ok {
+		return
+	}
+	if session.Status != sessions.StatusActive {
+		finishAdminAction(actor, entry, http.StatusConflict, errors.New("session has already ended"))
+		httputils.ErrorJSON(w, fmt.Sprintf("Session has already ended (%s)", session.Status), http.StatusConflict)
+		return
+	}
+
+	if !beginAdminAction(w, r, actor, entry) {
+		return
+	}
+	log.Printf("AdminEndSession: %s is ending session %s of user %s: %s", actor.UID, session.SessionID, session.UserID, action.Reason)
+	requestBody := map[string]interface{}{
+		"session_id": session.SessionID,
+		"user_id":    session.UserID,
+		"type":       "end_interview",
+	}
+	response, err := agentForSession(session).EndInterview(r.Context(), session.SessionID, session.UserID, requestBody)
+
+	result := map[string]interface{}{
+		"sessionId":  session.SessionID,
+		"userId":     session.UserID,
+		"status":     sessions.StatusTerminated,
+		"agentEnded": err == nil,
+	}
+	entry.Details = map[string]interface{}{"agentEnded": err == nil}
+	if err != nil {
+		// The agent may have lost the session or be down; the registry is closed anyway
+		log.Printf("AdminEndSession: Agent did not end session %s: %v", session.SessionID, err)
+		result["agentError"] = err.Error()
+		entry.Details["agentError"] = err.Error()
+		response = nil
+	} else {
+		result["response"] = response
+	}
+
+	finalizeSession(r.Context(), session, sessions.StatusTerminated, response)
+	finishAdminAction(actor, entry, http.StatusOK, nil)
+
+	httputils.ResponseJSON(w, result, http.StatusOK)
+}
+
+// AdminReplayTurnGCF handles POST /api/admin/sessions/{sessionId}/replay with
+// {"reason": "..."}. It sends the answer the agent last failed to process to the
+// session's agent again and records the turn as if the first attempt had worked.
+func AdminReplayTurnGCF(w http.ResponseWriter, r *http.Request) {
+	w, r, end := tracing.StartServer(w, r, "AdminReplayTurn")
+	defer end()
+
+	httputils.SetCORSHeaders(w, r)
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		httputils.ErrorJSON(w, "Only POST method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	entry := &audit.Entry{Action: audit.ActionReplayTurn}
+	actor, ok := authorizeAdmin(w, r, entry)
+	if !ok {
+		return
+	}
+
+	action, ok := decodeAdminAction(w, r)
+	if !ok {
+		return
+	}
+	entry.Reason = action.Reason
+
+	session, ok := findAdminSession(w, r, actor, entry)
+	if !ok {
+		return
+	}
+	if session.Status != sessions.StatusActive {
+		finishAdminAction(actor, entry, http.StatusConflict, errors.New("session has ended"))
+		httputils.ErrorJSON(w, fmt.Sprintf("Session has ended (%s)", session.Status), http.StatusConflict)
+		return
+	}
+
+	turn, err := sessionRegistry.OpenTurn(r.Context(), session.UserID, session.SessionID)
+	if err != nil {
+		log.Printf("AdminReplayTurn: %v", err)
+		finishAdminAction(actor, entry, http.StatusInternalServerError, err)
+		httputils.ErrorJSON(w, "Failed to read the open turn", http.StatusInternalServerError)
+		return
+	}
+	if turn == nil || !turn.Failed() {
+		finishAdminAction(actor, entry, http.StatusConflict, errors.New("no failed turn"))
+		httputils.ErrorJSON(w, "Session has no failed turn to replay", http.StatusConflict)
+		return
+	}
+	entry.Details = map[string]interface{}{
+		"turn":          turn.Index,
+		"failureCount":  turn.FailureCount,
+		"failureReason": turn.FailureReason,
+	}
+
+	if !beginAdminAction(w, r, actor, entry) {
+		return
+	}
+	log.Printf("AdminReplayTurn: %s is replaying turn %d of session %s: %s", actor.UID, turn.Index, session.SessionID, action.Reason)
+	response, responseID, err := replayFailedTurn(r.Context(), session, turn)
+	if err != nil {
+		finishAdminAction(actor, entry, agents.HTTPStatus(err), err)
+		writeAgentError(w, "Replay failed", err)
+		return
+	}
+	entry.Details["responseId"] = responseID
+	finishAdminAction(actor, entry, http.StatusOK, nil)
+
+	httputils.ResponseJSON(w, map[string]interface{}{
+		"sessionId":  session.SessionID,
+		"userId":     session.UserID,
+		"turn":       turn.Index,
+		"responseId": responseID,
+		"response":   response,
+	}, http.StatusOK)
+}
+
+// replayFailedTurn submits the turn's failed answer again, timed from its original
+// submission. Another failure is recorded on the turn like the first.
+func replayFailedTurn(ctx context.Context, session *sessions.Session, turn *sessions.Turn) (map[string]interface{}, string, error) {
+	userID, sessionID := session.UserID, session.SessionID
+	client := agentForSession(session)
+
+	responseID := uuid.New().String()
+	requestBody := map[string]interface{}{
+		"response":    turn.FailedAnswer,
+		"session_id":  sessionID,
+		"user_id":     userID,
+		"type":        "user_response",
+		"response_id": responseID,
+	}
+	meta := answerMeta{AnsweredAt: *turn.FailedAt}
+	measureAnswer(ctx, session, &meta, requestBody)
+
+	response, err := withResume(ctx, sessionResumer(client, session), func() (map[string]interface{}, error) {
+		return client.SubmitResponse(ctx, sessionID, userID, requestBody)
+	})
+	if err != nil {
+		recordFailedAnswer(userID, sessionID, requestBody, meta.AnsweredAt, err)
+		return nil, "", err
+	}
+
+	if recorded := recordTurn(ctx, userID, sessionID, responseID, meta, requestBody, response); recorded != nil {
+		meta.RevisionCount = &recorded.RevisionCount
+	}
+	recordUserResponse(sessionID, userID, responseID, meta, requestBody)
+	recordEvaluationScores(evaluationScoresFrom(sessionID, responseID, response))
+	return response, responseID, nil
+}
+
+// authorizeAdmin checks that the caller holds the admin claim. Callers with a valid
+// token but no claim are audited as denied. When the check fails it writes a 401 or
+// 403 response and returns false so the handler can stop.
+func authorizeAdmin(w http.ResponseWriter, r *http.Request, entry *audit.Entry) (adminActor, bool) {
+	token, err := auth.VerifyAdmin(r, firebaseAppSingleton, serviceConfig.AdminClaim)
+	if token == nil {
+		httputils.ErrorJSON(w, fmt.Sprintf("Unauthorized: %v", err), http.StatusUnauthorized)
+		return adminActor{}, false
+	}
+
+	actor := adminActor{UID: token.UID}
+	actor.Email, _ = token.Claims["email"].(string)
+	if errors.Is(err, auth.ErrNotAdmin) {
+		log.Printf("Rejected admin %s by user %s", entry.Action, actor.UID)
+		entry.SessionID = extractAdminSessionID(r.URL.Path)
+		entry.Outcome = audit.OutcomeDenied
+		recordAdminAction(actor, entry, http.StatusForbidden, nil)
+		httputils.ErrorJSON(w, "Forbidden", http.StatusForbidden)
+		return adminActor{}, false
+	}
+	return actor, true
+}
+
+// findAdminSession loads the session named in the path, whoever owns it. When it
+// cannot, it audits the failure, writes the response and returns false.
+func findAdminSession(w http.ResponseWriter, r *http.Request, actor adminActor, entry *audit.Entry) (*sessions.Session, bool) {
+	sessionID := extractAdminSessionID(r.URL.Path)
+	if sessionID == "" {
+		httputils.ErrorJSON(w, "Session ID not found in path", http.StatusBadRequest)
+		return nil, false
+	}
+	entry.SessionID = sessionID
+
+	session, err := sessionRegistry.Find(r.Context(), sessionID)
+	switch {
+	case err == nil:
+		entry.TargetUserID = session.UserID
+		return session, true
+	case errors.Is(err, sessions.ErrSessionNotFound):
+		finishAdminAction(actor, entry, http.StatusNotFound, err)
+		httputils.ErrorJSON(w, "Session not found", http.StatusNotFound)
+	default:
+		log.Printf("Admin session lookup failed: %v", err)
+		finishAdminAction(actor, entry, http.StatusInternalServerError, err)
+		httputils.ErrorJSON(w, "Failed to find session", http.StatusInternalServerError)
+	}
+	return nil, false
+}
+
+// decodeAdminAction reads the reason given for an admin action, answering 400 or 413
+// when the body is not acceptable
+func decodeAdminAction(w http.ResponseWriter, r *http.Request) (*schema.AdminActionRequest, bool) {
+	bodyBytes, err := schema.ReadBody(w, r, schema.MaxAdminBodyBytes)
+	if err != nil {
+		writeRequestError(w, err)
+		return nil, false
+	}
+	action, err := schema.DecodeAdminAction(bodyBytes)
+	if err != nil {
+		writeRequestError(w, err)
+		return nil, false
+	}
+	return action, true
+}
+
+// beginAdminAction writes the pending audit entry for an action that changes a
+// session. An action that cannot be audited is not taken: it answers 503 and
+// returns false.
+func beginAdminAction(w http.ResponseWriter, r *http.Request, actor adminActor, entry *audit.Entry) bool {
+	entry.ActorID = actor.UID
+	entry.ActorEmail = actor.Email
+	if err := auditLog.Begin(r.Context(), entry); err != nil {
+		log.Printf("Refusing admin %s by %s: %v", entry.Action, actor.UID, err)
+		httputils.ErrorJSON(w, "Failed to record the admin action; nothing was changed", http.StatusServiceUnavailable)
+		return false
+	}
+	return true
+}
+
+// finishAdminAction audits an action with the status it was answered with
+func finishAdminAction(actor adminActor, entry *audit.Entry, statusCode int, err error) {
+	entry.Outcome = audit.OutcomeSucceeded
+	if err != nil || statusCode >= http.StatusBadRequest {
+		entry.Outcome = audit.OutcomeFailed
+	}
+	recordAdminAction(actor, entry, statusCode, err)
+}
+
+// recordAdminAction writes the audit entry, or its outcome when beginAdminAction
+// wrote it as pending. It uses its own context so the entry is kept even when the
+// caller has gone away; if Firestore refuses it, the entry is logged in full instead.
+func recordAdminAction(actor adminActor, entry *audit.Entry, statusCode int, err error) {
+	entry.ActorID = actor.UID
+	entry.ActorEmail = actor.Email
+	entry.StatusCode = statusCode
+	if err != nil {
+		entry.Error = err.Error()
+	}
+
+	write := auditLog.Record
+	if entry.EntryID != "" {
+		write = auditLog.Complete
+	}
+	if recordErr := write(context.Background(), entry); recordErr != nil {
+		line, _ := json.Marshal(entry)
+		log.Printf("%v; entry: %s", recordErr, line)
+	}
+}
+
+// sessionBackendName names the agent backend serving a session
+func sessionBackendName(session *sessions.Session) string {
+	if session.Variant != "" {
+		return session.Variant
+	}
+	return agentRouter.Primary().Name
+}
+
+// extractAdminSessionID reads the session ID from /api/admin/sessions/{sessionId}/...
+func extractAdminSessionID(path string) string {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	for i, part := range parts {
+		if part == "sessions" && i+1 < len(parts) {
+			return parts[i+1]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,122 @@
+package pythonagentgateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"interviewai.wkv.local/pythonagentgateway/internal/agents"
+	"interviewai.wkv.local/pythonagentgateway/internal/config"
+	"interviewai.wkv.local/pythonagentgateway/internal/httputils"
+	"interviewai.wkv.local/pythonagentgateway/internal/secrets"
+)
+
+// userKeyCacheTTL is how long a user's key, or the lack of one, is remembered.
+// Every agent call needs the key, so Secret Manager is not asked each time; a key
+// stored or removed in settings applies within this window.
+const userKeyCacheTTL = 5 * time.Minute
+
+// userKeyCacheSize is the number of cached users past which expired entries are dropped
+const userKeyCacheSize = 1024
+
+var (
+	// errDefaultKeyRefused is returned when the policy requires users to bring their own key
+	errDefaultKeyRefused = fmt.Errorf("%w: the default key is not available to users without their own", agents.ErrNoAPIKey)
+	// errNoDefaultKey is returned when the user has no key and no default key is configured
+	errNoDefaultKey = fmt.Errorf("%w: DEFAULT_GEMINI_API_KEY is not set", agents.ErrNoAPIKey)
+	// errUserKeyLookup is returned when Secret Manager cannot say whether the user has a key
+	errUserKeyLookup = errors.New("failed to look up the user's API key")
+)
+
+type cachedUserKey struct {
+	key     string
+	expires time.Time
+}
+
+var userKeyCache = struct {
+	sync.Mutex
+	entries map[string]cachedUserKey
+}{entries: make(map[string]cachedUserKey)}
+
+// resolveAgentAPIKey picks the Gemini key agent calls for the user run on: their
+// own key when they stored one, otherwise the default key if the policy allows it.
+// A failed lookup is an error rather than a fall back to the default key, which
+// would bill a user's interview to the wrong account.
+func resolveAgentAPIKey(ctx context.Context, userID string) (*agents.APIKey, error) {
+	key, err := userAPIKey(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if key != "" {
+		return &agents.APIKey{Value: key, Source: agents.KeySourceUser}, nil
+	}
+	if serviceConfig.DefaultKeyPolicy == config.DefaultKeyBYOKOnly {
+		return nil, errDefaultKeyRefused
+	}
+	if serviceConfig.DefaultGeminiAPIKey == "" {
+		return nil, errNoDefaultKey
+	}
+	return &agents.APIKey{Value: serviceConfig.DefaultGeminiAPIKey, Source: agents.KeySourceDefault}, nil
+}
+
+// userAPIKey returns the user's own Gemini key from Secret Manager, or "" when they
+// have none. Only a missing secret means no key; any other failure is returned and
+// not cached, so the next call asks again.
+func userAPIKey(ctx context.Context, userID string) (string, error) {
+	if secretClientSingleton == nil || serviceConfig.GCPProjectID == "" {
+		return "", nil
+	}
+
+	userKeyCache.Lock()
+	cached, ok := userKeyCache.entries[userID]
+	userKeyCache.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.key, nil
+	}
+
+	key, err := secrets.GetUserAPIKey(ctx, secretClientSingleton, serviceConfig.GCPProjectID, userID)
+	if err != nil {
+		if status.Code(err) != codes.NotFound {
+			return "", fmt.Errorf("%w: %v", errUserKeyLookup, err)
+		}
+		key = ""
+	}
+
+	now := time.Now()
+	userKeyCache.Lock()
+	if len(userKeyCache.entries) >= userKeyCacheSize {
+		for id, entry := range userKeyCache.entries {
+			if now.After(entry.expires) {
+				delete(userKeyCache.entries, id)
+			}
+		}
+	}
+	userKeyCache.entries[userID] = cachedUserKey{key: key, expires: now.Add(userKeyCacheTTL)}
+	userKeyCache.Unlock()
+	return key, nil
+}
+
+// writeAPIKeyError tells the user why no interview can run on their behalf
+func writeAPIKeyError(w http.ResponseWriter, userID string, err error) {
+	log.Printf("Could not resolve API key for user %s: %v", userID, err)
+	message, statusCode := apiKeyErrorResponse(err)
+	httputils.ErrorJSON(w, message, statusCode)
+}
+
+// apiKeyErrorResponse is the message and status for a key that cannot be resolved
+func apiKeyErrorResponse(err error) (string, int) {
+	if errors.Is(err, errDefaultKeyRefused) {
+		return "Add your own Gemini API key in settings to start an interview", http.StatusForbidden
+	}
+	if errors.Is(err, errUserKeyLookup) {
+		return "Could not read your API key settings, please try again", http.StatusServiceUnavailable
+	}
+	return "AI service not configured (no default key)", http.StatusServiceUnavailable
+}
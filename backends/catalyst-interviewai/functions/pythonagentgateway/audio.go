@@ -0,0 +1,223 @@
+package pythonagentgateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"time"
+
+	"interviewai.wkv.local/pythonagentgateway/internal/auth"
+	"interviewai.wkv.local/pythonagentgateway/internal/httputils"
+	"interviewai.wkv.local/pythonagentgateway/internal/ratelimit"
+	"interviewai.wkv.local/pythonagentgateway/internal/schema"
+	"interviewai.wkv.local/pythonagentgateway/internal/sessions"
+	"interviewai.wkv.local/pythonagentgateway/internal/speech"
+	"interviewai.wkv.local/pythonagentgateway/internal/tracing"
+)
+
+// audioFormField is the multipart field carrying the recording
+const audioFormField = "audio"
+
+// multipartOverhead allows for the form's boundaries and text fields on top of the
+// recording itself
+const multipartOverhead = 1 << 20
+
+// audioReservedFields are set by the gateway and cannot be sent as form fields
+var audioReservedFields = map[string]bool{
+	audioFormField: true, "response": true, "session_id": true, "user_id": true,
+	"type": true, "response_id": true, "stream": true, "responseTime": true,
+}
+
+// newTranscriber creates the configured speech-to-text backend
+func newTranscriber(ctx context.Context) (speech.Transcriber, error) {
+	cfg := serviceConfig.Transcription
+	base := tracing.Transport(http.DefaultTransport)
+
+	switch cfg.Provider {
+	case "cloud":
+		transcriber, err := speech.NewCloudTranscriber(ctx, cfg.Language, cfg.Model, base)
+		if err != nil {
+			return nil, err
+		}
+		return transcriber, nil
+	case "whisper":
+		return speech.NewWhisperTranscriber(cfg.WhisperURL, cfg.WhisperModel, cfg.WhisperAPIKey, base), nil
+	default:
+		return nil, fmt.Errorf("unknown TRANSCRIBER %q", cfg.Provider)
+	}
+}
+
+// InterviewAudioResponseGCF handles POST /api/agents/interview/{sessionId}/respond-audio.
+// The body is multipart/form-data with the recording (webm/opus or wav) in the
+// "audio" field. The other text fields, question_id, question_text and language, are
+// validated and passed on as in /respond. The transcript is submitted to the agent as
+// the answer.
+func InterviewAudioResponseGCF(w http.ResponseWriter, r *http.Request) {
+	w, r, end := tracing.StartServer(w, r, "InterviewAudioResponse")
+	defer end()
+
+	httputils.SetCORSHeaders(w, r)
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		httputils.ErrorJSON(w, "Only POST method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	authedUser, err := auth.VerifyToken(r, firebaseAppSingleton)
+	if err != nil {
+		httputils.ErrorJSON(w, fmt.Sprintf("Unauthorized: %v", err), http.StatusUnauthorized)
+		return
+	}
+
+	userID := authedUser.UID
+	sessionID := extractSessionIDFromPath(r.URL.Path)
+	if sessionID == "" {
+		httputils.ErrorJSON(w, "Session ID not found in path", http.StatusBadRequest)
+		return
+	}
+
+	log.Printf("InterviewAudioResponse: User %s, Session %s", userID, sessionID)
+
+	if transcriber == nil {
+		httputils.ErrorJSON(w, "Spoken answers are not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	answeredAt := time.Now()
+	audio, fields, ok := readAudioUpload(w, r)
+	if !ok {
+		return
+	}
+	respondRequest, err := schema.RespondFromForm(fields)
+	if err != nil {
+		writeRequestError(w, err)
+		return
+	}
+	audio.Language = respondRequest.Language
+
+	session, ok := authorizeSession(w, r, userID, sessionID)
+	if !ok {
+		return
+	}
+
+	// Retries carrying the same Idempotency-Key must not transcribe or submit twice
+	idem, handled := beginIdempotentCall(w, r, userID, "respond/"+sessionID, audio.Data)
+	if handled {
+		return
+	}
+
+	// Replays above are free; transcription is paid per recording
+	if !allowRequest(w, r, userID, ratelimit.ClassTranscription) {
+		idem.abort()
+		return
+	}
+
+	transcript, err := transcriber.Transcribe(r.Context(), audio)
+	if err != nil {
+		idem.abort()
+		if errors.Is(err, speech.ErrNoSpeech) {
+			httputils.ErrorJSON(w, "No speech was detected in the recording", http.StatusUnprocessableEntity)
+			return
+		}
+		log.Printf("InterviewAudioResponse: %s transcription failed: %v", transcriber.Name(), err)
+		httputils.ErrorJSON(w, "Failed to transcribe the recording", http.StatusBadGateway)
+		return
+	}
+
+	stats := speech.StatsFor(audio, transcript)
+	info := audioInfo(audio, transcript, stats)
+	speakingSeconds := int(math.Round(stats.SpeakingTime.Seconds()))
+	log.Printf("InterviewAudioResponse: Transcribed %s of %s audio, %d words at %.0f wpm",
+		stats.Duration.Round(time.Second), audio.Format, stats.WordCount, stats.WordsPerMinute)
+
+	respondRequest.Response = transcript.Text
+	requestBody := respondRequest.Body()
+
+	// The speaking time stands in for the time to answer when the question's
+	// delivery was not recorded
+	submitAnswer(w, r, session, idem, requestBody, answerMeta{
+		AnsweredAt:          answeredAt,
+		Audio:               info,
+		ResponseTimeSeconds: &speakingSeconds,
+	})
+}
+
+// readAudioUpload reads the recording and the text fields of the multipart form.
+// When the upload is unusable it writes the error response and returns false.
+func readAudioUpload(w http.ResponseWriter, r *http.Request) (*speech.Audio, map[string]string, bool) {
+	maxBytes := serviceConfig.Transcription.MaxAudioBytes
+	r.Body = http.MaxBytesReader(w, r.Body, int64(maxBytes+multipartOverhead))
+	if err := r.ParseMultipartForm(int64(maxBytes + multipartOverhead)); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			httputils.ErrorJSON(w, fmt.Sprintf("Recording is larger than %d bytes", maxBytes), http.StatusRequestEntityTooLarge)
+		} else {
+			httputils.ErrorJSON(w, "Expected multipart/form-data with an audio file", http.StatusBadRequest)
+		}
+		return nil, nil, false
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, _, err := r.FormFile(audioFormField)
+	if err != nil {
+		httputils.ErrorJSON(w, "The audio field with a recording is required", http.StatusBadRequest)
+		return nil, nil, false
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, int64(maxBytes)+1))
+	if err != nil {
+		httputils.ErrorJSON(w, "Failed to read the recording", http.StatusBadRequest)
+		return nil, nil, false
+	}
+	if len(data) > maxBytes {
+		httputils.ErrorJSON(w, fmt.Sprintf("Recording is larger than %d bytes", maxBytes), http.StatusRequestEntityTooLarge)
+		return nil, nil, false
+	}
+
+	audio, err := speech.ParseAudio(data)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, speech.ErrUnsupportedFormat) {
+			status = http.StatusUnsupportedMediaType
+		}
+		httputils.ErrorJSON(w, err.Error(), status)
+		return nil, nil, false
+	}
+
+	fields := make(map[string]string)
+	for key, values := range r.MultipartForm.Value {
+		if len(values) > 0 && !audioReservedFields[key] {
+			fields[key] = values[0]
+		}
+	}
+	return audio, fields, true
+}
+
+// audioInfo is the stored description of a spoken answer
+func audioInfo(audio *speech.Audio, transcript *speech.Transcript, stats speech.Stats) *sessions.AudioInfo {
+	return &sessions.AudioInfo{
+		Format:          string(audio.Format),
+		Transcriber:     transcriber.Name(),
+		DurationSeconds: roundTo(stats.Duration.Seconds(), 2),
+		SpeakingSeconds: roundTo(stats.SpeakingTime.Seconds(), 2),
+		WordCount:       stats.WordCount,
+		WordsPerMinute:  roundTo(stats.WordsPerMinute, 1),
+		Confidence:      roundTo(transcript.Confidence, 3),
+		Language:        transcript.Language,
+	}
+}
+
+// roundTo rounds value to the given number of decimal places
+func roundTo(value float64, places int) float64 {
+	scale := math.Pow(10, float64(places))
+	return math.Round(value*scale) / scale
+}
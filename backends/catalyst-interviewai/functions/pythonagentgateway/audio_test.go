@@ -0,0 +1,392 @@
+package pythonagentgateway
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"cloud.google.com/go/firestore/apiv1/firestorepb"
+	firebase "firebase.google.com/go/v4"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"interviewai.wkv.local/pythonagentgateway/internal/agents"
+	"interviewai.wkv.local/pythonagentgateway/internal/config"
+	"interviewai.wkv.local/pythonagentgateway/internal/sessions"
+	"interviewai.wkv.local/pythonagentgateway/internal/speech"
+)
+
+const (
+	testProjectID = "test-project"
+	testUserID    = "user1"
+	testSessionID = "s1"
+)
+
+// fakeFirestore serves documents for reads. Writes are not implemented, so the
+// handlers' transcript bookkeeping fails and is only logged.
+type fakeFirestore struct {
+	firestorepb.UnimplementedFirestoreServer
+	// docs holds the fields of each document by its path, e.g. "users/u1/agentSessions/s1"
+	docs map[string]map[string]*firestorepb.Value
+}
+
+func (f *fakeFirestore) BatchGetDocuments(req *firestorepb.BatchGetDocumentsRequest, stream firestorepb.Firestore_BatchGetDocumentsServer) error {
+	now := timestamppb.Now()
+	for _, name := range req.Documents {
+		response := &firestorepb.BatchGetDocumentsResponse{ReadTime: now}
+		if fields, ok := f.docs[strings.TrimPrefix(name, req.Database+"/documents/")]; ok {
+			response.Result = &firestorepb.BatchGetDocumentsResponse_Found{
+				Found: &firestorepb.Document{Name: name, Fields: fields, CreateTime: now, UpdateTime: now},
+			}
+		} else {
+			response.Result = &firestorepb.BatchGetDocumentsResponse_Missing{Missing: name}
+		}
+		if err := stream.Send(response); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func firestoreString(s string) *firestorepb.Value {
+	return &firestorepb.Value{ValueType: &firestorepb.Value_StringValue{StringValue: s}}
+}
+
+// emulatorIDToken is an unsigned ID token for uid, which the auth emulator accepts
+func emulatorIDToken(uid string) string {
+	now := time.Now().Unix()
+	claims, _ := json.Marshal(map[string]interface{}{
+		"iss": "https://securetoken.google.com/" + testProjectID, "aud": testProjectID,
+		"sub": uid, "iat": now, "exp": now + 3600, "auth_time": now,
+	})
+	return base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","typ":"JWT"}`)) + "." +
+		base64.RawURLEncoding.EncodeToString(claims) + "."
+}
+
+// setUpAudioHandler points the handler at an auth emulator, a Firestore holding
+// session s1 of testUserID, the whisper server and the agent
+func setUpAudioHandler(t *testing.T, whisperURL, agentURL string) {
+	t.Helper()
+	ctx := context.Background()
+
+	authEmulator := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"users":[{"localId":%q}]}`, testUserID)
+	}))
+	t.Cleanup(authEmulator.Close)
+	t.Setenv("FIREBASE_AUTH_EMULATOR_HOST", strings.TrimPrefix(authEmulator.URL, "http://"))
+	app, err := firebase.NewApp(ctx, &firebase.Config{ProjectID: testProjectID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := grpc.NewServer()
+	firestorepb.RegisterFirestoreServer(server, &fakeFirestore{docs: map[string]map[string]*firestorepb.Value{
+		"users/" + testUserID + "/agentSessions/" + testSessionID: {
+			"sessionId":     firestoreString(testSessionID),
+			"userId":        firestoreString(testUserID),
+			"interviewType": firestoreString("behavioral"),
+			"status":        firestoreString(sessions.StatusActive),
+		},
+	}})
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+	conn, err := grpc.Dial(listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	firestoreClient, err := firestore.NewClient(ctx, testProjectID, option.WithGRPCConn(conn))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { firestoreClient.Close() })
+
+	router, err := agents.NewRouter(&agents.Backend{Name: "primary", Weight: 1, Client: agents.NewAgentClient(agentURL, 0)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	oldApp, oldConfig, oldRegistry, oldTranscriber, oldRouter := firebaseAppSingleton, serviceConfig, sessionRegistry, transcriber, agentRouter
+	t.Cleanup(func() {
+		firebaseAppSingleton, serviceConfig, sessionRegistry, transcriber, agentRouter = oldApp, oldConfig, oldRegistry, oldTranscriber, oldRouter
+	})
+	firebaseAppSingleton = app
+	serviceConfig = &config.ServiceConfig{Transcription: config.TranscriptionConfig{MaxAudioBytes: 1 << 20}}
+	sessionRegistry = sessions.NewRegistry(firestoreClient)
+	transcriber = speech.NewWhisperTranscriber(whisperURL, "", "", http.DefaultTransport)
+	agentRouter = router
+}
+
+// testWAV is a silent 16 kHz mono recording
+func testWAV(length time.Duration) []byte {
+	const byteRate = 32000
+	size := int(length.Seconds() * byteRate)
+
+	var buf bytes.Buffer
+	buf.WriteString("RIFF")
+	binary.Write(&buf, binary.LittleEndian, uint32(36+size))
+	buf.WriteString("WAVEfmt ")
+	// PCM, one channel, 16000 samples a second of 2 bytes each
+	for _, v := range []interface{}{uint32(16), uint16(1), uint16(1), uint32(16000), uint32(byteRate), uint16(2), uint16(16)} {
+		binary.Write(&buf, binary.LittleEndian, v)
+	}
+	buf.WriteString("data")
+	binary.Write(&buf, binary.LittleEndian, uint32(size))
+	buf.Write(make([]byte, size))
+	return buf.Bytes()
+}
+
+// audioRequest builds a respond-audio request carrying audio, when set, and fields
+func audioRequest(t *testing.T, sessionID string, audio []byte, fields map[string]string) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	if audio != nil {
+		file, err := form.CreateFormFile(audioFormField, "answer")
+		if err != nil {
+			t.Fatal(err)
+		}
+		file.Write(audio)
+	}
+	for name, value := range fields {
+		form.WriteField(name, value)
+	}
+	form.Close()
+
+	r := httptest.NewRequest(http.MethodPost, "/api/agents/interview/"+sessionID+"/respond-audio", &body)
+	r.Header.Set("Content-Type", form.FormDataContentType())
+	r.Header.Set("Authorization", "Bearer "+emulatorIDToken(testUserID))
+	return r
+}
+
+// whisperWords are n words of 0.4s each, spoken back to back from start
+func whisperWords(n int, start float64) []map[string]interface{} {
+	var words []map[string]interface{}
+	for i := 0; i < n; i++ {
+		at := start + float64(i)*0.4
+		words = append(words, map[string]interface{}{"word": fmt.Sprintf(" word%d", i), "start": at, "end": at + 0.4})
+	}
+	return words
+}
+
+func TestInterviewAudioResponseGCF(t *testing.T) {
+	recording := testWAV(10 * time.Second)
+	const text = "I led the migration of our billing system to event sourcing."
+
+	var mu sync.Mutex
+	var whisperForm map[string]string
+	var whisperFile []byte
+	whisper := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/audio/transcriptions" {
+			http.NotFound(w, r)
+			return
+		}
+		if err := r.ParseMultipartForm(4 << 20); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		data, _ := io.ReadAll(file)
+
+		mu.Lock()
+		whisperFile = data
+		whisperForm = map[string]string{}
+		for name, values := range r.MultipartForm.Value {
+			whisperForm[name] = values[0]
+		}
+		mu.Unlock()
+
+		// 20 words from 1s to 9s
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"text": " " + text + " ", "language": "english", "duration": 10.0, "words": whisperWords(20, 1),
+		})
+	}))
+	defer whisper.Close()
+
+	var agentPath string
+	var agentBody map[string]interface{}
+	agent := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		agentPath = r.URL.Path
+		json.NewDecoder(r.Body).Decode(&agentBody)
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"response":"Thanks. What was the hardest part?","state":"questioning"}`)
+	}))
+	defer agent.Close()
+
+	setUpAudioHandler(t, whisper.URL, agent.URL)
+
+	w := httptest.NewRecorder()
+	InterviewAudioResponseGCF(w, audioRequest(t, testSessionID, recording, map[string]string{
+		"question_id": "q1", "language": "en-US",
+	}))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body.String())
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	// Whisper gets the recording and the language in its own form
+	if !bytes.Equal(whisperFile, recording) {
+		t.Errorf("whisper got %d bytes, want the %d byte recording", len(whisperFile), len(recording))
+	}
+	wantForm := map[string]string{"model": "whisper-1", "response_format": "verbose_json", "timestamp_granularities[]": "word", "language": "en"}
+	if !reflect.DeepEqual(whisperForm, wantForm) {
+		t.Errorf("whisper form = %v, want %v", whisperForm, wantForm)
+	}
+
+	// The agent gets the transcript as the answer and the speaking time as the time to answer
+	if agentPath != "/interview/"+testSessionID+"/respond" {
+		t.Errorf("agent called at %s", agentPath)
+	}
+	for key, want := range map[string]interface{}{
+		"response": text, "responseTime": 8.0, "question_id": "q1", "language": "en-US",
+		"session_id": testSessionID, "user_id": testUserID, "type": "user_response",
+	} {
+		if got := agentBody[key]; got != want {
+			t.Errorf("agent got %s = %v, want %v", key, got, want)
+		}
+	}
+
+	// The candidate gets the agent's reply with what was heard and how it was spoken
+	var response struct {
+		Response   string             `json:"response"`
+		Transcript string             `json:"transcript"`
+		Audio      sessions.AudioInfo `json:"audio"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if response.Response != "Thanks. What was the hardest part?" || response.Transcript != text {
+		t.Errorf("response = %q with transcript %q", response.Response, response.Transcript)
+	}
+	wantAudio := sessions.AudioInfo{
+		Format: "wav", Transcriber: "whisper", DurationSeconds: 10, SpeakingSeconds: 8,
+		WordCount: 20, WordsPerMinute: 150, Language: "english",
+	}
+	if response.Audio != wantAudio {
+		t.Errorf("audio = %+v, want %+v", response.Audio, wantAudio)
+	}
+}
+
+func TestInterviewAudioResponseGCFErrors(t *testing.T) {
+	recording := testWAV(2 * time.Second)
+
+	tests := []struct {
+		name          string
+		sessionID     string // testSessionID when empty
+		audio         []byte
+		fields        map[string]string
+		signedOut     bool
+		contentType   string // the form's when empty
+		whisperStatus int    // 200 when zero
+		whisperBody   string // a short answer when empty
+		wantStatus    int
+		wantWhisper   bool
+	}{
+		{name: "not signed in", audio: recording, signedOut: true, wantStatus: http.StatusUnauthorized},
+		{name: "not a multipart form", audio: recording, contentType: "application/json", wantStatus: http.StatusBadRequest},
+		{name: "no recording", fields: map[string]string{"question_id": "q1"}, wantStatus: http.StatusBadRequest},
+		{name: "recording too large", audio: testWAV(40 * time.Second), wantStatus: http.StatusRequestEntityTooLarge},
+		{name: "unsupported format", audio: []byte("ID3\x03\x00\x00\x00"), wantStatus: http.StatusUnsupportedMediaType},
+		{name: "wav without a data chunk", audio: recording[:36], wantStatus: http.StatusBadRequest},
+		{name: "unknown form field", audio: recording, fields: map[string]string{"difficulty": "hard"}, wantStatus: http.StatusBadRequest},
+		{name: "unknown session", sessionID: "s2", audio: recording, wantStatus: http.StatusNotFound},
+		{
+			name:        "no speech",
+			audio:       recording,
+			whisperBody: `{"text":"  ","duration":2.0}`,
+			wantStatus:  http.StatusUnprocessableEntity,
+			wantWhisper: true,
+		},
+		{
+			name:          "transcriber failing",
+			audio:         recording,
+			whisperStatus: http.StatusInternalServerError,
+			whisperBody:   `{"error":"model not loaded"}`,
+			wantStatus:    http.StatusBadGateway,
+			wantWhisper:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			whisperCalls, agentCalls := 0, 0
+			whisper := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				whisperCalls++
+				mu.Unlock()
+				if tt.whisperStatus != 0 {
+					w.WriteHeader(tt.whisperStatus)
+				}
+				body := tt.whisperBody
+				if body == "" {
+					body = `{"text":"Yes.","duration":2.0}`
+				}
+				fmt.Fprint(w, body)
+			}))
+			defer whisper.Close()
+			agent := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				agentCalls++
+				mu.Unlock()
+				fmt.Fprint(w, `{"response":"Thanks."}`)
+			}))
+			defer agent.Close()
+			setUpAudioHandler(t, whisper.URL, agent.URL)
+
+			sessionID := tt.sessionID
+			if sessionID == "" {
+				sessionID = testSessionID
+			}
+			r := audioRequest(t, sessionID, tt.audio, tt.fields)
+			if tt.signedOut {
+				r.Header.Del("Authorization")
+			}
+			if tt.contentType != "" {
+				r.Header.Set("Content-Type", tt.contentType)
+			}
+
+			w := httptest.NewRecorder()
+			InterviewAudioResponseGCF(w, r)
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d: %s", w.Code, tt.wantStatus, w.Body.String())
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if (whisperCalls > 0) != tt.wantWhisper {
+				t.Errorf("whisper called %d times, want called %v", whisperCalls, tt.wantWhisper)
+			}
+			if agentCalls > 0 {
+				t.Errorf("agent called %d times for a failed answer", agentCalls)
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package pythonagentgateway
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"interviewai.wkv.local/pkg/analytics"
+	"interviewai.wkv.local/pythonagentgateway/internal/sessions"
+)
+
+// analyticsWrites tracks background analytics inserts so a batch job such as the
+// sweeper can wait for them before its function instance is frozen
+var analyticsWrites sync.WaitGroup
+
+// completionStats are the question and answer counts of a finished session
+type completionStats struct {
+	QuestionCount    int // questions the agent asked
+	AnswerCount      int // questions the candidate answered
+	PlannedQuestions int // questions the agent planned for the session, if known
+}
+
+// completionStatsFrom reads question and answer counts from an agent end or status
+// payload. The counts may sit at the top level or in a session_summary object.
+// answersRecorded, the gateway's own count of answered turns, fills in whatever the
+// agent does not report.
+func completionStatsFrom(payload map[string]interface{}, answersRecorded int) completionStats {
+	sources := []map[string]interface{}{payload}
+	for _, key := range []string{"session_summary", "summary", "stats"} {
+		if nested, ok := payload[key].(map[string]interface{}); ok {
+			sources = append(sources, nested)
+		}
+	}
+
+	stats := completionStats{
+		QuestionCount:    firstInt(sources, "questions_asked", "question_count", "questionCount"),
+		AnswerCount:      firstInt(sources, "questions_answered", "responses_received", "answer_count", "response_count"),
+		PlannedQuestions: firstInt(sources, "planned_questions", "total_questions", "max_questions"),
+	}
+	if stats.AnswerCount == 0 {
+		stats.AnswerCount = answersRecorded
+	}
+	if stats.QuestionCount < stats.AnswerCount {
+		// Every answer was to a question, even if the agent lost count
+		stats.QuestionCount = stats.AnswerCount
+	}
+	return stats
+}
+
+// CompletionRate is the percentage of questions answered, measured against the
+// planned question count when the agent reports one. It is nil when no question
+// was asked.
+func (s completionStats) CompletionRate() *float64 {
+	total := s.PlannedQuestions
+	if total == 0 {
+		total = s.QuestionCount
+	}
+	if total == 0 {
+		return nil
+	}
+	rate := float64(s.AnswerCount) / float64(total) * 100
+	if rate > 100 {
+		rate = 100
+	}
+	return &rate
+}
+
+// endStatus decides how an ended session is recorded. A session the candidate left
+// idle past the configured SESSION_IDLE_TIMEOUT is abandoned even if it is ended
+// explicitly later.
+func endStatus(session *sessions.Session, now time.Time) string {
+	if now.Sub(session.LastActivityAt) > serviceConfig.SessionIdleTimeout {
+		return sessions.StatusAbandoned
+	}
+	return sessions.StatusCompleted
+}
+
+// finalizeSession marks a session ended in the registry and writes its final
+// analytics row
+func finalizeSession(ctx context.Context, session *sessions.Session, finalStatus string, payload map[string]interface{}) {
+	endedAt := time.Now().UTC()
+	if err := sessionRegistry.MarkEnded(ctx, session.UserID, session.SessionID, finalStatus); err != nil {
+		log.Printf("Failed to mark session %s %s: %v", session.SessionID, finalStatus, err)
+	}
+
+	stats := completionStatsFrom(payload, session.ResponseCount)
+	log.Printf("Session %s %s after %s: %d/%d questions answered",
+		session.SessionID, finalStatus, sessionDuration(session, finalStatus, endedAt).Round(time.Second), stats.AnswerCount, stats.QuestionCount)
+	recordInterviewEnd(session, finalStatus, endedAt, stats)
+}
+
+// sessionDuration is how long the candidate spent in the session. An abandoned
+// session only counts up to its last activity, not the idle time after it.
+func sessionDuration(session *sessions.Session, finalStatus string, endedAt time.Time) time.Duration {
+	if finalStatus == sessions.StatusAbandoned && session.LastActivityAt.After(session.CreatedAt) && session.LastActivityAt.Before(endedAt) {
+		return session.LastActivityAt.Sub(session.CreatedAt)
+	}
+	return endedAt.Sub(session.CreatedAt)
+}
+
+// recordInterviewEnd logs the final state of a session to BigQuery in the background.
+// Rows are append-only, so this adds the completed (or abandoned) row that
+// supersedes the active one written at start.
+func recordInterviewEnd(session *sessions.Session, finalStatus string, endedAt time.Time, stats completionStats) {
+	if analyticsClient == nil {
+		return
+	}
+
+	analyticsWrites.Add(1)
+	go func() {
+		defer analyticsWrites.Done()
+		row := &analytics.InterviewSession{
+			SessionID:       session.SessionID,
+			UserID:          session.UserID,
+			StartedAt:       session.CreatedAt,
+			EndedAt:         &endedAt,
+			Status:          finalStatus,
+			InterviewType:   session.InterviewType,
+			DurationSeconds: analytics.Int64Ptr(int64(sessionDuration(session, finalStatus, endedAt).Seconds())),
+			QuestionCount:   analytics.IntPtr(stats.QuestionCount),
+			CompletionRate:  stats.CompletionRate(),
+			Metadata: map[string]interface{}{
+				"answer_count":      stats.AnswerCount,
+				"planned_questions": stats.PlannedQuestions,
+				"last_activity_at":  session.LastActivityAt,
+				"agent_variant":     session.Variant,
+				"api_key_source":    session.KeySource,
+			},
+		}
+		if session.TargetRole != "" {
+			row.TargetRole = analytics.StringPtr(session.TargetRole)
+		}
+		if session.Company != "" {
+			row.Company = analytics.StringPtr(session.Company)
+		}
+
+		if err := analyticsClient.InsertInterviewSession(context.Background(), row); err != nil {
+			log.Printf("Failed to log interview completion to BigQuery: %v", err)
+		}
+	}()
+}
+
+// firstInt returns the first numeric value found under any of keys
+func firstInt(sources []map[string]interface{}, keys ...string) int {
+	for _, source := range sources {
+		for _, key := range keys {
+			if v, ok := source[key].(float64); ok {
+				return int(v)
+			}
+		}
+	}
+	return 0
+}
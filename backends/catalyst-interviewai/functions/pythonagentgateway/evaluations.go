@@ -0,0 +1,170 @@
+package pythonagentgateway
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"interviewai.wkv.local/pkg/analytics"
+)
+
+// defaultEvaluatorAgent names the agent that scored an answer when the payload does not
+const defaultEvaluatorAgent = "evaluator"
+
+// The evaluation section of a respond payload.
+//
+// The orchestrator returns its evaluator's assessment of the answer just submitted
+// under "evaluation". The gateway is the only writer of evaluation_scores, so the
+// agents log nothing themselves. The section looks like:
+//
+//	{
+//	  "response_id": "...",          // echoes the response_id sent with the answer
+//	  "evaluation_id": "...",        // optional
+//	  "overall_score": 0.82,
+//	  "score_scale": 1,              // the top score; 100 when absent
+//	  "scores": {"clarity": 0.8, "relevance": 0.9, "depth": 0.7, "structure": 0.85},
+//	  "strengths": ["..."],
+//	  "improvements": ["..."],
+//	  "feedback": "...",
+//	  "evaluator_agent": "evaluator"
+//	}
+//
+// The evaluator's own dimension names (communication_clarity, problem_understanding,
+// technical_depth, solution_approach) are accepted as aliases.
+
+// evaluationScoresFrom extracts the evaluation row from a respond payload. An
+// evaluation that does not name a response is linked to responseID, the ID
+// generated for the answer being submitted.
+func evaluationScoresFrom(sessionID, responseID string, payload map[string]interface{}) []*analytics.EvaluationScore {
+	evaluation, ok := payload["evaluation"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	if row := evaluationScore(sessionID, responseID, evaluation); row != nil {
+		return []*analytics.EvaluationScore{row}
+	}
+	return nil
+}
+
+// evaluationScore converts one evaluation section into a row. It returns nil when
+// the section has no overall score or cannot be linked to a response.
+func evaluationScore(sessionID, responseID string, evaluation map[string]interface{}) *analytics.EvaluationScore {
+	if id, ok := evaluation["response_id"].(string); ok && id != "" {
+		responseID = id
+	}
+	if responseID == "" {
+		return nil
+	}
+
+	scale := 100.0
+	if declared, ok := evaluation["score_scale"].(float64); ok && declared > 0 {
+		scale = declared
+	}
+	overall, ok := scoreValue(evaluation, scale, "overall_score", "overallScore", "score")
+	if !ok {
+		return nil
+	}
+
+	dimensions := evaluation
+	if scores, ok := evaluation["scores"].(map[string]interface{}); ok {
+		dimensions = scores
+	}
+
+	row := &analytics.EvaluationScore{
+		EvaluationID:   stringValue(evaluation, "evaluation_id", "evaluationId"),
+		ResponseID:     responseID,
+		SessionID:      sessionID,
+		EvaluatedAt:    time.Now().UTC(),
+		OverallScore:   overall,
+		ClarityScore:   scorePtr(dimensions, scale, "clarity", "clarity_score", "communication_clarity"),
+		RelevanceScore: scorePtr(dimensions, scale, "relevance", "relevance_score", "problem_understanding"),
+		DepthScore:     scorePtr(dimensions, scale, "depth", "depth_score", "technical_depth"),
+		StructureScore: scorePtr(dimensions, scale, "structure", "structure_score", "solution_approach"),
+		Strengths:      stringsValue(evaluation, "strengths"),
+		Improvements:   stringsValue(evaluation, "improvements", "growth_areas", "areas_for_improvement"),
+		EvaluatorAgent: stringValue(evaluation, "evaluator_agent", "evaluatorAgent", "agent"),
+	}
+	if row.EvaluationID == "" {
+		row.EvaluationID = uuid.New().String()
+	}
+	if row.EvaluatorAgent == "" {
+		row.EvaluatorAgent = defaultEvaluatorAgent
+	}
+
+	// Feedback is either text or the evaluator's {"message": ...} object
+	feedback := stringValue(evaluation, "feedback", "feedback_text")
+	if nested, ok := evaluation["feedback"].(map[string]interface{}); ok {
+		feedback = stringValue(nested, "message", "text")
+	}
+	if feedback != "" {
+		row.FeedbackText = analytics.StringPtr(feedback)
+	}
+
+	return row
+}
+
+// recordEvaluationScores logs evaluation rows to BigQuery in the background
+func recordEvaluationScores(rows []*analytics.EvaluationScore) {
+	if analyticsClient == nil || len(rows) == 0 {
+		return
+	}
+
+	analyticsWrites.Add(1)
+	go func() {
+		defer analyticsWrites.Done()
+		ctx := context.Background()
+		for _, row := range rows {
+			if err := analyticsClient.InsertEvaluationScore(ctx, row); err != nil {
+				log.Printf("Failed to log evaluation score to BigQuery: %v", err)
+			}
+		}
+	}()
+}
+
+// scoreValue reads a score on 0..scale and puts it on the 0-100 scale used by
+// evaluation_scores
+func scoreValue(m map[string]interface{}, scale float64, keys ...string) (float64, bool) {
+	for _, key := range keys {
+		if v, ok := m[key].(float64); ok {
+			return v * 100 / scale, true
+		}
+	}
+	return 0, false
+}
+
+func scorePtr(m map[string]interface{}, scale float64, keys ...string) *float64 {
+	if v, ok := scoreValue(m, scale, keys...); ok {
+		return &v
+	}
+	return nil
+}
+
+func stringValue(m map[string]interface{}, keys ...string) string {
+	for _, key := range keys {
+		if s, ok := m[key].(string); ok && strings.TrimSpace(s) != "" {
+			return strings.TrimSpace(s)
+		}
+	}
+	return ""
+}
+
+func stringsValue(m map[string]interface{}, keys ...string) []string {
+	for _, key := range keys {
+		raw, ok := m[key].([]interface{})
+		if !ok {
+			continue
+		}
+		var result []string
+		for _, item := range raw {
+			if s, ok := item.(string); ok && strings.TrimSpace(s) != "" {
+				result = append(result, strings.TrimSpace(s))
+			}
+		}
+		if len(result) > 0 {
+			return result
+		}
+	}
+	return nil
+}
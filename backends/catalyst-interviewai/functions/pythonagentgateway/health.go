@@ -0,0 +1,107 @@
+package pythonagentgateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"interviewai.wkv.local/pythonagentgateway/internal/agents"
+	"interviewai.wkv.local/pythonagentgateway/internal/health"
+
+	"cloud.google.com/go/firestore"
+	"cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
+	firebaseauth "firebase.google.com/go/v4/auth"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// healthCacheTTL lets uptime checks and the frontend status banner poll freely
+// without each poll hitting every dependency
+const healthCacheTTL = 10 * time.Second
+
+// healthProbeUID is looked up in Firebase Auth to prove the service answers; it never exists
+const healthProbeUID = "health-probe"
+
+// newHealthChecker describes the gateway's dependencies. The agent service, Firebase
+// Auth and Firestore are needed to serve interviews; Secret Manager is only read at
+// cold start and BigQuery only receives analytics, so their failures degrade the
+// gateway rather than take it down.
+func newHealthChecker(firestoreClient *firestore.Client) *health.Checker {
+	checker := health.NewChecker("pythonagentgateway", healthCacheTTL,
+		health.Check{Name: "firebase_auth", Critical: true, Probe: probeFirebaseAuth},
+		health.Check{Name: "firestore", Critical: true, Probe: probeFirestore(firestoreClient)},
+		health.Check{Name: "secret_manager", Probe: probeSecretManager(userContextSigningKeySecret)},
+	)
+	// Each agent backend serves its own sessions, so any of them failing is critical
+	for _, backend := range agentRouter.Backends() {
+		name := "agent"
+		if len(agentRouter.Backends()) > 1 {
+			name = "agent_" + backend.Name
+		}
+		checker.Add(health.Check{Name: name, Critical: true, Timeout: 5 * time.Second, SlowAfter: 2 * time.Second, Probe: probeAgent(backend.Client)})
+	}
+	if analyticsClient != nil {
+		checker.Add(health.Check{Name: "bigquery", Probe: analyticsClient.Ping})
+	}
+	return checker
+}
+
+// probeAgent calls an agent service's own health endpoint. The agent reports
+// "degraded" when one of its sub-agents is impaired.
+func probeAgent(client *agents.AgentClient) health.ProbeFunc {
+	return func(ctx context.Context) error {
+		response, err := client.Health(ctx)
+		if err != nil {
+			return err
+		}
+		switch agentStatus := getStringFromMap(response, "status", ""); agentStatus {
+		case "healthy":
+			return nil
+		case "degraded":
+			return health.Degraded("agent service reports degraded")
+		default:
+			return fmt.Errorf("agent service reports %q", agentStatus)
+		}
+	}
+}
+
+// probeFirebaseAuth looks up a user that does not exist; a not-found answer proves
+// the Auth API is reachable with our credentials
+func probeFirebaseAuth(ctx context.Context) error {
+	client, err := firebaseAppSingleton.Auth(ctx)
+	if err != nil {
+		return err
+	}
+	if _, err := client.GetUser(ctx, healthProbeUID); err != nil && !firebaseauth.IsUserNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// probeFirestore reads a document that need not exist
+func probeFirestore(client *firestore.Client) health.ProbeFunc {
+	return func(ctx context.Context) error {
+		_, err := client.Collection("health").Doc("probe").Get(ctx)
+		if err != nil && status.Code(err) != codes.NotFound {
+			return err
+		}
+		return nil
+	}
+}
+
+// probeSecretManager reads the latest version of secretID. A missing secret still
+// proves Secret Manager is reachable.
+func probeSecretManager(secretID string) health.ProbeFunc {
+	return func(ctx context.Context) error {
+		if secretClientSingleton == nil {
+			return errors.New("Secret Manager client not initialized")
+		}
+		name := fmt.Sprintf("projects/%s/secrets/%s/versions/latest", serviceConfig.GCPProjectID, secretID)
+		_, err := secretClientSingleton.AccessSecretVersion(ctx, &secretmanagerpb.AccessSecretVersionRequest{Name: name})
+		if err != nil && status.Code(err) != codes.NotFound {
+			return err
+		}
+		return nil
+	}
+}
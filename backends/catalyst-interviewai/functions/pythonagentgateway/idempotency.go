@@ -0,0 +1,105 @@
+package pythonagentgateway
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"interviewai.wkv.local/pythonagentgateway/internal/httputils"
+	"interviewai.wkv.local/pythonagentgateway/internal/idempotency"
+)
+
+// idempotencyKeyHeader is the request header clients use to make retries safe
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// idempotentCall tracks a request made under an Idempotency-Key.
+// A nil *idempotentCall is valid and means the request carried no key.
+type idempotentCall struct {
+	recordID string
+}
+
+// beginIdempotentCall reserves the request's Idempotency-Key, if any.
+// For a duplicate request it writes the stored response (or a 409/422) and returns
+// handled=true, in which case the handler must not forward the request again.
+func beginIdempotentCall(w http.ResponseWriter, r *http.Request, userID, scope string, body []byte) (call *idempotentCall, handled bool) {
+	key := strings.TrimSpace(r.Header.Get(idempotencyKeyHeader))
+	if key == "" || idempotencyStore == nil {
+		return nil, false
+	}
+	if err := idempotency.ValidateKey(key); err != nil {
+		httputils.ErrorJSON(w, err.Error(), http.StatusBadRequest)
+		return nil, true
+	}
+
+	record := idempotency.NewRecord(userID, scope, key, body, idempotency.DefaultTTL, idempotency.DefaultLease)
+	existing, err := idempotencyStore.Reserve(r.Context(), record)
+	if err != nil {
+		// Failing open keeps the endpoint usable if the store is unavailable
+		log.Printf("Idempotency store unavailable, processing %s without key: %v", scope, err)
+		return nil, false
+	}
+	if existing == nil {
+		return &idempotentCall{recordID: record.ID}, false
+	}
+
+	switch {
+	case existing.RequestHash != record.RequestHash:
+		httputils.ErrorJSON(w, "Idempotency-Key was already used with a different request body", http.StatusUnprocessableEntity)
+	case existing.State != idempotency.StateCompleted:
+		w.Header().Set("Retry-After", "1")
+		httputils.ErrorJSON(w, "A request with this Idempotency-Key is still in progress", http.StatusConflict)
+	default:
+		log.Printf("Replaying stored response for %s (user %s)", scope, userID)
+		replayIdempotentResponse(w, r, existing)
+	}
+	return nil, true
+}
+
+// replayIdempotentResponse writes a stored response. Streaming clients get it as a
+// single completion event.
+func replayIdempotentResponse(w http.ResponseWriter, r *http.Request, record *idempotency.Record) {
+	w.Header().Set("Idempotent-Replayed", "true")
+
+	if streamRequested(r) {
+		sse, err := httputils.NewSSEWriter(w)
+		if err == nil {
+			sse.Event("done", string(record.Response))
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(record.StatusCode)
+	if _, err := w.Write(record.Response); err != nil {
+		log.Printf("Failed to write replayed response: %v", err)
+	}
+}
+
+// complete stores the response so later duplicates replay it
+func (c *idempotentCall) complete(statusCode int, response interface{}) {
+	if c == nil {
+		return
+	}
+
+	body, err := json.Marshal(response)
+	if err != nil {
+		log.Printf("Failed to encode response for idempotency record: %v", err)
+		c.abort()
+		return
+	}
+	if err := idempotencyStore.Complete(context.Background(), c.recordID, statusCode, body); err != nil {
+		log.Printf("Failed to store idempotent response: %v", err)
+	}
+}
+
+// abort releases the key after a failure so the client can retry
+func (c *idempotentCall) abort() {
+	if c == nil {
+		return
+	}
+	if err := idempotencyStore.Release(context.Background(), c.recordID); err != nil {
+		log.Printf("Failed to release idempotency key: %v", err)
+	}
+}
@@ -0,0 +1,71 @@
+package agents
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// APIKeyHeader carries the Gemini key an agent request runs on, sealed with
+// SealAPIKey so it never travels or gets logged in the clear
+const APIKeyHeader = "X-Agent-API-Key"
+
+// APIKeySourceHeader says whose key APIKeyHeader carries
+const APIKeySourceHeader = "X-Agent-API-Key-Source"
+
+// apiKeyVersion prefixes sealed keys so the format can evolve
+const apiKeyVersion = "v1"
+
+// apiKeyDerivationLabel derives the sealing key from the user context signing key.
+// The Python service derives the same key.
+const apiKeyDerivationLabel = "agent-api-key"
+
+// Sources of the key an agent session runs on
+const (
+	KeySourceUser    = "user"    // the user's own key (BYOK)
+	KeySourceDefault = "default" // the platform's default key
+)
+
+// ErrNoAPIKey is returned by an APIKeyResolver when no key may be used for the user
+var ErrNoAPIKey = errors.New("no API key available")
+
+// APIKey is the Gemini key chosen for a user and where it came from
+type APIKey struct {
+	Value  string
+	Source string
+}
+
+// APIKeyResolver picks the Gemini key the agents use on a user's behalf
+type APIKeyResolver func(ctx context.Context, userID string) (*APIKey, error)
+
+// SealAPIKey encrypts key for userID as "v1.<nonce>.<ciphertext>" (base64url) with
+// AES-256-GCM. The sealing key is HMAC-SHA256(signingKey, "agent-api-key") and the
+// associated data is "v1.<userID>", so a sealed key is only accepted for that user.
+func SealAPIKey(signingKey []byte, userID, key string) (string, error) {
+	mac := hmac.New(sha256.New, signingKey)
+	mac.Write([]byte(apiKeyDerivationLabel))
+
+	block, err := aes.NewCipher(mac.Sum(nil))
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	sealed := gcm.Seal(nil, nonce, []byte(key), []byte(apiKeyVersion+"."+userID))
+
+	return apiKeyVersion + "." + base64.RawURLEncoding.EncodeToString(nonce) + "." +
+		base64.RawURLEncoding.EncodeToString(sealed), nil
+}
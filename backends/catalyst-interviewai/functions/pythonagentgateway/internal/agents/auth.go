@@ -0,0 +1,165 @@
+package agents
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"golang.org/x/oauth2"
+	"google.golang.org/api/idtoken"
+)
+
+// UserContextHeader carries the signed user context to the Python agent service
+const UserContextHeader = "X-User-Context"
+
+// userContextVersion prefixes signed values so the format can evolve
+const userContextVersion = "v1"
+
+// UserContextTTL is how long a signed user context is accepted after signing
+const UserContextTTL = 5 * time.Minute
+
+// DevSigningKey signs user contexts against a local agent service. The Python
+// service accepts the same key when running in development.
+const DevSigningKey = "interview-ai-local-dev-user-context-key"
+
+// tokenRefreshMargin refreshes identity tokens this long before they expire so a
+// token never lapses mid-request
+const tokenRefreshMargin = 5 * time.Minute
+
+// RequestAuthenticator adds service credentials to an outgoing agent request
+type RequestAuthenticator interface {
+	Authenticate(req *http.Request, userID string) error
+}
+
+// UserContext is the signed statement of which user a request is made for
+type UserContext struct {
+	UserID    string `json:"uid"`
+	Source    string `json:"src"`
+	Method    string `json:"mth"`
+	Path      string `json:"pth"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// ServiceAuthenticator attaches a Google-signed identity token for the agent
+// service and an HMAC-signed user context to every request, plus the user's sealed
+// Gemini key when it has an APIKeyResolver
+type ServiceAuthenticator struct {
+	tokens     oauth2.TokenSource
+	signingKey []byte
+	apiKeys    APIKeyResolver
+	now        func() time.Time
+}
+
+// NewServiceAuthenticator mints identity tokens for the Cloud Run service at
+// serviceURL using the function's runtime service account. Tokens are cached and
+// refreshed shortly before they expire.
+func NewServiceAuthenticator(ctx context.Context, serviceURL string, signingKey []byte) (*ServiceAuthenticator, error) {
+	if len(signingKey) == 0 {
+		return nil, errors.New("user context signing key is required")
+	}
+
+	audience, err := AudienceForURL(serviceURL)
+	if err != nil {
+		return nil, err
+	}
+
+	source, err := idtoken.NewTokenSource(ctx, audience)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create identity token source for %s: %w", audience, err)
+	}
+
+	return &ServiceAuthenticator{
+		tokens:     oauth2.ReuseTokenSourceWithExpiry(nil, source, tokenRefreshMargin),
+		signingKey: signingKey,
+		now:        time.Now,
+	}, nil
+}
+
+// NewLocalAuthenticator signs user contexts without an identity token, for a
+// locally running agent service that is not behind Cloud Run IAM
+func NewLocalAuthenticator(signingKey []byte) *ServiceAuthenticator {
+	if len(signingKey) == 0 {
+		signingKey = []byte(DevSigningKey)
+	}
+	return &ServiceAuthenticator{signingKey: signingKey, now: time.Now}
+}
+
+// WithAPIKeys makes the authenticator forward the Gemini key resolver picks for each
+// user, sealed with the signing key
+func (a *ServiceAuthenticator) WithAPIKeys(resolver APIKeyResolver) *ServiceAuthenticator {
+	a.apiKeys = resolver
+	return a
+}
+
+// Authenticate sets the Authorization, user context and API key headers on req
+func (a *ServiceAuthenticator) Authenticate(req *http.Request, userID string) error {
+	if a.tokens != nil {
+		token, err := a.tokens.Token()
+		if err != nil {
+			return fmt.Errorf("failed to get identity token for agent service: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+	}
+
+	now := a.now().UTC()
+	signed, err := SignUserContext(a.signingKey, UserContext{
+		UserID:    userID,
+		Source:    "go-gateway",
+		Method:    req.Method,
+		Path:      req.URL.Path,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(UserContextTTL).Unix(),
+	})
+	if err != nil {
+		return err
+	}
+	req.Header.Set(UserContextHeader, signed)
+
+	// Calls the gateway makes for itself run on the agent service's own credentials
+	if a.apiKeys == nil || userID == SystemUserID {
+		return nil
+	}
+	key, err := a.apiKeys(req.Context(), userID)
+	if err != nil {
+		return err
+	}
+	sealedKey, err := SealAPIKey(a.signingKey, userID, key.Value)
+	if err != nil {
+		return fmt.Errorf("failed to seal API key: %w", err)
+	}
+	req.Header.Set(APIKeyHeader, sealedKey)
+	req.Header.Set(APIKeySourceHeader, key.Source)
+	return nil
+}
+
+// SignUserContext encodes uc as "v1.<payload>.<signature>", where payload is the
+// base64url JSON context and signature is the base64url HMAC-SHA256 of "v1.<payload>"
+func SignUserContext(key []byte, uc UserContext) (string, error) {
+	payload, err := json.Marshal(uc)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode user context: %w", err)
+	}
+
+	signingInput := userContextVersion + "." + base64.RawURLEncoding.EncodeToString(payload)
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(signingInput))
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// AudienceForURL returns the identity token audience for a Cloud Run URL: the
+// scheme and host, without any path
+func AudienceForURL(serviceURL string) (string, error) {
+	u, err := url.Parse(serviceURL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return "", fmt.Errorf("invalid agent service URL %q", serviceURL)
+	}
+	return u.Scheme + "://" + u.Host, nil
+}
@@ -0,0 +1,134 @@
+package agents
+
+import (
+	"sync"
+	"time"
+)
+
+// breakerState is the state of a circuit breaker
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+func (s breakerState) String() string {
+	switch s {
+	case breakerOpen:
+		return "open"
+	case breakerHalfOpen:
+		return "half_open"
+	}
+	return "closed"
+}
+
+// circuitBreaker fails fast after consecutive failures and lets a single probe
+// through once the cooldown has passed
+type circuitBreaker struct {
+	mu            sync.Mutex
+	state         breakerState
+	failures      int
+	threshold     int
+	cooldown      time.Duration
+	openedAt      time.Time
+	probeInFlight bool
+}
+
+// allow reports whether a call may proceed
+func (b *circuitBreaker) allow(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		if now.Sub(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.state = breakerHalfOpen
+		b.probeInFlight = true
+		return true
+	case breakerHalfOpen:
+		if b.probeInFlight {
+			return false
+		}
+		b.probeInFlight = true
+		return true
+	}
+	return true
+}
+
+// record updates the breaker with the outcome of a call
+func (b *circuitBreaker) record(success bool, now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probeInFlight = false
+	if success {
+		b.state = breakerClosed
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.state == breakerHalfOpen || b.failures >= b.threshold {
+		b.state = breakerOpen
+		b.openedAt = now
+	}
+}
+
+// release gives back a half-open probe slot when a call ended without a verdict
+func (b *circuitBreaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probeInFlight = false
+}
+
+func (b *circuitBreaker) snapshot() map[string]interface{} {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return map[string]interface{}{
+		"state":    b.state.String(),
+		"failures": b.failures,
+	}
+}
+
+// breakerSet holds one circuit breaker per agent operation
+type breakerSet struct {
+	mu        sync.Mutex
+	breakers  map[string]*circuitBreaker
+	threshold int
+	cooldown  time.Duration
+}
+
+func newBreakerSet(threshold int, cooldown time.Duration) *breakerSet {
+	return &breakerSet{
+		breakers:  make(map[string]*circuitBreaker),
+		threshold: threshold,
+		cooldown:  cooldown,
+	}
+}
+
+func (s *breakerSet) get(operation string) *circuitBreaker {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, ok := s.breakers[operation]
+	if !ok {
+		b = &circuitBreaker{threshold: s.threshold, cooldown: s.cooldown}
+		s.breakers[operation] = b
+	}
+	return b
+}
+
+func (s *breakerSet) snapshot() map[string]interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := make(map[string]interface{}, len(s.breakers))
+	for operation, b := range s.breakers {
+		result[operation] = b.snapshot()
+	}
+	return result
+}
@@ -4,139 +4,196 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"math/rand"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"interviewai.wkv.local/pythonagentgateway/internal/tracing"
 )
 
-// AgentClient handles communication with Python ADK agents
+// AgentClient handles communication with Python ADK agents.
+// A single client is shared by all handlers so connections are reused across requests.
 type AgentClient struct {
-	baseURL    string
-	httpClient *http.Client
-	timeout    time.Duration
+	baseURL      string
+	httpClient   *http.Client
+	streamClient *http.Client
+	timeout      time.Duration
+	options      Options
+	breakers     *breakerSet
 }
 
-// AgentRequest represents a request to the Python agents
-type AgentRequest struct {
-	Type      string                 `json:"type"`
-	SessionID string                 `json:"session_id,omitempty"`
-	UserID    string                 `json:"user_id"`
-	Data      map[string]interface{} `json:"data,omitempty"`
+// Options tunes retries and circuit breaking for an AgentClient
+type Options struct {
+	// Timeout bounds a single attempt of a non-streaming call
+	Timeout time.Duration
+	// MaxRetries is the number of extra attempts for idempotent calls
+	MaxRetries int
+	// BaseBackoff and MaxBackoff bound the jittered exponential backoff between attempts
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	// BreakerThreshold consecutive failures open an operation's breaker for BreakerCooldown
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+	// Authenticator adds service credentials to each request; nil sends none
+	Authenticator RequestAuthenticator
 }
 
-// AgentResponse represents a response from Python agents
-type AgentResponse struct {
-	Success   bool                   `json:"success"`
-	Data      map[string]interface{} `json:"data,omitempty"`
-	Error     string                 `json:"error,omitempty"`
-	SessionID string                 `json:"session_id,omitempty"`
-	Timestamp string                 `json:"timestamp,omitempty"`
+// DefaultOptions returns the options used by NewAgentClient
+func DefaultOptions() Options {
+	return Options{
+		Timeout:          90 * time.Second, // Default timeout for agent operations
+		MaxRetries:       2,
+		BaseBackoff:      250 * time.Millisecond,
+		MaxBackoff:       4 * time.Second,
+		BreakerThreshold: 5,
+		BreakerCooldown:  30 * time.Second,
+	}
 }
 
 // HealthStatus represents the health status of agents
 type HealthStatus struct {
-	Status        string                    `json:"status"`
-	Timestamp     string                    `json:"timestamp"`
-	Agents        map[string]AgentStatus    `json:"agents"`
-	Infrastructure map[string]interface{}   `json:"infrastructure"`
+	Status         string                 `json:"status"`
+	Timestamp      string                 `json:"timestamp"`
+	Agents         map[string]AgentStatus `json:"agents"`
+	Infrastructure map[string]interface{} `json:"infrastructure"`
 }
 
 // AgentStatus represents individual agent status
 type AgentStatus struct {
-	Name               string `json:"name"`
-	Status             string `json:"status"`
-	LastActivity       string `json:"lastActivity"`
-	UptimeSeconds      int64  `json:"uptimeSeconds"`
-	MessageQueueSize   int    `json:"messageQueueSize"`
+	Name                 string                 `json:"name"`
+	Status               string                 `json:"status"`
+	LastActivity         string                 `json:"lastActivity"`
+	UptimeSeconds        int64                  `json:"uptimeSeconds"`
+	MessageQueueSize     int                    `json:"messageQueueSize"`
 	CircuitBreakerHealth map[string]interface{} `json:"circuitBreakerHealth"`
 }
 
+// SystemUserID is the user the gateway makes its own calls, such as health checks, as
+const SystemUserID = "system"
+
+// Agent operations; each has its own circuit breaker
+const (
+	OpStartInterview = "start_interview"
+	OpSubmitResponse = "submit_response"
+	OpGetStatus      = "get_status"
+	OpEndInterview   = "end_interview"
+	OpGetReport      = "get_report"
+	OpResume         = "resume_interview"
+	OpHealth         = "health"
+)
+
 // NewAgentClient creates a new client for Python agents
 func NewAgentClient(baseURL string, timeout time.Duration) *AgentClient {
-	if timeout == 0 {
-		timeout = 90 * time.Second // Default timeout for agent operations
+	options := DefaultOptions()
+	if timeout != 0 {
+		options.Timeout = timeout
+	}
+	return NewAgentClientWithOptions(baseURL, options)
+}
+
+// NewAgentClientWithOptions creates a new client for Python agents with explicit resilience settings
+func NewAgentClientWithOptions(baseURL string, options Options) *AgentClient {
+	defaults := DefaultOptions()
+	if options.Timeout <= 0 {
+		options.Timeout = defaults.Timeout
+	}
+	if options.BaseBackoff <= 0 {
+		options.BaseBackoff = defaults.BaseBackoff
+	}
+	if options.MaxBackoff <= 0 {
+		options.MaxBackoff = defaults.MaxBackoff
+	}
+	if options.BreakerThreshold <= 0 {
+		options.BreakerThreshold = defaults.BreakerThreshold
+	}
+	if options.BreakerCooldown <= 0 {
+		options.BreakerCooldown = defaults.BreakerCooldown
 	}
 
+	base := http.DefaultTransport.(*http.Transport).Clone()
+	base.MaxIdleConnsPerHost = 20
+	// Each attempt gets a client span and carries traceparent to the agent service
+	transport := tracing.Transport(base)
+
 	return &AgentClient{
-		baseURL: baseURL,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
 		httpClient: &http.Client{
-			Timeout: timeout,
+			Transport: transport,
+			Timeout:   options.Timeout,
 		},
-		timeout: timeout,
+		// Streams are bounded by the request context rather than a client timeout
+		streamClient: &http.Client{Transport: transport},
+		timeout:      options.Timeout,
+		options:      options,
+		breakers:     newBreakerSet(options.BreakerThreshold, options.BreakerCooldown),
 	}
 }
 
-// StartInterview starts a new interview session
-func (c *AgentClient) StartInterview(ctx context.Context, userID string, request map[string]interface{}) (*AgentResponse, error) {
-	agentReq := AgentRequest{
-		Type:   "start_interview",
-		UserID: userID,
-		Data:   request,
-	}
+// BaseURL returns the agent service URL this client talks to
+func (c *AgentClient) BaseURL() string {
+	return c.baseURL
+}
 
-	return c.makeRequest(ctx, "POST", "/interview/start", agentReq)
+// StartInterview starts a new interview session
+func (c *AgentClient) StartInterview(ctx context.Context, userID string, request map[string]interface{}) (map[string]interface{}, error) {
+	return c.call(ctx, OpStartInterview, http.MethodPost, "/interview/start", request, userID, false)
 }
 
 // SubmitResponse submits a user response to the interview
-func (c *AgentClient) SubmitResponse(ctx context.Context, sessionID, userID string, response map[string]interface{}) (*AgentResponse, error) {
-	agentReq := AgentRequest{
-		Type:      "user_response",
-		SessionID: sessionID,
-		UserID:    userID,
-		Data:      response,
-	}
+func (c *AgentClient) SubmitResponse(ctx context.Context, sessionID, userID string, request map[string]interface{}) (map[string]interface{}, error) {
+	endpoint := fmt.Sprintf("/interview/%s/respond", url.PathEscape(sessionID))
+	return c.call(ctx, OpSubmitResponse, http.MethodPost, endpoint, request, userID, false)
+}
 
-	return c.makeRequest(ctx, "POST", fmt.Sprintf("/interview/%s/respond", sessionID), agentReq)
+// ResumeInterview rebuilds a session the agent no longer holds from its transcript.
+// The agent treats a session it already holds as resumed, so the call is retried.
+func (c *AgentClient) ResumeInterview(ctx context.Context, sessionID, userID string, request map[string]interface{}) (map[string]interface{}, error) {
+	endpoint := fmt.Sprintf("/interview/%s/resume", url.PathEscape(sessionID))
+	return c.call(ctx, OpResume, http.MethodPost, endpoint, request, userID, true)
 }
 
 // GetSessionStatus gets the current status of an interview session
-func (c *AgentClient) GetSessionStatus(ctx context.Context, sessionID, userID string) (*AgentResponse, error) {
-	agentReq := AgentRequest{
-		Type:      "get_status",
-		SessionID: sessionID,
-		UserID:    userID,
-	}
-
-	return c.makeRequest(ctx, "GET", fmt.Sprintf("/interview/%s/status", sessionID), agentReq)
+func (c *AgentClient) GetSessionStatus(ctx context.Context, sessionID, userID string) (map[string]interface{}, error) {
+	endpoint := fmt.Sprintf("/interview/%s/status", url.PathEscape(sessionID))
+	return c.call(ctx, OpGetStatus, http.MethodGet, endpoint, nil, userID, true)
 }
 
 // EndInterview ends an interview session
-func (c *AgentClient) EndInterview(ctx context.Context, sessionID, userID string) (*AgentResponse, error) {
-	agentReq := AgentRequest{
-		Type:      "end_interview",
-		SessionID: sessionID,
-		UserID:    userID,
-	}
-
-	return c.makeRequest(ctx, "POST", fmt.Sprintf("/interview/%s/end", sessionID), agentReq)
+func (c *AgentClient) EndInterview(ctx context.Context, sessionID, userID string, request map[string]interface{}) (map[string]interface{}, error) {
+	endpoint := fmt.Sprintf("/interview/%s/end", url.PathEscape(sessionID))
+	return c.call(ctx, OpEndInterview, http.MethodPost, endpoint, request, userID, false)
 }
 
 // GetReport retrieves the interview report
-func (c *AgentClient) GetReport(ctx context.Context, sessionID, userID, format string) (*AgentResponse, error) {
-	endpoint := fmt.Sprintf("/report/%s", sessionID)
+func (c *AgentClient) GetReport(ctx context.Context, sessionID, userID, format string) (map[string]interface{}, error) {
+	endpoint := fmt.Sprintf("/report/%s", url.PathEscape(sessionID))
 	if format != "" {
-		endpoint += "?format=" + format
-	}
-
-	agentReq := AgentRequest{
-		Type:      "get_report",
-		SessionID: sessionID,
-		UserID:    userID,
+		endpoint += "?format=" + url.QueryEscape(format)
 	}
+	return c.call(ctx, OpGetReport, http.MethodGet, endpoint, nil, userID, true)
+}
 
-	return c.makeRequest(ctx, "GET", endpoint, agentReq)
+// Health returns the raw health payload of the Python agent infrastructure
+func (c *AgentClient) Health(ctx context.Context) (map[string]interface{}, error) {
+	return c.call(ctx, OpHealth, http.MethodGet, "/health", nil, SystemUserID, true)
 }
 
 // GetHealth checks the health of the Python agent infrastructure
 func (c *AgentClient) GetHealth(ctx context.Context) (*HealthStatus, error) {
-	resp, err := c.makeRequest(ctx, "GET", "/health", nil)
+	payload, err := c.Health(ctx)
 	if err != nil {
 		return nil, err
 	}
 
 	// Convert response to HealthStatus
-	healthData, err := json.Marshal(resp.Data)
+	healthData, err := json.Marshal(payload)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal health data: %w", err)
 	}
@@ -149,57 +206,194 @@ func (c *AgentClient) GetHealth(ctx context.Context) (*HealthStatus, error) {
 	return &health, nil
 }
 
-// makeRequest makes an HTTP request to the Python agent service
-func (c *AgentClient) makeRequest(ctx context.Context, method, endpoint string, body interface{}) (*AgentResponse, error) {
-	// Construct URL
-	url := c.baseURL + endpoint
+// OpenStream starts a streaming call and returns the live response for the caller to
+// read and close. It goes through the operation's circuit breaker but is never retried.
+func (c *AgentClient) OpenStream(ctx context.Context, operation, method, endpoint string, body interface{}, userID string) (*http.Response, error) {
+	breaker := c.breakers.get(operation)
+	if !breaker.allow(time.Now()) {
+		return nil, &AgentError{Kind: KindUnavailable, Operation: operation, Err: ErrCircuitOpen}
+	}
 
-	// Prepare request body
-	var requestBody io.Reader
-	if body != nil {
-		bodyBytes, err := json.Marshal(body)
-		if err != nil {
-			return nil, fmt.Errorf("failed to marshal request body: %w", err)
-		}
-		requestBody = bytes.NewBuffer(bodyBytes)
+	req, err := c.newRequest(ctx, method, endpoint, body, userID)
+	if err != nil {
+		breaker.release()
+		return nil, err
 	}
+	req.Header.Set("Accept", "text/event-stream")
 
-	// Create request
-	req, err := http.NewRequestWithContext(ctx, method, url, requestBody)
+	resp, err := c.streamClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		agentErr := classifyTransportError(ctx, operation, err)
+		c.recordOutcome(breaker, agentErr)
+		return nil, agentErr
 	}
 
-	// Set headers
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Accept", "application/json")
-	req.Header.Set("X-Request-Source", "go-gateway")
+	if resp.StatusCode >= 400 {
+		defer resp.Body.Close()
+		errorBody, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		agentErr := classifyStatus(operation, resp.StatusCode, errorMessage(errorBody))
+		c.recordOutcome(breaker, agentErr)
+		return nil, agentErr
+	}
+
+	breaker.record(true, time.Now())
+	return resp, nil
+}
+
+// BreakerStates reports the circuit breaker state of every operation seen so far
+func (c *AgentClient) BreakerStates() map[string]interface{} {
+	return c.breakers.snapshot()
+}
+
+// call performs a JSON request, retrying idempotent operations with jittered exponential backoff
+func (c *AgentClient) call(ctx context.Context, operation, method, endpoint string, body interface{}, userID string, idempotent bool) (result map[string]interface{}, err error) {
+	// One span per operation groups its attempts and the backoff between them
+	ctx, span := tracing.Start(ctx, "agent."+operation, trace.SpanKindInternal,
+		attribute.String("agent.operation", operation),
+		attribute.String("agent.base_url", c.baseURL),
+	)
+	attempts := 1
+	if idempotent {
+		attempts += c.options.MaxRetries
+	}
+
+	tried := 0
+	defer func() {
+		span.SetAttributes(attribute.Int("agent.attempts", tried))
+		tracing.End(span, err)
+	}()
+
+	var lastErr error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			if err := c.sleep(ctx, attempt); err != nil {
+				return nil, classifyTransportError(ctx, operation, err)
+			}
+		}
+
+		tried++
+		result, err := c.attempt(ctx, operation, method, endpoint, body, userID)
+		if err == nil {
+			return result, nil
+		}
+		lastErr = err
+
+		if !retryable(err) {
+			break
+		}
+	}
+
+	return nil, lastErr
+}
+
+// attempt performs a single request through the operation's circuit breaker
+func (c *AgentClient) attempt(ctx context.Context, operation, method, endpoint string, body interface{}, userID string) (map[string]interface{}, error) {
+	breaker := c.breakers.get(operation)
+	if !breaker.allow(time.Now()) {
+		return nil, &AgentError{Kind: KindUnavailable, Operation: operation, Err: ErrCircuitOpen}
+	}
+
+	result, err := c.makeRequest(ctx, operation, method, endpoint, body, userID)
+	c.recordOutcome(breaker, err)
+	return result, err
+}
+
+// recordOutcome updates the operation's breaker with the result of a call
+func (c *AgentClient) recordOutcome(breaker *circuitBreaker, err error) {
+	var agentErr *AgentError
+	switch {
+	case err == nil:
+		breaker.record(true, time.Now())
+	case countsAsFailure(err):
+		breaker.record(false, time.Now())
+	case errors.As(err, &agentErr) && agentErr.Kind == KindRejected:
+		// The agent answered, so the endpoint is alive
+		breaker.record(true, time.Now())
+	default:
+		// Canceled by the caller or failed before sending: no verdict
+		breaker.release()
+	}
+}
+
+// makeRequest makes an HTTP request to the Python agent service
+func (c *AgentClient) makeRequest(ctx context.Context, operation, method, endpoint string, body interface{}, userID string) (map[string]interface{}, error) {
+	req, err := c.newRequest(ctx, method, endpoint, body, userID)
+	if err != nil {
+		return nil, err
+	}
 
 	// Make request
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
+		return nil, classifyTransportError(ctx, operation, err)
 	}
 	defer resp.Body.Close()
 
 	// Read response
 	responseBody, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
+		return nil, classifyTransportError(ctx, operation, err)
+	}
+
+	// Check for HTTP errors
+	if resp.StatusCode >= 400 {
+		return nil, classifyStatus(operation, resp.StatusCode, errorMessage(responseBody))
 	}
 
 	// Parse response
-	var agentResp AgentResponse
-	if err := json.Unmarshal(responseBody, &agentResp); err != nil {
-		return nil, fmt.Errorf("failed to parse response: %w", err)
+	var result map[string]interface{}
+	if err := json.Unmarshal(responseBody, &result); err != nil {
+		return nil, &AgentError{Kind: KindBadGateway, Operation: operation, StatusCode: resp.StatusCode, Message: "invalid JSON response", Err: err}
 	}
 
-	// Check for HTTP errors
-	if resp.StatusCode >= 400 {
-		return &agentResp, fmt.Errorf("agent returned error %d: %s", resp.StatusCode, agentResp.Error)
+	return result, nil
+}
+
+// newRequest builds a request to the agent service with the gateway's standard headers
+func (c *AgentClient) newRequest(ctx context.Context, method, endpoint string, body interface{}, userID string) (*http.Request, error) {
+	var requestBody io.Reader
+	if body != nil {
+		bodyBytes, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal request body: %w", err)
+		}
+		requestBody = bytes.NewReader(bodyBytes)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+endpoint, requestBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	c.SetUserContext(req, userID)
+	if c.options.Authenticator != nil {
+		if err := c.options.Authenticator.Authenticate(req, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	return req, nil
+}
+
+// sleep waits before the given retry attempt using full-jitter exponential backoff
+func (c *AgentClient) sleep(ctx context.Context, attempt int) error {
+	backoff := c.options.BaseBackoff << uint(attempt-1)
+	if backoff <= 0 || backoff > c.options.MaxBackoff {
+		backoff = c.options.MaxBackoff
 	}
+	delay := time.Duration(rand.Int63n(int64(backoff) + 1))
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
 
-	return &agentResp, nil
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
 }
 
 // SetUserContext adds user context headers to requests
@@ -220,12 +414,12 @@ func (c *AgentClient) IsHealthy(ctx context.Context) bool {
 // WaitForReady waits for the agent service to be ready
 func (c *AgentClient) WaitForReady(ctx context.Context, maxWait time.Duration) error {
 	deadline := time.Now().Add(maxWait)
-	
+
 	for time.Now().Before(deadline) {
 		if c.IsHealthy(ctx) {
 			return nil
 		}
-		
+
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
@@ -233,6 +427,19 @@ func (c *AgentClient) WaitForReady(ctx context.Context, maxWait time.Duration) e
 			// Continue checking
 		}
 	}
-	
+
 	return fmt.Errorf("agent service not ready after %v", maxWait)
-}
\ No newline at end of file
+}
+
+// errorMessage extracts a readable message from an agent error body
+func errorMessage(body []byte) string {
+	var payload map[string]interface{}
+	if err := json.Unmarshal(body, &payload); err == nil {
+		for _, key := range []string{"error", "detail", "message"} {
+			if msg, ok := payload[key].(string); ok && msg != "" {
+				return msg
+			}
+		}
+	}
+	return strings.TrimSpace(string(body))
+}
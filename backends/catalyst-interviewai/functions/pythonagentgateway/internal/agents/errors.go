@@ -0,0 +1,155 @@
+package agents
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+)
+
+// ErrorKind classifies failures talking to the Python agent service
+type ErrorKind int
+
+const (
+	// KindUnavailable means the agent could not be reached or is shedding load
+	KindUnavailable ErrorKind = iota
+	// KindTimeout means the agent did not answer in time
+	KindTimeout
+	// KindBadGateway means the agent answered with a server error or an unreadable body
+	KindBadGateway
+	// KindRejected means the agent rejected the request (4xx); the status is passed through
+	KindRejected
+	// KindCanceled means the caller went away before the agent answered
+	KindCanceled
+)
+
+// ErrCircuitOpen is returned without contacting the agent while an endpoint's breaker is open
+var ErrCircuitOpen = errors.New("agent circuit breaker open")
+
+// AgentError describes a failed call to the Python agent service
+type AgentError struct {
+	Kind       ErrorKind
+	Operation  string
+	StatusCode int // Upstream status code, if the agent answered
+	Message    string
+	Err        error
+}
+
+func (e *AgentError) Error() string {
+	if e.StatusCode != 0 {
+		return fmt.Sprintf("agent %s failed with status %d: %s", e.Operation, e.StatusCode, e.Message)
+	}
+	if e.Err != nil {
+		return fmt.Sprintf("agent %s failed: %v", e.Operation, e.Err)
+	}
+	return fmt.Sprintf("agent %s failed: %s", e.Operation, e.Message)
+}
+
+func (e *AgentError) Unwrap() error {
+	return e.Err
+}
+
+// HTTPStatus returns the status code the gateway should answer with
+func (e *AgentError) HTTPStatus() int {
+	switch e.Kind {
+	case KindUnavailable:
+		return http.StatusServiceUnavailable
+	case KindTimeout:
+		return http.StatusGatewayTimeout
+	case KindBadGateway:
+		return http.StatusBadGateway
+	case KindRejected:
+		if e.StatusCode >= 400 && e.StatusCode < 500 {
+			return e.StatusCode
+		}
+		return http.StatusBadGateway
+	case KindCanceled:
+		return 499 // Client closed request
+	}
+	return http.StatusInternalServerError
+}
+
+// HTTPStatus maps any error returned by AgentClient to a gateway status code
+func HTTPStatus(err error) int {
+	var agentErr *AgentError
+	if errors.As(err, &agentErr) {
+		return agentErr.HTTPStatus()
+	}
+	if errors.Is(err, ErrNoAPIKey) {
+		return http.StatusForbidden
+	}
+	return http.StatusInternalServerError
+}
+
+// IsRejected reports whether the agent answered err with the given 4xx status
+func IsRejected(err error, statusCode int) bool {
+	var agentErr *AgentError
+	return errors.As(err, &agentErr) && agentErr.Kind == KindRejected && agentErr.StatusCode == statusCode
+}
+
+// classifyTransportError turns an error from http.Client.Do into an AgentError
+func classifyTransportError(ctx context.Context, operation string, err error) *AgentError {
+	agentErr := &AgentError{Kind: KindUnavailable, Operation: operation, Err: err}
+
+	var netErr net.Error
+	switch {
+	case errors.Is(ctx.Err(), context.Canceled):
+		agentErr.Kind = KindCanceled
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		agentErr.Kind = KindTimeout
+	}
+	return agentErr
+}
+
+// classifyStatus turns a non-2xx agent response into an AgentError
+func classifyStatus(operation string, statusCode int, message string) *AgentError {
+	agentErr := &AgentError{Operation: operation, StatusCode: statusCode, Message: message}
+
+	switch {
+	case statusCode == http.StatusServiceUnavailable, statusCode == http.StatusTooManyRequests:
+		agentErr.Kind = KindUnavailable
+	case statusCode == http.StatusGatewayTimeout:
+		agentErr.Kind = KindTimeout
+	case statusCode >= 500:
+		agentErr.Kind = KindBadGateway
+	default:
+		agentErr.Kind = KindRejected
+	}
+	return agentErr
+}
+
+// retryable reports whether a failed idempotent call is worth repeating
+func retryable(err error) bool {
+	var agentErr *AgentError
+	if !errors.As(err, &agentErr) {
+		return false
+	}
+	switch agentErr.Kind {
+	case KindUnavailable, KindTimeout:
+		return !errors.Is(err, ErrCircuitOpen)
+	case KindBadGateway:
+		return agentErr.StatusCode >= 500
+	}
+	return false
+}
+
+// IsTransient reports whether err may clear up if the call is made again later,
+// as a queued retry would. Unlike retryable it includes an open circuit breaker,
+// which closes again after its cool-down.
+func IsTransient(err error) bool {
+	return errors.Is(err, ErrCircuitOpen) || retryable(err)
+}
+
+// countsAsFailure reports whether err should trip the endpoint's circuit breaker
+func countsAsFailure(err error) bool {
+	var agentErr *AgentError
+	if !errors.As(err, &agentErr) {
+		return false
+	}
+	switch agentErr.Kind {
+	case KindUnavailable, KindTimeout, KindBadGateway:
+		return agentErr.StatusCode != http.StatusTooManyRequests
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package agents
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+)
+
+// Backend is one agent deployment sessions can be assigned to
+type Backend struct {
+	Name   string
+	Weight int
+	Client *AgentClient
+}
+
+// Router splits new sessions across weighted backends. Assignment is a pure function
+// of the routing key, so every gateway instance makes the same choice.
+type Router struct {
+	backends []*Backend
+	byName   map[string]*Backend
+	total    int
+}
+
+// NewRouter creates a router. The first backend is the primary one, used for
+// sessions that predate routing or whose backend is no longer configured.
+func NewRouter(backends ...*Backend) (*Router, error) {
+	if len(backends) == 0 {
+		return nil, errors.New("at least one agent backend is required")
+	}
+
+	router := &Router{backends: backends, byName: make(map[string]*Backend)}
+	for _, backend := range backends {
+		if _, exists := router.byName[backend.Name]; exists {
+			return nil, fmt.Errorf("agent backend %q is configured twice", backend.Name)
+		}
+		if backend.Weight < 0 {
+			return nil, fmt.Errorf("agent backend %q has a negative weight", backend.Name)
+		}
+		router.byName[backend.Name] = backend
+		router.total += backend.Weight
+	}
+	if router.total == 0 {
+		return nil, errors.New("agent backend weights add up to zero")
+	}
+	return router, nil
+}
+
+// Assign picks the backend for a new session. The same key maps to the same backend
+// for as long as the weights are unchanged.
+func (r *Router) Assign(key string) *Backend {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	point := int(h.Sum64() % uint64(r.total))
+
+	for _, backend := range r.backends {
+		if point < backend.Weight {
+			return backend
+		}
+		point -= backend.Weight
+	}
+	return r.backends[0]
+}
+
+// Backend returns the named backend. Unknown or empty names get the primary backend
+// and found=false.
+func (r *Router) Backend(name string) (backend *Backend, found bool) {
+	if backend, ok := r.byName[name]; ok {
+		return backend, true
+	}
+	return r.backends[0], false
+}
+
+// Primary returns the first configured backend
+func (r *Router) Primary() *Backend {
+	return r.backends[0]
+}
+
+// Backends returns every configured backend, primary first
+func (r *Router) Backends() []*Backend {
+	return r.backends
+}
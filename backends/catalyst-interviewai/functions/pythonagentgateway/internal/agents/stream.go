@@ -0,0 +1,109 @@
+package agents
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// StreamEvent is a single event read from a streaming agent response
+type StreamEvent struct {
+	Event string `json:"event"`
+	Data  string `json:"data"`
+	ID    string `json:"id,omitempty"`
+}
+
+// Event names used by the agents when streaming a turn
+const (
+	StreamEventToken = "token"
+	StreamEventDone  = "done"
+	StreamEventError = "error"
+)
+
+// maxStreamLineSize bounds a single line of the upstream stream
+const maxStreamLineSize = 1024 * 1024
+
+// ReadStream reads a streaming agent response and calls fn for every event.
+// Server-Sent Events are parsed field by field. A JSON body is an agent that
+// answered the whole turn at once, so it becomes the done event. Any other content
+// type is treated as newline-delimited chunks, each of which becomes a token event.
+func ReadStream(body io.Reader, contentType string, fn func(StreamEvent) error) error {
+	if strings.HasPrefix(contentType, "application/json") {
+		data, err := io.ReadAll(io.LimitReader(body, maxStreamLineSize+1))
+		if err != nil {
+			return err
+		}
+		if len(data) > maxStreamLineSize {
+			return fmt.Errorf("agent response exceeds %d bytes", maxStreamLineSize)
+		}
+		return fn(StreamEvent{Event: StreamEventDone, Data: string(data)})
+	}
+
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxStreamLineSize)
+
+	if !strings.HasPrefix(contentType, "text/event-stream") {
+		for scanner.Scan() {
+			line := scanner.Text()
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+			if err := fn(StreamEvent{Event: StreamEventToken, Data: line}); err != nil {
+				return err
+			}
+		}
+		return scanner.Err()
+	}
+
+	var current StreamEvent
+	var data []string
+	hasData := false
+
+	dispatch := func() error {
+		if !hasData {
+			current = StreamEvent{}
+			return nil
+		}
+		current.Data = strings.Join(data, "\n")
+		if current.Event == "" {
+			current.Event = StreamEventToken
+		}
+		err := fn(current)
+		current = StreamEvent{}
+		data = data[:0]
+		hasData = false
+		return err
+	}
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			if err := dispatch(); err != nil {
+				return err
+			}
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue // Comment, usually an upstream heartbeat
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			current.Event = value
+		case "data":
+			data = append(data, value)
+			hasData = true
+		case "id":
+			current.ID = value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	// Flush a trailing event that was not terminated by a blank line
+	return dispatch()
+}
@@ -0,0 +1,98 @@
+// Package audit records what staff do through the admin endpoints, including
+// attempts that were refused, so every intervention in a user's session can be
+// traced to the person who made it
+package audit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/google/uuid"
+)
+
+// Collection is the top-level Firestore collection audit entries are written to
+const Collection = "adminAuditLog"
+
+// Admin actions
+const (
+	ActionListSessions = "list_sessions"
+	ActionViewSession  = "view_session"
+	ActionEndSession   = "end_session"
+	ActionReplayTurn   = "replay_turn"
+)
+
+// Outcomes of an admin action
+const (
+	OutcomeSucceeded = "succeeded"
+	OutcomeFailed    = "failed"
+	// OutcomePending is an action that changes a session and has not finished. An
+	// entry left pending is one whose outcome could not be recorded.
+	OutcomePending = "pending"
+	// OutcomeDenied is an attempt by a caller without the admin claim
+	OutcomeDenied = "denied"
+)
+
+// Entry is one admin action. Actions that change a session are written as pending
+// before they are taken and updated once with their outcome; other entries are only
+// ever added.
+type Entry struct {
+	EntryID    string `firestore:"entryId" json:"entryId"`
+	Action     string `firestore:"action" json:"action"`
+	ActorID    string `firestore:"actorId" json:"actorId"`
+	ActorEmail string `firestore:"actorEmail,omitempty" json:"actorEmail,omitempty"`
+	// TargetUserID and SessionID identify the session acted on, if any
+	TargetUserID string `firestore:"targetUserId,omitempty" json:"targetUserId,omitempty"`
+	SessionID    string `firestore:"sessionId,omitempty" json:"sessionId,omitempty"`
+	// Reason is the justification the admin gave, when the action asks for one
+	Reason     string                 `firestore:"reason,omitempty" json:"reason,omitempty"`
+	Details    map[string]interface{} `firestore:"details,omitempty" json:"details,omitempty"`
+	Outcome    string                 `firestore:"outcome" json:"outcome"`
+	StatusCode int                    `firestore:"statusCode" json:"statusCode"`
+	Error      string                 `firestore:"error,omitempty" json:"error,omitempty"`
+	At         time.Time              `firestore:"at" json:"at"`
+	// CompletedAt is when a pending entry was given its outcome
+	CompletedAt *time.Time `firestore:"completedAt,omitempty" json:"completedAt,omitempty"`
+}
+
+// Log writes audit entries to Firestore
+type Log struct {
+	client *firestore.Client
+}
+
+// NewLog creates an audit log backed by Firestore
+func NewLog(client *firestore.Client) *Log {
+	return &Log{client: client}
+}
+
+// Record adds an entry, filling in its ID and time
+func (l *Log) Record(ctx context.Context, entry *Entry) error {
+	entry.EntryID = uuid.New().String()
+	if entry.At.IsZero() {
+		entry.At = time.Now().UTC()
+	}
+
+	if _, err := l.client.Collection(Collection).Doc(entry.EntryID).Create(ctx, entry); err != nil {
+		return fmt.Errorf("failed to record admin action %s: %w", entry.Action, err)
+	}
+	return nil
+}
+
+// Begin adds the entry as pending before its action is taken, so the action is on
+// record even if its outcome never is
+func (l *Log) Begin(ctx context.Context, entry *Entry) error {
+	entry.Outcome = OutcomePending
+	return l.Record(ctx, entry)
+}
+
+// Complete writes the outcome of an entry added by Begin
+func (l *Log) Complete(ctx context.Context, entry *Entry) error {
+	now := time.Now().UTC()
+	entry.CompletedAt = &now
+
+	if _, err := l.client.Collection(Collection).Doc(entry.EntryID).Set(ctx, entry); err != nil {
+		return fmt.Errorf("failed to complete admin action %s: %w", entry.Action, err)
+	}
+	return nil
+}
@@ -0,0 +1,27 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+
+	firebase "firebase.google.com/go/v4"
+	"firebase.google.com/go/v4/auth"
+)
+
+// ErrNotAdmin is returned when a verified caller lacks the admin claim
+var ErrNotAdmin = errors.New("caller is not an administrator")
+
+// VerifyAdmin verifies the Firebase ID token like VerifyToken and requires the custom
+// claim to be true on it. Callers without the claim get their token back together
+// with ErrNotAdmin, so the refusal can be attributed.
+func VerifyAdmin(r *http.Request, firebaseApp *firebase.App, claim string) (*auth.Token, error) {
+	token, err := VerifyToken(r, firebaseApp)
+	if err != nil {
+		return nil, err
+	}
+
+	if isAdmin, _ := token.Claims[claim].(bool); !isAdmin || claim == "" {
+		return token, ErrNotAdmin
+	}
+	return token, nil
+}
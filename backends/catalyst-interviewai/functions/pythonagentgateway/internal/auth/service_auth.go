@@ -0,0 +1,40 @@
+package auth
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"google.golang.org/api/idtoken"
+)
+
+// VerifyServiceAccount checks that the request carries a Google-signed identity token
+// for the service account email, as Cloud Scheduler sends with an OIDC token. The
+// token must have been minted for audience, so a token the account obtained for
+// another service cannot be replayed here.
+func VerifyServiceAccount(r *http.Request, audience, email string) error {
+	if email == "" {
+		return fmt.Errorf("no service account is allowed to call this endpoint")
+	}
+	if audience == "" {
+		return fmt.Errorf("no identity token audience is configured for this endpoint")
+	}
+
+	const bearerPrefix = "Bearer "
+	authHeader := r.Header.Get("Authorization")
+	if !strings.HasPrefix(authHeader, bearerPrefix) {
+		return fmt.Errorf("authorization header must start with 'Bearer '")
+	}
+
+	payload, err := idtoken.Validate(r.Context(), strings.TrimPrefix(authHeader, bearerPrefix), audience)
+	if err != nil {
+		return fmt.Errorf("failed to verify identity token: %w", err)
+	}
+
+	tokenEmail, _ := payload.Claims["email"].(string)
+	verified, _ := payload.Claims["email_verified"].(bool)
+	if !verified || !strings.EqualFold(tokenEmail, email) {
+		return fmt.Errorf("identity token is for %q, not %q", tokenEmail, email)
+	}
+	return nil
+}
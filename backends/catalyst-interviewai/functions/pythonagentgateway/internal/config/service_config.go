@@ -1,10 +1,13 @@
 package config
 
 import (
+	"encoding/json"
 	"fmt"
 	"log"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 )
 
 // ServiceConfig holds configuration for the Python agent service connection
@@ -13,22 +16,211 @@ type ServiceConfig struct {
 	Environment        string
 	GCPProjectID       string
 	UseLocalService    bool
+	IdempotencyStore   string // "firestore" or "memory"
+	RateLimitStore     string // "firestore" or "memory"
+	// UserContextSigningKey signs the user context sent to the agents. When unset it is
+	// read from Secret Manager, or the development key is used for a local service.
+	UserContextSigningKey string
+	// AgentBackends are the agent deployments sessions are split across. Without
+	// PYTHON_AGENT_BACKENDS it is a single "default" backend at PythonAgentBaseURL.
+	AgentBackends []AgentBackend
+	// SessionIdleTimeout is how long a session can go without activity before it
+	// counts as abandoned
+	SessionIdleTimeout time.Duration
+	// Sweep configures the scheduled sweep that closes abandoned sessions
+	Sweep SweepConfig
+	// Transcription configures speech-to-text for spoken answers
+	Transcription TranscriptionConfig
+	// StartJobs configures interview starts run in the background (?async=true)
+	StartJobs StartJobsConfig
+	// DefaultGeminiAPIKey is the platform key agent sessions fall back to when the
+	// user has not stored their own
+	DefaultGeminiAPIKey string
+	// DefaultKeyPolicy is "allow" to fall back to DefaultGeminiAPIKey, or "byok_only"
+	// to require every user to bring their own key
+	DefaultKeyPolicy string
+	// AdminClaim is the Firebase custom claim that grants access to the admin
+	// endpoints; it must be set to true on the caller's token
+	AdminClaim string
+}
+
+// Default key policies
+const (
+	DefaultKeyAllow    = "allow"
+	DefaultKeyBYOKOnly = "byok_only"
+)
+
+// TranscriptionConfig selects and configures the speech-to-text backend
+type TranscriptionConfig struct {
+	// Provider is "cloud" (Google Speech-to-Text) or "whisper" (a local Whisper-style
+	// HTTP service). It defaults to whisper with a local agent service, cloud otherwise.
+	Provider string
+	Language string // default BCP-47 language of answers
+	Model    string // Speech-to-Text model, e.g. "latest_long"
+	// WhisperURL, WhisperModel and WhisperAPIKey configure the whisper provider
+	WhisperURL    string
+	WhisperModel  string
+	WhisperAPIKey string
+	// MaxAudioBytes caps uploaded recordings. Speech-to-Text takes at most 10 MB of
+	// base64 audio inline.
+	MaxAudioBytes int
+}
+
+// StartJobsConfig configures background interview starts. Without a Queue the job
+// runs on the instance that accepted it.
+type StartJobsConfig struct {
+	// Queue is the Cloud Tasks queue, projects/{project}/locations/{location}/queues/{queue}
+	Queue string
+	// WorkerURL is the URL of RunStartInterviewJobGCF, which the queue calls. It is
+	// also the identity token audience the worker requires, so it must be set with
+	// InvokerEmail.
+	WorkerURL string
+	// InvokerEmail is the service account the queue calls the worker as
+	InvokerEmail string
+	// Timeout bounds one attempt at a start
+	Timeout time.Duration
+}
+
+// SweepConfig configures the abandoned session sweeper
+type SweepConfig struct {
+	DryRun    bool // report what would be swept without ending anything
+	BatchSize int  // most sessions closed per run
+	// PartialReports resumes sessions the agent has lost so ending them still
+	// produces a report from the turns so far
+	PartialReports bool
+	// InvokerEmail is the service account Cloud Scheduler calls the sweeper as
+	InvokerEmail string
+	// Audience is the identity token audience the sweeper requires, normally its
+	// own URL. It must be set with InvokerEmail.
+	Audience string
+}
+
+// AgentBackend is one Python agent deployment, e.g. the current build or a canary
+type AgentBackend struct {
+	Name   string `json:"name"`
+	URL    string `json:"url"`
+	Weight int    `json:"weight"`
 }
 
 // LoadServiceConfig loads configuration from environment variables
 func LoadServiceConfig() *ServiceConfig {
 	config := &ServiceConfig{
-		Environment:  getEnvWithDefault("ENVIRONMENT", "development"),
-		GCPProjectID: os.Getenv("GCP_PROJECT_ID"),
+		Environment:      getEnvWithDefault("ENVIRONMENT", "development"),
+		GCPProjectID:     os.Getenv("GCP_PROJECT_ID"),
+		IdempotencyStore: getEnvWithDefault("IDEMPOTENCY_STORE", "firestore"),
+		RateLimitStore:   getEnvWithDefault("RATE_LIMIT_STORE", "firestore"),
+
+		UserContextSigningKey: os.Getenv("USER_CONTEXT_SIGNING_KEY"),
+
+		SessionIdleTimeout: getDurationWithDefault("SESSION_IDLE_TIMEOUT", 30*time.Minute),
+		Sweep: SweepConfig{
+			DryRun:         os.Getenv("SWEEP_DRY_RUN") == "true",
+			BatchSize:      getIntWithDefault("SWEEP_BATCH_SIZE", 100),
+			PartialReports: os.Getenv("SWEEP_PARTIAL_REPORTS") == "true",
+			InvokerEmail:   os.Getenv("SWEEP_INVOKER_EMAIL"),
+			Audience:       os.Getenv("SWEEP_AUDIENCE"),
+		},
+
+		StartJobs: StartJobsConfig{
+			Queue:        os.Getenv("START_JOB_QUEUE"),
+			WorkerURL:    os.Getenv("START_JOB_WORKER_URL"),
+			InvokerEmail: os.Getenv("START_JOB_INVOKER_EMAIL"),
+			Timeout:      getDurationWithDefault("START_JOB_TIMEOUT", 5*time.Minute),
+		},
+
+		DefaultGeminiAPIKey: os.Getenv("DEFAULT_GEMINI_API_KEY"),
+		DefaultKeyPolicy:    getEnvWithDefault("AGENT_DEFAULT_KEY_POLICY", DefaultKeyAllow),
+
+		AdminClaim: getEnvWithDefault("ADMIN_CLAIM", "admin"),
+	}
+
+	// Identity tokens are only bound to an endpoint by their audience
+	if config.Sweep.InvokerEmail != "" && config.Sweep.Audience == "" {
+		log.Fatalf("SWEEP_AUDIENCE is required with SWEEP_INVOKER_EMAIL")
+	}
+	if config.StartJobs.InvokerEmail != "" && config.StartJobs.WorkerURL == "" {
+		log.Fatalf("START_JOB_WORKER_URL is required with START_JOB_INVOKER_EMAIL")
+	}
+
+	if config.DefaultKeyPolicy != DefaultKeyAllow && config.DefaultKeyPolicy != DefaultKeyBYOKOnly {
+		log.Printf("Warning: Invalid AGENT_DEFAULT_KEY_POLICY %q, using %s", config.DefaultKeyPolicy, DefaultKeyAllow)
+		config.DefaultKeyPolicy = DefaultKeyAllow
 	}
 
 	// Determine Python agent service URL
 	config.PythonAgentBaseURL = determinePythonAgentURL()
 	config.UseLocalService = isLocalService(config.PythonAgentBaseURL)
 
+	config.Transcription = TranscriptionConfig{
+		Provider:      os.Getenv("TRANSCRIBER"),
+		Language:      getEnvWithDefault("SPEECH_LANGUAGE", "en-US"),
+		Model:         getEnvWithDefault("SPEECH_MODEL", "latest_long"),
+		WhisperURL:    getEnvWithDefault("WHISPER_URL", "http://localhost:9000"),
+		WhisperModel:  os.Getenv("WHISPER_MODEL"),
+		WhisperAPIKey: os.Getenv("WHISPER_API_KEY"),
+		MaxAudioBytes: getIntWithDefault("MAX_AUDIO_BYTES", 7<<20),
+	}
+
+	// PYTHON_AGENT_BACKENDS splits new sessions across several agent deployments;
+	// the first backend is the primary one
+	if raw := os.Getenv("PYTHON_AGENT_BACKENDS"); raw != "" {
+		backends, err := ParseAgentBackends(raw)
+		if err != nil {
+			log.Fatalf("Invalid PYTHON_AGENT_BACKENDS: %v", err)
+		}
+		config.AgentBackends = backends
+		config.PythonAgentBaseURL = backends[0].URL
+		config.UseLocalService = isLocalService(config.PythonAgentBaseURL)
+	} else {
+		config.AgentBackends = []AgentBackend{{Name: "default", URL: config.PythonAgentBaseURL, Weight: 100}}
+	}
+
+	if config.Transcription.Provider == "" {
+		config.Transcription.Provider = "cloud"
+		if config.UseLocalService {
+			config.Transcription.Provider = "whisper"
+		}
+	}
+
 	return config
 }
 
+// ParseAgentBackends parses a JSON list of weighted backends, e.g.
+// [{"name":"stable","url":"https://...","weight":90},{"name":"canary","url":"https://...","weight":10}]
+func ParseAgentBackends(raw string) ([]AgentBackend, error) {
+	var backends []AgentBackend
+	if err := json.Unmarshal([]byte(raw), &backends); err != nil {
+		return nil, fmt.Errorf("expected a JSON list of {name, url, weight}: %w", err)
+	}
+	if len(backends) == 0 {
+		return nil, fmt.Errorf("at least one backend is required")
+	}
+
+	seen := make(map[string]bool)
+	total := 0
+	for i := range backends {
+		backend := &backends[i]
+		backend.Name = strings.TrimSpace(backend.Name)
+		backend.URL = strings.TrimSuffix(strings.TrimSpace(backend.URL), "/")
+		switch {
+		case backend.Name == "":
+			return nil, fmt.Errorf("backend %d has no name", i)
+		case seen[backend.Name]:
+			return nil, fmt.Errorf("backend name %q is used twice", backend.Name)
+		case backend.URL == "":
+			return nil, fmt.Errorf("backend %q has no url", backend.Name)
+		case backend.Weight < 0:
+			return nil, fmt.Errorf("backend %q has a negative weight", backend.Name)
+		}
+		seen[backend.Name] = true
+		total += backend.Weight
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("backend weights add up to zero")
+	}
+	return backends, nil
+}
+
 // determinePythonAgentURL determines the Python agent service URL based on environment
 func determinePythonAgentURL() string {
 	// Priority order for determining service URL:
@@ -117,6 +309,36 @@ func getEnvWithDefault(key, defaultValue string) string {
 	return defaultValue
 }
 
+// getDurationWithDefault parses a duration such as "45m" from an environment
+// variable. Invalid values fall back to the default with a warning.
+func getDurationWithDefault(key string, defaultValue time.Duration) time.Duration {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		log.Printf("Warning: Invalid %s %q, using %s", key, value, defaultValue)
+		return defaultValue
+	}
+	return duration
+}
+
+// getIntWithDefault parses a positive integer from an environment variable. Invalid
+// values fall back to the default with a warning.
+func getIntWithDefault(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n <= 0 {
+		log.Printf("Warning: Invalid %s %q, using %d", key, value, defaultValue)
+		return defaultValue
+	}
+	return n
+}
+
 // GetLocalDevelopmentConfig returns configuration for local development
 func GetLocalDevelopmentConfig() *ServiceConfig {
 	return &ServiceConfig{
@@ -146,5 +368,18 @@ func (sc *ServiceConfig) LogConfiguration() {
 	log.Printf("  Environment: %s", sc.Environment)
 	log.Printf("  GCP Project ID: %s", sc.GCPProjectID)
 	log.Printf("  Python Agent URL: %s", sc.PythonAgentBaseURL)
+	for _, backend := range sc.AgentBackends {
+		log.Printf("  Agent Backend: %s (weight %d) %s", backend.Name, backend.Weight, backend.URL)
+	}
 	log.Printf("  Using Local Service: %t", sc.UseLocalService)
+	log.Printf("  Idempotency Store: %s", sc.IdempotencyStore)
+	log.Printf("  Rate Limit Store: %s", sc.RateLimitStore)
+	log.Printf("  Session Idle Timeout: %s", sc.SessionIdleTimeout)
+	log.Printf("  Transcriber: %s", sc.Transcription.Provider)
+	if sc.StartJobs.Queue != "" {
+		log.Printf("  Start Job Queue: %s", sc.StartJobs.Queue)
+	}
+	log.Printf("  Default Key Policy: %s (default key set: %t)", sc.DefaultKeyPolicy, sc.DefaultGeminiAPIKey != "")
+	log.Printf("  Admin Claim: %s", sc.AdminClaim)
+	log.Printf("  Sweep: dry run %t, batch %d, partial reports %t", sc.Sweep.DryRun, sc.Sweep.BatchSize, sc.Sweep.PartialReports)
 }
\ No newline at end of file
@@ -0,0 +1,73 @@
+package httputils
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// SSEWriter writes Server-Sent Events to an HTTP response.
+// It is safe for concurrent use so heartbeats can be sent from a separate goroutine.
+type SSEWriter struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+	mu      sync.Mutex
+}
+
+// NewSSEWriter prepares the response for an event stream and returns a writer for it
+func NewSSEWriter(w http.ResponseWriter) (*SSEWriter, error) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return nil, fmt.Errorf("response writer does not support flushing")
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no") // Disable proxy buffering
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	return &SSEWriter{w: w, flusher: flusher}, nil
+}
+
+// Event sends a named event. Multi-line data is split across several data fields.
+func (s *SSEWriter) Event(event, data string) error {
+	var b strings.Builder
+	if event != "" {
+		fmt.Fprintf(&b, "event: %s\n", event)
+	}
+	for _, line := range strings.Split(data, "\n") {
+		fmt.Fprintf(&b, "data: %s\n", line)
+	}
+	b.WriteString("\n")
+
+	return s.write(b.String())
+}
+
+// JSON sends a named event whose data is the JSON encoding of v
+func (s *SSEWriter) JSON(event string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event data: %w", err)
+	}
+	return s.Event(event, string(data))
+}
+
+// Comment sends an SSE comment line, which clients ignore
+func (s *SSEWriter) Comment(text string) error {
+	return s.write(fmt.Sprintf(": %s\n\n", text))
+}
+
+func (s *SSEWriter) write(payload string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.w.Write([]byte(payload)); err != nil {
+		return err
+	}
+	s.flusher.Flush()
+	return nil
+}
@@ -0,0 +1,83 @@
+package idempotency
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// FirestoreStore keeps idempotency records in a Firestore collection so every
+// function instance sees the same keys. Configure a TTL policy on expiresAt to
+// have Firestore delete old records.
+type FirestoreStore struct {
+	client     *firestore.Client
+	collection string
+}
+
+// NewFirestoreStore creates a store backed by the given collection
+func NewFirestoreStore(client *firestore.Client, collection string) *FirestoreStore {
+	if collection == "" {
+		collection = "idempotencyKeys"
+	}
+	return &FirestoreStore{client: client, collection: collection}
+}
+
+// Reserve claims rec.ID unless a record still holds it
+func (s *FirestoreStore) Reserve(ctx context.Context, rec *Record) (*Record, error) {
+	docRef := s.client.Collection(s.collection).Doc(rec.ID)
+
+	var existing *Record
+	err := s.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		existing = nil
+
+		doc, err := tx.Get(docRef)
+		if err != nil && status.Code(err) != codes.NotFound {
+			return err
+		}
+		if err == nil {
+			var stored Record
+			if err := doc.DataTo(&stored); err != nil {
+				return err
+			}
+			if stored.Holds(time.Now()) {
+				existing = &stored
+				return nil
+			}
+		}
+
+		return tx.Set(docRef, rec)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to reserve idempotency key: %w", err)
+	}
+
+	return existing, nil
+}
+
+// Complete stores the response for a reserved key
+func (s *FirestoreStore) Complete(ctx context.Context, id string, statusCode int, response []byte) error {
+	_, err := s.client.Collection(s.collection).Doc(id).Update(ctx, []firestore.Update{
+		{Path: "state", Value: StateCompleted},
+		{Path: "statusCode", Value: statusCode},
+		{Path: "response", Value: response},
+	})
+	if status.Code(err) == codes.NotFound {
+		return ErrRecordNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to complete idempotency key: %w", err)
+	}
+	return nil
+}
+
+// Release forgets a reserved key
+func (s *FirestoreStore) Release(ctx context.Context, id string) error {
+	if _, err := s.client.Collection(s.collection).Doc(id).Delete(ctx); err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package idempotency
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps idempotency records in process memory.
+// It is intended for local development and tests; records do not survive restarts
+// and are not shared between function instances.
+type MemoryStore struct {
+	mu      sync.Mutex
+	records map[string]*Record
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{records: make(map[string]*Record)}
+}
+
+// Reserve claims rec.ID unless a record still holds it
+func (s *MemoryStore) Reserve(ctx context.Context, rec *Record) (*Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if existing, ok := s.records[rec.ID]; ok && existing.Holds(time.Now()) {
+		copied := *existing
+		return &copied, nil
+	}
+
+	stored := *rec
+	s.records[rec.ID] = &stored
+	return nil, nil
+}
+
+// Complete stores the response for a reserved key
+func (s *MemoryStore) Complete(ctx context.Context, id string, statusCode int, response []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rec, ok := s.records[id]
+	if !ok {
+		return ErrRecordNotFound
+	}
+	rec.State = StateCompleted
+	rec.StatusCode = statusCode
+	rec.Response = append([]byte(nil), response...)
+	return nil
+}
+
+// Release forgets a reserved key
+func (s *MemoryStore) Release(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.records, id)
+	return nil
+}
@@ -0,0 +1,214 @@
+package idempotency
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreReserve(t *testing.T) {
+	body := []byte(`{"answer":"hello"}`)
+	now := time.Now()
+
+	tests := []struct {
+		name       string
+		stored     *Record // already reserved under the same ID, if any
+		wantHolder bool    // whether Reserve returns the stored record
+	}{
+		{name: "new key"},
+		{
+			name:       "in progress",
+			stored:     NewRecord("user1", "respond", "key", body, DefaultTTL, DefaultLease),
+			wantHolder: true,
+		},
+		{
+			name: "completed",
+			stored: &Record{
+				ID: RecordID("user1", "respond", "key"), State: StateCompleted,
+				CreatedAt: now.Add(-time.Hour), ExpiresAt: now.Add(time.Hour), LeaseUntil: now.Add(-50 * time.Minute),
+			},
+			wantHolder: true,
+		},
+		{
+			name: "lease ran out",
+			stored: &Record{
+				ID: RecordID("user1", "respond", "key"), State: StateInProgress,
+				CreatedAt: now.Add(-time.Hour), ExpiresAt: now.Add(time.Hour), LeaseUntil: now.Add(-time.Minute),
+			},
+		},
+		{
+			name: "record without a lease, past the default",
+			stored: &Record{
+				ID: RecordID("user1", "respond", "key"), State: StateInProgress,
+				CreatedAt: now.Add(-DefaultLease - time.Minute), ExpiresAt: now.Add(time.Hour),
+			},
+		},
+		{
+			name: "record without a lease, within the default",
+			stored: &Record{
+				ID: RecordID("user1", "respond", "key"), State: StateInProgress,
+				CreatedAt: now.Add(-time.Minute), ExpiresAt: now.Add(time.Hour),
+			},
+			wantHolder: true,
+		},
+		{
+			name: "completed and expired",
+			stored: &Record{
+				ID: RecordID("user1", "respond", "key"), State: StateCompleted,
+				CreatedAt: now.Add(-DefaultTTL), ExpiresAt: now.Add(-time.Second),
+			},
+		},
+	}
+
+	ctx := context.Background()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewMemoryStore()
+			if tt.stored != nil {
+				if holder, err := store.Reserve(ctx, tt.stored); err != nil || holder != nil {
+					t.Fatalf("first Reserve() = %v, %v", holder, err)
+				}
+			}
+
+			rec := NewRecord("user1", "respond", "key", body, DefaultTTL, DefaultLease)
+			holder, err := store.Reserve(ctx, rec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (holder != nil) != tt.wantHolder {
+				t.Fatalf("Reserve() returned holder %v, want holder %v", holder, tt.wantHolder)
+			}
+			if holder != nil && holder.State != tt.stored.State {
+				t.Errorf("holder has state %q, want %q", holder.State, tt.stored.State)
+			}
+
+			// A taken-over key is now held by the new reservation
+			if !tt.wantHolder {
+				again, err := store.Reserve(ctx, NewRecord("user1", "respond", "key", body, DefaultTTL, DefaultLease))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if again == nil || again.LeaseUntil != rec.LeaseUntil {
+					t.Errorf("second Reserve() = %v, want the record just reserved", again)
+				}
+			}
+		})
+	}
+}
+
+func TestMemoryStoreComplete(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	rec := NewRecord("user1", "start", "key", []byte(`{}`), DefaultTTL, DefaultLease)
+	if _, err := store.Reserve(ctx, rec); err != nil {
+		t.Fatal(err)
+	}
+
+	response := []byte(`{"sessionId":"s1"}`)
+	if err := store.Complete(ctx, rec.ID, 201, response); err != nil {
+		t.Fatal(err)
+	}
+	// The store keeps its own copy of the response
+	response[0] = 'x'
+
+	holder, err := store.Reserve(ctx, NewRecord("user1", "start", "key", []byte(`{}`), DefaultTTL, DefaultLease))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if holder == nil {
+		t.Fatal("Reserve() after Complete() returned no holder")
+	}
+	if holder.State != StateCompleted || holder.StatusCode != 201 || string(holder.Response) != `{"sessionId":"s1"}` {
+		t.Errorf("holder = %+v, want the completed response", holder)
+	}
+	if holder.RequestHash != rec.RequestHash {
+		t.Errorf("holder has request hash %q, want %q", holder.RequestHash, rec.RequestHash)
+	}
+
+	// The returned record is a copy
+	holder.State = StateInProgress
+	if again, _ := store.Reserve(ctx, rec); again == nil || again.State != StateCompleted {
+		t.Errorf("changing the returned record changed the store: %+v", again)
+	}
+}
+
+func TestMemoryStoreUnknownKey(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+
+	tests := []struct {
+		name    string
+		run     func() error
+		wantErr error
+	}{
+		{name: "complete", run: func() error { return store.Complete(ctx, "missing", 200, nil) }, wantErr: ErrRecordNotFound},
+		{name: "release", run: func() error { return store.Release(ctx, "missing") }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.run(); !errors.Is(err, tt.wantErr) {
+				t.Errorf("got error %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMemoryStoreRelease(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	rec := NewRecord("user1", "respond", "key", []byte(`{}`), DefaultTTL, DefaultLease)
+	if _, err := store.Reserve(ctx, rec); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Release(ctx, rec.ID); err != nil {
+		t.Fatal(err)
+	}
+	if holder, err := store.Reserve(ctx, rec); err != nil || holder != nil {
+		t.Errorf("Reserve() after Release() = %v, %v, want the key free", holder, err)
+	}
+}
+
+func TestRecordID(t *testing.T) {
+	tests := []struct {
+		name                string
+		userA, scopeA, keyA string
+		userB, scopeB, keyB string
+		wantSame            bool
+	}{
+		{name: "same request", userA: "u1", scopeA: "start", keyA: "k", userB: "u1", scopeB: "start", keyB: "k", wantSame: true},
+		{name: "other user", userA: "u1", scopeA: "start", keyA: "k", userB: "u2", scopeB: "start", keyB: "k"},
+		{name: "other endpoint", userA: "u1", scopeA: "start", keyA: "k", userB: "u1", scopeB: "respond", keyB: "k"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			same := RecordID(tt.userA, tt.scopeA, tt.keyA) == RecordID(tt.userB, tt.scopeB, tt.keyB)
+			if same != tt.wantSame {
+				t.Errorf("IDs equal = %v, want %v", same, tt.wantSame)
+			}
+		})
+	}
+}
+
+func TestHashRequest(t *testing.T) {
+	tests := []struct {
+		name     string
+		a, b     string
+		wantSame bool
+	}{
+		{name: "key order and whitespace", a: `{"a":1,"b":"x"}`, b: "{ \"b\": \"x\",\n \"a\": 1 }", wantSame: true},
+		{name: "different values", a: `{"a":1}`, b: `{"a":2}`},
+		{name: "invalid JSON hashed as is", a: `not json`, b: `not  json`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			same := HashRequest([]byte(tt.a)) == HashRequest([]byte(tt.b))
+			if same != tt.wantSame {
+				t.Errorf("hashes equal = %v, want %v", same, tt.wantSame)
+			}
+		})
+	}
+}
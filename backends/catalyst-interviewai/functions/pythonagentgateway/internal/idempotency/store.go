@@ -0,0 +1,123 @@
+package idempotency
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Record states
+const (
+	StateInProgress = "in_progress"
+	StateCompleted  = "completed"
+)
+
+// DefaultTTL is how long a key is remembered
+const DefaultTTL = 24 * time.Hour
+
+// DefaultLease is how long an in-progress reservation holds its key. It outlasts
+// the longest a gateway function may run (540s), so a reservation left by an
+// instance that crashed or timed out is taken over once that request is surely
+// dead, rather than blocking retries until the record expires.
+const DefaultLease = 10 * time.Minute
+
+// MaxKeyLength bounds the Idempotency-Key header
+const MaxKeyLength = 255
+
+// ErrRecordNotFound is returned when completing or releasing an unknown key
+var ErrRecordNotFound = errors.New("idempotency record not found")
+
+// Record is a stored idempotency key and, once the request finished, its response
+type Record struct {
+	ID          string    `firestore:"id" json:"id"`
+	UserID      string    `firestore:"userId" json:"userId"`
+	Scope       string    `firestore:"scope" json:"scope"`
+	RequestHash string    `firestore:"requestHash" json:"requestHash"`
+	State       string    `firestore:"state" json:"state"`
+	StatusCode  int       `firestore:"statusCode" json:"statusCode"`
+	Response    []byte    `firestore:"response" json:"response"`
+	CreatedAt   time.Time `firestore:"createdAt" json:"createdAt"`
+	ExpiresAt   time.Time `firestore:"expiresAt" json:"expiresAt"`
+	LeaseUntil  time.Time `firestore:"leaseUntil" json:"leaseUntil"`
+}
+
+// Holds reports whether the record still claims its key at now: a completed
+// record until it expires, an in-progress one until its lease runs out
+func (r *Record) Holds(now time.Time) bool {
+	if !now.Before(r.ExpiresAt) {
+		return false
+	}
+	if r.State == StateCompleted {
+		return true
+	}
+	leaseUntil := r.LeaseUntil
+	if leaseUntil.IsZero() {
+		// Written before reservations carried a lease
+		leaseUntil = r.CreatedAt.Add(DefaultLease)
+	}
+	return now.Before(leaseUntil)
+}
+
+// Store persists idempotency records
+type Store interface {
+	// Reserve atomically claims rec.ID. If a record still holds the ID (see
+	// Record.Holds), that record is returned and rec is not stored.
+	Reserve(ctx context.Context, rec *Record) (*Record, error)
+	// Complete stores the response for a reserved key
+	Complete(ctx context.Context, id string, statusCode int, response []byte) error
+	// Release forgets a reserved key so the request can be retried
+	Release(ctx context.Context, id string) error
+}
+
+// RecordID derives the storage ID for a client key. Keys are scoped per user and
+// endpoint so two users (or two endpoints) can never collide.
+func RecordID(userID, scope, key string) string {
+	sum := sha256.Sum256([]byte(userID + "\x00" + scope + "\x00" + key))
+	return hex.EncodeToString(sum[:])
+}
+
+// HashRequest returns a stable fingerprint of a JSON request body.
+// The body is re-encoded so key order and whitespace do not matter.
+func HashRequest(body []byte) string {
+	var decoded interface{}
+	if err := json.Unmarshal(body, &decoded); err == nil {
+		if canonical, err := json.Marshal(decoded); err == nil {
+			body = canonical
+		}
+	}
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// NewRecord builds an in-progress record for a request, leased for lease
+func NewRecord(userID, scope, key string, body []byte, ttl, lease time.Duration) *Record {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	if lease <= 0 {
+		lease = DefaultLease
+	}
+	now := time.Now().UTC()
+	return &Record{
+		ID:          RecordID(userID, scope, key),
+		UserID:      userID,
+		Scope:       scope,
+		RequestHash: HashRequest(body),
+		State:       StateInProgress,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(ttl),
+		LeaseUntil:  now.Add(lease),
+	}
+}
+
+// ValidateKey checks a client supplied Idempotency-Key
+func ValidateKey(key string) error {
+	if len(key) > MaxKeyLength {
+		return fmt.Errorf("Idempotency-Key must be at most %d characters", MaxKeyLength)
+	}
+	return nil
+}
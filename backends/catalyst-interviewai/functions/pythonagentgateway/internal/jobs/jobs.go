@@ -0,0 +1,259 @@
+// Package jobs keeps the state of work the gateway runs in the background, such as
+// interview starts that may outlast the client's request, so any function instance
+// can report on it
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Job statuses
+const (
+	StatusPending   = "pending"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+// KindStartInterview starts an interview session
+const KindStartInterview = "start_interview"
+
+// DefaultTTL is how long a job is kept after it is created
+const DefaultTTL = 24 * time.Hour
+
+var (
+	// ErrJobNotFound is returned when the user has no such job
+	ErrJobNotFound = errors.New("job not found")
+	// ErrJobFinished is returned when claiming a job that has already finished
+	ErrJobFinished = errors.New("job has already finished")
+	// ErrJobLeased is returned when another attempt is still running the job
+	ErrJobLeased = errors.New("job is being run by another attempt")
+)
+
+// Job is a background operation started on behalf of a user. Jobs live at
+// users/{uid}/agentJobs/{jobId}; configure a TTL policy on expiresAt to have
+// Firestore delete old ones.
+type Job struct {
+	JobID  string `firestore:"jobId" json:"jobId"`
+	UserID string `firestore:"userId" json:"-"`
+	Kind   string `firestore:"kind" json:"kind"`
+	Status string `firestore:"status" json:"status"`
+	// Request is what the job runs with
+	Request map[string]interface{} `firestore:"request" json:"-"`
+	// Variant is the agent backend the job runs on
+	Variant string `firestore:"variant,omitempty" json:"-"`
+	// Result is the payload of a job that succeeded
+	Result map[string]interface{} `firestore:"result,omitempty" json:"result,omitempty"`
+	// Error and ErrorCode describe a job that failed; ErrorCode is the HTTP status the
+	// synchronous endpoint would have answered with
+	Error     string `firestore:"error,omitempty" json:"error,omitempty"`
+	ErrorCode int    `firestore:"errorCode,omitempty" json:"errorCode,omitempty"`
+	// Attempts counts the times the job was claimed to run
+	Attempts    int        `firestore:"attempts" json:"attempts"`
+	LeaseUntil  *time.Time `firestore:"leaseUntil,omitempty" json:"-"`
+	CreatedAt   time.Time  `firestore:"createdAt" json:"createdAt"`
+	UpdatedAt   time.Time  `firestore:"updatedAt" json:"updatedAt"`
+	CompletedAt *time.Time `firestore:"completedAt,omitempty" json:"completedAt,omitempty"`
+	ExpiresAt   time.Time  `firestore:"expiresAt" json:"-"`
+}
+
+// Finished reports whether the job has succeeded or failed
+func (j *Job) Finished() bool {
+	return j.Status == StatusSucceeded || j.Status == StatusFailed
+}
+
+// Leased reports whether an attempt holds the job at now
+func (j *Job) Leased(now time.Time) bool {
+	return j.LeaseUntil != nil && now.Before(*j.LeaseUntil)
+}
+
+// Store keeps jobs in Firestore
+type Store struct {
+	client *firestore.Client
+}
+
+// NewStore creates a job store backed by Firestore
+func NewStore(client *firestore.Client) *Store {
+	return &Store{client: client}
+}
+
+func (s *Store) jobDoc(userID, jobID string) *firestore.DocumentRef {
+	return s.client.Collection("users").Doc(userID).Collection("agentJobs").Doc(jobID)
+}
+
+// Create records a new pending job for the user
+func (s *Store) Create(ctx context.Context, userID, kind, variant string, request map[string]interface{}) (*Job, error) {
+	now := time.Now().UTC()
+	job := &Job{
+		JobID:     uuid.New().String(),
+		UserID:    userID,
+		Kind:      kind,
+		Status:    StatusPending,
+		Request:   request,
+		Variant:   variant,
+		CreatedAt: now,
+		UpdatedAt: now,
+		ExpiresAt: now.Add(DefaultTTL),
+	}
+	if _, err := s.jobDoc(userID, job.JobID).Create(ctx, job); err != nil {
+		return nil, fmt.Errorf("failed to create job: %w", err)
+	}
+	return job, nil
+}
+
+// Get returns one of the user's jobs
+func (s *Store) Get(ctx context.Context, userID, jobID string) (*Job, error) {
+	doc, err := s.jobDoc(userID, jobID).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, ErrJobNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read job %s: %w", jobID, err)
+	}
+
+	var job Job
+	if err := doc.DataTo(&job); err != nil {
+		return nil, fmt.Errorf("failed to parse job %s: %w", jobID, err)
+	}
+	return &job, nil
+}
+
+// Claim takes a pending job to run for up to lease. Task queues deliver at least
+// once, so a job another attempt holds is refused until its lease runs out.
+func (s *Store) Claim(ctx context.Context, userID, jobID string, lease time.Duration) (*Job, error) {
+	ref := s.jobDoc(userID, jobID)
+	var claimed *Job
+	err := s.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(ref)
+		if status.Code(err) == codes.NotFound {
+			return ErrJobNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		var job Job
+		if err := doc.DataTo(&job); err != nil {
+			return err
+		}
+		now := time.Now().UTC()
+		switch {
+		case job.Finished():
+			return ErrJobFinished
+		case job.Leased(now):
+			return ErrJobLeased
+		}
+
+		leaseUntil := now.Add(lease)
+		job.Attempts++
+		job.LeaseUntil = &leaseUntil
+		job.UpdatedAt = now
+		claimed = &job
+		return tx.Update(ref, []firestore.Update{
+			{Path: "attempts", Value: job.Attempts},
+			{Path: "leaseUntil", Value: leaseUntil},
+			{Path: "updatedAt", Value: now},
+		})
+	})
+	if err != nil {
+		if errors.Is(err, ErrJobNotFound) || errors.Is(err, ErrJobFinished) || errors.Is(err, ErrJobLeased) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to claim job %s: %w", jobID, err)
+	}
+	return claimed, nil
+}
+
+// Release gives up the lease on a job left pending, so the next attempt can claim it
+// straight away
+func (s *Store) Release(ctx context.Context, userID, jobID string) error {
+	_, err := s.jobDoc(userID, jobID).Update(ctx, []firestore.Update{
+		{Path: "leaseUntil", Value: firestore.Delete},
+		{Path: "updatedAt", Value: time.Now().UTC()},
+	})
+	if status.Code(err) == codes.NotFound {
+		return ErrJobNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to release job %s: %w", jobID, err)
+	}
+	return nil
+}
+
+// Succeed records the job's result. A job that has already finished is left as it
+// is and ErrJobFinished returned.
+func (s *Store) Succeed(ctx context.Context, userID, jobID string, result map[string]interface{}) error {
+	now := time.Now().UTC()
+	return s.finish(ctx, userID, jobID, false, []firestore.Update{
+		{Path: "status", Value: StatusSucceeded},
+		{Path: "result", Value: result},
+		{Path: "updatedAt", Value: now},
+		{Path: "completedAt", Value: now},
+		{Path: "leaseUntil", Value: firestore.Delete},
+	})
+}
+
+// Fail records why the job failed and the HTTP status that goes with it. A job that
+// has already finished is left as it is and ErrJobFinished returned.
+func (s *Store) Fail(ctx context.Context, userID, jobID, message string, code int) error {
+	return s.finish(ctx, userID, jobID, false, failUpdates(message, code))
+}
+
+// TimeOut fails a job no attempt is running, returning ErrJobLeased while one still
+// holds its lease
+func (s *Store) TimeOut(ctx context.Context, userID, jobID, message string, code int) error {
+	return s.finish(ctx, userID, jobID, true, failUpdates(message, code))
+}
+
+func failUpdates(message string, code int) []firestore.Update {
+	now := time.Now().UTC()
+	return []firestore.Update{
+		{Path: "status", Value: StatusFailed},
+		{Path: "error", Value: message},
+		{Path: "errorCode", Value: code},
+		{Path: "updatedAt", Value: now},
+		{Path: "completedAt", Value: now},
+		{Path: "leaseUntil", Value: firestore.Delete},
+	}
+}
+
+// finish applies updates that finish a pending job, refusing a leased one when
+// unleased is set
+func (s *Store) finish(ctx context.Context, userID, jobID string, unleased bool, updates []firestore.Update) error {
+	ref := s.jobDoc(userID, jobID)
+	err := s.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(ref)
+		if status.Code(err) == codes.NotFound {
+			return ErrJobNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		var job Job
+		if err := doc.DataTo(&job); err != nil {
+			return err
+		}
+		switch {
+		case job.Finished():
+			return ErrJobFinished
+		case unleased && job.Leased(time.Now()):
+			return ErrJobLeased
+		}
+		return tx.Update(ref, updates)
+	})
+	if err != nil {
+		if errors.Is(err, ErrJobNotFound) || errors.Is(err, ErrJobFinished) || errors.Is(err, ErrJobLeased) {
+			return err
+		}
+		return fmt.Errorf("failed to finish job %s: %w", jobID, err)
+	}
+	return nil
+}
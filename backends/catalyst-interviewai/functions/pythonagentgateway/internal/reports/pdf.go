@@ -0,0 +1,310 @@
+package reports
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// A4 page geometry in PDF points
+const (
+	pdfPageWidth  = 595.28
+	pdfPageHeight = 841.89
+	pdfMargin     = 56.0
+	pdfTextWidth  = pdfPageWidth - 2*pdfMargin
+)
+
+// Standard Type 1 fonts, available in every PDF viewer without embedding
+const (
+	fontRegular = "F1"
+	fontBold    = "F2"
+	fontItalic  = "F3"
+)
+
+type pdfColor string
+
+const (
+	colorText  pdfColor = "0.12 0.16 0.22"
+	colorMuted pdfColor = "0.42 0.45 0.50"
+	colorBrand pdfColor = "0.22 0.19 0.64"
+)
+
+// renderPDF lays the report out as a text PDF. It is deliberately small: the
+// standard Helvetica fonts, word wrapping and page breaks are all a report needs.
+func renderPDF(report *Report) ([]byte, error) {
+	d := newPDFDocument()
+
+	d.paragraph(report.Title(), fontBold, 20, colorText, 0)
+	if subtitle := report.Subtitle(); subtitle != "" {
+		d.paragraph(subtitle, fontRegular, 12, colorMuted, 0)
+	}
+	d.paragraph(fmt.Sprintf("Session %s - Generated %s", report.SessionID, report.GeneratedAt.Format("2 January 2006 15:04 MST")), fontRegular, 9, colorMuted, 0)
+	d.rule()
+
+	if report.OverallScore != nil {
+		d.section("Overall Score")
+		d.paragraph(FormatScore(*report.OverallScore)+" / 100", fontBold, 18, colorBrand, 0)
+	}
+	if report.Summary != "" {
+		d.section("Summary")
+		d.paragraph(report.Summary, fontRegular, 11, colorText, 0)
+	}
+	if len(report.Scores) > 0 {
+		d.section("Scores")
+		for _, score := range report.Scores {
+			d.scoreRow(score.Name, FormatScore(score.Value))
+		}
+	}
+	d.bulletSection("Strengths", report.Strengths)
+	d.bulletSection("Areas for Improvement", report.Improvements)
+	d.bulletSection("Recommendations", report.Recommendations)
+
+	if len(report.Questions) > 0 {
+		d.section("Question by Question")
+		for _, q := range report.Questions {
+			heading := fmt.Sprintf("Question %d", q.Number)
+			if q.Score != nil {
+				heading += " - Score " + FormatScore(*q.Score)
+			}
+			d.space(6)
+			d.paragraph(heading, fontBold, 12, colorText, 0)
+			if q.Question != "" {
+				d.paragraph(q.Question, fontItalic, 11, colorText, 0)
+			}
+			if q.Response != "" {
+				d.paragraph("Your answer", fontBold, 10, colorMuted, 0)
+				d.paragraph(q.Response, fontRegular, 10, colorText, 12)
+			}
+			if q.Feedback != "" {
+				d.paragraph("Feedback: "+q.Feedback, fontRegular, 10, colorText, 0)
+			}
+		}
+	}
+
+	return d.bytes(), nil
+}
+
+// pdfDocument accumulates page content streams and tracks the cursor
+type pdfDocument struct {
+	pages []*bytes.Buffer
+	page  *bytes.Buffer
+	y     float64
+}
+
+func newPDFDocument() *pdfDocument {
+	d := &pdfDocument{}
+	d.newPage()
+	return d
+}
+
+func (d *pdfDocument) newPage() {
+	if d.page != nil {
+		d.footer()
+	}
+	d.page = &bytes.Buffer{}
+	d.pages = append(d.pages, d.page)
+	d.y = pdfPageHeight - pdfMargin
+}
+
+func (d *pdfDocument) footer() {
+	d.text(pdfMargin, pdfMargin/2, fontRegular, 8, colorMuted, fmt.Sprintf("Page %d", len(d.pages)))
+}
+
+// ensure starts a new page when fewer than height points are left
+func (d *pdfDocument) ensure(height float64) {
+	if d.y-height < pdfMargin {
+		d.newPage()
+	}
+}
+
+func (d *pdfDocument) space(height float64) {
+	d.y -= height
+}
+
+func (d *pdfDocument) text(x, y float64, font string, size float64, color pdfColor, s string) {
+	fmt.Fprintf(d.page, "%s rg BT /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n", color, font, size, x, y, encodePDFText(s))
+}
+
+// paragraph writes wrapped text, honouring blank lines between paragraphs
+func (d *pdfDocument) paragraph(s, font string, size float64, color pdfColor, indent float64) {
+	leading := size * 1.35
+	for _, block := range strings.Split(s, "\n") {
+		lines := wrapText(block, font, size, pdfTextWidth-indent)
+		if len(lines) == 0 {
+			d.space(leading / 2)
+			continue
+		}
+		for _, line := range lines {
+			d.ensure(leading)
+			d.y -= leading
+			d.text(pdfMargin+indent, d.y, font, size, color, line)
+		}
+	}
+	d.space(size * 0.4)
+}
+
+func (d *pdfDocument) section(title string) {
+	d.ensure(60)
+	d.space(12)
+	d.paragraph(title, fontBold, 14, colorBrand, 0)
+}
+
+func (d *pdfDocument) bulletSection(title string, items []string) {
+	if len(items) == 0 {
+		return
+	}
+	d.section(title)
+	for _, item := range items {
+		lines := wrapText(item, fontRegular, 11, pdfTextWidth-14)
+		for i, line := range lines {
+			d.ensure(15)
+			d.y -= 15
+			if i == 0 {
+				d.text(pdfMargin+2, d.y, fontRegular, 11, colorBrand, "•")
+			}
+			d.text(pdfMargin+14, d.y, fontRegular, 11, colorText, line)
+		}
+	}
+	d.space(4)
+}
+
+func (d *pdfDocument) scoreRow(name, value string) {
+	d.ensure(16)
+	d.y -= 16
+	d.text(pdfMargin, d.y, fontRegular, 11, colorText, name)
+	d.text(pdfMargin+pdfTextWidth-textWidth(value, fontBold, 11), d.y, fontBold, 11, colorText, value)
+}
+
+func (d *pdfDocument) rule() {
+	d.space(6)
+	fmt.Fprintf(d.page, "0.9 0.91 0.92 RG 1 w %.2f %.2f m %.2f %.2f l S\n", pdfMargin, d.y, pdfPageWidth-pdfMargin, d.y)
+	d.space(6)
+}
+
+// bytes serialises the document: catalog, page tree, fonts, then one page and
+// content stream object per page, followed by the cross-reference table
+func (d *pdfDocument) bytes() []byte {
+	d.footer()
+
+	var out bytes.Buffer
+	var offsets []int
+	object := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	const firstPageObject = 6
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPageObject+2*i)
+	}
+
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Oblique /Encoding /WinAnsiEncoding >>")
+	for i, page := range d.pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] /Resources << /Font << /%s 3 0 R /%s 4 0 R /%s 5 0 R >> >> /Contents %d 0 R >>",
+			pdfPageWidth, pdfPageHeight, fontRegular, fontBold, fontItalic, firstPageObject+2*i+1))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", page.Len(), page.String()))
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	return out.Bytes()
+}
+
+// wrapText breaks s into lines that fit width, splitting overlong words
+func wrapText(s, font string, size, width float64) []string {
+	var lines []string
+	var line string
+	for _, word := range strings.Fields(s) {
+		for textWidth(word, font, size) > width {
+			runes := []rune(word)
+			cut := len(runes)
+			for cut > 1 && textWidth(string(runes[:cut]), font, size) > width {
+				cut--
+			}
+			if line != "" {
+				lines = append(lines, line)
+				line = ""
+			}
+			lines = append(lines, string(runes[:cut]))
+			word = string(runes[cut:])
+		}
+		candidate := word
+		if line != "" {
+			candidate = line + " " + word
+		}
+		if textWidth(candidate, font, size) > width && line != "" {
+			lines = append(lines, line)
+			candidate = word
+		}
+		line = candidate
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// textWidth approximates Helvetica advance widths closely enough for wrapping
+func textWidth(s, font string, size float64) float64 {
+	var units float64
+	for _, r := range s {
+		switch {
+		case strings.ContainsRune("il.,:;!|'`Ijft", r):
+			units += 0.28
+		case r == ' ' || strings.ContainsRune("r()[]-", r):
+			units += 0.33
+		case r == 'm' || r == 'w' || r == 'M' || r == 'W':
+			units += 0.83
+		case r >= 'A' && r <= 'Z':
+			units += 0.67
+		default:
+			units += 0.556
+		}
+	}
+	if font == fontBold {
+		units *= 1.06
+	}
+	return units * size
+}
+
+// winAnsiPunctuation maps common Unicode punctuation onto WinAnsiEncoding
+var winAnsiPunctuation = map[rune]byte{
+	'‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94,
+	'•': 0x95, '–': 0x96, '—': 0x97, '…': 0x85,
+	'€': 0x80,
+}
+
+// encodePDFText converts s to a WinAnsi string literal body, escaping delimiters
+func encodePDFText(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\t':
+			b.WriteString("    ")
+		case r >= 0x20 && r < 0x7f:
+			b.WriteRune(r)
+		case r >= 0xa0 && r <= 0xff:
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			if c, ok := winAnsiPunctuation[r]; ok {
+				fmt.Fprintf(&b, "\\%03o", c)
+			} else {
+				b.WriteByte('?')
+			}
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,182 @@
+package reports
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+func TestEncodePDFText(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "ASCII", in: "Score 80 / 100", want: "Score 80 / 100"},
+		{name: "delimiters escaped", in: `f(x) \ y`, want: `f\(x\) \\ y`},
+		{name: "tab", in: "a\tb", want: "a    b"},
+		{name: "Latin-1 as octal", in: "café ü ©", want: `caf\351 \374 \251`},
+		{name: "smart quotes", in: "‘it’s’ “quoted”", want: `\221it\222s\222 \223quoted\224`},
+		{name: "bullet, dashes and ellipsis", in: "• – — …", want: `\225 \226 \227 \205`},
+		{name: "euro", in: "€5", want: `\2005`},
+		{name: "outside WinAnsi", in: "日本 ✓", want: "?? ?"},
+		{name: "control characters", in: "a\x01b\u0080", want: "a?b?"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := encodePDFText(tt.in); got != tt.want {
+				t.Errorf("encodePDFText(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWrapText(t *testing.T) {
+	tests := []struct {
+		name  string
+		in    string
+		width float64
+		want  []string
+	}{
+		{name: "fits", in: "one two", width: 100, want: []string{"one two"}},
+		{name: "wraps between words", in: "one two three", width: 30, want: []string{"one", "two", "three"}},
+		{name: "collapses whitespace", in: "  one \n two  ", width: 100, want: []string{"one two"}},
+		{name: "splits an overlong word", in: "ab abcdefghij", width: 30, want: []string{"ab", "abcde", "fghij"}},
+		{name: "empty", in: "   ", width: 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := wrapText(tt.in, fontRegular, 10, tt.width)
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("wrapText() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// goldenReport has a long transcript so that the PDF runs over several pages
+func goldenReport() *Report {
+	report := &Report{
+		SessionID:       "session-123",
+		InterviewType:   "system_design",
+		TargetRole:      "Backend Engineer",
+		Company:         "Acme (EU)",
+		GeneratedAt:     time.Date(2026, 3, 2, 14, 30, 0, 0, time.UTC),
+		OverallScore:    score(72.5),
+		Summary:         "A solid design with clear trade-offs.\n\nCapacity estimates need work – practise them.",
+		Scores:          []Score{{Name: "Communication Clarity", Value: 80}, {Name: "Technical Depth", Value: 65.5}},
+		Strengths:       []string{"Clear structure", "Discussed “hot” keys and caching"},
+		Improvements:    []string{"Back-of-the-envelope numbers"},
+		Recommendations: []string{"Practise sharding questions…"},
+	}
+	for i := 1; i <= 12; i++ {
+		report.Questions = append(report.Questions, QuestionResult{
+			Number:   i,
+			Question: fmt.Sprintf("How would you scale component %d of the service?", i),
+			Response: strings.Repeat("I would partition the data by customer and put a cache in front of the reads. ", 4),
+			Score:    score(float64(60 + i)),
+			Feedback: "Consider the write path as well.",
+		})
+	}
+	return report
+}
+
+func TestRenderPDFGolden(t *testing.T) {
+	got, err := Render(goldenReport(), FormatPDF)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	golden := filepath.Join("testdata", "report.pdf.golden")
+	if *update {
+		if err := os.WriteFile(golden, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("%v (run the test with -update to create it)", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("rendered PDF differs from %s; run the test with -update and review the diff", golden)
+	}
+}
+
+func TestRenderPDFStructure(t *testing.T) {
+	pdf, err := Render(goldenReport(), FormatPDF)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.HasPrefix(pdf, []byte("%PDF-1.4\n")) || !bytes.HasSuffix(pdf, []byte("%%EOF\n")) {
+		t.Fatal("missing PDF header or trailer")
+	}
+
+	// startxref points at the cross-reference table
+	match := regexp.MustCompile(`startxref\n(\d+)\n%%EOF\n$`).FindSubmatch(pdf)
+	if match == nil {
+		t.Fatal("no startxref")
+	}
+	xref, _ := strconv.Atoi(string(match[1]))
+	if !bytes.HasPrefix(pdf[xref:], []byte("xref\n")) {
+		t.Fatalf("startxref %d does not point at the xref table", xref)
+	}
+
+	// Every xref entry points at its object
+	var count int
+	if _, err := fmt.Sscanf(string(pdf[xref:]), "xref\n0 %d\n", &count); err != nil {
+		t.Fatal(err)
+	}
+	entries := regexp.MustCompile(`(\d{10}) 00000 n \n`).FindAllSubmatch(pdf[xref:], -1)
+	if len(entries) != count-1 {
+		t.Fatalf("xref has %d entries, declares %d objects", len(entries), count-1)
+	}
+	for i, entry := range entries {
+		offset, _ := strconv.Atoi(string(entry[1]))
+		if want := fmt.Sprintf("%d 0 obj\n", i+1); !bytes.HasPrefix(pdf[offset:], []byte(want)) {
+			t.Errorf("object %d is not at offset %d", i+1, offset)
+		}
+	}
+	if !bytes.Contains(pdf, []byte(fmt.Sprintf("trailer\n<< /Size %d /Root 1 0 R >>", count))) {
+		t.Error("trailer size does not match the xref table")
+	}
+
+	// Each page has a content stream of the declared length, and the page tree counts them
+	streams := regexp.MustCompile(`(?s)<< /Length (\d+) >>\nstream\n(.*?)endstream`).FindAllSubmatch(pdf, -1)
+	if len(streams) < 2 {
+		t.Fatalf("got %d pages, want the transcript to run over several", len(streams))
+	}
+	for i, stream := range streams {
+		length, _ := strconv.Atoi(string(stream[1]))
+		if length != len(stream[2]) {
+			t.Errorf("page %d declares length %d, has %d", i+1, length, len(stream[2]))
+		}
+		if footer := fmt.Sprintf("(Page %d) Tj", i+1); !bytes.Contains(stream[2], []byte(footer)) {
+			t.Errorf("page %d has no footer", i+1)
+		}
+	}
+	if want := fmt.Sprintf("/Count %d >>", len(streams)); !bytes.Contains(pdf, []byte(want)) {
+		t.Errorf("page tree does not contain %q", want)
+	}
+	if count != 6+2*len(streams) {
+		t.Errorf("got %d objects for %d pages, want catalog, page tree, three fonts and two per page", count-1, len(streams))
+	}
+
+	// Text is escaped and WinAnsi encoded
+	for _, want := range []string{"(Backend Engineer at Acme \\(EU\\))", "(Discussed \\223hot\\224 keys and caching)", "(\\225)"} {
+		if !bytes.Contains(pdf, []byte(want)) {
+			t.Errorf("PDF does not contain %s", want)
+		}
+	}
+}
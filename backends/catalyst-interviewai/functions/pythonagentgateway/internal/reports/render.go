@@ -0,0 +1,123 @@
+package reports
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"math"
+	"strings"
+	texttemplate "text/template"
+)
+
+// Format is an output format for a rendered report
+type Format string
+
+const (
+	FormatJSON     Format = "json"
+	FormatMarkdown Format = "markdown"
+	FormatHTML     Format = "html"
+	FormatPDF      Format = "pdf"
+)
+
+//go:embed templates/*.tmpl
+var templateFS embed.FS
+
+var (
+	markdownTemplate = texttemplate.Must(texttemplate.New("report.md.tmpl").Funcs(texttemplate.FuncMap{
+		"score": FormatScore,
+		"quote": quoteMarkdown,
+	}).ParseFS(templateFS, "templates/report.md.tmpl"))
+
+	htmlTemplate = htmltemplate.Must(htmltemplate.New("report.html.tmpl").Funcs(htmltemplate.FuncMap{
+		"score":   FormatScore,
+		"percent": percent,
+	}).ParseFS(templateFS, "templates/report.html.tmpl"))
+)
+
+// ParseFormat maps the format query parameter onto a Format
+func ParseFormat(s string) (Format, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "json":
+		return FormatJSON, nil
+	case "markdown", "md":
+		return FormatMarkdown, nil
+	case "html":
+		return FormatHTML, nil
+	case "pdf":
+		return FormatPDF, nil
+	}
+	return "", fmt.Errorf("unsupported report format %q (use json, markdown, html or pdf)", s)
+}
+
+// ContentType returns the MIME type for the format
+func (f Format) ContentType() string {
+	switch f {
+	case FormatMarkdown:
+		return "text/markdown; charset=utf-8"
+	case FormatHTML:
+		return "text/html; charset=utf-8"
+	case FormatPDF:
+		return "application/pdf"
+	}
+	return "application/json; charset=utf-8"
+}
+
+// Extension returns the file extension for downloads
+func (f Format) Extension() string {
+	switch f {
+	case FormatMarkdown:
+		return "md"
+	case FormatHTML:
+		return "html"
+	case FormatPDF:
+		return "pdf"
+	}
+	return "json"
+}
+
+// Filename returns the download filename for a session's report
+func Filename(sessionID string, f Format) string {
+	safe := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' {
+			return r
+		}
+		return '-'
+	}, sessionID)
+	return fmt.Sprintf("interview-report-%s.%s", safe, f.Extension())
+}
+
+// Render renders the report in a document format. JSON is not rendered here;
+// callers return the structured payload directly.
+func Render(report *Report, f Format) ([]byte, error) {
+	var buf bytes.Buffer
+	switch f {
+	case FormatMarkdown:
+		if err := markdownTemplate.Execute(&buf, report); err != nil {
+			return nil, fmt.Errorf("failed to render markdown report: %w", err)
+		}
+	case FormatHTML:
+		if err := htmlTemplate.Execute(&buf, report); err != nil {
+			return nil, fmt.Errorf("failed to render HTML report: %w", err)
+		}
+	case FormatPDF:
+		return renderPDF(report)
+	default:
+		return nil, fmt.Errorf("format %s cannot be rendered", f)
+	}
+	return buf.Bytes(), nil
+}
+
+// quoteMarkdown prefixes every line with "> " so multi-line answers stay in one block quote
+func quoteMarkdown(s string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight("> "+line, " ")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// percent clamps a dimension score to 0-100 for score bars
+func percent(v float64) string {
+	return FormatScore(math.Max(0, math.Min(100, v)))
+}
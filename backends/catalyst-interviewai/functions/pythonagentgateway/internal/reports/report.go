@@ -0,0 +1,274 @@
+package reports
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ErrEmptyReport is returned when the agent payload contains nothing to render
+var ErrEmptyReport = errors.New("report is empty")
+
+// Report is the structured interview report rendered for candidates
+type Report struct {
+	SessionID       string
+	InterviewType   string
+	TargetRole      string
+	Company         string
+	GeneratedAt     time.Time
+	OverallScore    *float64 // 0-100
+	Summary         string
+	Scores          []Score // 0-100
+	Strengths       []string
+	Improvements    []string
+	Recommendations []string
+	Questions       []QuestionResult
+}
+
+// Score is a single scored dimension, e.g. communication clarity
+type Score struct {
+	Name  string
+	Value float64
+}
+
+// QuestionResult is one question of the transcript with the candidate's answer
+type QuestionResult struct {
+	Number   int
+	Question string
+	Response string
+	Score    *float64 // 0-100
+	Feedback string
+}
+
+// Title returns a human readable report title
+func (r *Report) Title() string {
+	parts := []string{}
+	if r.InterviewType != "" {
+		parts = append(parts, Humanize(r.InterviewType))
+	}
+	parts = append(parts, "Interview Report")
+	return strings.Join(parts, " ")
+}
+
+// Subtitle describes the role and company the interview targeted
+func (r *Report) Subtitle() string {
+	switch {
+	case r.TargetRole != "" && r.Company != "":
+		return fmt.Sprintf("%s at %s", r.TargetRole, r.Company)
+	case r.TargetRole != "":
+		return r.TargetRole
+	default:
+		return r.Company
+	}
+}
+
+// FromAgentResponse builds a Report from the synthesis agent's JSON payload.
+// The agent has used several key spellings over time, so each field accepts the
+// known aliases. The payload may also be wrapped in a top-level "report" or
+// "content" object.
+//
+// Scores are reported on 0-100. An agent scoring on another scale declares its top
+// score as "score_scale" (1 for the evaluator's 0-1 scores), and every score is
+// rescaled from it.
+func FromAgentResponse(sessionID string, payload map[string]interface{}) (*Report, error) {
+	data := payload
+	for _, key := range []string{"report", "content"} {
+		if nested, ok := data[key].(map[string]interface{}); ok {
+			data = nested
+		}
+	}
+
+	report := &Report{
+		SessionID:       sessionID,
+		InterviewType:   stringField(data, "interview_type", "interviewType"),
+		TargetRole:      stringField(data, "target_role", "targetRole", "job_title", "jobTitle"),
+		Company:         stringField(data, "company", "target_company", "targetCompany"),
+		Summary:         stringField(data, "executive_summary", "executiveSummary", "summary", "overall_feedback", "overallFeedback"),
+		Strengths:       stringList(data, "strengths", "consistent_strengths"),
+		Improvements:    stringList(data, "areas_for_improvement", "areasForImprovement", "improvements", "growth_areas", "consistent_weaknesses"),
+		Recommendations: stringList(data, "recommendations", "next_steps", "nextSteps"),
+		GeneratedAt:     time.Now().UTC(),
+	}
+
+	if generated := stringField(data, "generated_at", "generatedAt", "timestamp"); generated != "" {
+		if t, err := time.Parse(time.RFC3339, generated); err == nil {
+			report.GeneratedAt = t.UTC()
+		}
+	}
+
+	scale := 100.0
+	if declared, ok := numberField(data, "score_scale", "scoreScale"); ok && declared > 0 {
+		scale = declared
+	}
+
+	if overall, ok := numberField(data, "overall_score", "overallScore", "overall_average", "score"); ok {
+		overall = rescale(overall, scale)
+		report.OverallScore = &overall
+	}
+
+	for _, key := range []string{"scores", "dimension_scores", "dimensionScores", "average_scores", "detailed_performance", "detailedPerformance"} {
+		if scores, ok := data[key].(map[string]interface{}); ok {
+			report.Scores = scoreList(scores, scale)
+			break
+		}
+	}
+
+	for _, key := range []string{"questions", "question_breakdown", "questionBreakdown", "transcript"} {
+		if entries, ok := data[key].([]interface{}); ok {
+			report.Questions = questionList(entries, scale)
+			break
+		}
+	}
+
+	if report.OverallScore == nil && report.Summary == "" && len(report.Scores) == 0 &&
+		len(report.Strengths) == 0 && len(report.Improvements) == 0 && len(report.Questions) == 0 {
+		return nil, ErrEmptyReport
+	}
+
+	return report, nil
+}
+
+// rescale maps a score on 0..scale onto 0-100
+func rescale(v, scale float64) float64 {
+	return v * 100 / scale
+}
+
+// scoreList turns a dimension->score map into a stable, sorted list
+func scoreList(scores map[string]interface{}, scale float64) []Score {
+	result := make([]Score, 0, len(scores))
+	for name, raw := range scores {
+		if value, ok := toFloat(raw); ok {
+			result = append(result, Score{Name: Humanize(name), Value: rescale(value, scale)})
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// questionList accepts either question records ({question, response, score, feedback})
+// or conversation turns ({role, content}), which are paired into questions.
+func questionList(entries []interface{}, scale float64) []QuestionResult {
+	var results []QuestionResult
+	var current *QuestionResult
+
+	for _, entry := range entries {
+		m, ok := entry.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		if role := strings.ToLower(stringField(m, "role", "speaker")); role != "" {
+			content := stringField(m, "content", "text", "message")
+			switch role {
+			case "interviewer", "assistant", "agent", "model":
+				results = append(results, QuestionResult{Question: content})
+				current = &results[len(results)-1]
+			default:
+				if current == nil {
+					results = append(results, QuestionResult{})
+					current = &results[len(results)-1]
+				}
+				if current.Response != "" {
+					current.Response += "\n\n"
+				}
+				current.Response += content
+			}
+			continue
+		}
+
+		result := QuestionResult{
+			Question: stringField(m, "question", "question_text", "questionText", "prompt"),
+			Response: stringField(m, "response", "answer", "user_response", "userResponse", "response_text"),
+			Feedback: stringField(m, "feedback", "evaluation", "comments"),
+		}
+		if score, ok := numberField(m, "score", "overall_score", "overallScore"); ok {
+			score = rescale(score, scale)
+			result.Score = &score
+		}
+		results = append(results, result)
+		current = &results[len(results)-1]
+	}
+
+	for i := range results {
+		results[i].Number = i + 1
+	}
+	return results
+}
+
+// Humanize turns identifiers like "technical_system_design" into "Technical System Design"
+func Humanize(s string) string {
+	words := strings.FieldsFunc(s, func(r rune) bool { return r == '_' || r == '-' || r == ' ' })
+	for i, word := range words {
+		first, size := utf8.DecodeRuneInString(word)
+		words[i] = strings.ToUpper(string(first)) + word[size:]
+	}
+	return strings.Join(words, " ")
+}
+
+// FormatScore prints a score without trailing zeros
+func FormatScore(v float64) string {
+	if v == math.Trunc(v) {
+		return fmt.Sprintf("%.0f", v)
+	}
+	return fmt.Sprintf("%.1f", v)
+}
+
+func stringField(m map[string]interface{}, keys ...string) string {
+	for _, key := range keys {
+		if s, ok := m[key].(string); ok && strings.TrimSpace(s) != "" {
+			return strings.TrimSpace(s)
+		}
+	}
+	return ""
+}
+
+func numberField(m map[string]interface{}, keys ...string) (float64, bool) {
+	for _, key := range keys {
+		if v, ok := toFloat(m[key]); ok {
+			return v, true
+		}
+	}
+	return 0, false
+}
+
+func stringList(m map[string]interface{}, keys ...string) []string {
+	for _, key := range keys {
+		raw, ok := m[key].([]interface{})
+		if !ok || len(raw) == 0 {
+			continue
+		}
+		result := make([]string, 0, len(raw))
+		for _, item := range raw {
+			switch v := item.(type) {
+			case string:
+				if s := strings.TrimSpace(v); s != "" {
+					result = append(result, s)
+				}
+			case map[string]interface{}:
+				if s := stringField(v, "text", "description", "title", "area"); s != "" {
+					result = append(result, s)
+				}
+			}
+		}
+		if len(result) > 0 {
+			return result
+		}
+	}
+	return nil
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	}
+	return 0, false
+}
@@ -0,0 +1,178 @@
+package reports
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func score(v float64) *float64 {
+	return &v
+}
+
+func TestFromAgentResponse(t *testing.T) {
+	generated := time.Date(2026, 3, 2, 14, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		payload string
+		want    *Report
+		wantErr error
+	}{
+		{
+			name: "snake_case keys",
+			payload: `{"interview_type":"system_design","target_role":"Backend Engineer","company":"Acme",
+				"generated_at":"2026-03-02T15:30:00+01:00","overall_score":72,"executive_summary":" Solid. ",
+				"scores":{"communication_clarity":80,"technical_depth":65.5},
+				"strengths":["Clear structure"," ",{"text":"Good trade-offs"}],
+				"areas_for_improvement":["Capacity estimates"],"recommendations":["Practise sharding"],
+				"questions":[{"question":"Design a URL shortener","response":"I would...","score":70,"feedback":"Good start"}]}`,
+			want: &Report{
+				SessionID: "s1", InterviewType: "system_design", TargetRole: "Backend Engineer", Company: "Acme",
+				GeneratedAt: generated, OverallScore: score(72), Summary: "Solid.",
+				Scores:          []Score{{Name: "Communication Clarity", Value: 80}, {Name: "Technical Depth", Value: 65.5}},
+				Strengths:       []string{"Clear structure", "Good trade-offs"},
+				Improvements:    []string{"Capacity estimates"},
+				Recommendations: []string{"Practise sharding"},
+				Questions:       []QuestionResult{{Number: 1, Question: "Design a URL shortener", Response: "I would...", Score: score(70), Feedback: "Good start"}},
+			},
+		},
+		{
+			name: "camelCase keys",
+			payload: `{"interviewType":"behavioral","jobTitle":"Manager","targetCompany":"Acme","generatedAt":"2026-03-02T14:30:00Z",
+				"overallScore":81,"executiveSummary":"Strong.","dimensionScores":{"leadership":90},
+				"areasForImprovement":["Brevity"],"nextSteps":["Mock interviews"],
+				"questionBreakdown":[{"questionText":"Tell me about a conflict","userResponse":"Once...","overallScore":85,"evaluation":"Concrete"}]}`,
+			want: &Report{
+				SessionID: "s1", InterviewType: "behavioral", TargetRole: "Manager", Company: "Acme",
+				GeneratedAt: generated, OverallScore: score(81), Summary: "Strong.",
+				Scores:          []Score{{Name: "Leadership", Value: 90}},
+				Improvements:    []string{"Brevity"},
+				Recommendations: []string{"Mock interviews"},
+				Questions:       []QuestionResult{{Number: 1, Question: "Tell me about a conflict", Response: "Once...", Score: score(85), Feedback: "Concrete"}},
+			},
+		},
+		{
+			name:    "wrapped in report",
+			payload: `{"report":{"summary":"Wrapped.","generated_at":"2026-03-02T14:30:00Z"}}`,
+			want:    &Report{SessionID: "s1", Summary: "Wrapped.", GeneratedAt: generated},
+		},
+		{
+			name:    "wrapped in report and content",
+			payload: `{"report":{"content":{"overall_feedback":"Twice wrapped.","timestamp":"2026-03-02T14:30:00Z"}}}`,
+			want:    &Report{SessionID: "s1", Summary: "Twice wrapped.", GeneratedAt: generated},
+		},
+		{
+			name: "evaluator's 0-1 scale",
+			payload: `{"score_scale":1,"overall_average":0.75,"average_scores":{"clarity":0.5},"generated_at":"2026-03-02T14:30:00Z",
+				"transcript":[{"question":"Why us?","score":0.25}]}`,
+			want: &Report{
+				SessionID: "s1", GeneratedAt: generated, OverallScore: score(75),
+				Scores:    []Score{{Name: "Clarity", Value: 50}},
+				Questions: []QuestionResult{{Number: 1, Question: "Why us?", Score: score(25)}},
+			},
+		},
+		{
+			name:    "scale of 10 in camelCase",
+			payload: `{"scoreScale":10,"score":7.5,"generated_at":"2026-03-02T14:30:00Z"}`,
+			want:    &Report{SessionID: "s1", GeneratedAt: generated, OverallScore: score(75)},
+		},
+		{
+			name:    "non-positive scale ignored",
+			payload: `{"score_scale":0,"overall_score":60,"generated_at":"2026-03-02T14:30:00Z"}`,
+			want:    &Report{SessionID: "s1", GeneratedAt: generated, OverallScore: score(60)},
+		},
+		{
+			name: "conversation turns paired into questions",
+			payload: `{"generated_at":"2026-03-02T14:30:00Z","transcript":[
+				{"role":"user","content":"Hello"},
+				{"role":"interviewer","content":"Tell me about yourself."},
+				{"role":"candidate","content":"I build APIs."},
+				{"speaker":"User","text":"Mostly in Go."},
+				{"role":"assistant","content":"Why this role?"},
+				"not a turn"]}`,
+			want: &Report{
+				SessionID: "s1", GeneratedAt: generated,
+				Questions: []QuestionResult{
+					{Number: 1, Response: "Hello"},
+					{Number: 2, Question: "Tell me about yourself.", Response: "I build APIs.\n\nMostly in Go."},
+					{Number: 3, Question: "Why this role?"},
+				},
+			},
+		},
+		{
+			name:    "nothing to render",
+			payload: `{"interview_type":"technical","target_role":"SRE","strengths":[]}`,
+			wantErr: ErrEmptyReport,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var payload map[string]interface{}
+			if err := json.Unmarshal([]byte(tt.payload), &payload); err != nil {
+				t.Fatal(err)
+			}
+			got, err := FromAgentResponse("s1", payload)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("FromAgentResponse() error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				gotJSON, _ := json.MarshalIndent(got, "", "  ")
+				wantJSON, _ := json.MarshalIndent(tt.want, "", "  ")
+				t.Errorf("FromAgentResponse() = %s, want %s", gotJSON, wantJSON)
+			}
+		})
+	}
+}
+
+func TestFromAgentResponseGeneratedAt(t *testing.T) {
+	before := time.Now().UTC()
+	report, err := FromAgentResponse("s1", map[string]interface{}{"summary": "Fine.", "generated_at": "yesterday"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.GeneratedAt.Before(before) || report.GeneratedAt.Location() != time.UTC {
+		t.Errorf("GeneratedAt = %v, want the current time in UTC for an unparseable timestamp", report.GeneratedAt)
+	}
+}
+
+func TestHumanize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: "system_design", want: "System Design"},
+		{in: "system-design", want: "System Design"},
+		{in: "communication clarity", want: "Communication Clarity"},
+		{in: "  leading__spaces ", want: "Leading Spaces"},
+		{in: "équipe", want: "Équipe"},
+		{in: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		if got := Humanize(tt.in); got != tt.want {
+			t.Errorf("Humanize(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestFormatScore(t *testing.T) {
+	tests := []struct {
+		in   float64
+		want string
+	}{
+		{in: 80, want: "80"},
+		{in: 65.5, want: "65.5"},
+		{in: 66.666, want: "66.7"},
+		{in: 0, want: "0"},
+	}
+
+	for _, tt := range tests {
+		if got := FormatScore(tt.in); got != tt.want {
+			t.Errorf("FormatScore(%v) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
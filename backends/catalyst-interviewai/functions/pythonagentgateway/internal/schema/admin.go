@@ -0,0 +1,35 @@
+package schema
+
+import "strings"
+
+// MaxAdminBodyBytes caps the body of an admin action
+const MaxAdminBodyBytes = 8 << 10
+
+// MaxReasonLength caps the justification given for an admin action, in characters
+const MaxReasonLength = 1000
+
+// AdminActionRequest is the body of the admin endpoints that change a session. The
+// reason is kept in the audit log.
+type AdminActionRequest struct {
+	Reason string `json:"reason"`
+}
+
+// DecodeAdminAction parses and validates an admin action
+func DecodeAdminAction(body []byte) (*AdminActionRequest, error) {
+	req := &AdminActionRequest{}
+	if err := decodeStrict(body, req, false); err != nil {
+		return nil, err
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+
+	var errs Errors
+	if req.Reason == "" {
+		errs.add("reason", "is required")
+	}
+	errs.checkLength("reason", req.Reason, MaxReasonLength)
+
+	if err := errs.err(); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
@@ -0,0 +1,244 @@
+package schema
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Body size limits. Start requests carry a parsed resume and a job description.
+const (
+	MaxStartBodyBytes   = 256 << 10
+	MaxRespondBodyBytes = 64 << 10
+	MaxEndBodyBytes     = 4 << 10
+)
+
+// Field limits, in characters
+const (
+	MaxResponseLength       = 10000
+	MaxJobDescriptionLength = 20000
+	MaxNameLength           = 200 // target role, company and question ID
+	MaxQuestionLength       = 2000
+	MaxResponseTimeSeconds  = 24 * 60 * 60
+)
+
+// InterviewTypes are the interviews the agents can run, spelled as the agents'
+// scoring and complexity tables key them
+var InterviewTypes = []string{"behavioral", "technical", "system_design", "leadership"}
+
+// interviewTypeAliases maps other spellings clients send to the agents' spelling
+var interviewTypeAliases = map[string]string{
+	"system-design": "system_design",
+	"system design": "system_design",
+}
+
+// FAANGLevels are the levels an interview can target
+var FAANGLevels = []string{"L3", "L4", "L5", "L6", "L7"}
+
+// languagePattern matches BCP-47 tags such as "en" or "en-US"
+var languagePattern = regexp.MustCompile(`^[A-Za-z]{2,3}(-[A-Za-z0-9]{2,8})*$`)
+
+// StartInterviewRequest is the body of POST /api/agents/interview/start
+type StartInterviewRequest struct {
+	InterviewType  string
+	FAANGLevel     string
+	TargetRole     string
+	TargetCompany  string
+	JobDescription string
+	Resume         map[string]interface{}
+}
+
+// startInterviewWire is the JSON form, including the snake_case names the gateway
+// used to read
+type startInterviewWire struct {
+	InterviewType  string                 `json:"interviewType"`
+	FAANGLevel     string                 `json:"faangLevel"`
+	TargetRole     string                 `json:"targetRole"`
+	TargetCompany  string                 `json:"targetCompany"`
+	JobDescription string                 `json:"jobDescription"`
+	Resume         map[string]interface{} `json:"resume"`
+
+	LegacyInterviewType string `json:"interview_type"`
+	LegacyTargetRole    string `json:"target_role"`
+	LegacyCompany       string `json:"company"`
+}
+
+// DecodeStartInterview parses and validates a start request
+func DecodeStartInterview(body []byte) (*StartInterviewRequest, error) {
+	var wire startInterviewWire
+	if err := decodeStrict(body, &wire, false); err != nil {
+		return nil, err
+	}
+
+	var errs Errors
+	req := &StartInterviewRequest{
+		InterviewType:  strings.TrimSpace(errs.alias("interviewType", wire.InterviewType, "interview_type", wire.LegacyInterviewType)),
+		FAANGLevel:     strings.ToUpper(strings.TrimSpace(wire.FAANGLevel)),
+		TargetRole:     strings.TrimSpace(errs.alias("targetRole", wire.TargetRole, "target_role", wire.LegacyTargetRole)),
+		TargetCompany:  strings.TrimSpace(errs.alias("targetCompany", wire.TargetCompany, "company", wire.LegacyCompany)),
+		JobDescription: strings.TrimSpace(wire.JobDescription),
+		Resume:         wire.Resume,
+	}
+
+	if req.InterviewType == "" {
+		errs.add("interviewType", "is required")
+	} else {
+		if canonical, ok := interviewTypeAliases[strings.ToLower(req.InterviewType)]; ok {
+			req.InterviewType = canonical
+		}
+		errs.checkOneOf("interviewType", req.InterviewType, InterviewTypes)
+	}
+	if req.FAANGLevel == "" {
+		errs.add("faangLevel", "is required")
+	} else {
+		errs.checkOneOf("faangLevel", req.FAANGLevel, FAANGLevels)
+	}
+	errs.checkLength("targetRole", req.TargetRole, MaxNameLength)
+	errs.checkLength("targetCompany", req.TargetCompany, MaxNameLength)
+	errs.checkLength("jobDescription", req.JobDescription, MaxJobDescriptionLength)
+
+	if err := errs.err(); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// Body is the request forwarded to the agent service
+func (req *StartInterviewRequest) Body() map[string]interface{} {
+	body := map[string]interface{}{
+		"interviewType": req.InterviewType,
+		"faangLevel":    req.FAANGLevel,
+	}
+	setIfPresent(body, "targetRole", req.TargetRole)
+	setIfPresent(body, "targetCompany", req.TargetCompany)
+	setIfPresent(body, "jobDescription", req.JobDescription)
+	if req.Resume != nil {
+		body["resume"] = req.Resume
+	}
+	return body
+}
+
+// RespondRequest is the body of POST /api/agents/interview/{sessionId}/respond
+type RespondRequest struct {
+	Response string
+	// ResponseTime is the client's own measure, used when the gateway has none
+	ResponseTime *float64
+	QuestionID   string
+	QuestionText string
+	Language     string
+}
+
+// respondWire is the JSON form; question fields are accepted in either case style
+type respondWire struct {
+	Response     string   `json:"response"`
+	ResponseTime *float64 `json:"responseTime"`
+	QuestionID   string   `json:"question_id"`
+	QuestionText string   `json:"question_text"`
+	Language     string   `json:"language"`
+
+	CamelQuestionID   string `json:"questionId"`
+	CamelQuestionText string `json:"questionText"`
+}
+
+// DecodeRespond parses and validates an answer
+func DecodeRespond(body []byte) (*RespondRequest, error) {
+	var wire respondWire
+	if err := decodeStrict(body, &wire, false); err != nil {
+		return nil, err
+	}
+
+	var errs Errors
+	req := &RespondRequest{
+		Response:     wire.Response,
+		ResponseTime: wire.ResponseTime,
+		QuestionID:   strings.TrimSpace(errs.alias("question_id", wire.QuestionID, "questionId", wire.CamelQuestionID)),
+		QuestionText: strings.TrimSpace(errs.alias("question_text", wire.QuestionText, "questionText", wire.CamelQuestionText)),
+		Language:     strings.TrimSpace(wire.Language),
+	}
+	req.validate(&errs, true)
+
+	if err := errs.err(); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// RespondFromForm validates the text fields sent with a spoken answer. The answer
+// itself is the transcript, so it is not among them.
+func RespondFromForm(fields map[string]string) (*RespondRequest, error) {
+	var errs Errors
+	known := map[string]bool{"question_id": true, "questionId": true, "question_text": true, "questionText": true, "language": true}
+	names := make([]string, 0, len(fields))
+	for field := range fields {
+		names = append(names, field)
+	}
+	sort.Strings(names)
+	for _, field := range names {
+		if !known[field] {
+			errs.add(field, "is not a known field")
+		}
+	}
+
+	value := func(field string) string { return strings.TrimSpace(fields[field]) }
+	req := &RespondRequest{
+		QuestionID:   errs.alias("question_id", value("question_id"), "questionId", value("questionId")),
+		QuestionText: errs.alias("question_text", value("question_text"), "questionText", value("questionText")),
+		Language:     value("language"),
+	}
+	req.validate(&errs, false)
+
+	if err := errs.err(); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// validate checks the fields; the answer text only when it came from the client
+func (req *RespondRequest) validate(errs *Errors, withResponse bool) {
+	if withResponse {
+		if strings.TrimSpace(req.Response) == "" {
+			errs.add("response", "is required")
+		}
+		errs.checkLength("response", req.Response, MaxResponseLength)
+	}
+	if req.ResponseTime != nil && (*req.ResponseTime < 0 || *req.ResponseTime > MaxResponseTimeSeconds) {
+		errs.add("responseTime", "must be between 0 and %d seconds", MaxResponseTimeSeconds)
+	}
+	errs.checkLength("question_id", req.QuestionID, MaxNameLength)
+	errs.checkLength("question_text", req.QuestionText, MaxQuestionLength)
+	if req.Language != "" && !languagePattern.MatchString(req.Language) {
+		errs.add("language", "must be a language tag such as en-US")
+	}
+}
+
+// Body is the request forwarded to the agent service, before the gateway adds the
+// session and answer IDs
+func (req *RespondRequest) Body() map[string]interface{} {
+	body := map[string]interface{}{"response": req.Response}
+	if req.ResponseTime != nil {
+		body["responseTime"] = *req.ResponseTime
+	}
+	setIfPresent(body, "question_id", req.QuestionID)
+	setIfPresent(body, "question_text", req.QuestionText)
+	setIfPresent(body, "language", req.Language)
+	return body
+}
+
+// EndInterviewRequest is the body of POST /api/agents/interview/{sessionId}/end. It
+// has no fields; the body may be empty or {}.
+type EndInterviewRequest struct{}
+
+// DecodeEndInterview checks that an end request carries nothing unexpected
+func DecodeEndInterview(body []byte) (*EndInterviewRequest, error) {
+	req := &EndInterviewRequest{}
+	if err := decodeStrict(body, req, true); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+func setIfPresent(body map[string]interface{}, key, value string) {
+	if value != "" {
+		body[key] = value
+	}
+}
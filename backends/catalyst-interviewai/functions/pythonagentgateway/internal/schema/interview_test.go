@@ -0,0 +1,311 @@
+package schema
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fieldErrors returns the field and message of each problem in err
+func fieldErrors(t *testing.T, err error) []FieldError {
+	t.Helper()
+	if err == nil {
+		return nil
+	}
+	var errs Errors
+	if !errors.As(err, &errs) {
+		t.Fatalf("got error %v, want schema.Errors", err)
+	}
+	return errs
+}
+
+func TestDecodeStartInterview(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantBody   map[string]interface{}
+		wantErrors []FieldError
+	}{
+		{
+			name:     "required fields",
+			body:     `{"interviewType":"behavioral","faangLevel":"L5"}`,
+			wantBody: map[string]interface{}{"interviewType": "behavioral", "faangLevel": "L5"},
+		},
+		{
+			name: "all fields trimmed",
+			body: `{"interviewType":" technical ","faangLevel":"l4","targetRole":" Backend Engineer ","targetCompany":"Acme","jobDescription":"  Build APIs  ","resume":{"skills":["Go"]}}`,
+			wantBody: map[string]interface{}{
+				"interviewType":  "technical",
+				"faangLevel":     "L4",
+				"targetRole":     "Backend Engineer",
+				"targetCompany":  "Acme",
+				"jobDescription": "Build APIs",
+				"resume":         map[string]interface{}{"skills": []interface{}{"Go"}},
+			},
+		},
+		{
+			name:     "legacy snake_case names",
+			body:     `{"interview_type":"leadership","faangLevel":"L6","target_role":"Manager","company":"Acme"}`,
+			wantBody: map[string]interface{}{"interviewType": "leadership", "faangLevel": "L6", "targetRole": "Manager", "targetCompany": "Acme"},
+		},
+		{
+			name:     "legacy name agreeing with the new one",
+			body:     `{"interviewType":"behavioral","interview_type":"behavioral","faangLevel":"L3"}`,
+			wantBody: map[string]interface{}{"interviewType": "behavioral", "faangLevel": "L3"},
+		},
+		{
+			name:     "system design in the agents' spelling",
+			body:     `{"interviewType":"system_design","faangLevel":"L5"}`,
+			wantBody: map[string]interface{}{"interviewType": "system_design", "faangLevel": "L5"},
+		},
+		{
+			name:     "system design with a hyphen",
+			body:     `{"interviewType":"system-design","faangLevel":"L5"}`,
+			wantBody: map[string]interface{}{"interviewType": "system_design", "faangLevel": "L5"},
+		},
+		{
+			name:     "system design with a space",
+			body:     `{"interview_type":"System Design","faangLevel":"L5"}`,
+			wantBody: map[string]interface{}{"interviewType": "system_design", "faangLevel": "L5"},
+		},
+		{
+			name:       "legacy name conflicting with the new one",
+			body:       `{"interviewType":"behavioral","interview_type":"technical","faangLevel":"L3"}`,
+			wantErrors: []FieldError{{Field: "interview_type", Message: "conflicts with interviewType"}},
+		},
+		{
+			name: "missing required fields",
+			body: `{}`,
+			wantErrors: []FieldError{
+				{Field: "interviewType", Message: "is required"},
+				{Field: "faangLevel", Message: "is required"},
+			},
+		},
+		{
+			name: "values outside the allowed sets",
+			body: `{"interviewType":"coding","faangLevel":"L9"}`,
+			wantErrors: []FieldError{
+				{Field: "interviewType", Message: "must be one of behavioral, technical, system_design, leadership"},
+				{Field: "faangLevel", Message: "must be one of L3, L4, L5, L6, L7"},
+			},
+		},
+		{
+			name:       "long role counted in characters",
+			body:       `{"interviewType":"behavioral","faangLevel":"L5","targetRole":"` + strings.Repeat("é", MaxNameLength+1) + `"}`,
+			wantErrors: []FieldError{{Field: "targetRole", Message: "must be at most 200 characters"}},
+		},
+		{
+			name:     "role at the limit in multi-byte characters",
+			body:     `{"interviewType":"behavioral","faangLevel":"L5","targetRole":"` + strings.Repeat("é", MaxNameLength) + `"}`,
+			wantBody: map[string]interface{}{"interviewType": "behavioral", "faangLevel": "L5", "targetRole": strings.Repeat("é", MaxNameLength)},
+		},
+		{
+			name:       "unknown field",
+			body:       `{"interviewType":"behavioral","faangLevel":"L5","difficulty":"hard"}`,
+			wantErrors: []FieldError{{Field: "difficulty", Message: "is not a known field"}},
+		},
+		{
+			name:       "wrong type",
+			body:       `{"interviewType":"behavioral","faangLevel":"L5","resume":"my resume"}`,
+			wantErrors: []FieldError{{Field: "resume", Message: "must be an object"}},
+		},
+		{
+			name:       "not an object",
+			body:       `["behavioral"]`,
+			wantErrors: []FieldError{{Field: "body", Message: "must be a JSON object"}},
+		},
+		{
+			name:       "invalid JSON",
+			body:       `{"interviewType":}`,
+			wantErrors: []FieldError{{Field: "body", Message: "invalid JSON at offset 18"}},
+		},
+		{
+			name:       "truncated",
+			body:       `{"interviewType":"behavioral"`,
+			wantErrors: []FieldError{{Field: "body", Message: "is truncated"}},
+		},
+		{
+			name:       "two objects",
+			body:       `{"interviewType":"behavioral","faangLevel":"L5"} {}`,
+			wantErrors: []FieldError{{Field: "body", Message: "must contain a single JSON object"}},
+		},
+		{
+			name:       "empty body",
+			body:       " \n",
+			wantErrors: []FieldError{{Field: "body", Message: "a JSON object is required"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := DecodeStartInterview([]byte(tt.body))
+			if got := fieldErrors(t, err); !reflect.DeepEqual(got, tt.wantErrors) {
+				t.Fatalf("errors = %v, want %v", got, tt.wantErrors)
+			}
+			if err != nil {
+				return
+			}
+			if got := req.Body(); !reflect.DeepEqual(got, tt.wantBody) {
+				t.Errorf("Body() = %v, want %v", got, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestDecodeRespond(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantBody   map[string]interface{}
+		wantErrors []FieldError
+	}{
+		{
+			name:     "answer only",
+			body:     `{"response":"I led the migration."}`,
+			wantBody: map[string]interface{}{"response": "I led the migration."},
+		},
+		{
+			name: "all fields",
+			body: `{"response":"  I led it.  ","responseTime":42.5,"question_id":" q1 ","question_text":"Tell me about a project","language":"en-US"}`,
+			wantBody: map[string]interface{}{
+				"response":      "  I led it.  ",
+				"responseTime":  42.5,
+				"question_id":   "q1",
+				"question_text": "Tell me about a project",
+				"language":      "en-US",
+			},
+		},
+		{
+			name:     "camelCase question fields",
+			body:     `{"response":"Yes","questionId":"q2","questionText":"Ready?"}`,
+			wantBody: map[string]interface{}{"response": "Yes", "question_id": "q2", "question_text": "Ready?"},
+		},
+		{
+			name:     "zero response time is kept",
+			body:     `{"response":"Yes","responseTime":0}`,
+			wantBody: map[string]interface{}{"response": "Yes", "responseTime": 0.0},
+		},
+		{
+			name:       "question IDs conflicting",
+			body:       `{"response":"Yes","question_id":"q1","questionId":"q2"}`,
+			wantErrors: []FieldError{{Field: "questionId", Message: "conflicts with question_id"}},
+		},
+		{
+			name:       "blank answer",
+			body:       `{"response":"   "}`,
+			wantErrors: []FieldError{{Field: "response", Message: "is required"}},
+		},
+		{
+			name:       "answer too long",
+			body:       `{"response":"` + strings.Repeat("ü", MaxResponseLength+1) + `"}`,
+			wantErrors: []FieldError{{Field: "response", Message: "must be at most 10000 characters"}},
+		},
+		{
+			name: "response time out of range and bad language",
+			body: `{"response":"Yes","responseTime":-1,"language":"english please"}`,
+			wantErrors: []FieldError{
+				{Field: "responseTime", Message: "must be between 0 and 86400 seconds"},
+				{Field: "language", Message: "must be a language tag such as en-US"},
+			},
+		},
+		{
+			name:       "response time as a string",
+			body:       `{"response":"Yes","responseTime":"42"}`,
+			wantErrors: []FieldError{{Field: "responseTime", Message: "must be a number"}},
+		},
+		{
+			name:       "unknown field",
+			body:       `{"response":"Yes","score":5}`,
+			wantErrors: []FieldError{{Field: "score", Message: "is not a known field"}},
+		},
+		{
+			name:       "empty body",
+			body:       "",
+			wantErrors: []FieldError{{Field: "body", Message: "a JSON object is required"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := DecodeRespond([]byte(tt.body))
+			if got := fieldErrors(t, err); !reflect.DeepEqual(got, tt.wantErrors) {
+				t.Fatalf("errors = %v, want %v", got, tt.wantErrors)
+			}
+			if err != nil {
+				return
+			}
+			if got := req.Body(); !reflect.DeepEqual(got, tt.wantBody) {
+				t.Errorf("Body() = %v, want %v", got, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestDecodeEndInterview(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantErrors []FieldError
+	}{
+		{name: "empty body", body: ""},
+		{name: "empty object", body: "{}"},
+		{name: "any field", body: `{"reason":"done"}`, wantErrors: []FieldError{{Field: "reason", Message: "is not a known field"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := DecodeEndInterview([]byte(tt.body))
+			if got := fieldErrors(t, err); !reflect.DeepEqual(got, tt.wantErrors) {
+				t.Errorf("errors = %v, want %v", got, tt.wantErrors)
+			}
+		})
+	}
+}
+
+func TestRespondFromForm(t *testing.T) {
+	tests := []struct {
+		name       string
+		fields     map[string]string
+		wantBody   map[string]interface{}
+		wantErrors []FieldError
+	}{
+		{
+			name:     "no fields",
+			wantBody: map[string]interface{}{"response": ""},
+		},
+		{
+			name:     "question and language",
+			fields:   map[string]string{"questionId": " q3 ", "question_text": "Why us?", "language": "de"},
+			wantBody: map[string]interface{}{"response": "", "question_id": "q3", "question_text": "Why us?", "language": "de"},
+		},
+		{
+			name:   "unknown fields in name order",
+			fields: map[string]string{"zeta": "1", "alpha": "2"},
+			wantErrors: []FieldError{
+				{Field: "alpha", Message: "is not a known field"},
+				{Field: "zeta", Message: "is not a known field"},
+			},
+		},
+		{
+			name:       "answer text is not a form field",
+			fields:     map[string]string{"response": "typed answer"},
+			wantErrors: []FieldError{{Field: "response", Message: "is not a known field"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := RespondFromForm(tt.fields)
+			if got := fieldErrors(t, err); !reflect.DeepEqual(got, tt.wantErrors) {
+				t.Fatalf("errors = %v, want %v", got, tt.wantErrors)
+			}
+			if err != nil {
+				return
+			}
+			if got := req.Body(); !reflect.DeepEqual(got, tt.wantBody) {
+				t.Errorf("Body() = %v, want %v", got, tt.wantBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+// Package schema decodes and validates the JSON bodies the agent gateway accepts, so
+// malformed requests are refused with field-level errors before anything is called
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrBodyTooLarge is returned when a body exceeds the limit for its endpoint
+var ErrBodyTooLarge = errors.New("request body is too large")
+
+// FieldError is a problem with one field of a request. Field is the JSON name, or
+// "body" for problems with the body as a whole.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Errors lists every problem found in a request
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	parts := make([]string, len(e))
+	for i, fieldErr := range e {
+		parts[i] = fieldErr.Field + ": " + fieldErr.Message
+	}
+	return strings.Join(parts, "; ")
+}
+
+// add records a problem with field
+func (e *Errors) add(field, format string, args ...interface{}) {
+	*e = append(*e, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// err returns the errors, or nil when there are none
+func (e Errors) err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+// ReadBody reads at most limit bytes of the request body, returning ErrBodyTooLarge
+// for anything longer
+func ReadBody(w http.ResponseWriter, r *http.Request, limit int64) ([]byte, error) {
+	defer r.Body.Close()
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, limit))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return nil, fmt.Errorf("%w (limit %d bytes)", ErrBodyTooLarge, limit)
+		}
+		return nil, Errors{{Field: "body", Message: "could not be read"}}
+	}
+	return body, nil
+}
+
+// decodeStrict decodes a single JSON object into dst, refusing fields dst does not
+// have. An empty body is allowed only when optional is set.
+func decodeStrict(body []byte, dst interface{}, optional bool) error {
+	if len(bytes.TrimSpace(body)) == 0 {
+		if optional {
+			return nil
+		}
+		return Errors{{Field: "body", Message: "a JSON object is required"}}
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(dst); err != nil {
+		return Errors{decodeError(err)}
+	}
+	if decoder.More() {
+		return Errors{{Field: "body", Message: "must contain a single JSON object"}}
+	}
+	return nil
+}
+
+// decodeError describes a JSON decoding failure in terms of the field it concerns
+func decodeError(err error) FieldError {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		return FieldError{Field: "body", Message: fmt.Sprintf("invalid JSON at offset %d", syntaxErr.Offset)}
+	case errors.As(err, &typeErr):
+		if typeErr.Field == "" {
+			return FieldError{Field: "body", Message: "must be a JSON object"}
+		}
+		return FieldError{Field: typeErr.Field, Message: "must be " + jsonKind(typeErr.Type.Kind().String())}
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		return FieldError{Field: field, Message: "is not a known field"}
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return FieldError{Field: "body", Message: "is truncated"}
+	default:
+		return FieldError{Field: "body", Message: "is not valid JSON"}
+	}
+}
+
+// jsonKind names a Go kind the way a JSON client thinks of it
+func jsonKind(kind string) string {
+	switch kind {
+	case "string":
+		return "a string"
+	case "bool":
+		return "a boolean"
+	case "float32", "float64", "int", "int64", "int32":
+		return "a number"
+	case "map", "struct":
+		return "an object"
+	case "slice", "array":
+		return "an array"
+	default:
+		return "a " + kind
+	}
+}
+
+// checkLength records an error when value has more than max characters
+func (e *Errors) checkLength(field, value string, max int) {
+	if utf8.RuneCountInString(value) > max {
+		e.add(field, "must be at most %d characters", max)
+	}
+}
+
+// checkOneOf records an error when value is not one of allowed
+func (e *Errors) checkOneOf(field, value string, allowed []string) {
+	for _, option := range allowed {
+		if value == option {
+			return
+		}
+	}
+	e.add(field, "must be one of %s", strings.Join(allowed, ", "))
+}
+
+// alias resolves a field that older clients send under a second name. Sending both
+// with different values is an error.
+func (e *Errors) alias(field, value, legacyField, legacyValue string) string {
+	switch {
+	case legacyValue == "":
+		return value
+	case value == "":
+		return legacyValue
+	case value != legacyValue:
+		e.add(legacyField, "conflicts with %s", field)
+	}
+	return value
+}
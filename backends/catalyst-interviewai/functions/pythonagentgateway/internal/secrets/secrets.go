@@ -0,0 +1,77 @@
+package secrets
+
+// Secret Manager helpers for GCF
+
+import (
+	"context"
+	"fmt"
+
+	secretmanager "cloud.google.com/go/secretmanager/apiv1"
+	"cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"interviewai.wkv.local/pythonagentgateway/internal/tracing"
+)
+
+// SecretNameForUser generates the Google Secret Manager secret name for a given user ID.
+func SecretNameForUser(userID string) string {
+	return fmt.Sprintf("user-gemini-api-key-%s", userID)
+}
+
+// GetUserAPIKey accesses a secret version from Secret Manager.
+// It expects the Secret Manager client and GCP Project ID to be passed or be accessible.
+func GetUserAPIKey(ctx context.Context, client *secretmanager.Client, gcpProjectID string, userID string) (string, error) {
+	if client == nil {
+		return "", fmt.Errorf("Secret Manager client not initialized")
+	}
+	secretID := SecretNameForUser(userID)
+	secretVersionName := fmt.Sprintf("projects/%s/secrets/%s/versions/latest", gcpProjectID, secretID)
+
+	accessRequest := &secretmanagerpb.AccessSecretVersionRequest{
+		Name: secretVersionName,
+	}
+
+	result, err := accessSecretVersion(ctx, client, secretID, accessRequest)
+	if err != nil {
+		st, ok := status.FromError(err)
+		if ok && (st.Code() == codes.NotFound || st.Code() == codes.PermissionDenied || st.Code() == codes.FailedPrecondition) {
+			return "", fmt.Errorf("no active API key found for user %s: %w", userID, err) // Specific error for not found/disabled
+		}
+		return "", fmt.Errorf("failed to access secret version for user %s: %w", userID, err) // General error
+	}
+
+	return string(result.Payload.Data), nil
+}
+
+// GetSystemSecret accesses the latest version of a project-wide secret, such as a
+// key shared between services.
+func GetSystemSecret(ctx context.Context, client *secretmanager.Client, gcpProjectID string, secretID string) (string, error) {
+	if client == nil {
+		return "", fmt.Errorf("Secret Manager client not initialized")
+	}
+	secretVersionName := fmt.Sprintf("projects/%s/secrets/%s/versions/latest", gcpProjectID, secretID)
+
+	result, err := accessSecretVersion(ctx, client, secretID, &secretmanagerpb.AccessSecretVersionRequest{
+		Name: secretVersionName,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to access secret %s: %w", secretID, err)
+	}
+
+	return string(result.Payload.Data), nil
+}
+
+// accessSecretVersion reads a secret inside a client span, so slow Secret Manager
+// reads show up in the request's trace
+func accessSecretVersion(ctx context.Context, client *secretmanager.Client, secretID string, req *secretmanagerpb.AccessSecretVersionRequest) (*secretmanagerpb.AccessSecretVersionResponse, error) {
+	ctx, span := tracing.Start(ctx, "SecretManager.AccessSecretVersion", trace.SpanKindClient,
+		attribute.String("rpc.system", "grpc"),
+		attribute.String("rpc.service", "google.cloud.secretmanager.v1.SecretManagerService"),
+		attribute.String("secret.id", secretID),
+	)
+	result, err := client.AccessSecretVersion(ctx, req)
+	tracing.End(span, err)
+	return result, err
+}
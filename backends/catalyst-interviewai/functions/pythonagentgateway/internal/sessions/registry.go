@@ -0,0 +1,252 @@
+package sessions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Session statuses
+const (
+	StatusActive    = "active"
+	StatusCompleted = "completed"
+	StatusAbandoned = "abandoned"
+	// StatusTerminated marks a session an administrator ended
+	StatusTerminated = "terminated"
+)
+
+var (
+	// ErrSessionNotFound is returned when the caller has no such session
+	ErrSessionNotFound = errors.New("session not found")
+	// ErrSessionForbidden is returned when a session belongs to another user
+	ErrSessionForbidden = errors.New("session belongs to another user")
+)
+
+// Session is a registry entry for an agent interview session owned by a user.
+// Entries live at users/{uid}/agentSessions/{sessionId}.
+type Session struct {
+	SessionID      string     `firestore:"sessionId" json:"sessionId"`
+	UserID         string     `firestore:"userId" json:"userId"`
+	InterviewType  string     `firestore:"interviewType" json:"interviewType"`
+	TargetRole     string     `firestore:"targetRole,omitempty" json:"targetRole,omitempty"`
+	Company        string     `firestore:"company,omitempty" json:"company,omitempty"`
+	Status         string     `firestore:"status" json:"status"`
+	CreatedAt      time.Time  `firestore:"createdAt" json:"createdAt"`
+	LastActivityAt time.Time  `firestore:"lastActivityAt" json:"lastActivityAt"`
+	EndedAt        *time.Time `firestore:"endedAt,omitempty" json:"endedAt,omitempty"`
+	ResponseCount  int        `firestore:"responseCount" json:"responseCount"`
+	// Variant names the agent backend the session was assigned to
+	Variant string `firestore:"variant,omitempty" json:"variant,omitempty"`
+	// KeySource is whose Gemini key the session was started on, "user" or "default"
+	KeySource string `firestore:"keySource,omitempty" json:"keySource,omitempty"`
+	// TurnCount is the number of turns in the session's transcript
+	TurnCount int `firestore:"turnCount" json:"turnCount"`
+	// AgentState is the interview state the agent last reported
+	AgentState string `firestore:"agentState,omitempty" json:"agentState,omitempty"`
+	// StartRequest is the body the session was started with, replayed to resume it
+	StartRequest map[string]interface{} `firestore:"startRequest,omitempty" json:"-"`
+	// ResumeCount counts how often the session was rebuilt on the agent from its transcript
+	ResumeCount int `firestore:"resumeCount,omitempty" json:"resumeCount,omitempty"`
+}
+
+// Registry records which user owns which agent session
+type Registry struct {
+	client *firestore.Client
+}
+
+// NewRegistry creates a session registry backed by Firestore
+func NewRegistry(client *firestore.Client) *Registry {
+	return &Registry{client: client}
+}
+
+func (r *Registry) sessionDoc(userID, sessionID string) *firestore.DocumentRef {
+	return r.client.Collection("users").Doc(userID).Collection("agentSessions").Doc(sessionID)
+}
+
+// Register records a newly started session for its owner
+func (r *Registry) Register(ctx context.Context, session *Session) error {
+	if session.SessionID == "" || session.UserID == "" {
+		return fmt.Errorf("session ID and user ID are required")
+	}
+
+	now := time.Now().UTC()
+	if session.CreatedAt.IsZero() {
+		session.CreatedAt = now
+	}
+	session.LastActivityAt = now
+	if session.Status == "" {
+		session.Status = StatusActive
+	}
+
+	if _, err := r.sessionDoc(session.UserID, session.SessionID).Set(ctx, session); err != nil {
+		return fmt.Errorf("failed to register session %s: %w", session.SessionID, err)
+	}
+	return nil
+}
+
+// Authorize returns the session if it belongs to userID.
+// It returns ErrSessionNotFound if the caller has no such session.
+func (r *Registry) Authorize(ctx context.Context, userID, sessionID string) (*Session, error) {
+	doc, err := r.sessionDoc(userID, sessionID).Get(ctx)
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return nil, ErrSessionNotFound
+		}
+		return nil, fmt.Errorf("failed to look up session %s: %w", sessionID, err)
+	}
+
+	var session Session
+	if err := doc.DataTo(&session); err != nil {
+		return nil, fmt.Errorf("failed to parse session %s: %w", sessionID, err)
+	}
+
+	// The document path already scopes by user; this guards against misfiled entries
+	if session.UserID != userID {
+		return nil, ErrSessionForbidden
+	}
+
+	return &session, nil
+}
+
+// MarkEnded sets the final status of a session
+func (r *Registry) MarkEnded(ctx context.Context, userID, sessionID, finalStatus string) error {
+	now := time.Now().UTC()
+	_, err := r.sessionDoc(userID, sessionID).Update(ctx, []firestore.Update{
+		{Path: "status", Value: finalStatus},
+		{Path: "endedAt", Value: now},
+		{Path: "lastActivityAt", Value: now},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to end session %s: %w", sessionID, err)
+	}
+	return nil
+}
+
+// List returns the user's sessions, most recent first
+func (r *Registry) List(ctx context.Context, userID string, limit int) ([]*Session, error) {
+	query := r.client.Collection("users").Doc(userID).Collection("agentSessions").
+		OrderBy("createdAt", firestore.Desc)
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	iter := query.Documents(ctx)
+	defer iter.Stop()
+
+	var result []*Session
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list sessions: %w", err)
+		}
+
+		var session Session
+		if err := doc.DataTo(&session); err != nil {
+			return nil, fmt.Errorf("failed to parse session %s: %w", doc.Ref.ID, err)
+		}
+		result = append(result, &session)
+	}
+
+	return result, nil
+}
+
+// Find returns the session with the given ID whichever user owns it. The query
+// needs the agentSessions collection group index on sessionId.
+func (r *Registry) Find(ctx context.Context, sessionID string) (*Session, error) {
+	iter := r.client.CollectionGroup("agentSessions").
+		Where("sessionId", "==", sessionID).
+		Limit(1).
+		Documents(ctx)
+	defer iter.Stop()
+
+	doc, err := iter.Next()
+	if err == iterator.Done {
+		return nil, ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find session %s: %w", sessionID, err)
+	}
+
+	var session Session
+	if err := doc.DataTo(&session); err != nil {
+		return nil, fmt.Errorf("failed to parse session %s: %w", sessionID, err)
+	}
+	return &session, nil
+}
+
+// Active returns active sessions of every user, most recent first. The query needs
+// the agentSessions collection group index on status and createdAt.
+func (r *Registry) Active(ctx context.Context, limit int) ([]*Session, error) {
+	query := r.client.CollectionGroup("agentSessions").
+		Where("status", "==", StatusActive).
+		OrderBy("createdAt", firestore.Desc)
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	iter := query.Documents(ctx)
+	defer iter.Stop()
+
+	var result []*Session
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list active sessions: %w", err)
+		}
+
+		var session Session
+		if err := doc.DataTo(&session); err != nil {
+			return nil, fmt.Errorf("failed to parse session %s: %w", doc.Ref.ID, err)
+		}
+		result = append(result, &session)
+	}
+
+	return result, nil
+}
+
+// Idle returns active sessions of every user with no activity since before, the
+// longest idle first. The query needs the agentSessions collection group index on
+// status and lastActivityAt.
+func (r *Registry) Idle(ctx context.Context, before time.Time, limit int) ([]*Session, error) {
+	query := r.client.CollectionGroup("agentSessions").
+		Where("status", "==", StatusActive).
+		Where("lastActivityAt", "<", before.UTC()).
+		OrderBy("lastActivityAt", firestore.Asc)
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	iter := query.Documents(ctx)
+	defer iter.Stop()
+
+	var result []*Session
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to list idle sessions: %w", err)
+		}
+
+		var session Session
+		if err := doc.DataTo(&session); err != nil {
+			return nil, fmt.Errorf("failed to parse session %s: %w", doc.Ref.ID, err)
+		}
+		result = append(result, &session)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,371 @@
+package sessions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Turn is one exchange of an interview: the agent's question, the candidate's answer
+// and the agent's reply. Turns live at users/{uid}/agentSessions/{sessionId}/turns/{index}
+// so a session can be rebuilt if the agent loses it.
+type Turn struct {
+	Index      int        `firestore:"index" json:"index"`
+	Question   string     `firestore:"question,omitempty" json:"question,omitempty"`
+	AskedAt    *time.Time `firestore:"askedAt,omitempty" json:"askedAt,omitempty"`
+	ResponseID string     `firestore:"responseId,omitempty" json:"responseId,omitempty"`
+	Answer     string     `firestore:"answer,omitempty" json:"answer,omitempty"`
+	AnsweredAt *time.Time `firestore:"answeredAt,omitempty" json:"answeredAt,omitempty"`
+	Reply      string     `firestore:"reply,omitempty" json:"reply,omitempty"`
+	RepliedAt  *time.Time `firestore:"repliedAt,omitempty" json:"repliedAt,omitempty"`
+	// State is the agent's interview state after its reply
+	State string `firestore:"state,omitempty" json:"state,omitempty"`
+	// Audio describes the recording when the answer was spoken
+	Audio *AudioInfo `firestore:"audio,omitempty" json:"audio,omitempty"`
+	// Draft is the latest unsubmitted version of the answer, kept until it is submitted
+	Draft        string     `firestore:"draft,omitempty" json:"-"`
+	FirstDraftAt *time.Time `firestore:"firstDraftAt,omitempty" json:"firstDraftAt,omitempty"`
+	// RevisionCount counts the times the candidate changed their answer after first
+	// drafting it, including a submission that differs from the last draft
+	RevisionCount int `firestore:"revisionCount,omitempty" json:"revisionCount,omitempty"`
+	// ResponseTimeSeconds runs from when the question was delivered to the answer
+	ResponseTimeSeconds *int `firestore:"responseTimeSeconds,omitempty" json:"responseTimeSeconds,omitempty"`
+	WordCount           int  `firestore:"wordCount,omitempty" json:"wordCount,omitempty"`
+	// FailedAnswer is the last answer the agent failed to process, kept until the turn
+	// is answered so it can be replayed
+	FailedAnswer  string     `firestore:"failedAnswer,omitempty" json:"failedAnswer,omitempty"`
+	FailedAt      *time.Time `firestore:"failedAt,omitempty" json:"failedAt,omitempty"`
+	FailureReason string     `firestore:"failureReason,omitempty" json:"failureReason,omitempty"`
+	// FailureCount counts the failed submissions of the turn, replays included
+	FailureCount int `firestore:"failureCount,omitempty" json:"failureCount,omitempty"`
+}
+
+// ErrNoOpenTurn is returned when a draft arrives while no question is waiting for an answer
+var ErrNoOpenTurn = errors.New("no question is waiting for an answer")
+
+// AudioInfo describes a spoken answer. The recording itself is not kept.
+type AudioInfo struct {
+	Format          string  `firestore:"format" json:"format"`
+	Transcriber     string  `firestore:"transcriber" json:"transcriber"`
+	DurationSeconds float64 `firestore:"durationSeconds" json:"durationSeconds"`
+	// SpeakingSeconds runs from the first word to the last
+	SpeakingSeconds float64 `firestore:"speakingSeconds" json:"speakingSeconds"`
+	WordCount       int     `firestore:"wordCount" json:"wordCount"`
+	WordsPerMinute  float64 `firestore:"wordsPerMinute" json:"wordsPerMinute"`
+	Confidence      float64 `firestore:"confidence,omitempty" json:"confidence,omitempty"`
+	Language        string  `firestore:"language,omitempty" json:"language,omitempty"`
+}
+
+// Answered reports whether the candidate has answered the turn
+func (t *Turn) Answered() bool {
+	return t.AnsweredAt != nil
+}
+
+// Failed reports whether the turn holds an answer the agent failed to process
+func (t *Turn) Failed() bool {
+	return !t.Answered() && t.FailedAt != nil
+}
+
+// Answer is a candidate's answer together with the agent's reply to it
+type Answer struct {
+	ResponseID string
+	Text       string
+	AnsweredAt time.Time
+	Reply      string
+	RepliedAt  time.Time
+	State      string
+	// Audio is set for spoken answers
+	Audio *AudioInfo
+	// ResponseTimeSeconds is the time to answer, when known
+	ResponseTimeSeconds *int
+	WordCount           int
+	// NextQuestion opens the following turn when the reply asks one
+	NextQuestion string
+}
+
+func (r *Registry) turns(userID, sessionID string) *firestore.CollectionRef {
+	return r.sessionDoc(userID, sessionID).Collection("turns")
+}
+
+// turnID zero-pads the index so turn documents sort in order in the console
+func turnID(index int) string {
+	return fmt.Sprintf("%05d", index)
+}
+
+// AskQuestion opens a new turn with a question from the agent
+func (r *Registry) AskQuestion(ctx context.Context, userID, sessionID, question string, askedAt time.Time) error {
+	sessionRef := r.sessionDoc(userID, sessionID)
+	err := r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(sessionRef)
+		if err != nil {
+			return err
+		}
+		index := turnCount(doc)
+
+		askedAt := askedAt.UTC()
+		turn := &Turn{Index: index, Question: question, AskedAt: &askedAt}
+		if err := tx.Set(r.turns(userID, sessionID).Doc(turnID(index)), turn); err != nil {
+			return err
+		}
+		return tx.Update(sessionRef, []firestore.Update{{Path: "turnCount", Value: index + 1}})
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record question on session %s: %w", sessionID, err)
+	}
+	return nil
+}
+
+// OpenTurn returns the turn whose question is waiting for an answer, or nil when
+// there is none
+func (r *Registry) OpenTurn(ctx context.Context, userID, sessionID string) (*Turn, error) {
+	doc, err := r.sessionDoc(userID, sessionID).Get(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read session %s: %w", sessionID, err)
+	}
+	turn, err := lastOpenTurn(ctx, r.turns(userID, sessionID), turnCount(doc), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read open turn of session %s: %w", sessionID, err)
+	}
+	return turn, nil
+}
+
+// SaveDraft keeps the candidate's unsubmitted answer on the open turn. A draft that
+// differs from the previous one counts as a revision.
+func (r *Registry) SaveDraft(ctx context.Context, userID, sessionID, text string, at time.Time) (*Turn, error) {
+	sessionRef := r.sessionDoc(userID, sessionID)
+	var saved *Turn
+	err := r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(sessionRef)
+		if err != nil {
+			return err
+		}
+		turn, err := lastOpenTurn(ctx, r.turns(userID, sessionID), turnCount(doc), tx)
+		if err != nil {
+			return err
+		}
+		if turn == nil {
+			return ErrNoOpenTurn
+		}
+
+		at := at.UTC()
+		if text != turn.Draft {
+			if turn.FirstDraftAt == nil {
+				turn.FirstDraftAt = &at
+			} else {
+				turn.RevisionCount++
+			}
+			turn.Draft = text
+			if err := tx.Set(r.turns(userID, sessionID).Doc(turnID(turn.Index)), turn); err != nil {
+				return err
+			}
+		}
+		saved = turn
+		return tx.Update(sessionRef, []firestore.Update{{Path: "lastActivityAt", Value: at}})
+	})
+	if err != nil {
+		if errors.Is(err, ErrNoOpenTurn) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to save draft on session %s: %w", sessionID, err)
+	}
+	return saved, nil
+}
+
+// RecordAnswer completes the open turn with the candidate's answer and the agent's
+// reply, and counts the answer on the session. An answer with no open turn, such as
+// one to a question the gateway never saw, gets a turn of its own.
+func (r *Registry) RecordAnswer(ctx context.Context, userID, sessionID string, answer Answer) (*Turn, error) {
+	sessionRef := r.sessionDoc(userID, sessionID)
+	var recorded *Turn
+	err := r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(sessionRef)
+		if err != nil {
+			return err
+		}
+		count := turnCount(doc)
+
+		turn, err := lastOpenTurn(ctx, r.turns(userID, sessionID), count, tx)
+		if err != nil {
+			return err
+		}
+		if turn == nil {
+			turn = &Turn{Index: count}
+		}
+
+		// Submitting something other than the last draft is one more revision
+		if turn.Draft != "" && turn.Draft != answer.Text {
+			turn.RevisionCount++
+		}
+		turn.Draft = ""
+		turn.FailedAnswer = ""
+		turn.FailedAt = nil
+		turn.FailureReason = ""
+
+		answeredAt := answer.AnsweredAt.UTC()
+		repliedAt := answer.RepliedAt.UTC()
+		turn.ResponseID = answer.ResponseID
+		turn.Answer = answer.Text
+		turn.AnsweredAt = &answeredAt
+		turn.Reply = answer.Reply
+		turn.RepliedAt = &repliedAt
+		turn.State = answer.State
+		turn.Audio = answer.Audio
+		turn.ResponseTimeSeconds = answer.ResponseTimeSeconds
+		turn.WordCount = answer.WordCount
+		if err := tx.Set(r.turns(userID, sessionID).Doc(turnID(turn.Index)), turn); err != nil {
+			return err
+		}
+
+		next := turn.Index + 1
+		if answer.NextQuestion != "" {
+			nextTurn := &Turn{Index: next, Question: answer.NextQuestion, AskedAt: &repliedAt}
+			if err := tx.Set(r.turns(userID, sessionID).Doc(turnID(next)), nextTurn); err != nil {
+				return err
+			}
+			next++
+		}
+
+		updates := []firestore.Update{
+			{Path: "lastActivityAt", Value: repliedAt},
+			{Path: "responseCount", Value: firestore.Increment(1)},
+			{Path: "turnCount", Value: next},
+		}
+		if answer.State != "" {
+			updates = append(updates, firestore.Update{Path: "agentState", Value: answer.State})
+		}
+		recorded = turn
+		return tx.Update(sessionRef, updates)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to record answer on session %s: %w", sessionID, err)
+	}
+	return recorded, nil
+}
+
+// RecordFailure keeps an answer the agent failed to process on the open turn, with
+// the reason, so it can be replayed. Like RecordAnswer, an answer with no open turn
+// gets a turn of its own.
+func (r *Registry) RecordFailure(ctx context.Context, userID, sessionID, text, reason string, at time.Time) (*Turn, error) {
+	sessionRef := r.sessionDoc(userID, sessionID)
+	var recorded *Turn
+	err := r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(sessionRef)
+		if err != nil {
+			return err
+		}
+		count := turnCount(doc)
+
+		turn, err := lastOpenTurn(ctx, r.turns(userID, sessionID), count, tx)
+		if err != nil {
+			return err
+		}
+		failedAt := at.UTC()
+		var updates []firestore.Update
+		if turn == nil {
+			turn = &Turn{Index: count}
+			updates = append(updates, firestore.Update{Path: "turnCount", Value: count + 1})
+		}
+		// A replay records the original submission time, which must not rewind activity
+		if last, ok := doc.Data()["lastActivityAt"].(time.Time); !ok || failedAt.After(last) {
+			updates = append(updates, firestore.Update{Path: "lastActivityAt", Value: failedAt})
+		}
+
+		turn.FailedAnswer = text
+		turn.FailedAt = &failedAt
+		turn.FailureReason = reason
+		turn.FailureCount++
+		if err := tx.Set(r.turns(userID, sessionID).Doc(turnID(turn.Index)), turn); err != nil {
+			return err
+		}
+		recorded = turn
+		if len(updates) == 0 {
+			return nil
+		}
+		return tx.Update(sessionRef, updates)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to record failed answer on session %s: %w", sessionID, err)
+	}
+	return recorded, nil
+}
+
+// Transcript returns the session's turns in order
+func (r *Registry) Transcript(ctx context.Context, userID, sessionID string) ([]*Turn, error) {
+	iter := r.turns(userID, sessionID).OrderBy("index", firestore.Asc).Documents(ctx)
+	defer iter.Stop()
+
+	var result []*Turn
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read transcript of session %s: %w", sessionID, err)
+		}
+
+		var turn Turn
+		if err := doc.DataTo(&turn); err != nil {
+			return nil, fmt.Errorf("failed to parse turn %s of session %s: %w", doc.Ref.ID, sessionID, err)
+		}
+		result = append(result, &turn)
+	}
+	return result, nil
+}
+
+// MarkResumed records that the session was rebuilt on the agent from its transcript
+func (r *Registry) MarkResumed(ctx context.Context, userID, sessionID string) error {
+	_, err := r.sessionDoc(userID, sessionID).Update(ctx, []firestore.Update{
+		{Path: "resumeCount", Value: firestore.Increment(1)},
+		{Path: "lastActivityAt", Value: time.Now().UTC()},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to mark session %s resumed: %w", sessionID, err)
+	}
+	return nil
+}
+
+// lastOpenTurn returns the last of count turns when it is still unanswered, reading
+// inside tx when one is given
+func lastOpenTurn(ctx context.Context, turns *firestore.CollectionRef, count int, tx *firestore.Transaction) (*Turn, error) {
+	if count == 0 {
+		return nil, nil
+	}
+
+	ref := turns.Doc(turnID(count - 1))
+	var doc *firestore.DocumentSnapshot
+	var err error
+	if tx != nil {
+		doc, err = tx.Get(ref)
+	} else {
+		doc, err = ref.Get(ctx)
+	}
+	if status.Code(err) == codes.NotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var turn Turn
+	if err := doc.DataTo(&turn); err != nil {
+		return nil, err
+	}
+	if turn.Answered() {
+		return nil, nil
+	}
+	return &turn, nil
+}
+
+// turnCount reads the number of turns from a session document. Sessions registered
+// before transcripts existed have none.
+func turnCount(doc *firestore.DocumentSnapshot) int {
+	count, _ := doc.Data()["turnCount"].(int64)
+	return int(count)
+}
@@ -0,0 +1,105 @@
+package speech
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Format is an audio container the transcribers accept
+type Format string
+
+// Supported formats
+const (
+	// FormatWAV is PCM in a RIFF/WAVE container
+	FormatWAV Format = "wav"
+	// FormatWebMOpus is Opus in a WebM container, as browsers' MediaRecorder produces
+	FormatWebMOpus Format = "webm"
+)
+
+// ErrUnsupportedFormat is returned for audio that is neither WAV nor WebM
+var ErrUnsupportedFormat = errors.New("unsupported audio format: use webm/opus or wav")
+
+// Audio is a recorded answer
+type Audio struct {
+	Data   []byte
+	Format Format
+	// SampleRate and Channels are read from the header when the format has one
+	SampleRate int
+	Channels   int
+	// Duration is read from the header when the format has one. WebM recordings from
+	// MediaRecorder usually carry none.
+	Duration time.Duration
+	// Language is a BCP-47 hint such as "en-US"; empty lets the transcriber decide
+	Language string
+}
+
+// ContentType is the MIME type of the audio
+func (a *Audio) ContentType() string {
+	if a.Format == FormatWAV {
+		return "audio/wav"
+	}
+	return "audio/webm"
+}
+
+// Filename is a name for the audio in multipart uploads
+func (a *Audio) Filename() string {
+	return "answer." + string(a.Format)
+}
+
+var (
+	riffMagic = []byte("RIFF")
+	waveMagic = []byte("WAVE")
+	ebmlMagic = []byte{0x1A, 0x45, 0xDF, 0xA3}
+)
+
+// ParseAudio identifies the format of a recording from its content, not the
+// uploaded content type, and reads what its header says about it
+func ParseAudio(data []byte) (*Audio, error) {
+	switch {
+	case len(data) >= 12 && bytes.Equal(data[0:4], riffMagic) && bytes.Equal(data[8:12], waveMagic):
+		return parseWAV(data)
+	case len(data) >= 4 && bytes.Equal(data[0:4], ebmlMagic):
+		return &Audio{Data: data, Format: FormatWebMOpus}, nil
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+}
+
+// parseWAV reads the fmt chunk and the size of the data chunk
+func parseWAV(data []byte) (*Audio, error) {
+	audio := &Audio{Data: data, Format: FormatWAV}
+	var byteRate uint32
+
+	for offset := 12; offset+8 <= len(data); {
+		id := string(data[offset : offset+4])
+		size := int(binary.LittleEndian.Uint32(data[offset+4 : offset+8]))
+		body := offset + 8
+
+		switch id {
+		case "fmt ":
+			if size < 16 || body+16 > len(data) {
+				return nil, fmt.Errorf("invalid wav: short fmt chunk")
+			}
+			audio.Channels = int(binary.LittleEndian.Uint16(data[body+2 : body+4]))
+			audio.SampleRate = int(binary.LittleEndian.Uint32(data[body+4 : body+8]))
+			byteRate = binary.LittleEndian.Uint32(data[body+8 : body+12])
+		case "data":
+			if byteRate == 0 {
+				return nil, fmt.Errorf("invalid wav: data chunk before fmt chunk")
+			}
+			// Recorders that stream WAV leave the size unset; use what was uploaded
+			if size == 0 || body+size > len(data) {
+				size = len(data) - body
+			}
+			audio.Duration = time.Duration(float64(size) / float64(byteRate) * float64(time.Second))
+			return audio, nil
+		}
+
+		// Chunks are padded to an even length
+		offset = body + size + size%2
+	}
+	return nil, fmt.Errorf("invalid wav: no data chunk")
+}
@@ -0,0 +1,180 @@
+package speech
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+	"time"
+)
+
+// chunk is one RIFF chunk. Its header gives the body's length unless size is set,
+// or 0 when unsized is.
+type chunk struct {
+	id      string
+	body    []byte
+	size    int
+	unsized bool
+}
+
+// wavFile builds a RIFF/WAVE file of chunks
+func wavFile(chunks ...chunk) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("RIFF")
+	binary.Write(&buf, binary.LittleEndian, uint32(0))
+	buf.WriteString("WAVE")
+	for _, c := range chunks {
+		size := len(c.body)
+		if c.size != 0 {
+			size = c.size
+		}
+		if c.unsized {
+			size = 0
+		}
+		buf.WriteString(c.id)
+		binary.Write(&buf, binary.LittleEndian, uint32(size))
+		buf.Write(c.body)
+		if len(c.body)%2 == 1 {
+			buf.WriteByte(0)
+		}
+	}
+	return buf.Bytes()
+}
+
+// fmtChunk is a PCM fmt chunk for 16-bit samples
+func fmtChunk(channels, sampleRate int) chunk {
+	body := make([]byte, 16)
+	binary.LittleEndian.PutUint16(body[0:2], 1)
+	binary.LittleEndian.PutUint16(body[2:4], uint16(channels))
+	binary.LittleEndian.PutUint32(body[4:8], uint32(sampleRate))
+	binary.LittleEndian.PutUint32(body[8:12], uint32(sampleRate*channels*2))
+	binary.LittleEndian.PutUint16(body[12:14], uint16(channels*2))
+	binary.LittleEndian.PutUint16(body[14:16], 16)
+	return chunk{id: "fmt ", body: body}
+}
+
+func TestParseAudio(t *testing.T) {
+	// One second of 16 kHz mono is 32000 bytes
+	second := make([]byte, 32000)
+
+	tests := []struct {
+		name           string
+		data           []byte
+		wantFormat     Format
+		wantSampleRate int
+		wantChannels   int
+		wantDuration   time.Duration
+		wantErr        string
+	}{
+		{
+			name:           "wav",
+			data:           wavFile(fmtChunk(1, 16000), chunk{id: "data", body: second}),
+			wantFormat:     FormatWAV,
+			wantSampleRate: 16000,
+			wantChannels:   1,
+			wantDuration:   time.Second,
+		},
+		{
+			name:           "stereo wav",
+			data:           wavFile(fmtChunk(2, 48000), chunk{id: "data", body: make([]byte, 48000)}),
+			wantFormat:     FormatWAV,
+			wantSampleRate: 48000,
+			wantChannels:   2,
+			wantDuration:   250 * time.Millisecond,
+		},
+		{
+			name:           "odd-sized chunk before the data",
+			data:           wavFile(fmtChunk(1, 16000), chunk{id: "LIST", body: []byte("abc")}, chunk{id: "data", body: second}),
+			wantFormat:     FormatWAV,
+			wantSampleRate: 16000,
+			wantChannels:   1,
+			wantDuration:   time.Second,
+		},
+		{
+			name:           "streamed wav without a data size",
+			data:           wavFile(fmtChunk(1, 16000), chunk{id: "data", body: second[:16000], unsized: true}),
+			wantFormat:     FormatWAV,
+			wantSampleRate: 16000,
+			wantChannels:   1,
+			wantDuration:   500 * time.Millisecond,
+		},
+		{
+			name:           "data size past the end of the upload",
+			data:           wavFile(fmtChunk(1, 16000), chunk{id: "data", body: second[:8000], size: 1 << 20}),
+			wantFormat:     FormatWAV,
+			wantSampleRate: 16000,
+			wantChannels:   1,
+			wantDuration:   250 * time.Millisecond,
+		},
+		{
+			name:       "webm",
+			data:       []byte{0x1A, 0x45, 0xDF, 0xA3, 0x01, 0x02},
+			wantFormat: FormatWebMOpus,
+		},
+		{
+			name:    "short fmt chunk",
+			data:    wavFile(chunk{id: "fmt ", body: make([]byte, 8)}, chunk{id: "data", body: second}),
+			wantErr: "invalid wav: short fmt chunk",
+		},
+		{
+			name:    "fmt chunk cut off",
+			data:    wavFile(fmtChunk(1, 16000))[:30],
+			wantErr: "invalid wav: short fmt chunk",
+		},
+		{
+			name:    "data before fmt",
+			data:    wavFile(chunk{id: "data", body: second}, fmtChunk(1, 16000)),
+			wantErr: "invalid wav: data chunk before fmt chunk",
+		},
+		{
+			name:    "no data chunk",
+			data:    wavFile(fmtChunk(1, 16000)),
+			wantErr: "invalid wav: no data chunk",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			audio, err := ParseAudio(tt.data)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("ParseAudio() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if audio.Format != tt.wantFormat || audio.SampleRate != tt.wantSampleRate || audio.Channels != tt.wantChannels {
+				t.Errorf("ParseAudio() = %s at %d Hz with %d channels, want %s at %d Hz with %d channels",
+					audio.Format, audio.SampleRate, audio.Channels, tt.wantFormat, tt.wantSampleRate, tt.wantChannels)
+			}
+			if audio.Duration != tt.wantDuration {
+				t.Errorf("Duration = %v, want %v", audio.Duration, tt.wantDuration)
+			}
+			if !bytes.Equal(audio.Data, tt.data) {
+				t.Error("Data is not the uploaded recording")
+			}
+		})
+	}
+}
+
+func TestParseAudioUnsupported(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{name: "empty"},
+		{name: "mp3", data: []byte("ID3\x03\x00\x00\x00\x00\x00\x00")},
+		{name: "RIFF but not WAVE", data: []byte("RIFF\x00\x00\x00\x00AVI LIST")},
+		{name: "truncated RIFF header", data: []byte("RIFF\x00\x00")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseAudio(tt.data); !errors.Is(err, ErrUnsupportedFormat) {
+				t.Errorf("ParseAudio() error = %v, want %v", err, ErrUnsupportedFormat)
+			}
+		})
+	}
+}
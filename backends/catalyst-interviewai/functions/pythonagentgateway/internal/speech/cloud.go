@@ -0,0 +1,274 @@
+package speech
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+const (
+	cloudSpeechEndpoint = "https://speech.googleapis.com/v1"
+	cloudSpeechScope    = "https://www.googleapis.com/auth/cloud-platform"
+
+	// syncRecognizeLimit is the longest recording speech:recognize accepts; longer
+	// ones go through speech:longrunningrecognize
+	syncRecognizeLimit = time.Minute
+	// operationPollInterval is how often a long-running recognition is checked
+	operationPollInterval = time.Second
+	// opusSampleRate is the rate Opus always decodes at, whatever was recorded
+	opusSampleRate = 48000
+)
+
+// CloudTranscriber uses Google Cloud Speech-to-Text with the function's credentials
+type CloudTranscriber struct {
+	client   *http.Client
+	endpoint string
+	language string
+	model    string
+}
+
+// NewCloudTranscriber creates a Speech-to-Text client. language is the default
+// BCP-47 code for recordings that do not name one; base is the transport under the
+// OAuth2 credentials (nil for http.DefaultTransport).
+func NewCloudTranscriber(ctx context.Context, language, model string, base http.RoundTripper) (*CloudTranscriber, error) {
+	tokens, err := google.DefaultTokenSource(ctx, cloudSpeechScope)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get credentials for Speech-to-Text: %w", err)
+	}
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if language == "" {
+		language = "en-US"
+	}
+
+	return &CloudTranscriber{
+		client: &http.Client{
+			Timeout:   2 * time.Minute,
+			Transport: &oauth2.Transport{Source: tokens, Base: base},
+		},
+		endpoint: cloudSpeechEndpoint,
+		language: language,
+		model:    model,
+	}, nil
+}
+
+// Name implements Transcriber
+func (t *CloudTranscriber) Name() string {
+	return "cloud"
+}
+
+// Transcribe implements Transcriber. Recordings up to a minute are recognised in one
+// call; longer ones, or ones whose length the header does not give and which turn out
+// too long, are recognised as a long-running operation.
+func (t *CloudTranscriber) Transcribe(ctx context.Context, audio *Audio) (*Transcript, error) {
+	request := t.recognizeRequest(audio)
+
+	if audio.Duration <= syncRecognizeLimit {
+		var response cloudRecognizeResponse
+		err := t.post(ctx, "/speech:recognize", request, &response)
+		if err == nil {
+			return response.transcript()
+		}
+		if !isTooLong(err) {
+			return nil, err
+		}
+	}
+
+	var operation cloudOperation
+	if err := t.post(ctx, "/speech:longrunningrecognize", request, &operation); err != nil {
+		return nil, err
+	}
+	for !operation.Done {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(operationPollInterval):
+		}
+		if err := t.get(ctx, "/operations/"+operation.Name, &operation); err != nil {
+			return nil, err
+		}
+	}
+	if operation.Error != nil {
+		return nil, fmt.Errorf("speech recognition failed: %s", operation.Error.Message)
+	}
+	return operation.Response.transcript()
+}
+
+// recognizeRequest builds the request body. WAV headers give the encoding and
+// sample rate; WebM must state them.
+func (t *CloudTranscriber) recognizeRequest(audio *Audio) map[string]interface{} {
+	language := audio.Language
+	if language == "" {
+		language = t.language
+	}
+
+	config := map[string]interface{}{
+		"languageCode":               language,
+		"enableWordTimeOffsets":      true,
+		"enableAutomaticPunctuation": true,
+	}
+	if t.model != "" {
+		config["model"] = t.model
+	}
+	if audio.Format == FormatWebMOpus {
+		config["encoding"] = "WEBM_OPUS"
+		config["sampleRateHertz"] = opusSampleRate
+	}
+
+	return map[string]interface{}{
+		"config": config,
+		"audio":  map[string]string{"content": base64.StdEncoding.EncodeToString(audio.Data)},
+	}
+}
+
+func (t *CloudTranscriber) post(ctx context.Context, path string, body, out interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to encode speech request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.endpoint+path, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return t.do(req, out)
+}
+
+func (t *CloudTranscriber) get(ctx context.Context, path string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, t.endpoint+path, nil)
+	if err != nil {
+		return err
+	}
+	return t.do(req, out)
+}
+
+func (t *CloudTranscriber) do(req *http.Request, out interface{}) error {
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("speech request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read speech response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		var failure struct {
+			Error cloudStatus `json:"error"`
+		}
+		message := strings.TrimSpace(string(body))
+		if json.Unmarshal(body, &failure) == nil && failure.Error.Message != "" {
+			message = failure.Error.Message
+		}
+		return &cloudError{StatusCode: resp.StatusCode, Message: message}
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("invalid speech response: %w", err)
+	}
+	return nil
+}
+
+// cloudError is a non-200 answer from Speech-to-Text
+type cloudError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *cloudError) Error() string {
+	return fmt.Sprintf("speech-to-text returned %d: %s", e.StatusCode, e.Message)
+}
+
+// isTooLong reports whether speech:recognize refused the recording for its length
+func isTooLong(err error) bool {
+	cloudErr, ok := err.(*cloudError)
+	return ok && cloudErr.StatusCode == http.StatusBadRequest && strings.Contains(strings.ToLower(cloudErr.Message), "too long")
+}
+
+type cloudStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type cloudOperation struct {
+	Name     string                 `json:"name"`
+	Done     bool                   `json:"done"`
+	Error    *cloudStatus           `json:"error"`
+	Response cloudRecognizeResponse `json:"response"`
+}
+
+type cloudRecognizeResponse struct {
+	Results []struct {
+		Alternatives []struct {
+			Transcript string  `json:"transcript"`
+			Confidence float64 `json:"confidence"`
+			Words      []struct {
+				StartTime string `json:"startTime"`
+				EndTime   string `json:"endTime"`
+				Word      string `json:"word"`
+			} `json:"words"`
+		} `json:"alternatives"`
+		ResultEndTime string `json:"resultEndTime"`
+		LanguageCode  string `json:"languageCode"`
+	} `json:"results"`
+	TotalBilledTime string `json:"totalBilledTime"`
+}
+
+// transcript joins the best alternative of every result. Each result covers a
+// stretch of the recording in order.
+func (r *cloudRecognizeResponse) transcript() (*Transcript, error) {
+	transcript := &Transcript{}
+	var parts []string
+	var confidence float64
+
+	for _, result := range r.Results {
+		if end := parseCloudDuration(result.ResultEndTime); end > transcript.Duration {
+			transcript.Duration = end
+		}
+		if len(result.Alternatives) == 0 {
+			continue
+		}
+		best := result.Alternatives[0]
+		if text := strings.TrimSpace(best.Transcript); text != "" {
+			parts = append(parts, text)
+			confidence += best.Confidence
+		}
+		if transcript.Language == "" {
+			transcript.Language = result.LanguageCode
+		}
+		for _, word := range best.Words {
+			transcript.Words = append(transcript.Words, Word{
+				Text:  word.Word,
+				Start: parseCloudDuration(word.StartTime),
+				End:   parseCloudDuration(word.EndTime),
+			})
+		}
+	}
+
+	if len(parts) == 0 {
+		return nil, ErrNoSpeech
+	}
+	transcript.Text = strings.Join(parts, " ")
+	transcript.Confidence = confidence / float64(len(parts))
+	return transcript, nil
+}
+
+// parseCloudDuration reads protobuf JSON durations such as "1.300s"
+func parseCloudDuration(value string) time.Duration {
+	seconds, err := strconv.ParseFloat(strings.TrimSuffix(value, "s"), 64)
+	if err != nil {
+		return 0
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
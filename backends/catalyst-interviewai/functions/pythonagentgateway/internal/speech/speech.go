@@ -0,0 +1,83 @@
+// Package speech turns spoken interview answers into text
+package speech
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+// ErrNoSpeech is returned when the audio contains no recognisable speech
+var ErrNoSpeech = errors.New("no speech detected in audio")
+
+// Transcriber converts a recorded answer to text
+type Transcriber interface {
+	// Name identifies the implementation in stored metadata, e.g. "cloud" or "whisper"
+	Name() string
+	Transcribe(ctx context.Context, audio *Audio) (*Transcript, error)
+}
+
+// Transcript is the text of a recording and the timing of its words
+type Transcript struct {
+	Text     string
+	Language string
+	// Confidence is the recogniser's confidence in the text, 0-1, when it reports one
+	Confidence float64
+	// Duration is the length of the recording as the recogniser measured it
+	Duration time.Duration
+	// Words carry per-word timings when the recogniser reports them
+	Words []Word
+}
+
+// Word is one recognised word and when it was spoken
+type Word struct {
+	Text  string
+	Start time.Duration
+	End   time.Duration
+}
+
+// Stats describes how an answer was spoken
+type Stats struct {
+	// Duration is the length of the recording
+	Duration time.Duration
+	// SpeakingTime runs from the first word to the last, leaving out silence at the
+	// start and end of the recording
+	SpeakingTime time.Duration
+	WordCount    int
+	// WordsPerMinute is measured over the speaking time
+	WordsPerMinute float64
+}
+
+// StatsFor measures a transcript of audio. The recording length comes from the audio
+// header when it has one, otherwise from the recogniser; speaking time falls back to
+// the recording length when the recogniser reports no word timings.
+func StatsFor(audio *Audio, transcript *Transcript) Stats {
+	stats := Stats{
+		Duration:  audio.Duration,
+		WordCount: countWords(transcript),
+	}
+	if stats.Duration == 0 {
+		stats.Duration = transcript.Duration
+	}
+
+	stats.SpeakingTime = stats.Duration
+	if n := len(transcript.Words); n > 0 {
+		if spoken := transcript.Words[n-1].End - transcript.Words[0].Start; spoken > 0 {
+			stats.SpeakingTime = spoken
+		}
+	}
+
+	if stats.SpeakingTime > 0 {
+		stats.WordsPerMinute = float64(stats.WordCount) / stats.SpeakingTime.Minutes()
+	}
+	return stats
+}
+
+// countWords prefers the recogniser's word list, which matches the timings
+func countWords(transcript *Transcript) int {
+	if len(transcript.Words) > 0 {
+		return len(transcript.Words)
+	}
+	return len(strings.Fields(transcript.Text))
+}
@@ -0,0 +1,69 @@
+package speech
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStatsFor(t *testing.T) {
+	// words returns n words spoken one per half second from start
+	words := func(n int, start time.Duration) []Word {
+		var list []Word
+		for i := 0; i < n; i++ {
+			at := start + time.Duration(i)*500*time.Millisecond
+			list = append(list, Word{Text: "word", Start: at, End: at + 500*time.Millisecond})
+		}
+		return list
+	}
+
+	tests := []struct {
+		name       string
+		audio      Audio
+		transcript Transcript
+		want       Stats
+	}{
+		{
+			name:       "speaking time from the word timings",
+			audio:      Audio{Duration: 40 * time.Second},
+			transcript: Transcript{Text: "ignored when there are words", Words: words(60, 5*time.Second)},
+			want:       Stats{Duration: 40 * time.Second, SpeakingTime: 30 * time.Second, WordCount: 60, WordsPerMinute: 120},
+		},
+		{
+			name:       "recording length from the recogniser",
+			transcript: Transcript{Duration: 20 * time.Second, Words: words(20, 0)},
+			want:       Stats{Duration: 20 * time.Second, SpeakingTime: 10 * time.Second, WordCount: 20, WordsPerMinute: 120},
+		},
+		{
+			name:       "header length wins over the recogniser's",
+			audio:      Audio{Duration: 30 * time.Second},
+			transcript: Transcript{Text: "one two three", Duration: 29 * time.Second},
+			want:       Stats{Duration: 30 * time.Second, SpeakingTime: 30 * time.Second, WordCount: 3, WordsPerMinute: 6},
+		},
+		{
+			name:       "no word timings",
+			audio:      Audio{Duration: 30 * time.Second},
+			transcript: Transcript{Text: "  I led the\nmigration  "},
+			want:       Stats{Duration: 30 * time.Second, SpeakingTime: 30 * time.Second, WordCount: 4, WordsPerMinute: 8},
+		},
+		{
+			name:       "a single word without length",
+			audio:      Audio{Duration: 3 * time.Second},
+			transcript: Transcript{Text: "Yes", Words: []Word{{Text: "Yes", Start: time.Second, End: time.Second}}},
+			want:       Stats{Duration: 3 * time.Second, SpeakingTime: 3 * time.Second, WordCount: 1, WordsPerMinute: 20},
+		},
+		{
+			name:       "no length known",
+			audio:      Audio{Format: FormatWebMOpus},
+			transcript: Transcript{Text: "Yes"},
+			want:       Stats{WordCount: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := StatsFor(&tt.audio, &tt.transcript); got != tt.want {
+				t.Errorf("StatsFor() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package speech
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// WhisperTranscriber calls a Whisper-style HTTP service that implements the OpenAI
+// POST /v1/audio/transcriptions API, such as faster-whisper-server or whisper.cpp's
+// server, for local development
+type WhisperTranscriber struct {
+	client  *http.Client
+	baseURL string
+	model   string
+	apiKey  string
+}
+
+// NewWhisperTranscriber creates a client for the service at baseURL. apiKey is sent
+// as a bearer token when set; model defaults to "whisper-1".
+func NewWhisperTranscriber(baseURL, model, apiKey string, base http.RoundTripper) *WhisperTranscriber {
+	if model == "" {
+		model = "whisper-1"
+	}
+	return &WhisperTranscriber{
+		client:  &http.Client{Timeout: 2 * time.Minute, Transport: base},
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		model:   model,
+		apiKey:  apiKey,
+	}
+}
+
+// Name implements Transcriber
+func (t *WhisperTranscriber) Name() string {
+	return "whisper"
+}
+
+// Transcribe implements Transcriber
+func (t *WhisperTranscriber) Transcribe(ctx context.Context, audio *Audio) (*Transcript, error) {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+
+	file, err := form.CreateFormFile("file", audio.Filename())
+	if err != nil {
+		return nil, err
+	}
+	if _, err := file.Write(audio.Data); err != nil {
+		return nil, err
+	}
+	fields := map[string]string{
+		"model":                     t.model,
+		"response_format":           "verbose_json",
+		"timestamp_granularities[]": "word",
+	}
+	// Whisper takes ISO-639-1 codes, so "en-US" becomes "en"
+	if audio.Language != "" {
+		fields["language"] = strings.ToLower(strings.SplitN(audio.Language, "-", 2)[0])
+	}
+	for name, value := range fields {
+		if err := form.WriteField(name, value); err != nil {
+			return nil, err
+		}
+	}
+	if err := form.Close(); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.baseURL+"/v1/audio/transcriptions", &body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	if t.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+t.apiKey)
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("whisper request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	payload, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read whisper response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("whisper returned %d: %s", resp.StatusCode, strings.TrimSpace(string(payload)))
+	}
+
+	var response whisperResponse
+	if err := json.Unmarshal(payload, &response); err != nil {
+		return nil, fmt.Errorf("invalid whisper response: %w", err)
+	}
+	return response.transcript()
+}
+
+type whisperWord struct {
+	Word  string  `json:"word"`
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+}
+
+type whisperResponse struct {
+	Text     string        `json:"text"`
+	Language string        `json:"language"`
+	Duration float64       `json:"duration"`
+	Words    []whisperWord `json:"words"`
+	// Some servers only report word timings inside segments
+	Segments []struct {
+		Words []whisperWord `json:"words"`
+	} `json:"segments"`
+}
+
+func (r *whisperResponse) transcript() (*Transcript, error) {
+	text := strings.TrimSpace(r.Text)
+	if text == "" {
+		return nil, ErrNoSpeech
+	}
+
+	words := r.Words
+	if len(words) == 0 {
+		for _, segment := range r.Segments {
+			words = append(words, segment.Words...)
+		}
+	}
+
+	transcript := &Transcript{
+		Text:     text,
+		Language: r.Language,
+		Duration: seconds(r.Duration),
+	}
+	for _, word := range words {
+		transcript.Words = append(transcript.Words, Word{
+			Text:  strings.TrimSpace(word.Word),
+			Start: seconds(word.Start),
+			End:   seconds(word.End),
+		})
+	}
+	return transcript, nil
+}
+
+func seconds(value float64) time.Duration {
+	return time.Duration(value * float64(time.Second))
+}
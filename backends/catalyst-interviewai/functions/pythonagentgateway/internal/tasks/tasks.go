@@ -0,0 +1,121 @@
+// Package tasks enqueues HTTP tasks on a Cloud Tasks queue, used to run work that
+// must outlive the request that started it
+package tasks
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+const (
+	cloudTasksEndpoint = "https://cloudtasks.googleapis.com/v2"
+	cloudTasksScope    = "https://www.googleapis.com/auth/cloud-platform"
+)
+
+// Queue enqueues tasks on one Cloud Tasks queue with the function's credentials
+type Queue struct {
+	client   *http.Client
+	endpoint string
+	name     string
+}
+
+// NewQueue creates a client for the queue named
+// projects/{project}/locations/{location}/queues/{queue}. base is the transport
+// under the OAuth2 credentials (nil for http.DefaultTransport).
+func NewQueue(ctx context.Context, name string, base http.RoundTripper) (*Queue, error) {
+	if strings.Count(name, "/") != 5 || !strings.HasPrefix(name, "projects/") {
+		return nil, fmt.Errorf("queue %q is not projects/{project}/locations/{location}/queues/{queue}", name)
+	}
+	tokens, err := google.DefaultTokenSource(ctx, cloudTasksScope)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get credentials for Cloud Tasks: %w", err)
+	}
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	return &Queue{
+		client: &http.Client{
+			Timeout:   30 * time.Second,
+			Transport: &oauth2.Transport{Source: tokens, Base: base},
+		},
+		endpoint: cloudTasksEndpoint,
+		name:     name,
+	}, nil
+}
+
+// HTTPTask is a POST of a JSON body to a URL, authenticated with an OIDC token for
+// ServiceAccount whose audience is the URL
+type HTTPTask struct {
+	// ID names the task so the queue refuses duplicates; empty lets the queue pick one
+	ID             string
+	URL            string
+	Body           interface{}
+	ServiceAccount string
+	// Deadline is how long the queue waits for the handler before retrying
+	Deadline time.Duration
+}
+
+// Enqueue adds task to the queue. A task whose ID the queue has already seen is
+// treated as enqueued.
+func (q *Queue) Enqueue(ctx context.Context, task HTTPTask) error {
+	body, err := json.Marshal(task.Body)
+	if err != nil {
+		return fmt.Errorf("failed to encode task body: %w", err)
+	}
+
+	httpRequest := map[string]interface{}{
+		"url":        task.URL,
+		"httpMethod": "POST",
+		"headers":    map[string]string{"Content-Type": "application/json"},
+		"body":       base64.StdEncoding.EncodeToString(body),
+	}
+	if task.ServiceAccount != "" {
+		httpRequest["oidcToken"] = map[string]string{
+			"serviceAccountEmail": task.ServiceAccount,
+			"audience":            task.URL,
+		}
+	}
+	spec := map[string]interface{}{"httpRequest": httpRequest}
+	if task.ID != "" {
+		spec["name"] = q.name + "/tasks/" + task.ID
+	}
+	if task.Deadline > 0 {
+		spec["dispatchDeadline"] = fmt.Sprintf("%ds", int(task.Deadline.Seconds()))
+	}
+
+	payload, err := json.Marshal(map[string]interface{}{"task": spec})
+	if err != nil {
+		return fmt.Errorf("failed to encode task: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, q.endpoint+"/"+q.name+"/tasks", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := q.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("cloud tasks request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusConflict {
+		return nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 4<<10))
+		return fmt.Errorf("cloud tasks returned %d: %s", resp.StatusCode, strings.TrimSpace(string(message)))
+	}
+	return nil
+}
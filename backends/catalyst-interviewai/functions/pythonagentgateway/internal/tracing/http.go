@@ -0,0 +1,96 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// flushTimeout bounds the export at the end of each request
+const flushTimeout = 2 * time.Second
+
+// StartServer starts the server span for a GCF handler, continuing any trace the
+// caller propagated. Handlers use the returned writer and request and defer end:
+//
+//	w, r, end := tracing.StartServer(w, r, "StartInterview")
+//	defer end()
+func StartServer(w http.ResponseWriter, r *http.Request, name string) (http.ResponseWriter, *http.Request, func()) {
+	ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+	ctx, span := otel.Tracer(instrumentationName).Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			semconv.HTTPMethod(r.Method),
+			semconv.HTTPTarget(r.URL.Path),
+			semconv.UserAgentOriginal(r.UserAgent()),
+		),
+	)
+
+	// Let clients quote the trace when they report a slow or failed call
+	if span.SpanContext().HasTraceID() {
+		w.Header().Set("X-Trace-ID", span.SpanContext().TraceID().String())
+	}
+
+	recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	end := func() {
+		span.SetAttributes(semconv.HTTPStatusCode(recorder.status))
+		if recorder.status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, fmt.Sprintf("HTTP %d", recorder.status))
+		}
+		span.End()
+
+		flushCtx, cancel := context.WithTimeout(context.Background(), flushTimeout)
+		defer cancel()
+		Flush(flushCtx)
+	}
+	return recorder, r.WithContext(ctx), end
+}
+
+// Transport wraps base so every outbound request gets a client span and carries the
+// caller's trace context in traceparent
+func Transport(base http.RoundTripper) http.RoundTripper {
+	return otelhttp.NewTransport(base,
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+			return fmt.Sprintf("HTTP %s %s", r.Method, r.URL.Path)
+		}),
+	)
+}
+
+// statusRecorder remembers the status a handler wrote. It keeps http.Flusher so
+// streamed responses still reach the client as they are written.
+type statusRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	return r.ResponseWriter.Write(b)
+}
+
+func (r *statusRecorder) Flush() {
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -1,20 +1,35 @@
 package pythonagentgateway
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"log"
+	"math"
 	"net/http"
+	"net/url"
 	"os"
+	"strconv"
 	"strings"
+	"testing"
 	"time"
 
+	"interviewai.wkv.local/pythonagentgateway/internal/agents"
+	"interviewai.wkv.local/pythonagentgateway/internal/audit"
 	"interviewai.wkv.local/pythonagentgateway/internal/auth"
 	"interviewai.wkv.local/pythonagentgateway/internal/config"
+	"interviewai.wkv.local/pythonagentgateway/internal/health"
 	"interviewai.wkv.local/pythonagentgateway/internal/httputils"
+	"interviewai.wkv.local/pythonagentgateway/internal/idempotency"
+	"interviewai.wkv.local/pythonagentgateway/internal/jobs"
+	"interviewai.wkv.local/pythonagentgateway/internal/ratelimit"
+	"interviewai.wkv.local/pythonagentgateway/internal/reports"
+	"interviewai.wkv.local/pythonagentgateway/internal/schema"
+	"interviewai.wkv.local/pythonagentgateway/internal/secrets"
+	"interviewai.wkv.local/pythonagentgateway/internal/sessions"
+	"interviewai.wkv.local/pythonagentgateway/internal/speech"
+	"interviewai.wkv.local/pythonagentgateway/internal/tasks"
+	"interviewai.wkv.local/pythonagentgateway/internal/tracing"
 	"interviewai.wkv.local/pkg/analytics"
 
 	secretmanager "cloud.google.com/go/secretmanager/apiv1"
@@ -23,19 +38,43 @@ import (
 	"github.com/google/uuid"
 )
 
+// userContextSigningKeySecret is the Secret Manager secret shared with the Python
+// agent service for signing user context headers
+const userContextSigningKeySecret = "agent-user-context-signing-key"
+
 var (
 	firebaseAppSingleton  *firebase.App
 	secretClientSingleton *secretmanager.Client
 	serviceConfig         *config.ServiceConfig
+	agentRouter           *agents.Router
 	analyticsClient       *analytics.Client
+	sessionRegistry       *sessions.Registry
+	jobStore              *jobs.Store
+	auditLog              *audit.Log
+	startJobQueue         *tasks.Queue
+	idempotencyStore      idempotency.Store
+	rateLimiter           *ratelimit.Limiter
+	healthChecker         *health.Checker
+	transcriber           speech.Transcriber
 )
 
 func init() {
+	// Handler tests set up only the clients they use; these need cloud credentials
+	if testing.Testing() {
+		return
+	}
+
 	ctx := context.Background()
 	
 	// Load service configuration
 	serviceConfig = config.LoadServiceConfig()
 	serviceConfig.LogConfiguration()
+
+	// Set up tracing first so the Secret Manager reads below are traced too. A broken
+	// exporter costs us traces, not the function.
+	if _, err := tracing.Setup(ctx, tracing.ConfigFromEnv("pythonagentgateway", serviceConfig.GCPProjectID)); err != nil {
+		log.Printf("Warning: Failed to set up tracing: %v", err)
+	}
 	
 	// Validate required configuration
 	if serviceConfig.GCPProjectID == "" && !serviceConfig.UseLocalService {
@@ -56,11 +95,58 @@ func init() {
 		log.Fatalf("firebase.NewApp in init: %v", err)
 	}
 
+	// Initialize the session registry
+	firestoreClient, err := firebaseAppSingleton.Firestore(ctx)
+	if err != nil {
+		log.Fatalf("firebaseApp.Firestore in init: %v", err)
+	}
+	sessionRegistry = sessions.NewRegistry(firestoreClient)
+	jobStore = jobs.NewStore(firestoreClient)
+	auditLog = audit.NewLog(firestoreClient)
+
+	// Initialize the idempotency store
+	switch serviceConfig.IdempotencyStore {
+	case "memory":
+		idempotencyStore = idempotency.NewMemoryStore()
+	case "firestore":
+		idempotencyStore = idempotency.NewFirestoreStore(firestoreClient, "idempotencyKeys")
+	default:
+		log.Fatalf("Unknown IDEMPOTENCY_STORE %q", serviceConfig.IdempotencyStore)
+	}
+
+	// Initialize per-user rate limits
+	switch serviceConfig.RateLimitStore {
+	case "memory":
+		rateLimiter = ratelimit.NewLimiter(ratelimit.NewMemoryStore(), nil)
+	case "firestore":
+		rateLimiter = ratelimit.NewLimiter(ratelimit.NewFirestoreStore(firestoreClient, "rateLimits"), nil)
+	default:
+		log.Fatalf("Unknown RATE_LIMIT_STORE %q", serviceConfig.RateLimitStore)
+	}
+
 	// Initialize Secret Manager
 	secretClientSingleton, err = secretmanager.NewClient(ctx)
 	if err != nil {
 		log.Fatalf("secretmanager.NewClient in init: %v", err)
 	}
+
+	// Shared clients for the Python agent backends
+	agentRouter, err = newAgentRouter(ctx)
+	if err != nil {
+		log.Fatalf("Failed to set up agent backends: %v", err)
+	}
+
+	// Background starts go through Cloud Tasks so they survive the request that began them
+	startJobQueue, err = newStartJobQueue(ctx)
+	if err != nil {
+		log.Printf("Warning: Failed to set up start job queue: %v", err)
+	}
+
+	// Speech-to-text for spoken answers; without it only typed answers are accepted
+	transcriber, err = newTranscriber(ctx)
+	if err != nil {
+		log.Printf("Warning: Failed to set up %s transcriber: %v", serviceConfig.Transcription.Provider, err)
+	}
 	
 	// Initialize BigQuery Analytics Client
 	if serviceConfig.GCPProjectID != "" && !serviceConfig.UseLocalService {
@@ -74,12 +160,77 @@ func init() {
 			// Don't fail the function, just log the error
 		}
 	}
+
+	healthChecker = newHealthChecker(firestoreClient)
 	
 	log.Println("PythonAgentGateway: Firebase App and Secret Manager Client initialized.")
 }
 
+// newAgentRouter creates a client for every configured agent backend (with a faster
+// timeout for local development) and the router that assigns sessions to them
+func newAgentRouter(ctx context.Context) (*agents.Router, error) {
+	signingKey, err := agentSigningKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var backends []*agents.Backend
+	for _, backend := range serviceConfig.AgentBackends {
+		options := agents.DefaultOptions()
+		if serviceConfig.UseLocalService {
+			options.Timeout = 30 * time.Second
+		}
+		options.Authenticator, err = newAgentAuthenticator(ctx, backend.URL, signingKey)
+		if err != nil {
+			return nil, fmt.Errorf("backend %s: %w", backend.Name, err)
+		}
+		backends = append(backends, &agents.Backend{
+			Name:   backend.Name,
+			Weight: backend.Weight,
+			Client: agents.NewAgentClientWithOptions(backend.URL, options),
+		})
+	}
+	return agents.NewRouter(backends...)
+}
+
+// agentSigningKey returns the key for signing user contexts: the configured key,
+// otherwise the Secret Manager secret in the cloud or the development key locally
+func agentSigningKey(ctx context.Context) ([]byte, error) {
+	if serviceConfig.UserContextSigningKey != "" {
+		return []byte(serviceConfig.UserContextSigningKey), nil
+	}
+	if serviceConfig.UseLocalService {
+		log.Println("Signing agent user context with the development key")
+		return nil, nil
+	}
+
+	key, err := secrets.GetSystemSecret(ctx, secretClientSingleton, serviceConfig.GCPProjectID, userContextSigningKeySecret)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(key), nil
+}
+
+// newAgentAuthenticator returns the credentials attached to requests for the agent
+// service at serviceURL. Cloud deployments send a Google-signed identity token plus
+// a user context signed with the shared key; a local agent service only gets the
+// signed user context. Both carry the user's sealed Gemini key.
+func newAgentAuthenticator(ctx context.Context, serviceURL string, signingKey []byte) (agents.RequestAuthenticator, error) {
+	if serviceConfig.UseLocalService {
+		return agents.NewLocalAuthenticator(signingKey).WithAPIKeys(resolveAgentAPIKey), nil
+	}
+	authenticator, err := agents.NewServiceAuthenticator(ctx, serviceURL, signingKey)
+	if err != nil {
+		return nil, err
+	}
+	return authenticator.WithAPIKeys(resolveAgentAPIKey), nil
+}
+
 // StartInterviewGCF handles POST /api/agents/interview/start
 func StartInterviewGCF(w http.ResponseWriter, r *http.Request) {
+	w, r, end := tracing.StartServer(w, r, "StartInterview")
+	defer end()
+
 	httputils.SetCORSHeaders(w, r)
 	if r.Method == http.MethodOptions {
 		w.WriteHeader(http.StatusNoContent)
@@ -100,17 +251,53 @@ func StartInterviewGCF(w http.ResponseWriter, r *http.Request) {
 	userID := authedUser.UID
 	log.Printf("StartInterview: User %s starting interview", userID)
 
-	// Parse request body
-	var requestBody map[string]interface{}
-	bodyBytes, err := io.ReadAll(r.Body)
+	// Malformed requests are refused before anything is reserved or called
+	bodyBytes, err := schema.ReadBody(w, r, schema.MaxStartBodyBytes)
+	if err != nil {
+		writeRequestError(w, err)
+		return
+	}
+	startRequest, err := schema.DecodeStartInterview(bodyBytes)
 	if err != nil {
-		httputils.ErrorJSON(w, "Failed to read request body", http.StatusBadRequest)
+		writeRequestError(w, err)
+		return
+	}
+	requestBody := startRequest.Body()
+
+	async := asyncRequested(r)
+	if async && streamRequested(r) {
+		httputils.ErrorJSON(w, "async and stream cannot be combined", http.StatusBadRequest)
+		return
+	}
+	// Cloud Functions throttles an instance's CPU once it has answered, so without a
+	// queue an async start would have to finish before the 202
+	if async && !asyncStartsAvailable() {
+		httputils.ErrorJSON(w, "async interview starts are not available", http.StatusNotImplemented)
+		return
+	}
+
+	// Retries carrying the same Idempotency-Key must not start a second session. An
+	// async start replays its job rather than a session.
+	scope := "start_interview"
+	if async {
+		scope = "start_interview/async"
+	}
+	idem, handled := beginIdempotentCall(w, r, userID, scope, bodyBytes)
+	if handled {
+		return
+	}
+
+	// Replays above are free; only new interviews count against the user's limits
+	if !allowRequest(w, r, userID, ratelimit.ClassInterview) {
+		idem.abort()
 		return
 	}
-	defer r.Body.Close()
 
-	if err := json.Unmarshal(bodyBytes, &requestBody); err != nil {
-		httputils.ErrorJSON(w, "Invalid JSON in request body", http.StatusBadRequest)
+	// Every agent call runs on a Gemini key, so refuse sessions that could not make any
+	apiKey, err := resolveAgentAPIKey(r.Context(), userID)
+	if err != nil {
+		idem.abort()
+		writeAPIKeyError(w, userID, err)
 		return
 	}
 
@@ -118,49 +305,65 @@ func StartInterviewGCF(w http.ResponseWriter, r *http.Request) {
 	requestBody["user_id"] = userID
 	requestBody["type"] = "start_interview"
 
+	// The session lives on this backend for the rest of its life
+	backend := assignAgentBackend(userID)
+	log.Printf("StartInterview: User %s assigned to agent backend %s", userID, backend.Name)
+
+	// Starts that may outlast the request run in the background; the client polls the job
+	if async {
+		startInterviewJob(w, r, idem, userID, backend, startRequest)
+		return
+	}
+
+	// Stream the agent's output when the client asks for it
+	if streamRequested(r) {
+		requestBody["stream"] = true
+		// Sessions that cannot be registered would be unusable, so the client gets an
+		// error event rather than the agent's greeting
+		register := func(response map[string]interface{}) error {
+			if err := registerSession(r.Context(), userID, backend.Name, apiKey.Source, startRequest, requestBody, response); err != nil {
+				log.Printf("StartInterview: %v", err)
+				return errors.New("failed to register interview session")
+			}
+			return nil
+		}
+		response, err := relayAgentStream(w, r, backend.Client, agents.OpStartInterview, "/interview/start", requestBody, userID, nil, register)
+		if err != nil {
+			idem.abort()
+			return
+		}
+		idem.complete(http.StatusOK, response)
+		recordInterviewStart(userID, backend.Name, apiKey.Source, startRequest, requestBody, response)
+		return
+	}
+
 	// Forward to Python agent service
-	response, err := forwardToPythonAgents("POST", "/interview/start", requestBody, userID)
+	response, err := backend.Client.StartInterview(r.Context(), userID, requestBody)
 	if err != nil {
-		httputils.ErrorJSON(w, fmt.Sprintf("Failed to start interview: %v", err), http.StatusInternalServerError)
+		idem.abort()
+		writeAgentError(w, "Failed to start interview", err)
 		return
 	}
 
-	// Log to BigQuery if analytics client is available
-	if analyticsClient != nil && response != nil {
-		go func() {
-			ctx := context.Background()
-			
-			// Extract session ID from response
-			sessionID := ""
-			if sid, ok := response["session_id"].(string); ok {
-				sessionID = sid
-			}
-			
-			if sessionID != "" {
-				// Create interview session record
-				session := &analytics.InterviewSession{
-					SessionID:     sessionID,
-					UserID:        userID,
-					StartedAt:     time.Now(),
-					Status:        "active",
-					InterviewType: getStringFromMap(requestBody, "interview_type", "behavioral"),
-					TargetRole:    getStringPtrFromMap(requestBody, "target_role"),
-					Company:       getStringPtrFromMap(requestBody, "company"),
-					Metadata:      requestBody,
-				}
-				
-				if err := analyticsClient.InsertInterviewSession(ctx, session); err != nil {
-					log.Printf("Failed to log interview session to BigQuery: %v", err)
-				}
-			}
-		}()
+	// Sessions that cannot be registered would be unusable, so fail the start
+	if err := registerSession(r.Context(), userID, backend.Name, apiKey.Source, startRequest, requestBody, response); err != nil {
+		idem.abort()
+		log.Printf("StartInterview: %v", err)
+		httputils.ErrorJSON(w, "Failed to register interview session", http.StatusInternalServerError)
+		return
 	}
 
+	idem.complete(http.StatusOK, response)
+	recordInterviewStart(userID, backend.Name, apiKey.Source, startRequest, requestBody, response)
+
 	httputils.ResponseJSON(w, response, http.StatusOK)
 }
 
 // InterviewResponseGCF handles POST /api/agents/interview/{sessionId}/respond
 func InterviewResponseGCF(w http.ResponseWriter, r *http.Request) {
+	w, r, end := tracing.StartServer(w, r, "InterviewResponse")
+	defer end()
+
 	httputils.SetCORSHeaders(w, r)
 	if r.Method == http.MethodOptions {
 		w.WriteHeader(http.StatusNoContent)
@@ -189,66 +392,120 @@ func InterviewResponseGCF(w http.ResponseWriter, r *http.Request) {
 
 	log.Printf("InterviewResponse: User %s, Session %s", userID, sessionID)
 
-	// Parse request body
-	var requestBody map[string]interface{}
-	bodyBytes, err := io.ReadAll(r.Body)
+	bodyBytes, err := schema.ReadBody(w, r, schema.MaxRespondBodyBytes)
 	if err != nil {
-		httputils.ErrorJSON(w, "Failed to read request body", http.StatusBadRequest)
+		writeRequestError(w, err)
+		return
+	}
+	respondRequest, err := schema.DecodeRespond(bodyBytes)
+	if err != nil {
+		writeRequestError(w, err)
 		return
 	}
-	defer r.Body.Close()
 
-	if err := json.Unmarshal(bodyBytes, &requestBody); err != nil {
-		httputils.ErrorJSON(w, "Invalid JSON in request body", http.StatusBadRequest)
+	session, ok := authorizeSession(w, r, userID, sessionID)
+	if !ok {
 		return
 	}
 
-	// Add context to request
+	// Retries carrying the same Idempotency-Key must not submit the answer twice
+	idem, handled := beginIdempotentCall(w, r, userID, "respond/"+sessionID, bodyBytes)
+	if handled {
+		return
+	}
+
+	meta := answerMeta{AnsweredAt: time.Now()}
+	if respondRequest.ResponseTime != nil {
+		seconds := int(math.Round(*respondRequest.ResponseTime))
+		meta.ResponseTimeSeconds = &seconds
+	}
+	submitAnswer(w, r, session, idem, respondRequest.Body(), meta)
+}
+
+// answerMeta is what the gateway knows about an answer besides its text
+type answerMeta struct {
+	AnsweredAt time.Time
+	// Audio describes a spoken answer
+	Audio *sessions.AudioInfo
+	// ResponseTimeSeconds is how long the candidate spent answering, when known
+	ResponseTimeSeconds *int
+	// Question is the question being answered, as delivered
+	Question  string
+	WordCount int
+	// RevisionCount is known once the turn is recorded
+	RevisionCount *int
+}
+
+// submitAnswer forwards an answer to the session's agent, streaming the reply when
+// the client asks for it, and records the turn. The caller has already reserved the
+// request's Idempotency-Key.
+func submitAnswer(w http.ResponseWriter, r *http.Request, session *sessions.Session, idem *idempotentCall, requestBody map[string]interface{}, meta answerMeta) {
+	userID, sessionID := session.UserID, session.SessionID
+	agentClient := agentForSession(session)
+	resume := sessionResumer(agentClient, session)
+
+	// Add context to request. The agent echoes response_id in its evaluation so
+	// scores can be linked to the answer.
+	responseID := uuid.New().String()
 	requestBody["session_id"] = sessionID
 	requestBody["user_id"] = userID
 	requestBody["type"] = "user_response"
+	requestBody["response_id"] = responseID
+	measureAnswer(r.Context(), session, &meta, requestBody)
+
+	// Stream the agent's output when the client asks for it
+	if streamRequested(r) {
+		requestBody["stream"] = true
+		endpoint := fmt.Sprintf("/interview/%s/respond", url.PathEscape(sessionID))
+		response, err := relayAgentStream(w, r, agentClient, agents.OpSubmitResponse, endpoint, requestBody, userID, resume, nil)
+		if err != nil {
+			idem.abort()
+			recordFailedAnswer(userID, sessionID, requestBody, meta.AnsweredAt, err)
+			return
+		}
+		idem.complete(http.StatusOK, response)
+		if turn := recordTurn(r.Context(), userID, sessionID, responseID, meta, requestBody, response); turn != nil {
+			meta.RevisionCount = &turn.RevisionCount
+		}
+		recordUserResponse(sessionID, userID, responseID, meta, requestBody)
+		recordEvaluationScores(evaluationScoresFrom(sessionID, responseID, response))
+		return
+	}
 
 	// Forward to Python agent service
-	response, err := forwardToPythonAgents("POST", fmt.Sprintf("/interview/%s/respond", sessionID), requestBody, userID)
+	response, err := withResume(r.Context(), resume, func() (map[string]interface{}, error) {
+		return agentClient.SubmitResponse(r.Context(), sessionID, userID, requestBody)
+	})
 	if err != nil {
-		httputils.ErrorJSON(w, fmt.Sprintf("Failed to process response: %v", err), http.StatusInternalServerError)
-		return
-	}
-
-	// Log user response to BigQuery
-	if analyticsClient != nil && response != nil {
-		go func() {
-			ctx := context.Background()
-			
-			// Create user response record
-			userResponse := &analytics.UserResponse{
-				ResponseID:        uuid.New().String(),
-				SessionID:         sessionID,
-				UserID:            userID,
-				QuestionID:        getStringFromMap(requestBody, "question_id", ""),
-				QuestionText:      getStringFromMap(requestBody, "question_text", ""),
-				ResponseText:      getStringFromMap(requestBody, "response", ""),
-				ResponseTimestamp: time.Now(),
-				IsFinalAnswer:     true,
-			}
-			
-			// Calculate word count
-			if userResponse.ResponseText != "" {
-				wordCount := len(strings.Fields(userResponse.ResponseText))
-				userResponse.WordCount = &wordCount
-			}
-			
-			if err := analyticsClient.InsertUserResponse(ctx, userResponse); err != nil {
-				log.Printf("Failed to log user response to BigQuery: %v", err)
-			}
-		}()
+		idem.abort()
+		recordFailedAnswer(userID, sessionID, requestBody, meta.AnsweredAt, err)
+		writeAgentError(w, "Failed to process response", err)
+		return
+	}
+
+	// Spoken answers return what was heard so the candidate can check it
+	if meta.Audio != nil {
+		response["transcript"] = requestBody["response"]
+		response["audio"] = meta.Audio
 	}
 
+	if turn := recordTurn(r.Context(), userID, sessionID, responseID, meta, requestBody, response); turn != nil {
+		meta.RevisionCount = &turn.RevisionCount
+	}
+	response["timing"] = answerTiming(meta)
+
+	idem.complete(http.StatusOK, response)
+	recordUserResponse(sessionID, userID, responseID, meta, requestBody)
+	recordEvaluationScores(evaluationScoresFrom(sessionID, responseID, response))
+
 	httputils.ResponseJSON(w, response, http.StatusOK)
 }
 
 // InterviewStatusGCF handles GET /api/agents/interview/{sessionId}/status
 func InterviewStatusGCF(w http.ResponseWriter, r *http.Request) {
+	w, r, end := tracing.StartServer(w, r, "InterviewStatus")
+	defer end()
+
 	httputils.SetCORSHeaders(w, r)
 	if r.Method == http.MethodOptions {
 		w.WriteHeader(http.StatusNoContent)
@@ -277,10 +534,18 @@ func InterviewStatusGCF(w http.ResponseWriter, r *http.Request) {
 
 	log.Printf("InterviewStatus: User %s, Session %s", userID, sessionID)
 
+	session, ok := authorizeSession(w, r, userID, sessionID)
+	if !ok {
+		return
+	}
+
 	// Forward to Python agent service
-	response, err := forwardToPythonAgents("GET", fmt.Sprintf("/interview/%s/status", sessionID), nil, userID)
+	agentClient := agentForSession(session)
+	response, err := withResume(r.Context(), sessionResumer(agentClient, session), func() (map[string]interface{}, error) {
+		return agentClient.GetSessionStatus(r.Context(), sessionID, userID)
+	})
 	if err != nil {
-		httputils.ErrorJSON(w, fmt.Sprintf("Failed to get status: %v", err), http.StatusInternalServerError)
+		writeAgentError(w, "Failed to get status", err)
 		return
 	}
 
@@ -289,6 +554,9 @@ func InterviewStatusGCF(w http.ResponseWriter, r *http.Request) {
 
 // EndInterviewGCF handles POST /api/agents/interview/{sessionId}/end
 func EndInterviewGCF(w http.ResponseWriter, r *http.Request) {
+	w, r, end := tracing.StartServer(w, r, "EndInterview")
+	defer end()
+
 	httputils.SetCORSHeaders(w, r)
 	if r.Method == http.MethodOptions {
 		w.WriteHeader(http.StatusNoContent)
@@ -317,6 +585,21 @@ func EndInterviewGCF(w http.ResponseWriter, r *http.Request) {
 
 	log.Printf("EndInterview: User %s, Session %s", userID, sessionID)
 
+	bodyBytes, err := schema.ReadBody(w, r, schema.MaxEndBodyBytes)
+	if err != nil {
+		writeRequestError(w, err)
+		return
+	}
+	if _, err := schema.DecodeEndInterview(bodyBytes); err != nil {
+		writeRequestError(w, err)
+		return
+	}
+
+	session, ok := authorizeSession(w, r, userID, sessionID)
+	if !ok {
+		return
+	}
+
 	requestBody := map[string]interface{}{
 		"session_id": sessionID,
 		"user_id":    userID,
@@ -324,17 +607,28 @@ func EndInterviewGCF(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Forward to Python agent service
-	response, err := forwardToPythonAgents("POST", fmt.Sprintf("/interview/%s/end", sessionID), requestBody, userID)
+	response, err := agentForSession(session).EndInterview(r.Context(), sessionID, userID, requestBody)
 	if err != nil {
-		httputils.ErrorJSON(w, fmt.Sprintf("Failed to end interview: %v", err), http.StatusInternalServerError)
+		// The agent forgets sessions that time out; record those as abandoned
+		if agents.IsRejected(err, http.StatusNotFound) && session.Status == sessions.StatusActive {
+			finalizeSession(r.Context(), session, sessions.StatusAbandoned, nil)
+		}
+		writeAgentError(w, "Failed to end interview", err)
 		return
 	}
 
+	if session.Status == sessions.StatusActive {
+		finalizeSession(r.Context(), session, endStatus(session, time.Now()), response)
+	}
+
 	httputils.ResponseJSON(w, response, http.StatusOK)
 }
 
 // GetReportGCF handles GET /api/agents/report/{sessionId}
 func GetReportGCF(w http.ResponseWriter, r *http.Request) {
+	w, r, end := tracing.StartServer(w, r, "GetReport")
+	defer end()
+
 	httputils.SetCORSHeaders(w, r)
 	if r.Method == http.MethodOptions {
 		w.WriteHeader(http.StatusNoContent)
@@ -364,23 +658,118 @@ func GetReportGCF(w http.ResponseWriter, r *http.Request) {
 	log.Printf("GetReport: User %s, Session %s", userID, sessionID)
 
 	// Get report format from query params
-	format := r.URL.Query().Get("format")
-	if format == "" {
-		format = "json"
+	format, err := reports.ParseFormat(r.URL.Query().Get("format"))
+	if err != nil {
+		httputils.ErrorJSON(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	// Forward to Python agent service
-	response, err := forwardToPythonAgents("GET", fmt.Sprintf("/report/%s?format=%s", sessionID, format), nil, userID)
+	session, ok := authorizeSession(w, r, userID, sessionID)
+	if !ok {
+		return
+	}
+
+	// The agent always returns the structured report; other formats are rendered here
+	response, err := agentForSession(session).GetReport(r.Context(), sessionID, userID, string(reports.FormatJSON))
 	if err != nil {
-		httputils.ErrorJSON(w, fmt.Sprintf("Failed to get report: %v", err), http.StatusInternalServerError)
+		writeAgentError(w, "Failed to get report", err)
 		return
 	}
 
-	httputils.ResponseJSON(w, response, http.StatusOK)
+	if format == reports.FormatJSON {
+		httputils.ResponseJSON(w, response, http.StatusOK)
+		return
+	}
+
+	report, err := reports.FromAgentResponse(sessionID, response)
+	if err != nil {
+		log.Printf("GetReport: Cannot build report for session %s: %v", sessionID, err)
+		httputils.ErrorJSON(w, "Report is not available yet", http.StatusNotFound)
+		return
+	}
+	if report.InterviewType == "" {
+		report.InterviewType = session.InterviewType
+	}
+	if report.TargetRole == "" {
+		report.TargetRole = session.TargetRole
+	}
+	if report.Company == "" {
+		report.Company = session.Company
+	}
+
+	document, err := reports.Render(report, format)
+	if err != nil {
+		log.Printf("GetReport: %v", err)
+		httputils.ErrorJSON(w, "Failed to render report", http.StatusInternalServerError)
+		return
+	}
+
+	// HTML opens in the browser unless a download is requested; other formats are files
+	disposition := "attachment"
+	if format == reports.FormatHTML && r.URL.Query().Get("download") != "true" {
+		disposition = "inline"
+	}
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("Content-Disposition", fmt.Sprintf("%s; filename=%q", disposition, reports.Filename(sessionID, format)))
+	w.Header().Set("Content-Length", strconv.Itoa(len(document)))
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(document); err != nil {
+		log.Printf("GetReport: Failed to write %s report: %v", format, err)
+	}
+}
+
+// ListSessionsGCF handles GET /api/agents/sessions
+func ListSessionsGCF(w http.ResponseWriter, r *http.Request) {
+	w, r, end := tracing.StartServer(w, r, "ListSessions")
+	defer end()
+
+	httputils.SetCORSHeaders(w, r)
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		httputils.ErrorJSON(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	authedUser, err := auth.VerifyToken(r, firebaseAppSingleton)
+	if err != nil {
+		httputils.ErrorJSON(w, fmt.Sprintf("Unauthorized: %v", err), http.StatusUnauthorized)
+		return
+	}
+
+	userID := authedUser.UID
+	log.Printf("ListSessions: User %s", userID)
+
+	limit := 20
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if parsedLimit, err := strconv.Atoi(limitStr); err == nil && parsedLimit > 0 && parsedLimit <= 100 {
+			limit = parsedLimit
+		}
+	}
+
+	userSessions, err := sessionRegistry.List(r.Context(), userID, limit)
+	if err != nil {
+		log.Printf("ListSessions: %v", err)
+		httputils.ErrorJSON(w, "Failed to list sessions", http.StatusInternalServerError)
+		return
+	}
+
+	httputils.ResponseJSON(w, map[string]interface{}{
+		"sessions": userSessions,
+		"total":    len(userSessions),
+	}, http.StatusOK)
 }
 
-// AgentHealthGCF handles GET /api/agents/health
+// AgentHealthGCF handles GET /api/agents/health. It reports every dependency by
+// default; ?probe=live and ?probe=ready (or the /live and /ready sub-paths) give
+// liveness and readiness answers for uptime checks.
 func AgentHealthGCF(w http.ResponseWriter, r *http.Request) {
+	w, r, end := tracing.StartServer(w, r, "AgentHealth")
+	defer end()
+
 	httputils.SetCORSHeaders(w, r)
 	if r.Method == http.MethodOptions {
 		w.WriteHeader(http.StatusNoContent)
@@ -393,19 +782,133 @@ func AgentHealthGCF(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Health endpoint might not require auth for monitoring purposes
-	log.Printf("AgentHealth: Health check requested")
+	mode := health.ModeForRequest(r)
+	log.Printf("AgentHealth: %s check requested", mode)
 
-	// Forward to Python agent service
-	response, err := forwardToPythonAgents("GET", "/health", nil, "system")
+	healthChecker.ServeHTTP(w, r)
+}
+
+// Helper functions
+
+// authorizeSession checks that the caller owns the session. When they do not, it writes
+// a 404 or 403 response and returns false so the handler can stop.
+func authorizeSession(w http.ResponseWriter, r *http.Request, userID, sessionID string) (*sessions.Session, bool) {
+	session, err := sessionRegistry.Authorize(r.Context(), userID, sessionID)
+	switch {
+	case err == nil:
+		return session, true
+	case errors.Is(err, sessions.ErrSessionNotFound):
+		httputils.ErrorJSON(w, "Session not found", http.StatusNotFound)
+	case errors.Is(err, sessions.ErrSessionForbidden):
+		log.Printf("Rejected access to session %s by user %s", sessionID, userID)
+		httputils.ErrorJSON(w, "Forbidden", http.StatusForbidden)
+	default:
+		log.Printf("Session lookup failed: %v", err)
+		httputils.ErrorJSON(w, "Failed to verify session", http.StatusInternalServerError)
+	}
+	return nil, false
+}
+
+// registerSession records the session returned by the agent in the caller's registry
+func registerSession(ctx context.Context, userID, variant, keySource string, startRequest *schema.StartInterviewRequest, requestBody, response map[string]interface{}) error {
+	sessionID := getStringFromMap(response, "session_id", "")
+	if sessionID == "" {
+		return fmt.Errorf("agent response did not include a session_id")
+	}
+
+	err := sessionRegistry.Register(ctx, &sessions.Session{
+		SessionID:     sessionID,
+		UserID:        userID,
+		InterviewType: startRequest.InterviewType,
+		TargetRole:    startRequest.TargetRole,
+		Company:       startRequest.TargetCompany,
+		Variant:       variant,
+		KeySource:     keySource,
+		StartRequest:  startRequestForResume(requestBody),
+	})
 	if err != nil {
-		httputils.ErrorJSON(w, fmt.Sprintf("Failed to get health status: %v", err), http.StatusServiceUnavailable)
+		return err
+	}
+
+	recordFirstQuestion(ctx, userID, sessionID, response)
+	return nil
+}
+
+// recordInterviewStart logs a new interview session to BigQuery in the background.
+// The agent backend variant is tagged in the metadata so builds can be compared, and
+// the key source so platform key spend can be told from BYOK.
+func recordInterviewStart(userID, variant, keySource string, startRequest *schema.StartInterviewRequest, requestBody, response map[string]interface{}) {
+	if analyticsClient == nil || response == nil {
 		return
 	}
 
-	httputils.ResponseJSON(w, response, http.StatusOK)
+	// Extract session ID from response
+	sessionID := getStringFromMap(response, "session_id", "")
+	if sessionID == "" {
+		return
+	}
+
+	metadata := make(map[string]interface{}, len(requestBody)+2)
+	for key, value := range requestBody {
+		metadata[key] = value
+	}
+	metadata["agent_variant"] = variant
+	metadata["api_key_source"] = keySource
+
+	go func() {
+		ctx := context.Background()
+
+		// Create interview session record
+		session := &analytics.InterviewSession{
+			SessionID:     sessionID,
+			UserID:        userID,
+			StartedAt:     time.Now(),
+			Status:        "active",
+			InterviewType: startRequest.InterviewType,
+			Metadata:      metadata,
+		}
+		if startRequest.TargetRole != "" {
+			session.TargetRole = analytics.StringPtr(startRequest.TargetRole)
+		}
+		if startRequest.TargetCompany != "" {
+			session.Company = analytics.StringPtr(startRequest.TargetCompany)
+		}
+
+		if err := analyticsClient.InsertInterviewSession(ctx, session); err != nil {
+			log.Printf("Failed to log interview session to BigQuery: %v", err)
+		}
+	}()
 }
 
-// Helper functions
+// recordUserResponse logs a submitted answer to BigQuery in the background
+func recordUserResponse(sessionID, userID, responseID string, meta answerMeta, requestBody map[string]interface{}) {
+	if analyticsClient == nil {
+		return
+	}
+
+	go func() {
+		ctx := context.Background()
+
+		// Create user response record
+		userResponse := &analytics.UserResponse{
+			ResponseID:          responseID,
+			SessionID:           sessionID,
+			UserID:              userID,
+			QuestionID:          getStringFromMap(requestBody, "question_id", ""),
+			QuestionText:        getStringFromMap(requestBody, "question_text", meta.Question),
+			ResponseText:        getStringFromMap(requestBody, "response", ""),
+			ResponseTimestamp:   meta.AnsweredAt,
+			ResponseTimeSeconds: meta.ResponseTimeSeconds,
+			WordCount:           analytics.IntPtr(meta.WordCount),
+			IsFinalAnswer:       true,
+			RevisionCount:       meta.RevisionCount,
+		}
+
+		if err := analyticsClient.InsertUserResponse(ctx, userResponse); err != nil {
+			log.Printf("Failed to log user response to BigQuery: %v", err)
+		}
+	}()
+}
 
 func extractSessionIDFromPath(path string) string {
 	// Extract session ID from paths like:
@@ -413,7 +916,7 @@ func extractSessionIDFromPath(path string) string {
 	// /api/agents/interview/{sessionId}/status
 	// /api/agents/interview/{sessionId}/end
 	// /api/agents/report/{sessionId}
-	
+
 	parts := strings.Split(strings.Trim(path, "/"), "/")
 	
 	// Look for common patterns
@@ -426,66 +929,32 @@ func extractSessionIDFromPath(path string) string {
 	return ""
 }
 
-func forwardToPythonAgents(method, endpoint string, body interface{}, userID string) (map[string]interface{}, error) {
-	// Construct target URL using service configuration
-	targetURL := fmt.Sprintf("%s%s", serviceConfig.PythonAgentBaseURL, endpoint)
-	
-	var requestBody io.Reader
-	if body != nil {
-		bodyBytes, err := json.Marshal(body)
-		if err != nil {
-			return nil, fmt.Errorf("failed to marshal request body: %w", err)
-		}
-		requestBody = bytes.NewBuffer(bodyBytes)
-	}
-
-	// Create HTTP request
-	req, err := http.NewRequest(method, targetURL, requestBody)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	// Set headers
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("X-User-ID", userID)
-	req.Header.Set("X-Request-Source", "go-gateway")
-
-	// Create HTTP client with timeout (adjust based on local vs cloud)
-	timeout := 90 * time.Second
-	if serviceConfig.UseLocalService {
-		timeout = 30 * time.Second // Faster timeout for local development
-	}
-	
-	client := &http.Client{
-		Timeout: timeout,
-	}
-
-	// Execute request
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to execute request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	// Read response
-	responseBody, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
-	}
-
-	// Parse response JSON
-	var responseJSON map[string]interface{}
-	if err := json.Unmarshal(responseBody, &responseJSON); err != nil {
-		return nil, fmt.Errorf("failed to parse response JSON: %w", err)
+// writeRequestError refuses a request body that is too large (413) or invalid (400,
+// listing each field's problem)
+func writeRequestError(w http.ResponseWriter, err error) {
+	if errors.Is(err, schema.ErrBodyTooLarge) {
+		httputils.ErrorJSON(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
 	}
 
-	// Check for non-2xx status codes
-	if resp.StatusCode >= 400 {
-		return nil, fmt.Errorf("Python agent returned error %d: %v", resp.StatusCode, responseJSON)
+	var fieldErrs schema.Errors
+	if !errors.As(err, &fieldErrs) {
+		httputils.ErrorJSON(w, "Invalid request body", http.StatusBadRequest)
+		return
 	}
+	httputils.ResponseJSON(w, map[string]interface{}{
+		"error":   "Invalid request: " + fieldErrs.Error(),
+		"code":    http.StatusBadRequest,
+		"success": false,
+		"fields":  fieldErrs,
+	}, http.StatusBadRequest)
+}
 
-	log.Printf("Successfully forwarded %s %s for user %s", method, endpoint, userID)
-	return responseJSON, nil
+// writeAgentError answers with the status that matches an agent client failure
+func writeAgentError(w http.ResponseWriter, message string, err error) {
+	statusCode := agents.HTTPStatus(err)
+	log.Printf("%s: %v (status %d)", message, err, statusCode)
+	httputils.ErrorJSON(w, fmt.Sprintf("%s: %v", message, err), statusCode)
 }
 
 // Helper functions for extracting values from maps
@@ -496,10 +965,3 @@ func getStringFromMap(m map[string]interface{}, key string, defaultValue string)
 	return defaultValue
 }
 
-func getStringPtrFromMap(m map[string]interface{}, key string) *string {
-	if val, ok := m[key].(string); ok {
-		return &val
-	}
-	return nil
-}
-
@@ -0,0 +1,44 @@
+package pythonagentgateway
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"interviewai.wkv.local/pythonagentgateway/internal/httputils"
+	"interviewai.wkv.local/pythonagentgateway/internal/ratelimit"
+)
+
+// allowRequest consumes one request from the user's limits for class and sets the
+// RateLimit-* headers. When the user is over a limit it writes a 429 and returns false.
+// Store errors fail open so a counter outage does not block interviews.
+func allowRequest(w http.ResponseWriter, r *http.Request, userID string, class ratelimit.Class) bool {
+	if rateLimiter == nil {
+		return true
+	}
+
+	decision, err := rateLimiter.Allow(r.Context(), userID, class, hasUserAPIKey(r.Context(), userID))
+	if err != nil {
+		log.Printf("Rate limit check failed for user %s: %v", userID, err)
+		return true
+	}
+
+	decision.WriteHeaders(w)
+	if !decision.Allowed {
+		log.Printf("User %s denied %s request (%s)", userID, class, decision.Reason)
+		httputils.ErrorJSON(w, decision.Message(), http.StatusTooManyRequests)
+		return false
+	}
+	return true
+}
+
+// hasUserAPIKey reports whether the user has stored their own Gemini API key. When
+// that cannot be told the default key's limits apply.
+func hasUserAPIKey(ctx context.Context, userID string) bool {
+	key, err := userAPIKey(ctx, userID)
+	if err != nil {
+		log.Printf("Rate limit: %v for user %s", err, userID)
+		return false
+	}
+	return key != ""
+}
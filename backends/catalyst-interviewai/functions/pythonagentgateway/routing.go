@@ -0,0 +1,26 @@
+package pythonagentgateway
+
+import (
+	"log"
+
+	"interviewai.wkv.local/pythonagentgateway/internal/agents"
+	"interviewai.wkv.local/pythonagentgateway/internal/sessions"
+)
+
+// assignAgentBackend picks the agent backend for a new session by hashing the user
+// ID, so every start by a user, retries included, lands on the same backend and
+// users spread across backends in proportion to their weights
+func assignAgentBackend(userID string) *agents.Backend {
+	return agentRouter.Assign(userID)
+}
+
+// agentForSession returns the client for the backend a session was assigned at start.
+// Sessions registered before routing existed have no variant and use the primary backend.
+func agentForSession(session *sessions.Session) *agents.AgentClient {
+	backend, found := agentRouter.Backend(session.Variant)
+	if !found && session.Variant != "" {
+		log.Printf("Session %s is on agent backend %q, which is no longer configured; using %s",
+			session.SessionID, session.Variant, backend.Name)
+	}
+	return backend.Client
+}
@@ -0,0 +1,331 @@
+package pythonagentgateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"interviewai.wkv.local/pythonagentgateway/internal/agents"
+	"interviewai.wkv.local/pythonagentgateway/internal/auth"
+	"interviewai.wkv.local/pythonagentgateway/internal/httputils"
+	"interviewai.wkv.local/pythonagentgateway/internal/jobs"
+	"interviewai.wkv.local/pythonagentgateway/internal/schema"
+	"interviewai.wkv.local/pythonagentgateway/internal/tasks"
+	"interviewai.wkv.local/pythonagentgateway/internal/tracing"
+)
+
+// jobPollInterval is the Retry-After suggested to clients polling a pending job
+const jobPollInterval = 2 * time.Second
+
+// startJobAttempts is how many times the queue may run a job that fails transiently,
+// and how many lease periods a job may stay pending before a poll reports it as
+// timed out
+const startJobAttempts = 3
+
+// startJobTask is the body the queue delivers to RunStartInterviewJobGCF
+type startJobTask struct {
+	UserID string `json:"userId"`
+	JobID  string `json:"jobId"`
+}
+
+// asyncRequested reports whether the client asked for ?async=true
+func asyncRequested(r *http.Request) bool {
+	switch strings.ToLower(r.URL.Query().Get("async")) {
+	case "true", "1":
+		return true
+	}
+	return false
+}
+
+// newStartJobQueue creates the Cloud Tasks queue for background starts, or returns
+// nil when none is configured and jobs run on the instance that accepted them
+func newStartJobQueue(ctx context.Context) (*tasks.Queue, error) {
+	cfg := serviceConfig.StartJobs
+	if cfg.Queue == "" {
+		if !serviceConfig.UseLocalService {
+			log.Println("Warning: START_JOB_QUEUE not set; async interview starts are refused")
+		}
+		return nil, nil
+	}
+	if cfg.WorkerURL == "" {
+		return nil, fmt.Errorf("START_JOB_WORKER_URL is required with START_JOB_QUEUE")
+	}
+	return tasks.NewQueue(ctx, cfg.Queue, tracing.Transport(http.DefaultTransport))
+}
+
+// asyncStartsAvailable reports whether background starts can outlast the request:
+// through the queue, or on a local service, which keeps running after it answers
+func asyncStartsAvailable() bool {
+	return startJobQueue != nil || serviceConfig.UseLocalService
+}
+
+// startInterviewJob records a pending start on backend, hands it to the queue and
+// answers 202 with the job to poll. The caller has validated the request and charged
+// the user's limits.
+func startInterviewJob(w http.ResponseWriter, r *http.Request, idem *idempotentCall, userID string, backend *agents.Backend, startRequest *schema.StartInterviewRequest) {
+	job, err := jobStore.Create(r.Context(), userID, jobs.KindStartInterview, backend.Name, startRequest.Body())
+	if err != nil {
+		idem.abort()
+		log.Printf("StartInterview: %v", err)
+		httputils.ErrorJSON(w, "Failed to create start job", http.StatusInternalServerError)
+		return
+	}
+
+	if err := dispatchStartJob(r.Context(), job); err != nil {
+		idem.abort()
+		log.Printf("StartInterview: Failed to dispatch job %s: %v", job.JobID, err)
+		if err := jobStore.Fail(context.Background(), userID, job.JobID, "Failed to schedule interview start", http.StatusServiceUnavailable); err != nil {
+			log.Printf("StartInterview: %v", err)
+		}
+		httputils.ErrorJSON(w, "Failed to schedule interview start", http.StatusServiceUnavailable)
+		return
+	}
+	log.Printf("StartInterview: User %s start job %s accepted", userID, job.JobID)
+
+	pollURL := "/api/agents/jobs/" + job.JobID
+	response := map[string]interface{}{
+		"jobId":   job.JobID,
+		"status":  job.Status,
+		"pollUrl": pollURL,
+	}
+	idem.complete(http.StatusAccepted, response)
+
+	w.Header().Set("Location", pollURL)
+	w.Header().Set("Retry-After", strconv.Itoa(int(jobPollInterval.Seconds())))
+	httputils.ResponseJSON(w, response, http.StatusAccepted)
+}
+
+// dispatchStartJob queues the job for RunStartInterviewJobGCF. The task is named after
+// the job, so dispatching it twice runs it once.
+func dispatchStartJob(ctx context.Context, job *jobs.Job) error {
+	if startJobQueue == nil {
+		// Only a local service gets here without a queue (see asyncStartsAvailable)
+		go runStartJob(context.Background(), job.UserID, job.JobID)
+		return nil
+	}
+
+	cfg := serviceConfig.StartJobs
+	return startJobQueue.Enqueue(ctx, tasks.HTTPTask{
+		ID:             job.JobID,
+		URL:            cfg.WorkerURL,
+		Body:           startJobTask{UserID: job.UserID, JobID: job.JobID},
+		ServiceAccount: cfg.InvokerEmail,
+		Deadline:       cfg.Timeout,
+	})
+}
+
+// RunStartInterviewJobGCF handles POST from the start job queue and runs one start.
+// Non-2xx answers make the queue retry.
+func RunStartInterviewJobGCF(w http.ResponseWriter, r *http.Request) {
+	w, r, end := tracing.StartServer(w, r, "RunStartInterviewJob")
+	defer end()
+
+	if r.Method != http.MethodPost {
+		httputils.ErrorJSON(w, "Only POST method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// A local agent service has no queue in front of it
+	if !serviceConfig.UseLocalService {
+		cfg := serviceConfig.StartJobs
+		if err := auth.VerifyServiceAccount(r, cfg.WorkerURL, cfg.InvokerEmail); err != nil {
+			log.Printf("RunStartInterviewJob: Rejected caller: %v", err)
+			httputils.ErrorJSON(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+	}
+
+	var task startJobTask
+	if err := json.NewDecoder(r.Body).Decode(&task); err != nil || task.UserID == "" || task.JobID == "" {
+		// Retrying a malformed task cannot help
+		log.Printf("RunStartInterviewJob: Dropping malformed task: %v", err)
+		httputils.ResponseJSON(w, map[string]interface{}{"dropped": true}, http.StatusOK)
+		return
+	}
+	defer r.Body.Close()
+
+	statusCode := runStartJob(r.Context(), task.UserID, task.JobID)
+	httputils.ResponseJSON(w, map[string]interface{}{"jobId": task.JobID}, statusCode)
+}
+
+// runStartJob claims the job and starts its interview, recording the session payload
+// or the error on the job. It returns the status for the queue: 2xx when there is
+// nothing left to do, otherwise a status that makes the queue try again.
+func runStartJob(ctx context.Context, userID, jobID string) int {
+	job, err := jobStore.Claim(ctx, userID, jobID, serviceConfig.StartJobs.Timeout)
+	switch {
+	case errors.Is(err, jobs.ErrJobNotFound), errors.Is(err, jobs.ErrJobFinished):
+		return http.StatusOK
+	case errors.Is(err, jobs.ErrJobLeased):
+		return http.StatusConflict
+	case err != nil:
+		log.Printf("RunStartInterviewJob: %v", err)
+		return http.StatusInternalServerError
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, serviceConfig.StartJobs.Timeout)
+	defer cancel()
+	log.Printf("RunStartInterviewJob: Starting job %s for user %s (attempt %d)", jobID, userID, job.Attempts)
+
+	fail := func(message string, statusCode int) int {
+		log.Printf("RunStartInterviewJob: Job %s failed: %s (status %d)", jobID, message, statusCode)
+		if err := jobStore.Fail(context.Background(), userID, jobID, message, statusCode); err != nil && !errors.Is(err, jobs.ErrJobFinished) {
+			log.Printf("RunStartInterviewJob: %v", err)
+			return http.StatusInternalServerError
+		}
+		return http.StatusOK
+	}
+
+	// Transient failures are left pending for the queue to try again, until the
+	// last attempt records them
+	retry := func(err error) bool {
+		if startJobQueue == nil || job.Attempts >= startJobAttempts {
+			return false
+		}
+		log.Printf("RunStartInterviewJob: Job %s attempt %d failed, leaving it to the queue: %v", jobID, job.Attempts, err)
+		if err := jobStore.Release(context.Background(), userID, jobID); err != nil {
+			log.Printf("RunStartInterviewJob: %v", err)
+		}
+		return true
+	}
+
+	body, err := json.Marshal(job.Request)
+	if err != nil {
+		return fail("Stored start request is unreadable", http.StatusInternalServerError)
+	}
+	startRequest, err := schema.DecodeStartInterview(body)
+	if err != nil {
+		return fail(fmt.Sprintf("Invalid request: %v", err), http.StatusBadRequest)
+	}
+
+	apiKey, err := resolveAgentAPIKey(ctx, userID)
+	if err != nil {
+		if errors.Is(err, errUserKeyLookup) && retry(err) {
+			return http.StatusServiceUnavailable
+		}
+		return fail(apiKeyErrorResponse(err))
+	}
+
+	backend, found := agentRouter.Backend(job.Variant)
+	if !found {
+		log.Printf("RunStartInterviewJob: Agent backend %q is no longer configured; using %s", job.Variant, backend.Name)
+	}
+
+	requestBody := startRequest.Body()
+	requestBody["user_id"] = userID
+	requestBody["type"] = "start_interview"
+
+	response, err := backend.Client.StartInterview(ctx, userID, requestBody)
+	if err != nil {
+		if agents.IsTransient(err) && retry(err) {
+			return agents.HTTPStatus(err)
+		}
+		return fail(fmt.Sprintf("Failed to start interview: %v", err), agents.HTTPStatus(err))
+	}
+
+	// Sessions that cannot be registered would be unusable, so fail the start
+	if err := registerSession(ctx, userID, backend.Name, apiKey.Source, startRequest, requestBody, response); err != nil {
+		log.Printf("RunStartInterviewJob: %v", err)
+		return fail("Failed to register interview session", http.StatusInternalServerError)
+	}
+	recordInterviewStart(userID, backend.Name, apiKey.Source, startRequest, requestBody, response)
+
+	if err := jobStore.Succeed(context.Background(), userID, jobID, response); err != nil {
+		if errors.Is(err, jobs.ErrJobFinished) {
+			// The client was told the start failed and will not use the session, which
+			// the sweeper ends once it is idle
+			log.Printf("RunStartInterviewJob: Job %s finished before session %s started; leaving the session to the sweeper", jobID, getStringFromMap(response, "session_id", ""))
+			return http.StatusOK
+		}
+		log.Printf("RunStartInterviewJob: %v", err)
+		return http.StatusInternalServerError
+	}
+	log.Printf("RunStartInterviewJob: Job %s started session %s", jobID, getStringFromMap(response, "session_id", ""))
+	return http.StatusOK
+}
+
+// GetAgentJobGCF handles GET /api/agents/jobs/{jobId}
+func GetAgentJobGCF(w http.ResponseWriter, r *http.Request) {
+	w, r, end := tracing.StartServer(w, r, "GetAgentJob")
+	defer end()
+
+	httputils.SetCORSHeaders(w, r)
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		httputils.ErrorJSON(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	authedUser, err := auth.VerifyToken(r, firebaseAppSingleton)
+	if err != nil {
+		httputils.ErrorJSON(w, fmt.Sprintf("Unauthorized: %v", err), http.StatusUnauthorized)
+		return
+	}
+
+	userID := authedUser.UID
+	jobID := extractJobIDFromPath(r.URL.Path)
+	if jobID == "" {
+		httputils.ErrorJSON(w, "Job ID not found in path", http.StatusBadRequest)
+		return
+	}
+
+	// Jobs are stored under their owner, so another user's job is simply not found
+	job, err := jobStore.Get(r.Context(), userID, jobID)
+	if errors.Is(err, jobs.ErrJobNotFound) {
+		httputils.ErrorJSON(w, "Job not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("GetAgentJob: %v", err)
+		httputils.ErrorJSON(w, "Failed to load job", http.StatusInternalServerError)
+		return
+	}
+
+	// A job whose every attempt was lost would otherwise stay pending until it expires.
+	// One an attempt still holds is left to finish.
+	if job.Status == jobs.StatusPending && !job.Leased(time.Now()) && time.Since(job.CreatedAt) > startJobAttempts*serviceConfig.StartJobs.Timeout {
+		const message = "Interview start timed out"
+		err := jobStore.TimeOut(r.Context(), userID, jobID, message, http.StatusGatewayTimeout)
+		switch {
+		case err == nil:
+			job.Status = jobs.StatusFailed
+			job.Error = message
+			job.ErrorCode = http.StatusGatewayTimeout
+		case errors.Is(err, jobs.ErrJobFinished), errors.Is(err, jobs.ErrJobLeased):
+			// An attempt finished or claimed the job since it was read
+			if current, err := jobStore.Get(r.Context(), userID, jobID); err == nil {
+				job = current
+			} else {
+				log.Printf("GetAgentJob: %v", err)
+			}
+		default:
+			log.Printf("GetAgentJob: %v", err)
+		}
+	}
+
+	if job.Status == jobs.StatusPending {
+		w.Header().Set("Retry-After", strconv.Itoa(int(jobPollInterval.Seconds())))
+	}
+	httputils.ResponseJSON(w, job, http.StatusOK)
+}
+
+// extractJobIDFromPath reads the job ID from /api/agents/jobs/{jobId}
+func extractJobIDFromPath(path string) string {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	for i, part := range parts {
+		if part == "jobs" && i+1 < len(parts) {
+			return parts[i+1]
+		}
+	}
+	return ""
+}
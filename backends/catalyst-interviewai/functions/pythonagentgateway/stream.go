@@ -0,0 +1,163 @@
+package pythonagentgateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"interviewai.wkv.local/pythonagentgateway/internal/agents"
+	"interviewai.wkv.local/pythonagentgateway/internal/httputils"
+)
+
+// streamHeartbeatInterval keeps idle connections open through proxies and load balancers
+const streamHeartbeatInterval = 15 * time.Second
+
+// streamRequested reports whether the client asked for a Server-Sent Events response,
+// either with ?stream=true or an Accept: text/event-stream header
+func streamRequested(r *http.Request) bool {
+	switch strings.ToLower(r.URL.Query().Get("stream")) {
+	case "true", "1":
+		return true
+	}
+	return strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+}
+
+// relayAgentStream forwards a streaming agent turn to the client as Server-Sent Events.
+// Token events are relayed as they arrive and heartbeat events are sent while the agent
+// is quiet; an agent that answers with one JSON body is relayed as the done event. It
+// returns the final agent payload once the stream completes so callers can run their
+// post-processing (analytics, registries) exactly once.
+//
+// If the upstream request fails before any event is written, a JSON error is returned
+// to the client instead. Errors after that point are reported as an error event.
+// A non-nil resume is called when the agent no longer knows the session, and the
+// stream is opened once more. A non-nil finish is handed the final payload before
+// the client sees the done event; if it fails, the client gets an error event
+// instead.
+func relayAgentStream(w http.ResponseWriter, r *http.Request, client *agents.AgentClient, operation, endpoint string, body interface{}, userID string, resume func(context.Context) error, finish func(map[string]interface{}) error) (map[string]interface{}, error) {
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	resp, err := client.OpenStream(ctx, operation, http.MethodPost, endpoint, body, userID)
+	if resume != nil && agents.IsRejected(err, http.StatusNotFound) {
+		if resumeErr := resume(ctx); resumeErr != nil {
+			log.Printf("Failed to resume session: %v", resumeErr)
+		} else {
+			resp, err = client.OpenStream(ctx, operation, http.MethodPost, endpoint, body, userID)
+		}
+	}
+	if err != nil {
+		writeAgentError(w, "Failed to reach agent service", err)
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	sse, err := httputils.NewSSEWriter(w)
+	if err != nil {
+		httputils.ErrorJSON(w, "Streaming not supported", http.StatusInternalServerError)
+		return nil, err
+	}
+
+	// Heartbeats run until the relay returns, which waits for the last one so nothing
+	// writes to the response after the handler is done
+	heartbeatsStopped := make(chan struct{})
+	defer func() {
+		cancel()
+		<-heartbeatsStopped
+	}()
+	go func() {
+		defer close(heartbeatsStopped)
+		ticker := time.NewTicker(streamHeartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case t := <-ticker.C:
+				if err := sse.JSON("heartbeat", map[string]interface{}{"timestamp": t.UTC().Format(time.RFC3339)}); err != nil {
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+
+	var final map[string]interface{}
+	var done string
+	var transcript strings.Builder
+	upstreamFailed := ""
+
+	readErr := agents.ReadStream(resp.Body, resp.Header.Get("Content-Type"), func(event agents.StreamEvent) error {
+		switch event.Event {
+		case agents.StreamEventToken:
+			transcript.WriteString(tokenText(event.Data))
+		case agents.StreamEventDone:
+			var payload map[string]interface{}
+			if err := json.Unmarshal([]byte(event.Data), &payload); err == nil {
+				final = payload
+			}
+			// Held back until finish has accepted the turn
+			done = event.Data
+			return nil
+		case agents.StreamEventError:
+			upstreamFailed = event.Data
+		}
+		return sse.Event(event.Event, event.Data)
+	})
+
+	if ctx.Err() != nil {
+		log.Printf("Stream %s for user %s cancelled: client disconnected", endpoint, userID)
+		return nil, ctx.Err()
+	}
+	if readErr != nil {
+		log.Printf("Stream %s for user %s failed: %v", endpoint, userID, readErr)
+		sse.JSON(agents.StreamEventError, map[string]interface{}{"error": "agent stream interrupted"})
+		return nil, readErr
+	}
+	if upstreamFailed != "" {
+		return nil, errors.New(upstreamFailed)
+	}
+
+	// Agents that only stream tokens get a synthesized completion event
+	if final == nil {
+		final = map[string]interface{}{"response": transcript.String()}
+		data, err := json.Marshal(final)
+		if err != nil {
+			return nil, err
+		}
+		done = string(data)
+	}
+
+	if finish != nil {
+		if err := finish(final); err != nil {
+			log.Printf("Stream %s for user %s could not be finished: %v", endpoint, userID, err)
+			sse.JSON(agents.StreamEventError, map[string]interface{}{"error": err.Error()})
+			return nil, err
+		}
+	}
+	if err := sse.Event(agents.StreamEventDone, done); err != nil {
+		return nil, err
+	}
+
+	log.Printf("Successfully streamed %s for user %s", endpoint, userID)
+	return final, nil
+}
+
+// tokenText extracts the text of a token event, which may be raw text or a
+// JSON object carrying the text in a "token" or "text" field
+func tokenText(data string) string {
+	var payload map[string]interface{}
+	if err := json.Unmarshal([]byte(data), &payload); err != nil {
+		return data
+	}
+	for _, key := range []string{"token", "text", "delta"} {
+		if s, ok := payload[key].(string); ok {
+			return s
+		}
+	}
+	return ""
+}
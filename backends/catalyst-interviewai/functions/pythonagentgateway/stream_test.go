@@ -0,0 +1,145 @@
+package pythonagentgateway
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"interviewai.wkv.local/pythonagentgateway/internal/agents"
+)
+
+func TestRelayAgentStream(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		status      int
+		upstream    string
+		finishErr   error
+		wantFinal   map[string]interface{}
+		wantEvents  []agents.StreamEvent
+		wantErr     bool
+		wantStatus  int
+	}{
+		{
+			name:        "agent answering with one JSON body",
+			contentType: "application/json",
+			upstream:    `{"session_id":"s1","response":"Tell me about yourself.","next_question":"Why this role?"}`,
+			wantFinal:   map[string]interface{}{"session_id": "s1", "response": "Tell me about yourself.", "next_question": "Why this role?"},
+			wantEvents: []agents.StreamEvent{
+				{Event: "done", Data: `{"session_id":"s1","response":"Tell me about yourself.","next_question":"Why this role?"}`},
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:        "JSON with a charset",
+			contentType: "application/json; charset=utf-8",
+			upstream:    `{"session_id":"s2"}`,
+			wantFinal:   map[string]interface{}{"session_id": "s2"},
+			wantEvents:  []agents.StreamEvent{{Event: "done", Data: `{"session_id":"s2"}`}},
+			wantStatus:  http.StatusOK,
+		},
+		{
+			name:        "server-sent events",
+			contentType: "text/event-stream",
+			upstream:    "event: token\ndata: {\"token\":\"Hel\"}\n\n: keep-alive\n\nevent: token\ndata: lo\n\nevent: done\ndata: {\"response\":\"Hello\",\"session_id\":\"s3\"}\n\n",
+			wantFinal:   map[string]interface{}{"response": "Hello", "session_id": "s3"},
+			wantEvents: []agents.StreamEvent{
+				{Event: "token", Data: `{"token":"Hel"}`},
+				{Event: "token", Data: "lo"},
+				{Event: "done", Data: `{"response":"Hello","session_id":"s3"}`},
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:        "tokens without a done event",
+			contentType: "text/event-stream",
+			upstream:    "data: Hel\n\ndata: lo\n\n",
+			wantFinal:   map[string]interface{}{"response": "Hello"},
+			wantEvents: []agents.StreamEvent{
+				{Event: "token", Data: "Hel"},
+				{Event: "token", Data: "lo"},
+				{Event: "done", Data: `{"response":"Hello"}`},
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:        "finish refuses the turn",
+			contentType: "application/json",
+			upstream:    `{"response":"Hi"}`,
+			finishErr:   errors.New("failed to register interview session"),
+			wantEvents:  []agents.StreamEvent{{Event: "error", Data: `{"error":"failed to register interview session"}`}},
+			wantErr:     true,
+			wantStatus:  http.StatusOK,
+		},
+		{
+			name:        "agent error event",
+			contentType: "text/event-stream",
+			upstream:    "event: error\ndata: model overloaded\n\n",
+			wantEvents:  []agents.StreamEvent{{Event: "error", Data: "model overloaded"}},
+			wantErr:     true,
+			wantStatus:  http.StatusOK,
+		},
+		{
+			name:        "agent refuses the request",
+			contentType: "application/json",
+			status:      http.StatusBadRequest,
+			upstream:    `{"detail":"unknown interview type"}`,
+			wantErr:     true,
+			wantStatus:  http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tt.contentType)
+				if tt.status != 0 {
+					w.WriteHeader(tt.status)
+				}
+				fmt.Fprint(w, tt.upstream)
+			}))
+			defer upstream.Close()
+			client := agents.NewAgentClient(upstream.URL, 0)
+
+			var finished map[string]interface{}
+			finish := func(final map[string]interface{}) error {
+				finished = final
+				return tt.finishErr
+			}
+
+			w := httptest.NewRecorder()
+			r := httptest.NewRequest(http.MethodPost, "/api/agents/interview/start?stream=true", nil)
+			final, err := relayAgentStream(w, r, client, agents.OpStartInterview, "/interview/start", map[string]interface{}{"stream": true}, "user1", nil, finish)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("relayAgentStream() error = %v, want error %v", err, tt.wantErr)
+			}
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if !reflect.DeepEqual(final, tt.wantFinal) {
+				t.Errorf("final = %v, want %v", final, tt.wantFinal)
+			}
+			if tt.wantFinal != nil && !reflect.DeepEqual(finished, tt.wantFinal) {
+				t.Errorf("finish got %v, want %v", finished, tt.wantFinal)
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var events []agents.StreamEvent
+			if err := agents.ReadStream(strings.NewReader(w.Body.String()), w.Header().Get("Content-Type"), func(event agents.StreamEvent) error {
+				events = append(events, event)
+				return nil
+			}); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(events, tt.wantEvents) {
+				t.Errorf("client got events %v, want %v", events, tt.wantEvents)
+			}
+		})
+	}
+}
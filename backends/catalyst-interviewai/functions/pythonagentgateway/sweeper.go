@@ -0,0 +1,290 @@
+package pythonagentgateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"interviewai.wkv.local/pythonagentgateway/internal/agents"
+	"interviewai.wkv.local/pythonagentgateway/internal/auth"
+	"interviewai.wkv.local/pythonagentgateway/internal/httputils"
+	"interviewai.wkv.local/pythonagentgateway/internal/reports"
+	"interviewai.wkv.local/pythonagentgateway/internal/sessions"
+	"interviewai.wkv.local/pythonagentgateway/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Outcomes of sweeping one session
+const (
+	sweepOutcomeSwept      = "swept"       // ended on the agent and recorded as abandoned
+	sweepOutcomeAgentLost  = "agent_lost"  // the agent had already forgotten it; recorded as abandoned
+	sweepOutcomeWouldSweep = "would_sweep" // dry run
+	sweepOutcomeSkipped    = "skipped"     // became active again after the query
+	sweepOutcomeFailed     = "failed"      // left active for the next run
+)
+
+// sweepOptions control one sweep run
+type sweepOptions struct {
+	IdleFor        time.Duration
+	DryRun         bool
+	Limit          int
+	PartialReports bool
+}
+
+// sweepResult is the summary of a sweep run. Its counts are also logged as the
+// session sweep metrics.
+type sweepResult struct {
+	DryRun     bool            `json:"dryRun"`
+	IdleFor    string          `json:"idleFor"`
+	Cutoff     time.Time       `json:"cutoff"`
+	Found      int             `json:"found"`
+	Swept      int             `json:"swept"`
+	WouldSweep int             `json:"wouldSweep"`
+	AgentLost  int             `json:"agentLost"`
+	Skipped    int             `json:"skipped"`
+	Failed     int             `json:"failed"`
+	Reports    int             `json:"reports"`
+	Sessions   []*sweptSession `json:"sessions"`
+}
+
+// sweptSession is what happened to one idle session
+type sweptSession struct {
+	SessionID string `json:"sessionId"`
+	UserID    string `json:"userId"`
+	IdleFor   string `json:"idleFor"`
+	Outcome   string `json:"outcome"`
+	Report    bool   `json:"report,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// SweepAbandonedSessionsGCF handles POST /api/agents/sessions/sweep. Cloud Scheduler
+// calls it with an OIDC token for SWEEP_INVOKER_EMAIL. It ends sessions that have
+// had no activity for SESSION_IDLE_TIMEOUT and records them as abandoned.
+//
+// ?dry_run=true lists the sessions without ending them, ?idle=45m overrides the idle
+// period and ?limit= caps how many sessions are swept.
+func SweepAbandonedSessionsGCF(w http.ResponseWriter, r *http.Request) {
+	w, r, end := tracing.StartServer(w, r, "SweepAbandonedSessions")
+	defer end()
+
+	if r.Method != http.MethodPost {
+		httputils.ErrorJSON(w, "Only POST method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// A local agent service has no scheduler in front of it
+	if !serviceConfig.UseLocalService {
+		if err := auth.VerifyServiceAccount(r, serviceConfig.Sweep.Audience, serviceConfig.Sweep.InvokerEmail); err != nil {
+			log.Printf("SweepAbandonedSessions: Rejected caller: %v", err)
+			httputils.ErrorJSON(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+	}
+
+	options, err := sweepOptionsFromRequest(r)
+	if err != nil {
+		httputils.ErrorJSON(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result, err := sweepAbandonedSessions(r.Context(), options)
+	if err != nil {
+		log.Printf("SweepAbandonedSessions: %v", err)
+		httputils.ErrorJSON(w, "Failed to find idle sessions", http.StatusInternalServerError)
+		return
+	}
+
+	// The analytics rows are written in the background; finish them before the
+	// instance is frozen
+	analyticsWrites.Wait()
+
+	trace.SpanFromContext(r.Context()).SetAttributes(
+		attribute.Bool("sweep.dry_run", result.DryRun),
+		attribute.Int("sweep.found", result.Found),
+		attribute.Int("sweep.swept", result.Swept),
+		attribute.Int("sweep.would_sweep", result.WouldSweep),
+		attribute.Int("sweep.agent_lost", result.AgentLost),
+		attribute.Int("sweep.failed", result.Failed),
+	)
+	logSweepMetrics(result)
+
+	httputils.ResponseJSON(w, result, http.StatusOK)
+}
+
+// sweepOptionsFromRequest applies the query overrides to the configured sweep. A
+// configured dry run cannot be turned off per request.
+func sweepOptionsFromRequest(r *http.Request) (sweepOptions, error) {
+	options := sweepOptions{
+		IdleFor:        serviceConfig.SessionIdleTimeout,
+		DryRun:         serviceConfig.Sweep.DryRun,
+		Limit:          serviceConfig.Sweep.BatchSize,
+		PartialReports: serviceConfig.Sweep.PartialReports,
+	}
+
+	query := r.URL.Query()
+	if query.Get("dry_run") == "true" {
+		options.DryRun = true
+	}
+	if idle := query.Get("idle"); idle != "" {
+		idleFor, err := time.ParseDuration(idle)
+		if err != nil || idleFor <= 0 {
+			return options, fmt.Errorf("idle must be a positive duration such as 45m")
+		}
+		options.IdleFor = idleFor
+	}
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n <= 0 {
+			return options, fmt.Errorf("limit must be a positive number")
+		}
+		options.Limit = n
+	}
+	return options, nil
+}
+
+// sweepAbandonedSessions ends every session idle for longer than options.IdleFor,
+// the longest idle first. A session that cannot be ended stays active and is tried
+// again on the next run.
+func sweepAbandonedSessions(ctx context.Context, options sweepOptions) (*sweepResult, error) {
+	now := time.Now().UTC()
+	cutoff := now.Add(-options.IdleFor)
+
+	idle, err := sessionRegistry.Idle(ctx, cutoff, options.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &sweepResult{
+		DryRun:   options.DryRun,
+		IdleFor:  options.IdleFor.String(),
+		Cutoff:   cutoff,
+		Found:    len(idle),
+		Sessions: make([]*sweptSession, 0, len(idle)),
+	}
+	for _, session := range idle {
+		if ctx.Err() != nil {
+			log.Printf("SweepAbandonedSessions: Stopping with %d sessions left: %v", len(idle)-len(result.Sessions), ctx.Err())
+			break
+		}
+
+		swept := sweepSession(ctx, session, cutoff, options)
+		swept.IdleFor = now.Sub(session.LastActivityAt).Round(time.Second).String()
+		result.Sessions = append(result.Sessions, swept)
+
+		switch swept.Outcome {
+		case sweepOutcomeSwept:
+			result.Swept++
+		case sweepOutcomeAgentLost:
+			result.Swept++
+			result.AgentLost++
+		case sweepOutcomeWouldSweep:
+			result.WouldSweep++
+		case sweepOutcomeSkipped:
+			result.Skipped++
+		case sweepOutcomeFailed:
+			result.Failed++
+			log.Printf("SweepAbandonedSessions: Failed to sweep session %s: %s", swept.SessionID, swept.Error)
+		}
+		if swept.Report {
+			result.Reports++
+		}
+	}
+	return result, nil
+}
+
+// sweepSession ends one idle session on its agent and records it as abandoned
+func sweepSession(ctx context.Context, candidate *sessions.Session, cutoff time.Time, options sweepOptions) *sweptSession {
+	swept := &sweptSession{SessionID: candidate.SessionID, UserID: candidate.UserID}
+
+	// The candidate may have answered since the query ran
+	session, err := sessionRegistry.Authorize(ctx, candidate.UserID, candidate.SessionID)
+	if err != nil {
+		swept.Outcome, swept.Error = sweepOutcomeFailed, err.Error()
+		return swept
+	}
+	if session.Status != sessions.StatusActive || !session.LastActivityAt.Before(cutoff) {
+		swept.Outcome = sweepOutcomeSkipped
+		return swept
+	}
+
+	if options.DryRun {
+		swept.Outcome = sweepOutcomeWouldSweep
+		return swept
+	}
+
+	client := agentForSession(session)
+	requestBody := map[string]interface{}{
+		"session_id": session.SessionID,
+		"user_id":    session.UserID,
+		"type":       "end_interview",
+	}
+
+	// Without partial reports a session the agent has lost is simply recorded; with
+	// them it is rebuilt from its transcript first so ending it produces a report
+	var resume func(context.Context) error
+	if options.PartialReports {
+		resume = sessionResumer(client, session)
+	}
+	response, err := withResume(ctx, resume, func() (map[string]interface{}, error) {
+		return client.EndInterview(ctx, session.SessionID, session.UserID, requestBody)
+	})
+	switch {
+	case err == nil:
+		swept.Outcome = sweepOutcomeSwept
+	case agents.IsRejected(err, http.StatusNotFound):
+		swept.Outcome = sweepOutcomeAgentLost
+		response = nil
+	default:
+		swept.Outcome, swept.Error = sweepOutcomeFailed, err.Error()
+		return swept
+	}
+
+	log.Printf("SweepAbandonedSessions: Ending session %s of user %s, idle since %s",
+		session.SessionID, session.UserID, session.LastActivityAt.Format(time.RFC3339))
+	finalizeSession(ctx, session, sessions.StatusAbandoned, response)
+
+	if options.PartialReports && swept.Outcome == sweepOutcomeSwept {
+		// Answers were scored as they were submitted; fetching the report has the
+		// agent generate it while it still holds the session
+		if _, err := client.GetReport(ctx, session.SessionID, session.UserID, string(reports.FormatJSON)); err != nil {
+			log.Printf("SweepAbandonedSessions: No partial report for session %s: %v", session.SessionID, err)
+		} else {
+			swept.Report = true
+		}
+	}
+	return swept
+}
+
+// logSweepMetrics writes the run's counts as a structured log entry. Cloud Logging
+// parses JSON lines on stdout, so log-based metrics can be built on the sweep fields
+// (e.g. jsonPayload.sweep.swept).
+func logSweepMetrics(result *sweepResult) {
+	entry := map[string]interface{}{
+		"severity": "INFO",
+		"message":  "session sweep finished",
+		"sweep": map[string]interface{}{
+			"dry_run":     result.DryRun,
+			"idle_for":    result.IdleFor,
+			"found":       result.Found,
+			"swept":       result.Swept,
+			"would_sweep": result.WouldSweep,
+			"agent_lost":  result.AgentLost,
+			"skipped":     result.Skipped,
+			"failed":      result.Failed,
+			"reports":     result.Reports,
+		},
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		log.Printf("SweepAbandonedSessions: Failed to encode metrics: %v", err)
+		return
+	}
+	fmt.Fprintln(os.Stdout, string(line))
+}
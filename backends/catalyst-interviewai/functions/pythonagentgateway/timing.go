@@ -0,0 +1,137 @@
+package pythonagentgateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"time"
+
+	"interviewai.wkv.local/pkg/analytics"
+	"interviewai.wkv.local/pythonagentgateway/internal/auth"
+	"interviewai.wkv.local/pythonagentgateway/internal/httputils"
+	"interviewai.wkv.local/pythonagentgateway/internal/sessions"
+	"interviewai.wkv.local/pythonagentgateway/internal/tracing"
+)
+
+// maxDraftBytes caps a draft answer; drafts are saved often, so they stay small
+const maxDraftBytes = 64 << 10
+
+// answerTimingInfo is returned with the agent's reply so the client can give
+// pacing feedback
+type answerTimingInfo struct {
+	ResponseTimeSeconds *int `json:"responseTimeSeconds,omitempty"`
+	WordCount           int  `json:"wordCount"`
+	RevisionCount       *int `json:"revisionCount,omitempty"`
+}
+
+// InterviewDraftGCF handles POST /api/agents/interview/{sessionId}/draft. The body is
+// {"text": "..."}, the answer as the candidate has it so far. Drafts are not sent to
+// the agent; each one that changes the answer counts as a revision.
+func InterviewDraftGCF(w http.ResponseWriter, r *http.Request) {
+	w, r, end := tracing.StartServer(w, r, "InterviewDraft")
+	defer end()
+
+	httputils.SetCORSHeaders(w, r)
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		httputils.ErrorJSON(w, "Only POST method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	authedUser, err := auth.VerifyToken(r, firebaseAppSingleton)
+	if err != nil {
+		httputils.ErrorJSON(w, fmt.Sprintf("Unauthorized: %v", err), http.StatusUnauthorized)
+		return
+	}
+
+	userID := authedUser.UID
+	sessionID := extractSessionIDFromPath(r.URL.Path)
+	if sessionID == "" {
+		httputils.ErrorJSON(w, "Session ID not found in path", http.StatusBadRequest)
+		return
+	}
+
+	session, ok := authorizeSession(w, r, userID, sessionID)
+	if !ok {
+		return
+	}
+	if session.Status != sessions.StatusActive {
+		httputils.ErrorJSON(w, "Interview has ended", http.StatusConflict)
+		return
+	}
+
+	var draft struct {
+		Text string `json:"text"`
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxDraftBytes)
+	if err := json.NewDecoder(r.Body).Decode(&draft); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			httputils.ErrorJSON(w, fmt.Sprintf("Draft is larger than %d bytes", maxDraftBytes), http.StatusRequestEntityTooLarge)
+			return
+		}
+		httputils.ErrorJSON(w, "Invalid JSON in request body", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	savedAt := time.Now()
+	turn, err := sessionRegistry.SaveDraft(r.Context(), userID, sessionID, draft.Text, savedAt)
+	if errors.Is(err, sessions.ErrNoOpenTurn) {
+		httputils.ErrorJSON(w, "No question is waiting for an answer", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Printf("InterviewDraft: %v", err)
+		httputils.ErrorJSON(w, "Failed to save draft", http.StatusInternalServerError)
+		return
+	}
+
+	httputils.ResponseJSON(w, map[string]interface{}{
+		"sessionId":     sessionID,
+		"turn":          turn.Index,
+		"revisionCount": turn.RevisionCount,
+		"wordCount":     analytics.WordCount(draft.Text),
+		"savedAt":       savedAt.UTC(),
+	}, http.StatusOK)
+}
+
+// measureAnswer works out how long the candidate took over the answer, from when its
+// question was delivered, and passes it to the agent as responseTime. Without a
+// recorded question the caller's estimate, if any, is kept.
+func measureAnswer(ctx context.Context, session *sessions.Session, meta *answerMeta, requestBody map[string]interface{}) {
+	meta.WordCount = analytics.WordCount(getStringFromMap(requestBody, "response", ""))
+
+	open, err := sessionRegistry.OpenTurn(ctx, session.UserID, session.SessionID)
+	if err != nil {
+		log.Printf("Failed to read open turn: %v", err)
+	}
+	if open != nil {
+		meta.Question = open.Question
+		if open.AskedAt != nil && !meta.AnsweredAt.Before(*open.AskedAt) {
+			seconds := int(math.Round(meta.AnsweredAt.Sub(*open.AskedAt).Seconds()))
+			meta.ResponseTimeSeconds = &seconds
+		}
+	}
+
+	if meta.ResponseTimeSeconds != nil {
+		requestBody["responseTime"] = *meta.ResponseTimeSeconds
+	}
+}
+
+// answerTiming is the timing returned with the agent's reply
+func answerTiming(meta answerMeta) *answerTimingInfo {
+	return &answerTimingInfo{
+		ResponseTimeSeconds: meta.ResponseTimeSeconds,
+		WordCount:           meta.WordCount,
+		RevisionCount:       meta.RevisionCount,
+	}
+}
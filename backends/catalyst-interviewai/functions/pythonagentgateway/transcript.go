@@ -0,0 +1,227 @@
+package pythonagentgateway
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"interviewai.wkv.local/pythonagentgateway/internal/agents"
+	"interviewai.wkv.local/pythonagentgateway/internal/auth"
+	"interviewai.wkv.local/pythonagentgateway/internal/httputils"
+	"interviewai.wkv.local/pythonagentgateway/internal/sessions"
+	"interviewai.wkv.local/pythonagentgateway/internal/tracing"
+)
+
+// startRequestOmittedKeys are set by the gateway on every call rather than chosen by
+// the client, so they are not kept for resuming
+var startRequestOmittedKeys = []string{"user_id", "type", "stream"}
+
+// InterviewTranscriptGCF handles GET /api/agents/interview/{sessionId}/transcript
+func InterviewTranscriptGCF(w http.ResponseWriter, r *http.Request) {
+	w, r, end := tracing.StartServer(w, r, "InterviewTranscript")
+	defer end()
+
+	httputils.SetCORSHeaders(w, r)
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		httputils.ErrorJSON(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	authedUser, err := auth.VerifyToken(r, firebaseAppSingleton)
+	if err != nil {
+		httputils.ErrorJSON(w, fmt.Sprintf("Unauthorized: %v", err), http.StatusUnauthorized)
+		return
+	}
+
+	userID := authedUser.UID
+	sessionID := extractSessionIDFromPath(r.URL.Path)
+	if sessionID == "" {
+		httputils.ErrorJSON(w, "Session ID not found in path", http.StatusBadRequest)
+		return
+	}
+
+	session, ok := authorizeSession(w, r, userID, sessionID)
+	if !ok {
+		return
+	}
+
+	turns, err := sessionRegistry.Transcript(r.Context(), userID, sessionID)
+	if err != nil {
+		log.Printf("InterviewTranscript: %v", err)
+		httputils.ErrorJSON(w, "Failed to load transcript", http.StatusInternalServerError)
+		return
+	}
+	if turns == nil {
+		turns = []*sessions.Turn{}
+	}
+
+	httputils.ResponseJSON(w, map[string]interface{}{
+		"sessionId": sessionID,
+		"status":    session.Status,
+		"turns":     turns,
+	}, http.StatusOK)
+}
+
+// startRequestForResume keeps the client's start options so a lost session can be
+// started again with the same settings
+func startRequestForResume(requestBody map[string]interface{}) map[string]interface{} {
+	kept := make(map[string]interface{}, len(requestBody))
+	for key, value := range requestBody {
+		kept[key] = value
+	}
+	for _, key := range startRequestOmittedKeys {
+		delete(kept, key)
+	}
+	return kept
+}
+
+// recordFirstQuestion opens the transcript with the question the agent started on
+func recordFirstQuestion(ctx context.Context, userID, sessionID string, response map[string]interface{}) {
+	question := questionFrom(response)
+	if question == "" {
+		return
+	}
+	if err := sessionRegistry.AskQuestion(ctx, userID, sessionID, question, time.Now()); err != nil {
+		log.Printf("Failed to record first question: %v", err)
+	}
+}
+
+// recordTurn stores an answered turn. It runs before the response is returned so the
+// transcript is complete whenever the candidate sees the agent's reply; a failure
+// costs the ability to resume, not the turn, and returns nil.
+func recordTurn(ctx context.Context, userID, sessionID, responseID string, meta answerMeta, requestBody, response map[string]interface{}) *sessions.Turn {
+	turn, err := sessionRegistry.RecordAnswer(ctx, userID, sessionID, sessions.Answer{
+		ResponseID:          responseID,
+		Text:                getStringFromMap(requestBody, "response", ""),
+		AnsweredAt:          meta.AnsweredAt,
+		Audio:               meta.Audio,
+		ResponseTimeSeconds: meta.ResponseTimeSeconds,
+		WordCount:           meta.WordCount,
+		Reply:               replyFrom(response),
+		RepliedAt:           time.Now(),
+		State:               getStringFromMap(response, "state", ""),
+		NextQuestion:        questionFrom(response),
+	})
+	if err != nil {
+		log.Printf("Failed to record turn: %v", err)
+		return nil
+	}
+	return turn
+}
+
+// recordFailedAnswer keeps an answer the agent could not process on its turn, so an
+// administrator can replay it. It does not use the request's context, which ends
+// when a streaming client goes away.
+func recordFailedAnswer(userID, sessionID string, requestBody map[string]interface{}, answeredAt time.Time, err error) {
+	text := getStringFromMap(requestBody, "response", "")
+	if text == "" {
+		return
+	}
+	if _, recordErr := sessionRegistry.RecordFailure(context.Background(), userID, sessionID, text, err.Error(), answeredAt); recordErr != nil {
+		log.Printf("Failed to record failed answer: %v", recordErr)
+	}
+}
+
+// questionFrom extracts the question an agent payload asks, if any
+func questionFrom(payload map[string]interface{}) string {
+	for _, key := range []string{"initial_question", "next_question", "question"} {
+		if question := strings.TrimSpace(getStringFromMap(payload, key, "")); question != "" {
+			return question
+		}
+	}
+	return ""
+}
+
+// replyFrom extracts what the agent said back to an answer: its interventions, or the
+// text of a streamed reply
+func replyFrom(payload map[string]interface{}) string {
+	var messages []string
+	if interventions, ok := payload["interventions"].([]interface{}); ok {
+		for _, item := range interventions {
+			if intervention, ok := item.(map[string]interface{}); ok {
+				if message := getStringFromMap(intervention, "message", ""); message != "" {
+					messages = append(messages, message)
+				}
+			}
+		}
+	}
+	if len(messages) > 0 {
+		return strings.Join(messages, "\n")
+	}
+	for _, key := range []string{"message", "response"} {
+		if reply := getStringFromMap(payload, key, ""); reply != "" {
+			return reply
+		}
+	}
+	return ""
+}
+
+// sessionResumer returns a function that rebuilds session on the agent from its
+// transcript, or nil when the session has ended and must not be brought back
+func sessionResumer(client *agents.AgentClient, session *sessions.Session) func(context.Context) error {
+	if session.Status != sessions.StatusActive {
+		return nil
+	}
+	return func(ctx context.Context) error {
+		return resumeAgentSession(ctx, client, session)
+	}
+}
+
+// withResume runs call and, if the agent no longer knows the session (its instance
+// restarted, say), resumes it and runs call once more
+func withResume(ctx context.Context, resume func(context.Context) error, call func() (map[string]interface{}, error)) (map[string]interface{}, error) {
+	response, err := call()
+	if resume == nil || !agents.IsRejected(err, http.StatusNotFound) {
+		return response, err
+	}
+
+	if resumeErr := resume(ctx); resumeErr != nil {
+		log.Printf("Failed to resume session: %v", resumeErr)
+		return nil, err
+	}
+	return call()
+}
+
+// resumeAgentSession starts the session again on the agent with its original start
+// options and every turn so far
+func resumeAgentSession(ctx context.Context, client *agents.AgentClient, session *sessions.Session) error {
+	turns, err := sessionRegistry.Transcript(ctx, session.UserID, session.SessionID)
+	if err != nil {
+		return err
+	}
+
+	transcript := make([]map[string]interface{}, 0, len(turns))
+	for _, turn := range turns {
+		transcript = append(transcript, map[string]interface{}{
+			"index":    turn.Index,
+			"question": turn.Question,
+			"answer":   turn.Answer,
+			"reply":    turn.Reply,
+			"state":    turn.State,
+		})
+	}
+
+	request := startRequestForResume(session.StartRequest)
+	request["user_id"] = session.UserID
+	request["type"] = "resume_interview"
+	request["state"] = session.AgentState
+	request["started_at"] = session.CreatedAt.UTC().Format(time.RFC3339)
+	request["transcript"] = transcript
+
+	log.Printf("Resuming session %s for user %s from %d turns", session.SessionID, session.UserID, len(turns))
+	if _, err := client.ResumeInterview(ctx, session.SessionID, session.UserID, request); err != nil {
+		return err
+	}
+	if err := sessionRegistry.MarkResumed(ctx, session.UserID, session.SessionID); err != nil {
+		log.Printf("%v", err)
+	}
+	return nil
+}
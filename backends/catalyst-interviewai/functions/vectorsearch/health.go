@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"interviewai.wkv.local/vectorsearch/internal/health"
+
+	"cloud.google.com/go/firestore"
+	firebaseauth "firebase.google.com/go/v4/auth"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// healthCacheTTL lets uptime checks poll freely without each poll hitting every dependency
+const healthCacheTTL = 10 * time.Second
+
+// healthProbeUID is looked up in Firebase Auth to prove the service answers; it never exists
+const healthProbeUID = "health-probe"
+
+// newHealthChecker describes the search function's dependencies. Indexed documents
+// live in Firestore and every request is authenticated, so both are critical.
+func newHealthChecker() *health.Checker {
+	return health.NewChecker("vectorsearch", healthCacheTTL,
+		health.Check{Name: "firebase_auth", Critical: true, Probe: probeFirebaseAuth},
+		health.Check{Name: "firestore", Critical: true, Probe: probeFirestore(firestoreClient)},
+	)
+}
+
+// probeFirebaseAuth looks up a user that does not exist; a not-found answer proves
+// the Auth API is reachable with our credentials
+func probeFirebaseAuth(ctx context.Context) error {
+	client, err := firebaseAppSingleton.Auth(ctx)
+	if err != nil {
+		return err
+	}
+	if _, err := client.GetUser(ctx, healthProbeUID); err != nil && !firebaseauth.IsUserNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// probeFirestore reads a document that need not exist
+func probeFirestore(client *firestore.Client) health.ProbeFunc {
+	return func(ctx context.Context) error {
+		_, err := client.Collection("health").Doc("probe").Get(ctx)
+		if err != nil && status.Code(err) != codes.NotFound {
+			return err
+		}
+		return nil
+	}
+}
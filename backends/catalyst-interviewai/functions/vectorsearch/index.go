@@ -0,0 +1,488 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"interviewai.wkv.local/vectorsearch/internal/auth"
+	"interviewai.wkv.local/vectorsearch/internal/httputils"
+	"interviewai.wkv.local/vectorsearch/internal/lexical"
+	"interviewai.wkv.local/vectorsearch/internal/vectorstore"
+	"interviewai.wkv.local/vectorsearch/models"
+
+	"cloud.google.com/go/firestore"
+	"cloud.google.com/go/firestore/apiv1/firestorepb"
+	"google.golang.org/api/aiplatform/v1"
+	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
+)
+
+// defaultSnapshotRefresh is how often searches check for an HNSW snapshot saved by
+// another instance, and for scraped documents the lexical index lacks
+const defaultSnapshotRefresh = 5 * time.Minute
+
+// indexBatchSize is how many scraped documents are read and indexed at a time
+const indexBatchSize = 100
+
+// lexicalRefresh tracks the checks that keep the lexical index in step with
+// scraped_content, which other instances and the scraper add to
+var lexicalRefresh struct {
+	sync.Mutex
+	every     time.Duration
+	checkedAt time.Time
+	version   contentVersion // of scraped_content when the index was last built
+}
+
+// updatedAtTypes are the lowest values of each type writers store updatedAt as:
+// Unix seconds, strings and timestamps. Firestore orders and filters each type
+// separately.
+var updatedAtTypes = [...]interface{}{int64(math.MinInt64), "", time.Time{}}
+
+// contentVersion is the number of scraped documents and, for each updatedAt
+// type, the most recently updated one. Adding or removing a document changes it,
+// and so does an edit that sets updatedAt.
+type contentVersion struct {
+	documents int64
+	updated   [len(updatedAtTypes)]string
+}
+
+// refreshInterval reads HNSW_REFRESH_INTERVAL
+func refreshInterval() (time.Duration, error) {
+	value := os.Getenv("HNSW_REFRESH_INTERVAL")
+	if value == "" {
+		return defaultSnapshotRefresh, nil
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid HNSW_REFRESH_INTERVAL %q", value)
+	}
+	return parsed, nil
+}
+
+// filterAttributes maps search filter names to the content attributes stored with
+// each vector
+var filterAttributes = map[string]string{
+	"interviewType": "interviewType",
+	"targetLevel":   "targetLevel",
+	"company":       "targetCompany",
+	"contentType":   "contentType",
+}
+
+// newVectorStore creates the index named by VECTOR_STORE: "hnsw" (the default) for
+// an in-process graph snapshotted to HNSW_SNAPSHOT, "firestore" for Firestore
+// vector search over VECTOR_COLLECTION, or "vertex" for the index deployed to
+// VERTEX_AI_INDEX_ENDPOINT_ID.
+func newVectorStore(ctx context.Context) (vectorstore.Store, error) {
+	switch name := os.Getenv("VECTOR_STORE"); name {
+	case "", "hnsw":
+		var snapshots vectorstore.Snapshots
+		if location := os.Getenv("HNSW_SNAPSHOT"); location != "" {
+			var err error
+			if snapshots, err = vectorstore.OpenSnapshots(ctx, location); err != nil {
+				return nil, err
+			}
+		}
+		refresh, err := refreshInterval()
+		if err != nil {
+			return nil, err
+		}
+		return vectorstore.NewHNSWStore(ctx, queryEmbedder.Dimensions(), vectorstore.DefaultHNSWParams, snapshots, refresh)
+
+	case "firestore":
+		collection := os.Getenv("VECTOR_COLLECTION")
+		if collection == "" {
+			collection = "content_vectors"
+		}
+		return vectorstore.NewFirestoreStore(firestoreClient, collection), nil
+
+	case "vertex":
+		deployedIndexID := os.Getenv("VERTEX_AI_DEPLOYED_INDEX_ID")
+		if indexEndpointIDEnv == "" || deployedIndexID == "" {
+			return nil, fmt.Errorf("VECTOR_STORE=vertex needs VERTEX_AI_INDEX_ENDPOINT_ID and VERTEX_AI_DEPLOYED_INDEX_ID")
+		}
+		// Public endpoints answer queries only on their own domain
+		service := aiplatformService
+		if domain := os.Getenv("VERTEX_AI_INDEX_ENDPOINT_DOMAIN"); domain != "" {
+			var err error
+			if service, err = aiplatform.NewService(ctx, option.WithEndpoint("https://"+domain+"/")); err != nil {
+				return nil, fmt.Errorf("failed to create index endpoint client: %w", err)
+			}
+		}
+		return vectorstore.NewVertexStore(service, gcpProjectIDEnv, locationEnv, indexEndpointIDEnv, deployedIndexID, os.Getenv("VERTEX_AI_INDEX_ID")), nil
+
+	default:
+		return nil, fmt.Errorf("unknown VECTOR_STORE %q", name)
+	}
+}
+
+// searchFilter turns request filters into vector attribute filters
+func searchFilter(filters map[string]interface{}) vectorstore.Filter {
+	filter := vectorstore.Filter{}
+	for name, attribute := range filterAttributes {
+		if value, ok := filters[name].(string); ok && value != "" {
+			filter[attribute] = value
+		}
+	}
+	return filter
+}
+
+// indexStats counts what a pass over scraped content indexed
+type indexStats struct {
+	Documents int
+	Vectors   int
+	// Skipped counts documents whose vectors cannot be compared with queries, by
+	// the model and size that made them
+	Skipped map[string]int
+}
+
+// add folds another pass's counts into s
+func (s *indexStats) add(other indexStats) {
+	s.Documents += other.Documents
+	s.Vectors += other.Vectors
+	for model, count := range other.Skipped {
+		s.skip(model, count)
+	}
+}
+
+// skip counts documents left out of the vector store
+func (s *indexStats) skip(model string, count int) {
+	if s.Skipped == nil {
+		s.Skipped = make(map[string]int)
+	}
+	s.Skipped[model] += count
+}
+
+// skippedCount is the number of documents whose vectors were left out
+func (s *indexStats) skippedCount() int {
+	total := 0
+	for _, count := range s.Skipped {
+		total += count
+	}
+	return total
+}
+
+// foreignVectors names the model and size of a document's vectors when they were
+// made by another model than queryEmbedder, or are of another size, and so cannot
+// be compared with queries. It returns "" for vectors that can.
+func foreignVectors(content *models.ScrapedContent) string {
+	if content.Embeddings == nil || len(content.Embeddings.Vectors) == 0 {
+		return ""
+	}
+	model := content.Embeddings.Model
+	dimensions := len(content.Embeddings.Vectors[0])
+	if (model == "" || model == queryEmbedder.Model()) && dimensions == queryEmbedder.Dimensions() {
+		return ""
+	}
+	if model == "" {
+		model = "unnamed model"
+	}
+	return fmt.Sprintf("%s (%d dimensions)", model, dimensions)
+}
+
+// contentItems lists the vectors of a scraped document under the keys the scraper
+// gave them. Vectors from another model, or of another size, cannot be compared
+// with queries and are left out; foreignVectors reports them.
+func contentItems(id string, content *models.ScrapedContent) []vectorstore.Item {
+	if content.Embeddings == nil || len(content.Embeddings.Vectors) == 0 || foreignVectors(content) != "" {
+		return nil
+	}
+
+	attributes := contentAttributes(content)
+	var items []vectorstore.Item
+	for key, value := range content.EmbeddingMetadata {
+		idx, ok := vectorIndex(value)
+		if !ok || idx >= len(content.Embeddings.Vectors) {
+			continue
+		}
+		vector := content.Embeddings.Vectors[idx]
+		if len(vector) != queryEmbedder.Dimensions() {
+			continue
+		}
+		items = append(items, vectorstore.Item{
+			ID:         vectorstore.ItemID(id, key),
+			DocumentID: id,
+			Key:        key,
+			Vector:     vector,
+			Attributes: attributes,
+		})
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].ID < items[j].ID })
+	return items
+}
+
+// contentAttributes returns the metadata searches filter content on
+func contentAttributes(content *models.ScrapedContent) map[string]string {
+	return map[string]string{
+		"interviewType": content.InterviewType,
+		"targetLevel":   content.TargetLevel,
+		"targetCompany": content.TargetCompany,
+		"contentType":   content.ContentType,
+	}
+}
+
+// indexDocuments adds the given scraped documents to the vector store and the
+// lexical index. Documents embedded by another model are an error, as searches
+// would never find them by meaning.
+func indexDocuments(ctx context.Context, ids []string) (indexStats, error) {
+	refs := make([]*firestore.DocumentRef, len(ids))
+	for i, id := range ids {
+		refs[i] = firestoreClient.Collection("scraped_content").Doc(id)
+	}
+	docs, err := firestoreClient.GetAll(ctx, refs)
+	if err != nil {
+		return indexStats{}, fmt.Errorf("failed to read documents: %w", err)
+	}
+	stats, err := indexSnapshots(ctx, docs, true, lexicalIndex)
+	if err == nil && stats.skippedCount() > 0 {
+		err = fmt.Errorf("%d documents have vectors that cannot be searched: %v", stats.skippedCount(), stats.Skipped)
+	}
+	return stats, err
+}
+
+// indexAllContent adds every scraped document to the lexical index into, and to the
+// vector store when withVectors is set. Without vectors only the text fields are
+// read.
+func indexAllContent(ctx context.Context, withVectors bool, into *lexical.Index) (indexStats, error) {
+	query := firestoreClient.Collection("scraped_content").Query
+	if !withVectors {
+		query = query.Select(lexicalFields...)
+	}
+	iter := query.Documents(ctx)
+	defer iter.Stop()
+
+	var stats indexStats
+	var batch []*firestore.DocumentSnapshot
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return stats, fmt.Errorf("iterator error: %w", err)
+		}
+
+		batch = append(batch, doc)
+		if len(batch) == indexBatchSize {
+			batchStats, err := indexSnapshots(ctx, batch, withVectors, into)
+			stats.add(batchStats)
+			if err != nil {
+				return stats, err
+			}
+			batch = nil
+		}
+	}
+
+	batchStats, err := indexSnapshots(ctx, batch, withVectors, into)
+	stats.add(batchStats)
+	return stats, err
+}
+
+// indexSnapshots adds the documents to the lexical index into and, when
+// withVectors is set, upserts their vectors
+func indexSnapshots(ctx context.Context, docs []*firestore.DocumentSnapshot, withVectors bool, into *lexical.Index) (indexStats, error) {
+	var items []vectorstore.Item
+	var stats indexStats
+	for _, doc := range docs {
+		if !doc.Exists() {
+			continue
+		}
+		var content models.ScrapedContent
+		if err := doc.DataTo(&content); err != nil {
+			log.Printf("Failed to parse document %s: %v", doc.Ref.ID, err)
+			continue
+		}
+		into.Add(contentDocument(doc.Ref.ID, &content))
+		if withVectors {
+			if model := foreignVectors(&content); model != "" {
+				stats.skip(model, 1)
+			}
+			items = append(items, contentItems(doc.Ref.ID, &content)...)
+		}
+		stats.Documents++
+	}
+	if len(items) > 0 {
+		if err := vectorStore.Upsert(ctx, items); err != nil {
+			return indexStats{}, err
+		}
+	}
+	stats.Vectors = len(items)
+	return stats, nil
+}
+
+// saveVectorStore shares upserts with other instances when the store is held in
+// memory
+func saveVectorStore(ctx context.Context) error {
+	if persister, ok := vectorStore.(vectorstore.Persister); ok {
+		return persister.Save(ctx)
+	}
+	return nil
+}
+
+// warmIndexes builds the lexical index from Firestore at cold start. An empty
+// in-process vector index is built in the same pass and saved, so the first
+// instance after a deploy can search before anyone calls /reindex.
+func warmIndexes(ctx context.Context) {
+	store, ok := vectorStore.(*vectorstore.HNSWStore)
+	withVectors := ok && store.Len() == 0
+
+	start := time.Now()
+	// Read before the build, so documents changed during it are picked up by a refresh
+	version, err := readContentVersion(ctx)
+	if err != nil {
+		log.Printf("VectorSearch: %v", err)
+	}
+	stats, err := indexAllContent(ctx, withVectors, lexicalIndex)
+	lexicalRefresh.Lock()
+	lexicalRefresh.checkedAt, lexicalRefresh.version = start, version
+	lexicalRefresh.Unlock()
+	if err != nil {
+		log.Printf("VectorSearch: Failed to build indexes after %d documents: %v", stats.Documents, err)
+		return
+	}
+	if withVectors {
+		if err := saveVectorStore(ctx); err != nil {
+			log.Printf("VectorSearch: %v", err)
+		}
+		log.Printf("VectorSearch: Built vector index of %d vectors", store.Len())
+		logSkippedVectors(stats)
+	}
+	log.Printf("VectorSearch: Indexed %d documents in %v", stats.Documents, time.Since(start))
+}
+
+// readContentVersion counts scraped_content and reads the newest updatedAt of each
+// type. Counting costs a fraction of a read per document and each newest document
+// one read.
+func readContentVersion(ctx context.Context) (contentVersion, error) {
+	collection := firestoreClient.Collection("scraped_content")
+	var version contentVersion
+
+	result, err := collection.NewAggregationQuery().WithCount("documents").Get(ctx)
+	if err != nil {
+		return version, fmt.Errorf("failed to count scraped documents: %w", err)
+	}
+	count, ok := result["documents"].(*firestorepb.Value)
+	if !ok {
+		return version, fmt.Errorf("failed to count scraped documents: no count in the result")
+	}
+	version.documents = count.GetIntegerValue()
+
+	for i, lowest := range updatedAtTypes {
+		docs, err := collection.Where("updatedAt", ">=", lowest).OrderBy("updatedAt", firestore.Desc).
+			Select("updatedAt").Limit(1).Documents(ctx).GetAll()
+		if err != nil {
+			return version, fmt.Errorf("failed to read the latest scraped document update: %w", err)
+		}
+		if len(docs) > 0 {
+			version.updated[i] = fmt.Sprintf("%s at %v", docs[0].Ref.ID, docs[0].Data()["updatedAt"])
+		}
+	}
+	return version, nil
+}
+
+// refreshLexicalIndex rebuilds the lexical index when the version of
+// scraped_content differs from the one it was built from, checking at most every
+// HNSW_REFRESH_INTERVAL. The text is only read again when documents were added,
+// removed or updated. A search that finds a check under way does not wait for it,
+// and a failed check keeps the current index.
+func refreshLexicalIndex(ctx context.Context) {
+	if lexicalRefresh.every <= 0 || !lexicalRefresh.TryLock() {
+		return
+	}
+	defer lexicalRefresh.Unlock()
+	if time.Since(lexicalRefresh.checkedAt) < lexicalRefresh.every {
+		return
+	}
+	lexicalRefresh.checkedAt = time.Now()
+
+	version, err := readContentVersion(ctx)
+	if err != nil {
+		log.Printf("VectorSearch: %v", err)
+		return
+	}
+	if version == lexicalRefresh.version {
+		return
+	}
+
+	start := time.Now()
+	rebuilt := lexical.NewIndex(lexical.DefaultParams)
+	stats, err := indexAllContent(ctx, false, rebuilt)
+	if err != nil {
+		log.Printf("VectorSearch: Failed to rebuild the lexical index after %d documents: %v", stats.Documents, err)
+		return
+	}
+	lexicalIndex.Replace(rebuilt)
+	lexicalRefresh.version = version
+	log.Printf("VectorSearch: Rebuilt the lexical index of %d documents in %v", stats.Documents, time.Since(start))
+}
+
+// logSkippedVectors reports documents left out of the vector store, which searches
+// can only find by keyword
+func logSkippedVectors(stats indexStats) {
+	for model, count := range stats.Skipped {
+		log.Printf("VectorSearch: Skipped %d documents embedded with %s; queries use %s (%d dimensions), so re-embed them",
+			count, model, queryEmbedder.Model(), queryEmbedder.Dimensions())
+	}
+}
+
+// handleReindex re-adds every scraped document to the vector store and the
+// lexical index. Only admins may call it.
+func handleReindex(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		httputils.ErrorJSON(w, "Only POST method is allowed for /reindex", http.StatusMethodNotAllowed)
+		return
+	}
+
+	authedUser, err := auth.VerifyToken(r, firebaseAppSingleton)
+	if err != nil {
+		log.Printf("Authentication failed: %v", err)
+		httputils.ErrorJSON(w, "Authentication required", http.StatusUnauthorized)
+		return
+	}
+	if isAdmin, _ := authedUser.Claims[adminClaimEnv].(bool); !isAdmin {
+		log.Printf("User %s denied reindex: missing %q claim", authedUser.UID, adminClaimEnv)
+		httputils.ErrorJSON(w, "Admin access required", http.StatusForbidden)
+		return
+	}
+
+	start := time.Now()
+	stats, err := indexAllContent(r.Context(), true, lexicalIndex)
+	if err == nil {
+		err = saveVectorStore(r.Context())
+	}
+	if err != nil {
+		log.Printf("Reindex failed after %d documents: %v", stats.Documents, err)
+		httputils.ErrorJSON(w, "Reindex failed", http.StatusInternalServerError)
+		return
+	}
+
+	// Documents the store could not take would only be found by keyword, so the
+	// reindex is reported as failed until they are re-embedded
+	if skipped := stats.skippedCount(); skipped > 0 {
+		logSkippedVectors(stats)
+		log.Printf("User %s reindexed %d documents into %s, but %d could not be vector indexed", authedUser.UID, stats.Documents, vectorStore.Name(), skipped)
+		httputils.RespondJSON(w, map[string]interface{}{
+			"success": false,
+			"error":   fmt.Sprintf("%d documents were embedded by another model than %s", skipped, queryEmbedder.Model()),
+			"indexed": stats.Documents,
+			"vectors": stats.Vectors,
+			"skipped": stats.Skipped,
+			"store":   vectorStore.Name(),
+		}, http.StatusConflict)
+		return
+	}
+
+	log.Printf("User %s reindexed %d documents into %s in %v", authedUser.UID, stats.Documents, vectorStore.Name(), time.Since(start))
+	httputils.RespondJSON(w, map[string]interface{}{
+		"success": true,
+		"indexed": stats.Documents,
+		"vectors": stats.Vectors,
+		"store":   vectorStore.Name(),
+	}, http.StatusOK)
+}
@@ -0,0 +1,81 @@
+// Package embeddings turns search queries and documents into vectors. Queries must
+// be embedded by the model that embedded the indexed documents, or their vectors
+// cannot be compared.
+package embeddings
+
+import (
+	"context"
+	"fmt"
+	"math"
+)
+
+// DefaultModel is the Vertex AI model the RAG pipeline embeds documents with
+// (rag/models.DefaultEmbeddingModel)
+const DefaultModel = "text-embedding-004"
+
+// DefaultDimensions is the output size of DefaultModel
+const DefaultDimensions = 768
+
+// Task tells the model what the text is for. Retrieval models embed queries and
+// the documents they should find differently.
+type Task string
+
+const (
+	TaskQuery    Task = "RETRIEVAL_QUERY"    // a search query
+	TaskDocument Task = "RETRIEVAL_DOCUMENT" // content to be found
+)
+
+// Embedder embeds texts into vectors of a fixed size
+type Embedder interface {
+	// Embed returns one vector per text, in order
+	Embed(ctx context.Context, texts []string, task Task) ([][]float64, error)
+	// Model names the embedding model, as stored with document vectors
+	Model() string
+	Dimensions() int
+}
+
+// EmbedOne embeds a single text
+func EmbedOne(ctx context.Context, embedder Embedder, text string, task Task) ([]float64, error) {
+	vectors, err := embedder.Embed(ctx, []string{text}, task)
+	if err != nil {
+		return nil, err
+	}
+	if len(vectors) != 1 {
+		return nil, fmt.Errorf("embedder returned %d vectors for 1 text", len(vectors))
+	}
+	return vectors[0], nil
+}
+
+// Normalize scales v to unit length in place, leaving a zero vector as it is
+func Normalize(v []float64) []float64 {
+	var sum float64
+	for _, x := range v {
+		sum += x * x
+	}
+	if sum == 0 {
+		return v
+	}
+	norm := math.Sqrt(sum)
+	for i := range v {
+		v[i] /= norm
+	}
+	return v
+}
+
+// Cosine returns the cosine similarity of two vectors, or 0 when their sizes
+// differ or either is zero
+func Cosine(a, b []float64) float64 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += a[i] * b[i]
+		normA += a[i] * a[i]
+		normB += b[i] * b[i]
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
@@ -0,0 +1,78 @@
+package embeddings
+
+import (
+	"context"
+	"hash/fnv"
+	"strings"
+	"unicode"
+)
+
+// LocalModel is the model name local embeddings are stored under
+const LocalModel = "local-hash"
+
+// bigramWeight is how much a pair of adjacent words counts against a single word
+const bigramWeight = 0.5
+
+// LocalEmbedder embeds texts by hashing their words and word pairs into a fixed
+// number of dimensions. It needs no network and always gives the same vector for
+// the same text, so ranking can be tested offline; texts sharing words score as
+// similar, but it knows nothing of meaning.
+type LocalEmbedder struct {
+	dimensions int
+}
+
+// NewLocalEmbedder creates a hashing embedder with the given output size
+func NewLocalEmbedder(dimensions int) *LocalEmbedder {
+	if dimensions <= 0 {
+		dimensions = DefaultDimensions
+	}
+	return &LocalEmbedder{dimensions: dimensions}
+}
+
+// Model returns LocalModel
+func (e *LocalEmbedder) Model() string { return LocalModel }
+
+// Dimensions returns the output size
+func (e *LocalEmbedder) Dimensions() int { return e.dimensions }
+
+// Embed hashes each text. Queries and documents are embedded alike.
+func (e *LocalEmbedder) Embed(_ context.Context, texts []string, _ Task) ([][]float64, error) {
+	vectors := make([][]float64, len(texts))
+	for i, text := range texts {
+		vectors[i] = e.embed(text)
+	}
+	return vectors, nil
+}
+
+func (e *LocalEmbedder) embed(text string) []float64 {
+	vector := make([]float64, e.dimensions)
+	words := Tokenize(text)
+	for i, word := range words {
+		e.add(vector, word, 1)
+		if i > 0 {
+			e.add(vector, words[i-1]+" "+word, bigramWeight)
+		}
+	}
+	return Normalize(vector)
+}
+
+// add hashes feature to a dimension and a sign, so unrelated features tend to
+// cancel rather than pile up
+func (e *LocalEmbedder) add(vector []float64, feature string, weight float64) {
+	h := fnv.New64a()
+	h.Write([]byte(feature))
+	sum := h.Sum64()
+
+	index := int(sum % uint64(e.dimensions))
+	if sum>>63 == 1 {
+		weight = -weight
+	}
+	vector[index] += weight
+}
+
+// Tokenize lowercases text and splits it into runs of letters and digits
+func Tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
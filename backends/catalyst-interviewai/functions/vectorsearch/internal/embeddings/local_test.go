@@ -0,0 +1,91 @@
+package embeddings
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestLocalEmbedderDeterministic(t *testing.T) {
+	tests := []struct {
+		name       string
+		dimensions int
+		text       string
+	}{
+		{name: "words", dimensions: 64, text: "Design a rate limiter"},
+		{name: "punctuation and case", dimensions: 64, text: "LRU cache, STAR method!"},
+		{name: "non-ASCII", dimensions: 64, text: "Entwurf eines Caches für Größen"},
+		{name: "default size", dimensions: 0, text: "system design interview"},
+	}
+
+	ctx := context.Background()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first, err := EmbedOne(ctx, NewLocalEmbedder(tt.dimensions), tt.text, TaskQuery)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// A new embedder, and documents rather than queries, give the same vector
+			second, err := EmbedOne(ctx, NewLocalEmbedder(tt.dimensions), tt.text, TaskDocument)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(first, second) {
+				t.Errorf("embeddings of %q differ between calls", tt.text)
+			}
+
+			wantDimensions := tt.dimensions
+			if wantDimensions == 0 {
+				wantDimensions = DefaultDimensions
+			}
+			if len(first) != wantDimensions {
+				t.Errorf("got %d dimensions, want %d", len(first), wantDimensions)
+			}
+			var norm float64
+			for _, x := range first {
+				norm += x * x
+			}
+			if math.Abs(norm-1) > 1e-9 {
+				t.Errorf("got squared norm %v, want 1", norm)
+			}
+		})
+	}
+}
+
+func TestLocalEmbedderSimilarity(t *testing.T) {
+	tests := []struct {
+		name          string
+		query         string
+		closer, other string
+	}{
+		{name: "shared words", query: "rate limiter design", closer: "designing a rate limiter", other: "behavioural questions about conflict"},
+		{name: "case and punctuation ignored", query: "LRU cache", closer: "lru-cache eviction", other: "graph traversal"},
+		{name: "word order", query: "binary search tree", closer: "binary search tree insertion", other: "tree search binary"},
+	}
+
+	ctx := context.Background()
+	embedder := NewLocalEmbedder(256)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vectors, err := embedder.Embed(ctx, []string{tt.query, tt.closer, tt.other}, TaskDocument)
+			if err != nil {
+				t.Fatal(err)
+			}
+			closer, other := Cosine(vectors[0], vectors[1]), Cosine(vectors[0], vectors[2])
+			if closer <= other {
+				t.Errorf("similarity to %q is %v, not above %v for %q", tt.closer, closer, other, tt.other)
+			}
+		})
+	}
+}
+
+func TestLocalEmbedderEmpty(t *testing.T) {
+	vector, err := EmbedOne(context.Background(), NewLocalEmbedder(8), " ,.! ", TaskQuery)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(vector, make([]float64, 8)) {
+		t.Errorf("got %v for text without words, want a zero vector", vector)
+	}
+}
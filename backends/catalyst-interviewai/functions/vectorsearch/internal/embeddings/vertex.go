@@ -0,0 +1,115 @@
+package embeddings
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/api/aiplatform/v1"
+)
+
+// vertexBatchSize is the most texts sent in one prediction request
+const vertexBatchSize = 100
+
+// VertexEmbedder embeds texts with a Vertex AI publisher model such as
+// text-embedding-004
+type VertexEmbedder struct {
+	service    *aiplatform.Service
+	endpoint   string
+	model      string
+	dimensions int
+}
+
+// NewVertexEmbedder creates an embedder for the publisher model in the project's
+// location. Zero dimensions keeps the model's default output size.
+func NewVertexEmbedder(service *aiplatform.Service, projectID, location, model string, dimensions int) *VertexEmbedder {
+	if model == "" {
+		model = DefaultModel
+	}
+	if dimensions <= 0 {
+		dimensions = DefaultDimensions
+	}
+	return &VertexEmbedder{
+		service:    service,
+		endpoint:   fmt.Sprintf("projects/%s/locations/%s/publishers/google/models/%s", projectID, location, model),
+		model:      model,
+		dimensions: dimensions,
+	}
+}
+
+// Model returns the publisher model name
+func (e *VertexEmbedder) Model() string { return e.model }
+
+// Dimensions returns the size of the vectors requested from the model
+func (e *VertexEmbedder) Dimensions() int { return e.dimensions }
+
+// Embed calls the model's predict endpoint, batching long lists of texts
+func (e *VertexEmbedder) Embed(ctx context.Context, texts []string, task Task) ([][]float64, error) {
+	vectors := make([][]float64, 0, len(texts))
+	for start := 0; start < len(texts); start += vertexBatchSize {
+		end := start + vertexBatchSize
+		if end > len(texts) {
+			end = len(texts)
+		}
+
+		batch, err := e.predict(ctx, texts[start:end], task)
+		if err != nil {
+			return nil, err
+		}
+		vectors = append(vectors, batch...)
+	}
+	return vectors, nil
+}
+
+func (e *VertexEmbedder) predict(ctx context.Context, texts []string, task Task) ([][]float64, error) {
+	instances := make([]interface{}, len(texts))
+	for i, text := range texts {
+		instances[i] = map[string]interface{}{
+			"content":   text,
+			"task_type": string(task),
+		}
+	}
+
+	resp, err := e.service.Projects.Locations.Publishers.Models.Predict(e.endpoint, &aiplatform.GoogleCloudAiplatformV1PredictRequest{
+		Instances: instances,
+		Parameters: map[string]interface{}{
+			"autoTruncate":         true,
+			"outputDimensionality": e.dimensions,
+		},
+	}).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed with %s: %w", e.model, err)
+	}
+	if len(resp.Predictions) != len(texts) {
+		return nil, fmt.Errorf("%s returned %d embeddings for %d texts", e.model, len(resp.Predictions), len(texts))
+	}
+
+	vectors := make([][]float64, len(resp.Predictions))
+	for i, prediction := range resp.Predictions {
+		vector, err := predictionValues(prediction)
+		if err != nil {
+			return nil, fmt.Errorf("invalid embedding %d from %s: %w", i, e.model, err)
+		}
+		vectors[i] = vector
+	}
+	return vectors, nil
+}
+
+// predictionValues reads {"embeddings": {"values": [...]}} from a prediction
+func predictionValues(prediction interface{}) ([]float64, error) {
+	fields, _ := prediction.(map[string]interface{})
+	embedding, _ := fields["embeddings"].(map[string]interface{})
+	values, ok := embedding["values"].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("no embeddings.values in prediction")
+	}
+
+	vector := make([]float64, len(values))
+	for i, value := range values {
+		number, ok := value.(float64)
+		if !ok {
+			return nil, fmt.Errorf("value %d is not a number", i)
+		}
+		vector[i] = number
+	}
+	return vector, nil
+}
@@ -0,0 +1,126 @@
+// Package fusion merges the rankings of several retrievers, such as vector and
+// keyword search, into one.
+package fusion
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Method is how rankings are combined
+type Method string
+
+const (
+	// RRF is reciprocal rank fusion: each ranking adds weight/(k+rank). It ignores
+	// the scores themselves, so retrievers with different scales mix safely.
+	RRF Method = "rrf"
+	// Weighted adds each retriever's score, scaled to 0..1 across its candidates,
+	// times its weight
+	Weighted Method = "weighted"
+)
+
+// DefaultRRFK is the rank offset from the original RRF paper. Larger values flatten
+// the difference between the top ranks.
+const DefaultRRFK = 60
+
+// Options tunes the fusion
+type Options struct {
+	Method        Method
+	VectorWeight  float64
+	LexicalWeight float64
+	RRFK          float64
+}
+
+// DefaultOptions weighs both retrievers equally with RRF
+var DefaultOptions = Options{Method: RRF, VectorWeight: 1, LexicalWeight: 1, RRFK: DefaultRRFK}
+
+// Validate checks the options can rank anything
+func (o Options) Validate() error {
+	switch o.Method {
+	case RRF, Weighted:
+	default:
+		return fmt.Errorf("method must be %q or %q", RRF, Weighted)
+	}
+	if o.VectorWeight < 0 || o.LexicalWeight < 0 {
+		return fmt.Errorf("weights cannot be negative")
+	}
+	if o.VectorWeight == 0 && o.LexicalWeight == 0 {
+		return fmt.Errorf("at least one weight must be positive")
+	}
+	if o.Method == RRF && o.RRFK <= 0 {
+		return fmt.Errorf("rrfK must be positive")
+	}
+	return nil
+}
+
+// Candidate is an item one retriever returned, in rank order
+type Candidate struct {
+	ID    string
+	Score float64
+}
+
+// Component is how one retriever ranked an item
+type Component struct {
+	Score float64 // the retriever's own score
+	Rank  int     // 1-based
+}
+
+// Result is an item with its fused score and the rankings it came from. A nil
+// component means that retriever did not return the item.
+type Result struct {
+	ID      string
+	Score   float64
+	Vector  *Component
+	Lexical *Component
+}
+
+// Fuse combines the vector and lexical rankings, highest fused score first
+func Fuse(vector, lexical []Candidate, opts Options) []Result {
+	byID := make(map[string]*Result)
+	var order []string
+	add := func(candidates []Candidate, weight float64, set func(*Result, *Component)) {
+		low, high := scoreRange(candidates)
+		for i, c := range candidates {
+			result := byID[c.ID]
+			if result == nil {
+				result = &Result{ID: c.ID}
+				byID[c.ID] = result
+				order = append(order, c.ID)
+			}
+			set(result, &Component{Score: c.Score, Rank: i + 1})
+
+			switch opts.Method {
+			case Weighted:
+				scaled := 1.0
+				if high > low {
+					scaled = (c.Score - low) / (high - low)
+				}
+				result.Score += weight * scaled
+			default:
+				result.Score += weight / (opts.RRFK + float64(i+1))
+			}
+		}
+	}
+	add(vector, opts.VectorWeight, func(r *Result, c *Component) { r.Vector = c })
+	add(lexical, opts.LexicalWeight, func(r *Result, c *Component) { r.Lexical = c })
+
+	results := make([]Result, len(order))
+	for i, id := range order {
+		results[i] = *byID[id]
+	}
+	// Stable, so ties keep vector results ahead of lexical-only ones
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Score > results[j].Score })
+	return results
+}
+
+func scoreRange(candidates []Candidate) (low, high float64) {
+	for i, c := range candidates {
+		if i == 0 || c.Score < low {
+			low = c.Score
+		}
+		if i == 0 || c.Score > high {
+			high = c.Score
+		}
+	}
+	return low, high
+}
@@ -0,0 +1,167 @@
+package fusion
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestFuse(t *testing.T) {
+	vector := []Candidate{{ID: "a", Score: 0.9}, {ID: "b", Score: 0.8}, {ID: "c", Score: 0.5}}
+	lexical := []Candidate{{ID: "c", Score: 12}, {ID: "d", Score: 6}, {ID: "a", Score: 3}}
+
+	tests := []struct {
+		name       string
+		vector     []Candidate
+		lexical    []Candidate
+		opts       Options
+		wantOrder  []string
+		wantScores map[string]float64
+	}{
+		{
+			name:      "rrf",
+			vector:    vector,
+			lexical:   lexical,
+			opts:      DefaultOptions,
+			wantOrder: []string{"a", "c", "b", "d"},
+			wantScores: map[string]float64{
+				"a": 1.0/61 + 1.0/63,
+				"b": 1.0 / 62,
+				"c": 1.0/63 + 1.0/61,
+				"d": 1.0 / 62,
+			},
+		},
+		{
+			name:      "rrf lexical weighted up",
+			vector:    vector,
+			lexical:   lexical,
+			opts:      Options{Method: RRF, VectorWeight: 1, LexicalWeight: 3, RRFK: 60},
+			wantOrder: []string{"c", "a", "d", "b"},
+			wantScores: map[string]float64{
+				"c": 1.0/63 + 3.0/61,
+				"d": 3.0 / 62,
+			},
+		},
+		{
+			name:      "rrf small k",
+			vector:    vector,
+			lexical:   nil,
+			opts:      Options{Method: RRF, VectorWeight: 1, LexicalWeight: 1, RRFK: 1},
+			wantOrder: []string{"a", "b", "c"},
+			wantScores: map[string]float64{
+				"a": 1.0 / 2,
+				"b": 1.0 / 3,
+				"c": 1.0 / 4,
+			},
+		},
+		{
+			name:      "weighted",
+			vector:    vector,
+			lexical:   lexical,
+			opts:      Options{Method: Weighted, VectorWeight: 1, LexicalWeight: 1},
+			wantOrder: []string{"a", "c", "b", "d"},
+			wantScores: map[string]float64{
+				"a": 1 + 0,
+				"b": 0.75,
+				"c": 0 + 1,
+				"d": 1.0 / 3,
+			},
+		},
+		{
+			name:      "weighted vector off",
+			vector:    vector,
+			lexical:   lexical,
+			opts:      Options{Method: Weighted, VectorWeight: 0, LexicalWeight: 1},
+			wantOrder: []string{"c", "d", "a", "b"},
+			wantScores: map[string]float64{
+				"a": 0,
+				"b": 0,
+			},
+		},
+		{
+			name:       "weighted equal scores",
+			vector:     []Candidate{{ID: "a", Score: 0.5}, {ID: "b", Score: 0.5}},
+			opts:       Options{Method: Weighted, VectorWeight: 2, LexicalWeight: 1},
+			wantOrder:  []string{"a", "b"},
+			wantScores: map[string]float64{"a": 2, "b": 2},
+		},
+		{
+			name:      "ties keep vector results first",
+			vector:    []Candidate{{ID: "v", Score: 0.7}},
+			lexical:   []Candidate{{ID: "l", Score: 4}},
+			opts:      DefaultOptions,
+			wantOrder: []string{"v", "l"},
+		},
+		{name: "nothing found", opts: DefaultOptions},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := Fuse(tt.vector, tt.lexical, tt.opts)
+			var order []string
+			for _, result := range results {
+				order = append(order, result.ID)
+				if want, ok := tt.wantScores[result.ID]; ok && math.Abs(result.Score-want) > 1e-12 {
+					t.Errorf("%s scored %v, want %v", result.ID, result.Score, want)
+				}
+			}
+			if !reflect.DeepEqual(order, tt.wantOrder) {
+				t.Errorf("got order %v, want %v", order, tt.wantOrder)
+			}
+		})
+	}
+}
+
+func TestFuseComponents(t *testing.T) {
+	results := Fuse(
+		[]Candidate{{ID: "a", Score: 0.9}, {ID: "b", Score: 0.8}},
+		[]Candidate{{ID: "b", Score: 7}},
+		DefaultOptions,
+	)
+
+	tests := []struct {
+		id          string
+		wantVector  *Component
+		wantLexical *Component
+	}{
+		{id: "a", wantVector: &Component{Score: 0.9, Rank: 1}},
+		{id: "b", wantVector: &Component{Score: 0.8, Rank: 2}, wantLexical: &Component{Score: 7, Rank: 1}},
+	}
+	for _, tt := range tests {
+		var found *Result
+		for i := range results {
+			if results[i].ID == tt.id {
+				found = &results[i]
+			}
+		}
+		if found == nil {
+			t.Fatalf("%s missing from %v", tt.id, results)
+		}
+		if !reflect.DeepEqual(found.Vector, tt.wantVector) || !reflect.DeepEqual(found.Lexical, tt.wantLexical) {
+			t.Errorf("%s has components %+v and %+v, want %+v and %+v", tt.id, found.Vector, found.Lexical, tt.wantVector, tt.wantLexical)
+		}
+	}
+}
+
+func TestOptionsValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    Options
+		wantErr bool
+	}{
+		{name: "defaults", opts: DefaultOptions},
+		{name: "weighted ignores k", opts: Options{Method: Weighted, VectorWeight: 1}},
+		{name: "unknown method", opts: Options{Method: "max", VectorWeight: 1, RRFK: 60}, wantErr: true},
+		{name: "negative weight", opts: Options{Method: RRF, VectorWeight: -1, LexicalWeight: 1, RRFK: 60}, wantErr: true},
+		{name: "both weights off", opts: Options{Method: RRF, RRFK: 60}, wantErr: true},
+		{name: "rrf without k", opts: Options{Method: RRF, VectorWeight: 1}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.opts.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,300 @@
+package health
+
+// Composite health, liveness and readiness reporting for GCF handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Status is the health of a single dependency or of the whole function
+type Status string
+
+const (
+	// StatusHealthy means every probed dependency responded in time
+	StatusHealthy Status = "healthy"
+	// StatusDegraded means a non-critical dependency is failing or a dependency is slow
+	StatusDegraded Status = "degraded"
+	// StatusUnhealthy means a critical dependency is failing
+	StatusUnhealthy Status = "unhealthy"
+)
+
+// Mode selects which dependencies a request probes
+type Mode string
+
+const (
+	// ModeLiveness reports whether the process can serve requests at all. It probes nothing.
+	ModeLiveness Mode = "liveness"
+	// ModeReadiness probes only the dependencies the function cannot serve without
+	ModeReadiness Mode = "readiness"
+	// ModeFull probes every dependency, including optional ones such as analytics
+	ModeFull Mode = "full"
+)
+
+// DefaultTimeout bounds a single probe when its check does not set one
+const DefaultTimeout = 3 * time.Second
+
+// ProbeFunc checks one dependency. It should be cheap and must honour ctx.
+type ProbeFunc func(ctx context.Context) error
+
+// DegradedError is returned by a probe whose dependency answers but reports itself impaired
+type DegradedError struct {
+	Reason string
+}
+
+func (e *DegradedError) Error() string { return e.Reason }
+
+// Degraded returns an error that marks a dependency degraded rather than unhealthy
+func Degraded(format string, args ...interface{}) error {
+	return &DegradedError{Reason: fmt.Sprintf(format, args...)}
+}
+
+// Check describes a dependency to probe
+type Check struct {
+	Name string
+	// Critical dependencies make the function unhealthy when they fail; others only degrade it
+	Critical bool
+	Timeout  time.Duration
+	// SlowAfter marks a dependency degraded when it answers but takes longer than this
+	SlowAfter time.Duration
+	Probe     ProbeFunc
+}
+
+// Result is the outcome of probing one dependency
+type Result struct {
+	Name      string `json:"name"`
+	Status    Status `json:"status"`
+	Critical  bool   `json:"critical"`
+	LatencyMs int64  `json:"latencyMs"`
+	Error     string `json:"error,omitempty"`
+}
+
+// Report is the composite health of a function
+type Report struct {
+	Service       string    `json:"service"`
+	Status        Status    `json:"status"`
+	Mode          Mode      `json:"mode"`
+	Timestamp     time.Time `json:"timestamp"`
+	UptimeSeconds int64     `json:"uptimeSeconds"`
+	Checks        []Result  `json:"checks"`
+}
+
+// HTTPStatus is 503 for an unhealthy report and 200 otherwise, so uptime checks
+// alert only when a critical dependency is down
+func (r *Report) HTTPStatus() int {
+	if r.Status == StatusUnhealthy {
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusOK
+}
+
+// Checker probes a function's dependencies
+type Checker struct {
+	service   string
+	checks    []Check
+	startedAt time.Time
+	// cacheTTL spares dependencies from probe storms when several monitors poll at once
+	cacheTTL time.Duration
+
+	mu     sync.Mutex
+	cached map[Mode]*Report
+}
+
+// NewChecker creates a checker for service. Reports are cached for cacheTTL; zero disables caching.
+func NewChecker(service string, cacheTTL time.Duration, checks ...Check) *Checker {
+	return &Checker{
+		service:   service,
+		checks:    checks,
+		startedAt: time.Now(),
+		cacheTTL:  cacheTTL,
+		cached:    make(map[Mode]*Report),
+	}
+}
+
+// Add registers another dependency
+func (c *Checker) Add(check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checks = append(c.checks, check)
+	c.cached = make(map[Mode]*Report)
+}
+
+// Run probes the dependencies selected by mode concurrently, each under its own timeout
+func (c *Checker) Run(ctx context.Context, mode Mode) *Report {
+	now := time.Now()
+
+	c.mu.Lock()
+	if cached, ok := c.cached[mode]; ok && now.Sub(cached.Timestamp) < c.cacheTTL {
+		c.mu.Unlock()
+		return cached
+	}
+	var checks []Check
+	if mode != ModeLiveness {
+		for _, check := range c.checks {
+			if mode == ModeFull || check.Critical {
+				checks = append(checks, check)
+			}
+		}
+	}
+	c.mu.Unlock()
+
+	results := make([]Result, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			results[i] = runCheck(ctx, check)
+		}(i, check)
+	}
+	wg.Wait()
+
+	report := &Report{
+		Service:       c.service,
+		Status:        overallStatus(results),
+		Mode:          mode,
+		Timestamp:     now.UTC(),
+		UptimeSeconds: int64(now.Sub(c.startedAt).Seconds()),
+		Checks:        results,
+	}
+
+	c.mu.Lock()
+	c.cached[mode] = report
+	c.mu.Unlock()
+	return report
+}
+
+// ServeHTTP answers with the report for the mode the request asks for
+func (c *Checker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	report := c.Run(r.Context(), ModeForRequest(r))
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(report.HTTPStatus())
+	json.NewEncoder(w).Encode(report)
+}
+
+// ModeForRequest reads the mode from the probe query parameter ("live" or "ready"),
+// falling back to the path: .../health/live and .../health/ready (or livez/readyz).
+// Anything else gets the full report.
+func ModeForRequest(r *http.Request) Mode {
+	switch r.URL.Query().Get("probe") {
+	case "live", "liveness":
+		return ModeLiveness
+	case "ready", "readiness":
+		return ModeReadiness
+	case "full":
+		return ModeFull
+	}
+
+	path := strings.TrimSuffix(r.URL.Path, "/")
+	switch {
+	case strings.HasSuffix(path, "/live"), strings.HasSuffix(path, "/livez"):
+		return ModeLiveness
+	case strings.HasSuffix(path, "/ready"), strings.HasSuffix(path, "/readyz"):
+		return ModeReadiness
+	}
+	return ModeFull
+}
+
+// IsHealthPath reports whether a request to a path-routed function is a health probe
+func IsHealthPath(path string) bool {
+	path = strings.TrimSuffix(path, "/")
+	for _, suffix := range []string{"/health", "/health/live", "/health/ready", "/livez", "/readyz"} {
+		if strings.HasSuffix(path, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+func runCheck(ctx context.Context, check Check) Result {
+	timeout := check.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	result := Result{Name: check.Name, Critical: check.Critical, Status: StatusHealthy}
+	start := time.Now()
+	err := probe(ctx, check.Probe)
+	latency := time.Since(start)
+	result.LatencyMs = latency.Milliseconds()
+
+	var degraded *DegradedError
+	switch {
+	case errors.As(err, &degraded):
+		result.Status = StatusDegraded
+		result.Error = err.Error()
+	case err != nil:
+		result.Status = StatusUnhealthy
+		result.Error = err.Error()
+	case check.SlowAfter > 0 && latency > check.SlowAfter:
+		result.Status = StatusDegraded
+		result.Error = fmt.Sprintf("responded in %s, above %s", latency.Round(time.Millisecond), check.SlowAfter)
+	}
+	return result
+}
+
+// probe runs fn but returns as soon as ctx expires, even if fn ignores ctx
+func probe(ctx context.Context, fn ProbeFunc) error {
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("probe panicked: %v", r)
+			}
+		}()
+		done <- fn(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("timed out: %w", ctx.Err())
+	}
+}
+
+// overallStatus is unhealthy when a critical dependency fails, degraded when any
+// other dependency fails or is slow, and healthy otherwise
+func overallStatus(results []Result) Status {
+	status := StatusHealthy
+	for _, result := range results {
+		switch {
+		case result.Status == StatusUnhealthy && result.Critical:
+			return StatusUnhealthy
+		case result.Status != StatusHealthy:
+			status = StatusDegraded
+		}
+	}
+	return status
+}
+
+// HTTPProbe checks that url answers a GET without a server error
+func HTTPProbe(client *http.Client, url string) ProbeFunc {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode >= http.StatusInternalServerError {
+			return fmt.Errorf("%s returned %d", url, resp.StatusCode)
+		}
+		return nil
+	}
+}
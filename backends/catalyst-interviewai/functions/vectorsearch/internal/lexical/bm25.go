@@ -0,0 +1,207 @@
+// Package lexical ranks documents by the query terms they contain, with an
+// in-memory inverted index scored by BM25. It finds exact terms such as "LRU" or
+// "STAR" that embeddings blur together.
+package lexical
+
+import (
+	"math"
+	"sort"
+	"sync"
+
+	"interviewai.wkv.local/vectorsearch/internal/embeddings"
+)
+
+// Params tunes BM25
+type Params struct {
+	K1 float64 // how quickly repeated terms stop adding to the score
+	B  float64 // how much longer documents are penalised, from 0 to 1
+}
+
+// DefaultParams are the usual BM25 settings
+var DefaultParams = Params{K1: 1.2, B: 0.75}
+
+// Field is text to index, weighted by how much a term in it counts: a term in a
+// field of weight 2 counts as two occurrences
+type Field struct {
+	Text   string
+	Weight float64
+}
+
+// Document is what the index stores for one scraped document
+type Document struct {
+	ID         string
+	Fields     []Field
+	Attributes map[string]string // metadata searches can filter on
+}
+
+// Hit is a document that contains query terms
+type Hit struct {
+	ID      string
+	Score   float64
+	Matched []string // the query terms found, in query order
+}
+
+type entry struct {
+	length     float64
+	terms      map[string]float64
+	attributes map[string]string
+}
+
+// Index is a BM25 inverted index, safe for concurrent use
+type Index struct {
+	mu          sync.RWMutex
+	params      Params
+	docs        map[string]*entry
+	postings    map[string]map[string]float64 // term -> document ID -> weighted frequency
+	totalLength float64
+}
+
+// NewIndex creates an empty index
+func NewIndex(params Params) *Index {
+	return &Index{
+		params:   params,
+		docs:     make(map[string]*entry),
+		postings: make(map[string]map[string]float64),
+	}
+}
+
+// Len returns the number of indexed documents
+func (x *Index) Len() int {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	return len(x.docs)
+}
+
+// Add indexes documents, replacing any with the same ID
+func (x *Index) Add(docs ...Document) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	for _, doc := range docs {
+		x.remove(doc.ID)
+
+		e := &entry{terms: make(map[string]float64), attributes: doc.Attributes}
+		for _, field := range doc.Fields {
+			for _, term := range embeddings.Tokenize(field.Text) {
+				e.terms[term] += field.Weight
+				e.length += field.Weight
+			}
+		}
+		if len(e.terms) == 0 {
+			continue
+		}
+
+		x.docs[doc.ID] = e
+		x.totalLength += e.length
+		for term, frequency := range e.terms {
+			if x.postings[term] == nil {
+				x.postings[term] = make(map[string]float64)
+			}
+			x.postings[term][doc.ID] = frequency
+		}
+	}
+}
+
+// Replace swaps in the contents of other, which must not be used afterwards. An
+// index rebuilt off to the side replaces this one without holding up searches.
+func (x *Index) Replace(other *Index) {
+	other.mu.RLock()
+	docs, postings, totalLength := other.docs, other.postings, other.totalLength
+	other.mu.RUnlock()
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.docs, x.postings, x.totalLength = docs, postings, totalLength
+}
+
+// Remove drops a document from the index
+func (x *Index) Remove(id string) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.remove(id)
+}
+
+func (x *Index) remove(id string) {
+	e, ok := x.docs[id]
+	if !ok {
+		return
+	}
+	for term := range e.terms {
+		delete(x.postings[term], id)
+		if len(x.postings[term]) == 0 {
+			delete(x.postings, term)
+		}
+	}
+	x.totalLength -= e.length
+	delete(x.docs, id)
+}
+
+// Search returns up to k documents by BM25 score, highest first. accept, when not
+// nil, decides from a document's attributes whether it may be returned.
+func (x *Index) Search(query string, k int, accept func(attributes map[string]string) bool) []Hit {
+	terms := Terms(query)
+	if len(terms) == 0 || k <= 0 {
+		return nil
+	}
+
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	if len(x.docs) == 0 {
+		return nil
+	}
+
+	n := float64(len(x.docs))
+	avgLength := x.totalLength / n
+	hits := make(map[string]*Hit)
+	for _, term := range terms {
+		posting := x.postings[term]
+		if len(posting) == 0 {
+			continue
+		}
+		df := float64(len(posting))
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+
+		for id, frequency := range posting {
+			e := x.docs[id]
+			if accept != nil && !accept(e.attributes) {
+				continue
+			}
+			norm := x.params.K1 * (1 - x.params.B + x.params.B*e.length/avgLength)
+			hit := hits[id]
+			if hit == nil {
+				hit = &Hit{ID: id}
+				hits[id] = hit
+			}
+			hit.Score += idf * frequency * (x.params.K1 + 1) / (frequency + norm)
+			hit.Matched = append(hit.Matched, term)
+		}
+	}
+
+	ranked := make([]Hit, 0, len(hits))
+	for _, hit := range hits {
+		ranked = append(ranked, *hit)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].Score != ranked[j].Score {
+			return ranked[i].Score > ranked[j].Score
+		}
+		return ranked[i].ID < ranked[j].ID
+	})
+	if len(ranked) > k {
+		ranked = ranked[:k]
+	}
+	return ranked
+}
+
+// Terms tokenizes a query as the index does, keeping the first occurrence of each
+// term
+func Terms(query string) []string {
+	seen := make(map[string]bool)
+	var terms []string
+	for _, term := range embeddings.Tokenize(query) {
+		if !seen[term] {
+			seen[term] = true
+			terms = append(terms, term)
+		}
+	}
+	return terms
+}
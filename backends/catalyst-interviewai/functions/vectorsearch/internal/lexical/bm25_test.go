@@ -0,0 +1,184 @@
+package lexical
+
+import (
+	"reflect"
+	"testing"
+)
+
+func testIndex() *Index {
+	x := NewIndex(DefaultParams)
+	x.Add(
+		Document{
+			ID:         "lru",
+			Fields:     []Field{{Text: "Implement an LRU cache", Weight: 2}, {Text: "Use a hash map and a linked list", Weight: 1}},
+			Attributes: map[string]string{"interviewType": "coding"},
+		},
+		Document{
+			ID:         "limiter",
+			Fields:     []Field{{Text: "Design a rate limiter", Weight: 2}, {Text: "Token buckets in a distributed cache", Weight: 1}},
+			Attributes: map[string]string{"interviewType": "system design"},
+		},
+		Document{
+			ID:         "star",
+			Fields:     []Field{{Text: "Answering with the STAR method", Weight: 2}, {Text: "Situation, task, action, result", Weight: 1}},
+			Attributes: map[string]string{"interviewType": "behavioral"},
+		},
+		Document{
+			ID:     "empty",
+			Fields: []Field{{Text: " -- ", Weight: 2}},
+		},
+	)
+	return x
+}
+
+func TestIndexSearch(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		k       int
+		accept  func(map[string]string) bool
+		want    []string
+		matched map[string][]string
+	}{
+		{
+			name:    "single term",
+			query:   "STAR",
+			k:       10,
+			want:    []string{"star"},
+			matched: map[string][]string{"star": {"star"}},
+		},
+		{
+			name:  "title weight ranks first",
+			query: "cache",
+			k:     10,
+			want:  []string{"lru", "limiter"},
+		},
+		{
+			name:    "terms matched in query order",
+			query:   "linked LRU hash, LRU",
+			k:       10,
+			want:    []string{"lru"},
+			matched: map[string][]string{"lru": {"linked", "lru", "hash"}},
+		},
+		{
+			name:  "k limits hits",
+			query: "cache",
+			k:     1,
+			want:  []string{"lru"},
+		},
+		{
+			name:   "filter",
+			query:  "cache",
+			k:      10,
+			accept: func(attributes map[string]string) bool { return attributes["interviewType"] == "system design" },
+			want:   []string{"limiter"},
+		},
+		{name: "no matching terms", query: "graph traversal", k: 10},
+		{name: "no terms", query: "?!", k: 10},
+		{name: "zero k", query: "cache", k: 0},
+	}
+
+	x := testIndex()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hits := x.Search(tt.query, tt.k, tt.accept)
+			var got []string
+			for _, hit := range hits {
+				got = append(got, hit.ID)
+				if hit.Score <= 0 {
+					t.Errorf("hit %s has score %v, want positive", hit.ID, hit.Score)
+				}
+				if want, ok := tt.matched[hit.ID]; ok && !reflect.DeepEqual(hit.Matched, want) {
+					t.Errorf("hit %s matched %v, want %v", hit.ID, hit.Matched, want)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Search(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIndexUpdates(t *testing.T) {
+	tests := []struct {
+		name    string
+		update  func(x *Index)
+		query   string
+		want    []string
+		wantLen int
+	}{
+		{
+			name:    "add replaces a document",
+			update:  func(x *Index) { x.Add(Document{ID: "lru", Fields: []Field{{Text: "Graph traversal", Weight: 1}}}) },
+			query:   "LRU graph",
+			want:    []string{"lru"},
+			wantLen: 3,
+		},
+		{
+			name:    "replaced terms are gone",
+			update:  func(x *Index) { x.Add(Document{ID: "lru", Fields: []Field{{Text: "Graph traversal", Weight: 1}}}) },
+			query:   "linked",
+			wantLen: 3,
+		},
+		{
+			name:    "remove",
+			update:  func(x *Index) { x.Remove("limiter") },
+			query:   "cache",
+			want:    []string{"lru"},
+			wantLen: 2,
+		},
+		{
+			name:    "remove unknown",
+			update:  func(x *Index) { x.Remove("missing") },
+			query:   "STAR",
+			want:    []string{"star"},
+			wantLen: 3,
+		},
+		{
+			name: "replace with another index",
+			update: func(x *Index) {
+				rebuilt := NewIndex(DefaultParams)
+				rebuilt.Add(Document{ID: "graphs", Fields: []Field{{Text: "Graph traversal", Weight: 1}}})
+				x.Replace(rebuilt)
+			},
+			query:   "graph cache",
+			want:    []string{"graphs"},
+			wantLen: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			x := testIndex()
+			tt.update(x)
+			var got []string
+			for _, hit := range x.Search(tt.query, 10, nil) {
+				got = append(got, hit.ID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Search(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+			if x.Len() != tt.wantLen {
+				t.Errorf("Len() = %d, want %d", x.Len(), tt.wantLen)
+			}
+		})
+	}
+}
+
+func TestTerms(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{query: "LRU cache, lru CACHE", want: []string{"lru", "cache"}},
+		{query: "Größe und Übung", want: []string{"größe", "und", "übung"}},
+		{query: "top-k O(n)", want: []string{"top", "k", "o", "n"}},
+		{query: "...", want: nil},
+	}
+
+	for _, tt := range tests {
+		if got := Terms(tt.query); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Terms(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+// Package passages splits long content into the chunks the scraper embedded, and
+// finds and highlights query terms in them. Offsets count characters (Unicode code
+// points), not bytes.
+package passages
+
+import (
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// ChunkWords is the size of the windows contentscraper embeds as chunk_N
+// (processors.EmbeddingService.chunkText)
+const ChunkWords = 500
+
+// Chunk is a window of whole words
+type Chunk struct {
+	Index int
+	Start int // offset of the first character in the full text
+	End   int // offset just past the last character
+	Text  string
+}
+
+// Span is a range of characters
+type Span struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// Split cuts text into windows of the given number of words, as the scraper does,
+// keeping each window's offsets in text
+func Split(text string, words int) []Chunk {
+	type word struct{ start, end, byteStart, byteEnd int }
+	var all []word
+	runes := 0
+	inWord := false
+	for i, r := range text {
+		if unicode.IsSpace(r) {
+			if inWord {
+				all[len(all)-1].end = runes
+				all[len(all)-1].byteEnd = i
+				inWord = false
+			}
+		} else if !inWord {
+			all = append(all, word{start: runes, byteStart: i})
+			inWord = true
+		}
+		runes++
+	}
+	if inWord {
+		all[len(all)-1].end = runes
+		all[len(all)-1].byteEnd = len(text)
+	}
+
+	var chunks []Chunk
+	for i := 0; i < len(all); i += words {
+		last := min(i+words, len(all)) - 1
+		chunks = append(chunks, Chunk{
+			Index: len(chunks),
+			Start: all[i].start,
+			End:   all[last].end,
+			Text:  text[all[i].byteStart:all[last].byteEnd],
+		})
+	}
+	return chunks
+}
+
+// Find returns where the terms occur in text as whole words, ignoring case, and
+// which of the terms were found, in the order given
+func Find(text string, terms []string) ([]Span, []string) {
+	wanted := make(map[string]bool, len(terms))
+	for _, term := range terms {
+		wanted[term] = true
+	}
+
+	var spans []Span
+	found := make(map[string]bool)
+	var token strings.Builder
+	start, offset := 0, 0
+	flush := func() {
+		if token.Len() > 0 && wanted[token.String()] {
+			spans = append(spans, Span{Start: start, End: offset})
+			found[token.String()] = true
+		}
+		token.Reset()
+	}
+	for _, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if token.Len() == 0 {
+				start = offset
+			}
+			token.WriteRune(unicode.ToLower(r))
+		} else {
+			flush()
+		}
+		offset++
+	}
+	flush()
+
+	var matched []string
+	for _, term := range terms {
+		if found[term] {
+			matched = append(matched, term)
+		}
+	}
+	return spans, matched
+}
+
+// snippetSlack is how far a snippet's edges may move to land between words
+const snippetSlack = 20
+
+// Snippet cuts about length characters of text around the densest cluster of
+// spans, and returns the spans inside it relative to the snippet
+func Snippet(text string, spans []Span, length int) (string, []Span) {
+	runes := []rune(text)
+	if len(runes) <= length {
+		return text, spans
+	}
+
+	// Start a little before the span that begins the most matches within length
+	start, best := 0, 0
+	for i, span := range spans {
+		count := 0
+		for _, other := range spans[i:] {
+			if other.End-span.Start > length {
+				break
+			}
+			count++
+		}
+		if count > best {
+			best, start = count, max(0, span.Start-length/5)
+		}
+	}
+	end := min(len(runes), start+length)
+	start = max(0, end-length)
+
+	// Land between words where one is close by
+	if start > 0 {
+		for i := start; i < min(start+snippetSlack, end); i++ {
+			if unicode.IsSpace(runes[i]) {
+				start = i + 1
+				break
+			}
+		}
+	}
+	if end < len(runes) {
+		for i := end - 1; i > max(end-snippetSlack, start); i-- {
+			if unicode.IsSpace(runes[i]) {
+				end = i
+				break
+			}
+		}
+	}
+
+	var inside []Span
+	for _, span := range spans {
+		if span.Start >= start && span.End <= end {
+			inside = append(inside, Span{Start: span.Start - start, End: span.End - start})
+		}
+	}
+	return string(runes[start:end]), inside
+}
+
+var isoDuration = regexp.MustCompile(`^PT(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?$`)
+
+// ParseISODuration reads the ISO 8601 durations YouTube reports, such as PT1H2M3S
+func ParseISODuration(value string) (time.Duration, bool) {
+	parts := isoDuration.FindStringSubmatch(value)
+	if parts == nil || value == "PT" {
+		return 0, false
+	}
+	var total time.Duration
+	for i, unit := range []time.Duration{time.Hour, time.Minute, time.Second} {
+		if parts[i+1] != "" {
+			n, _ := strconv.Atoi(parts[i+1])
+			total += time.Duration(n) * unit
+		}
+	}
+	return total, true
+}
+
+// Timestamp estimates the second of a video at which a transcript offset is
+// spoken, assuming an even pace. Scraped transcripts carry no cue times.
+func Timestamp(duration time.Duration, offset, length int) int {
+	if length <= 0 || offset <= 0 {
+		return 0
+	}
+	return int(duration.Seconds() * float64(offset) / float64(length))
+}
+
+// TimestampLink returns a YouTube URL that starts playing at the given second
+func TimestampLink(rawURL string, seconds int) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	query := u.Query()
+	query.Set("t", strconv.Itoa(seconds)+"s")
+	u.RawQuery = query.Encode()
+	return u.String()
+}
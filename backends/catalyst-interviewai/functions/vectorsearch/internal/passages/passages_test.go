@@ -0,0 +1,226 @@
+package passages
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// runeSlice returns the characters of text from start to end
+func runeSlice(text string, start, end int) string {
+	return string([]rune(text)[start:end])
+}
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		words int
+		want  []string
+	}{
+		{name: "ascii", text: "one two three four five", words: 2, want: []string{"one two", "three four", "five"}},
+		{name: "non-ASCII words", text: "Größe über naïve café déjà", words: 2, want: []string{"Größe über", "naïve café", "déjà"}},
+		{name: "CJK and emoji", text: "面接 の 準備 🚀 完了", words: 3, want: []string{"面接 の 準備", "🚀 完了"}},
+		{name: "surrounding and repeated space", text: "  Größe\t\tüber\n\nnaïve  ", words: 2, want: []string{"Größe\t\tüber", "naïve"}},
+		{name: "one window", text: "déjà vu", words: 500, want: []string{"déjà vu"}},
+		{name: "no words", text: " \n\t ", words: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunks := Split(tt.text, tt.words)
+			var got []string
+			for i, chunk := range chunks {
+				got = append(got, chunk.Text)
+				if chunk.Index != i {
+					t.Errorf("chunk %d has index %d", i, chunk.Index)
+				}
+				// Offsets count characters, so they must cut the same text
+				if sliced := runeSlice(tt.text, chunk.Start, chunk.End); sliced != chunk.Text {
+					t.Errorf("chunk %d offsets %d-%d cut %q, want %q", i, chunk.Start, chunk.End, sliced, chunk.Text)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Split() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFind(t *testing.T) {
+	tests := []struct {
+		name        string
+		text        string
+		terms       []string
+		wantSpans   []Span
+		wantMatched []string
+	}{
+		{
+			name:        "ascii",
+			text:        "Use an LRU cache; the cache evicts",
+			terms:       []string{"cache", "lru"},
+			wantSpans:   []Span{{7, 10}, {11, 16}, {22, 27}},
+			wantMatched: []string{"cache", "lru"},
+		},
+		{
+			name:        "offsets after multi-byte characters",
+			text:        "Die Größe des Caches ändert sich",
+			terms:       []string{"größe", "caches", "ändert"},
+			wantSpans:   []Span{{4, 9}, {14, 20}, {21, 27}},
+			wantMatched: []string{"größe", "caches", "ändert"},
+		},
+		{
+			name:        "emoji counts as one character",
+			text:        "🚀 ship it 🚀 ship",
+			terms:       []string{"ship"},
+			wantSpans:   []Span{{2, 6}, {12, 16}},
+			wantMatched: []string{"ship"},
+		},
+		{
+			name:        "whole words only",
+			text:        "caches and cached",
+			terms:       []string{"cache"},
+			wantMatched: nil,
+		},
+		{
+			name:        "matched keeps term order and drops missing terms",
+			text:        "STAR method",
+			terms:       []string{"method", "result", "star"},
+			wantSpans:   []Span{{0, 4}, {5, 11}},
+			wantMatched: []string{"method", "star"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spans, matched := Find(tt.text, tt.terms)
+			if !reflect.DeepEqual(spans, tt.wantSpans) {
+				t.Errorf("spans = %v, want %v", spans, tt.wantSpans)
+			}
+			if !reflect.DeepEqual(matched, tt.wantMatched) {
+				t.Errorf("matched = %v, want %v", matched, tt.wantMatched)
+			}
+			for _, span := range spans {
+				word := strings.ToLower(runeSlice(tt.text, span.Start, span.End))
+				if !slices.Contains(tt.terms, word) {
+					t.Errorf("span %v cuts %q, not a term", span, word)
+				}
+			}
+		})
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	filler := strings.Repeat("über naïve café ", 20)
+	tests := []struct {
+		name      string
+		text      string
+		terms     []string
+		length    int
+		wantWords []string // the terms the snippet's spans must cut
+	}{
+		{
+			name:      "short text is kept whole",
+			text:      "Größe des Caches",
+			terms:     []string{"caches"},
+			length:    100,
+			wantWords: []string{"Caches"},
+		},
+		{
+			name:      "cut around the match after non-ASCII text",
+			text:      filler + "the Größe of the LRU cache " + filler,
+			terms:     []string{"größe", "lru", "cache"},
+			length:    60,
+			wantWords: []string{"Größe", "LRU", "cache"},
+		},
+		{
+			name:      "densest cluster wins",
+			text:      "cache " + filler + "LRU cache eviction LRU " + filler,
+			terms:     []string{"lru", "cache"},
+			length:    50,
+			wantWords: []string{"LRU", "cache", "LRU"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spans, _ := Find(tt.text, tt.terms)
+			snippet, inside := Snippet(tt.text, spans, tt.length)
+			if n := len([]rune(snippet)); n > tt.length {
+				t.Errorf("snippet has %d characters, want at most %d", n, tt.length)
+			}
+			if !strings.Contains(tt.text, snippet) {
+				t.Errorf("snippet %q is not a cut of the text", snippet)
+			}
+
+			var words []string
+			for _, span := range inside {
+				words = append(words, runeSlice(snippet, span.Start, span.End))
+			}
+			if !reflect.DeepEqual(words, tt.wantWords) {
+				t.Errorf("snippet %q spans cut %q, want %q", snippet, words, tt.wantWords)
+			}
+		})
+	}
+}
+
+func TestParseISODuration(t *testing.T) {
+	tests := []struct {
+		value  string
+		want   time.Duration
+		wantOK bool
+	}{
+		{value: "PT1H2M3S", want: time.Hour + 2*time.Minute + 3*time.Second, wantOK: true},
+		{value: "PT15M", want: 15 * time.Minute, wantOK: true},
+		{value: "PT45S", want: 45 * time.Second, wantOK: true},
+		{value: "PT"},
+		{value: "P1D"},
+		{value: "12:30"},
+	}
+
+	for _, tt := range tests {
+		got, ok := ParseISODuration(tt.value)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("ParseISODuration(%q) = %v, %v, want %v, %v", tt.value, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestTimestamp(t *testing.T) {
+	tests := []struct {
+		name           string
+		duration       time.Duration
+		offset, length int
+		want           int
+	}{
+		{name: "start", duration: 10 * time.Minute, offset: 0, length: 1000, want: 0},
+		{name: "halfway", duration: 10 * time.Minute, offset: 500, length: 1000, want: 300},
+		{name: "no text", duration: 10 * time.Minute, offset: 5, length: 0, want: 0},
+	}
+
+	for _, tt := range tests {
+		if got := Timestamp(tt.duration, tt.offset, tt.length); got != tt.want {
+			t.Errorf("%s: Timestamp() = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTimestampLink(t *testing.T) {
+	tests := []struct {
+		url     string
+		seconds int
+		want    string
+	}{
+		{url: "https://www.youtube.com/watch?v=abc", seconds: 90, want: "https://www.youtube.com/watch?t=90s&v=abc"},
+		{url: "https://youtu.be/abc?t=5s", seconds: 12, want: "https://youtu.be/abc?t=12s"},
+		{url: "://bad", seconds: 1, want: "://bad"},
+	}
+
+	for _, tt := range tests {
+		if got := TimestampLink(tt.url, tt.seconds); got != tt.want {
+			t.Errorf("TimestampLink(%q, %d) = %q, want %q", tt.url, tt.seconds, got, tt.want)
+		}
+	}
+}
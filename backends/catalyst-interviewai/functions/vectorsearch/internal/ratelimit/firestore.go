@@ -0,0 +1,105 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// FirestoreStore keeps counters in a Firestore collection so limits hold across
+// function instances. Configure a TTL policy on expiresAt to have Firestore delete
+// old daily counters.
+type FirestoreStore struct {
+	client     *firestore.Client
+	collection string
+}
+
+type bucketDoc struct {
+	Tokens     float64   `firestore:"tokens"`
+	LastRefill time.Time `firestore:"lastRefill"`
+}
+
+type dailyDoc struct {
+	Count     int       `firestore:"count"`
+	ExpiresAt time.Time `firestore:"expiresAt"`
+}
+
+// NewFirestoreStore creates a store backed by the given collection
+func NewFirestoreStore(client *firestore.Client, collection string) *FirestoreStore {
+	if collection == "" {
+		collection = "rateLimits"
+	}
+	return &FirestoreStore{client: client, collection: collection}
+}
+
+// TakeToken refills the bucket for key and takes one token if available
+func (s *FirestoreStore) TakeToken(ctx context.Context, key string, capacity int, refillPerSecond float64, now time.Time) (float64, time.Duration, bool, error) {
+	docRef := s.client.Collection(s.collection).Doc(key)
+
+	var (
+		remaining  float64
+		retryAfter time.Duration
+		allowed    bool
+	)
+	err := s.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		state := bucketDoc{Tokens: float64(capacity), LastRefill: now}
+
+		doc, err := tx.Get(docRef)
+		if err != nil && status.Code(err) != codes.NotFound {
+			return err
+		}
+		if err == nil {
+			if err := doc.DataTo(&state); err != nil {
+				return err
+			}
+		}
+
+		remaining, retryAfter, allowed = takeToken(state.Tokens, state.LastRefill, capacity, refillPerSecond, now)
+		return tx.Set(docRef, bucketDoc{Tokens: remaining, LastRefill: now})
+	})
+	if err != nil {
+		return 0, 0, false, fmt.Errorf("failed to update rate limit bucket: %w", err)
+	}
+
+	return remaining, retryAfter, allowed, nil
+}
+
+// IncrementDaily counts one use of key unless quota has been reached
+func (s *FirestoreStore) IncrementDaily(ctx context.Context, key string, quota int, expiresAt time.Time) (int, bool, error) {
+	docRef := s.client.Collection(s.collection).Doc(key)
+
+	var (
+		count   int
+		allowed bool
+	)
+	err := s.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		state := dailyDoc{ExpiresAt: expiresAt}
+
+		doc, err := tx.Get(docRef)
+		if err != nil && status.Code(err) != codes.NotFound {
+			return err
+		}
+		if err == nil {
+			if err := doc.DataTo(&state); err != nil {
+				return err
+			}
+		}
+
+		if state.Count >= quota {
+			count, allowed = state.Count, false
+			return nil
+		}
+		state.Count++
+		count, allowed = state.Count, true
+		return tx.Set(docRef, state)
+	})
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to update daily quota: %w", err)
+	}
+
+	return count, allowed, nil
+}
@@ -0,0 +1,215 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestLimiterAllow(t *testing.T) {
+	type call struct {
+		after         time.Duration // how long after the previous call
+		user          string        // "u1" when empty
+		byok          bool
+		wantAllowed   bool
+		wantReason    string
+		wantRemaining int
+		wantLimit     int
+	}
+	// MemoryStore drops daily counters by the wall clock, so the calls happen today
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	start := today.Add(12 * time.Hour)
+
+	tests := []struct {
+		name  string
+		limit Limit
+		start time.Time // start when zero
+		calls []call
+	}{
+		{
+			name:  "burst then rate limited",
+			limit: Limit{Burst: 2, RefillPerMinute: 6},
+			calls: []call{
+				{wantAllowed: true, wantRemaining: 1, wantLimit: 2},
+				{wantAllowed: true, wantRemaining: 0, wantLimit: 2},
+				{wantReason: "rate_limited", wantRemaining: 0, wantLimit: 2},
+			},
+		},
+		{
+			name:  "refills over time",
+			limit: Limit{Burst: 1, RefillPerMinute: 6},
+			calls: []call{
+				{wantAllowed: true, wantRemaining: 0, wantLimit: 1},
+				{after: 5 * time.Second, wantReason: "rate_limited", wantLimit: 1},
+				{after: 5 * time.Second, wantAllowed: true, wantRemaining: 0, wantLimit: 1},
+			},
+		},
+		{
+			name:  "refill stops at the burst",
+			limit: Limit{Burst: 2, RefillPerMinute: 60},
+			calls: []call{
+				{wantAllowed: true, wantRemaining: 1, wantLimit: 2},
+				{after: time.Hour, wantAllowed: true, wantRemaining: 1, wantLimit: 2},
+			},
+		},
+		{
+			name:  "users have their own buckets",
+			limit: Limit{Burst: 1, RefillPerMinute: 1},
+			calls: []call{
+				{user: "u1", wantAllowed: true, wantLimit: 1},
+				{user: "u2", wantAllowed: true, wantLimit: 1},
+				{user: "u1", wantReason: "rate_limited", wantLimit: 1},
+			},
+		},
+		{
+			name:  "daily quota",
+			limit: Limit{Burst: 10, RefillPerMinute: 60, DailyQuota: 2, DailyQuotaBYOK: 3},
+			calls: []call{
+				{wantAllowed: true, wantRemaining: 1, wantLimit: 2},
+				{wantAllowed: true, wantRemaining: 0, wantLimit: 2},
+				{wantReason: "daily_quota_exceeded", wantRemaining: 0, wantLimit: 2},
+			},
+		},
+		{
+			name:  "own key raises the quota",
+			limit: Limit{Burst: 10, RefillPerMinute: 60, DailyQuota: 1, DailyQuotaBYOK: 3},
+			calls: []call{
+				{byok: true, wantAllowed: true, wantRemaining: 2, wantLimit: 3},
+				{byok: true, wantAllowed: true, wantRemaining: 1, wantLimit: 3},
+				{wantReason: "daily_quota_exceeded", wantLimit: 1},
+			},
+		},
+		{
+			name:  "quota resets the next day",
+			limit: Limit{Burst: 10, RefillPerMinute: 60, DailyQuota: 1},
+			start: today.Add(24*time.Hour - time.Minute),
+			calls: []call{
+				{wantAllowed: true, wantRemaining: 0, wantLimit: 1},
+				{wantReason: "daily_quota_exceeded", wantLimit: 1},
+				{after: 2 * time.Minute, wantAllowed: true, wantRemaining: 0, wantLimit: 1},
+			},
+		},
+		{
+			name:  "denied by the bucket does not count towards the quota",
+			limit: Limit{Burst: 1, RefillPerMinute: 60, DailyQuota: 2},
+			calls: []call{
+				{wantAllowed: true, wantRemaining: 0, wantLimit: 1},
+				{wantReason: "rate_limited", wantLimit: 1},
+				{after: time.Second, wantAllowed: true, wantRemaining: 0, wantLimit: 1},
+				{after: time.Second, wantReason: "daily_quota_exceeded", wantLimit: 2},
+			},
+		},
+	}
+
+	ctx := context.Background()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter := NewLimiter(NewMemoryStore(), map[Class]Limit{ClassSearch: tt.limit})
+			now := tt.start
+			if now.IsZero() {
+				now = start
+			}
+			limiter.now = func() time.Time { return now }
+
+			for i, c := range tt.calls {
+				now = now.Add(c.after)
+				user := c.user
+				if user == "" {
+					user = "u1"
+				}
+				decision, err := limiter.Allow(ctx, user, ClassSearch, c.byok)
+				if err != nil {
+					t.Fatalf("call %d: %v", i, err)
+				}
+				if decision.Allowed != c.wantAllowed || decision.Reason != c.wantReason {
+					t.Errorf("call %d: allowed %v with reason %q, want %v with %q", i, decision.Allowed, decision.Reason, c.wantAllowed, c.wantReason)
+				}
+				if decision.Remaining != c.wantRemaining || decision.Limit != c.wantLimit {
+					t.Errorf("call %d: remaining %d of %d, want %d of %d", i, decision.Remaining, decision.Limit, c.wantRemaining, c.wantLimit)
+				}
+				if !decision.Allowed && decision.RetryAfter <= 0 {
+					t.Errorf("call %d: denied without a retry time", i)
+				}
+			}
+		})
+	}
+}
+
+func TestLimiterUnknownClass(t *testing.T) {
+	limiter := NewLimiter(NewMemoryStore(), nil)
+	if _, err := limiter.Allow(context.Background(), "u1", Class("unknown"), false); err == nil {
+		t.Error("Allow() for an unknown class succeeded")
+	}
+}
+
+func TestMemoryStoreTakeToken(t *testing.T) {
+	now := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		capacity       int
+		refill         float64 // per second
+		takes          int     // tokens taken before the checked call
+		after          time.Duration
+		wantAllowed    bool
+		wantRemaining  float64
+		wantRetryAfter time.Duration
+	}{
+		{name: "full bucket", capacity: 3, refill: 1, wantAllowed: true, wantRemaining: 2},
+		{name: "empty bucket", capacity: 2, refill: 0.5, takes: 2, wantRetryAfter: 2 * time.Second},
+		{name: "half refilled", capacity: 2, refill: 0.5, takes: 2, after: time.Second, wantRemaining: 0.5, wantRetryAfter: time.Second},
+		{name: "refilled", capacity: 2, refill: 0.5, takes: 2, after: 2 * time.Second, wantAllowed: true, wantRemaining: 0},
+		{name: "no refill", capacity: 1, refill: 0, takes: 1, after: time.Hour, wantRetryAfter: 24 * time.Hour},
+	}
+
+	ctx := context.Background()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewMemoryStore()
+			for i := 0; i < tt.takes; i++ {
+				if _, _, allowed, _ := store.TakeToken(ctx, "key", tt.capacity, tt.refill, now); !allowed {
+					t.Fatalf("take %d was denied", i)
+				}
+			}
+			remaining, retryAfter, allowed, err := store.TakeToken(ctx, "key", tt.capacity, tt.refill, now.Add(tt.after))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if allowed != tt.wantAllowed || remaining != tt.wantRemaining || retryAfter != tt.wantRetryAfter {
+				t.Errorf("TakeToken() = %v, %v, %v, want %v, %v, %v", remaining, retryAfter, allowed, tt.wantRemaining, tt.wantRetryAfter, tt.wantAllowed)
+			}
+		})
+	}
+}
+
+func TestDecisionHeaders(t *testing.T) {
+	tests := []struct {
+		name     string
+		decision Decision
+		want     map[string]string
+	}{
+		{
+			name:     "allowed",
+			decision: Decision{Allowed: true, Class: ClassSearch, Limit: 30, Remaining: 29, Reset: 1500 * time.Millisecond, Policy: "30;w=30"},
+			want:     map[string]string{"RateLimit-Limit": "30", "RateLimit-Remaining": "29", "RateLimit-Reset": "2", "RateLimit-Policy": "30;w=30", "Retry-After": ""},
+		},
+		{
+			name:     "denied",
+			decision: Decision{Class: ClassSearch, Reason: "rate_limited", Limit: 30, Reset: 3 * time.Second, RetryAfter: 2100 * time.Millisecond},
+			want:     map[string]string{"RateLimit-Remaining": "0", "RateLimit-Reset": "3", "Retry-After": "3", "RateLimit-Policy": ""},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			tt.decision.WriteHeaders(w)
+			for name, want := range tt.want {
+				if got := w.Header().Get(name); got != want {
+					t.Errorf("%s = %q, want %q", name, got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package rerank
+
+import (
+	"fmt"
+	"math"
+)
+
+// DefaultLambda weighs relevance and variety equally. Relevance is spread over
+// 0..1, so a higher lambda lets near-duplicates of a top result through.
+const DefaultLambda = 0.5
+
+// ValidateLambda checks lambda is between 0 (only variety) and 1 (only relevance)
+func ValidateLambda(lambda float64) error {
+	if lambda < 0 || lambda > 1 || math.IsNaN(lambda) {
+		return fmt.Errorf("lambda must be between 0 and 1")
+	}
+	return nil
+}
+
+// Pick is an item MMR chose
+type Pick struct {
+	Index int     // the item's position in the input
+	Score float64 // its marginal relevance when it was picked
+}
+
+// MMR picks up to k items by maximal marginal relevance. Each pick is the item
+// with the highest lambda*relevance - (1-lambda)*similarity, where similarity is
+// its greatest similarity to an item already picked. Relevance is first scaled to
+// 0..1 across the items so it weighs against similarity whatever its scale.
+// similarity compares two items by position and should return 0..1.
+func MMR(relevance []float64, similarity func(i, j int) float64, lambda float64, k int) []Pick {
+	n := len(relevance)
+	k = min(k, n)
+	if k <= 0 {
+		return nil
+	}
+
+	low, high := relevance[0], relevance[0]
+	for _, score := range relevance {
+		low, high = min(low, score), max(high, score)
+	}
+	scaled := make([]float64, n)
+	for i, score := range relevance {
+		if high > low {
+			scaled[i] = (score - low) / (high - low)
+		} else {
+			scaled[i] = 1
+		}
+	}
+
+	picked := make([]bool, n)
+	closest := make([]float64, n) // greatest similarity to a picked item
+	picks := make([]Pick, 0, k)
+	for len(picks) < k {
+		best, bestScore := -1, math.Inf(-1)
+		for i := range relevance {
+			if picked[i] {
+				continue
+			}
+			score := lambda*scaled[i] - (1-lambda)*closest[i]
+			if score > bestScore {
+				best, bestScore = i, score
+			}
+		}
+
+		picked[best] = true
+		picks = append(picks, Pick{Index: best, Score: bestScore})
+		for i := range relevance {
+			if !picked[i] {
+				closest[i] = max(closest[i], similarity(i, best))
+			}
+		}
+	}
+	return picks
+}
@@ -0,0 +1,156 @@
+package rerank
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"testing"
+)
+
+// similarities builds a symmetric similarity function from the pairs given; other
+// pairs are unrelated
+func similarities(pairs map[[2]int]float64) func(i, j int) float64 {
+	return func(i, j int) float64 {
+		if i == j {
+			return 1
+		}
+		if s, ok := pairs[[2]int{i, j}]; ok {
+			return s
+		}
+		return pairs[[2]int{j, i}]
+	}
+}
+
+func TestMMR(t *testing.T) {
+	// Items 0 and 1 are near-duplicates; 2 is different but a little less relevant
+	duplicates := similarities(map[[2]int]float64{{0, 1}: 0.95, {0, 2}: 0.1, {1, 2}: 0.1})
+
+	tests := []struct {
+		name       string
+		relevance  []float64
+		similarity func(i, j int) float64
+		lambda     float64
+		k          int
+		want       []int
+	}{
+		{
+			name:       "only relevance keeps the order",
+			relevance:  []float64{0.9, 0.85, 0.6},
+			similarity: duplicates,
+			lambda:     1,
+			k:          3,
+			want:       []int{0, 1, 2},
+		},
+		{
+			name:       "default lambda passes over a near-duplicate",
+			relevance:  []float64{0.9, 0.85, 0.6},
+			similarity: duplicates,
+			lambda:     DefaultLambda,
+			k:          3,
+			want:       []int{0, 2, 1},
+		},
+		{
+			name:       "only variety",
+			relevance:  []float64{0.9, 0.85, 0.6},
+			similarity: duplicates,
+			lambda:     0,
+			k:          2,
+			want:       []int{0, 2},
+		},
+		{
+			name:       "relevance scale does not matter",
+			relevance:  []float64{90, 85, 60},
+			similarity: duplicates,
+			lambda:     DefaultLambda,
+			k:          3,
+			want:       []int{0, 2, 1},
+		},
+		{
+			name:       "first pick is the most relevant",
+			relevance:  []float64{0.2, 0.7, 0.4},
+			similarity: similarities(nil),
+			lambda:     DefaultLambda,
+			k:          1,
+			want:       []int{1},
+		},
+		{
+			name:       "equal relevance",
+			relevance:  []float64{0.5, 0.5, 0.5},
+			similarity: similarities(map[[2]int]float64{{0, 1}: 0.9}),
+			lambda:     DefaultLambda,
+			k:          3,
+			want:       []int{0, 2, 1},
+		},
+		{
+			name:       "k past the items",
+			relevance:  []float64{0.3, 0.6},
+			similarity: similarities(nil),
+			lambda:     DefaultLambda,
+			k:          5,
+			want:       []int{1, 0},
+		},
+		{name: "no items", similarity: similarities(nil), lambda: DefaultLambda, k: 3},
+		{name: "zero k", relevance: []float64{0.5}, similarity: similarities(nil), lambda: DefaultLambda, k: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			picks := MMR(tt.relevance, tt.similarity, tt.lambda, tt.k)
+			var got []int
+			for i, pick := range picks {
+				got = append(got, pick.Index)
+				if i > 0 && pick.Score > picks[i-1].Score+1e-12 {
+					t.Errorf("pick %d scored %v, above the earlier %v", i, pick.Score, picks[i-1].Score)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MMR() picked %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMMRScores(t *testing.T) {
+	picks := MMR([]float64{1, 0.5, 0}, similarities(map[[2]int]float64{{0, 1}: 0.4}), 0.5, 3)
+	// Relevance scales to 1, 0.5 and 0; item 1 is then held back by its similarity to 0
+	want := []Pick{{Index: 0, Score: 0.5}, {Index: 1, Score: 0.05}, {Index: 2, Score: 0}}
+	if len(picks) != len(want) {
+		t.Fatalf("got %v, want %v", picks, want)
+	}
+	for i := range want {
+		if picks[i].Index != want[i].Index || math.Abs(picks[i].Score-want[i].Score) > 1e-12 {
+			t.Errorf("pick %d = %+v, want %+v", i, picks[i], want[i])
+		}
+	}
+}
+
+func TestValidateLambda(t *testing.T) {
+	tests := []struct {
+		lambda  float64
+		wantErr bool
+	}{
+		{lambda: 0},
+		{lambda: DefaultLambda},
+		{lambda: 1},
+		{lambda: -0.1, wantErr: true},
+		{lambda: 1.5, wantErr: true},
+		{lambda: math.NaN(), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		if err := ValidateLambda(tt.lambda); (err != nil) != tt.wantErr {
+			t.Errorf("ValidateLambda(%v) = %v, want error %v", tt.lambda, err, tt.wantErr)
+		}
+	}
+}
+
+func TestNoop(t *testing.T) {
+	docs := []Document{{ID: "b", Score: 0.2}, {ID: "a", Score: 0.9}}
+	got, err := Noop{}.Rerank(context.Background(), "query", docs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, docs) {
+		t.Errorf("Rerank() = %v, want %v unchanged", got, docs)
+	}
+}
@@ -0,0 +1,34 @@
+// Package rerank reorders retrieved search results. A Reranker rescores the best
+// candidates with a model that reads the query and each document together, and MMR
+// trades relevance for variety so near-duplicates do not fill the top results.
+package rerank
+
+import "context"
+
+// Document is a search result to rerank
+type Document struct {
+	ID    string
+	Title string
+	Text  string
+	Score float64 // relevance to the query; rerankers replace it with their own
+}
+
+// Reranker scores documents by relevance to a query
+type Reranker interface {
+	// Name identifies the reranker in responses and logs
+	Name() string
+	// Rerank returns the documents best first, with their new scores
+	Rerank(ctx context.Context, query string, docs []Document) ([]Document, error)
+}
+
+// Noop keeps the retrieval order and scores. It is the default when no reranker
+// is configured.
+type Noop struct{}
+
+// Name returns "none"
+func (Noop) Name() string { return "none" }
+
+// Rerank returns docs unchanged
+func (Noop) Rerank(_ context.Context, _ string, docs []Document) ([]Document, error) {
+	return docs, nil
+}
@@ -0,0 +1,89 @@
+package rerank
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/api/discoveryengine/v1"
+)
+
+// DefaultModel is the Vertex AI ranking model used unless another is named
+const DefaultModel = "semantic-ranker-512@latest"
+
+// vertexMaxRecords is the most records one ranking request may carry
+const vertexMaxRecords = 200
+
+// VertexReranker scores documents with the Vertex AI ranking API. Its semantic
+// ranker models are cross-encoders: they read the query and a document together,
+// which judges relevance better than comparing separately made embeddings. Scores
+// run from 0 to 1.
+type VertexReranker struct {
+	service *discoveryengine.Service
+	config  string
+	model   string
+}
+
+// NewVertexReranker ranks with the project's default ranking config
+func NewVertexReranker(service *discoveryengine.Service, projectID, model string) *VertexReranker {
+	if model == "" {
+		model = DefaultModel
+	}
+	return &VertexReranker{
+		service: service,
+		config:  fmt.Sprintf("projects/%s/locations/global/rankingConfigs/default_ranking_config", projectID),
+		model:   model,
+	}
+}
+
+// Name returns "vertex"
+func (r *VertexReranker) Name() string { return "vertex" }
+
+// Rerank ranks up to vertexMaxRecords documents. Any beyond that, or any the API
+// leaves out, follow in their original order with a score of 0.
+func (r *VertexReranker) Rerank(ctx context.Context, query string, docs []Document) ([]Document, error) {
+	if len(docs) == 0 {
+		return docs, nil
+	}
+	ranked := docs[:min(len(docs), vertexMaxRecords)]
+
+	records := make([]*discoveryengine.GoogleCloudDiscoveryengineV1RankingRecord, len(ranked))
+	byID := make(map[string]Document, len(ranked))
+	for i, doc := range ranked {
+		records[i] = &discoveryengine.GoogleCloudDiscoveryengineV1RankingRecord{
+			Id:      doc.ID,
+			Title:   doc.Title,
+			Content: doc.Text,
+		}
+		byID[doc.ID] = doc
+	}
+
+	resp, err := r.service.Projects.Locations.RankingConfigs.Rank(r.config, &discoveryengine.GoogleCloudDiscoveryengineV1RankRequest{
+		Model:                         r.model,
+		Query:                         query,
+		Records:                       records,
+		TopN:                          int64(len(records)),
+		IgnoreRecordDetailsInResponse: true,
+	}).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("failed to rank with %s: %w", r.model, err)
+	}
+
+	// Records come back best first
+	results := make([]Document, 0, len(docs))
+	for _, record := range resp.Records {
+		doc, ok := byID[record.Id]
+		if !ok {
+			continue
+		}
+		delete(byID, record.Id)
+		doc.Score = record.Score
+		results = append(results, doc)
+	}
+	for i, doc := range docs {
+		if _, missed := byID[doc.ID]; missed || i >= len(ranked) {
+			doc.Score = 0
+			results = append(results, doc)
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,108 @@
+package vectorstore
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/firestore"
+)
+
+// firestoreBatchSize stays under Firestore's 500 writes per batch
+const firestoreBatchSize = 400
+
+const (
+	firestoreVectorField   = "embedding"
+	firestoreDistanceField = "vectorDistance"
+)
+
+// FirestoreStore searches with Firestore's native vector search (FindNearest).
+// Each item is a document in its own collection, with its attributes as
+// top-level fields so pre-filters can query them. Pre-filtering needs a composite
+// vector index over the filtered fields; post-filtering only the single-field one.
+type FirestoreStore struct {
+	client     *firestore.Client
+	collection string
+}
+
+// NewFirestoreStore keeps item vectors in the named collection
+func NewFirestoreStore(client *firestore.Client, collection string) *FirestoreStore {
+	return &FirestoreStore{client: client, collection: collection}
+}
+
+// Name returns "firestore"
+func (s *FirestoreStore) Name() string { return "firestore" }
+
+// Search runs a FindNearest query by cosine distance
+func (s *FirestoreStore) Search(ctx context.Context, query Query) ([]Match, error) {
+	if query.K <= 0 {
+		return nil, nil
+	}
+
+	q := s.client.Collection(s.collection).Query
+	if query.Mode != FilterPost {
+		for _, name := range query.Filter.names() {
+			q = q.Where(name, "==", query.Filter[name])
+		}
+	}
+
+	docs, err := q.FindNearest(firestoreVectorField, firestore.Vector64(query.Vector), query.fetchK(), firestore.DistanceMeasureCosine,
+		&firestore.FindNearestOptions{DistanceResultField: firestoreDistanceField}).Documents(ctx).GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("firestore vector search failed: %w", err)
+	}
+
+	matches := make([]Match, 0, len(docs))
+	for _, doc := range docs {
+		var stored struct {
+			DocumentID string            `firestore:"documentId"`
+			Key        string            `firestore:"key"`
+			Attributes map[string]string `firestore:"attributes"`
+			Distance   float64           `firestore:"vectorDistance"`
+		}
+		if err := doc.DataTo(&stored); err != nil {
+			return nil, fmt.Errorf("invalid vector document %s: %w", doc.Ref.ID, err)
+		}
+		matches = append(matches, Match{
+			ID:         ItemID(stored.DocumentID, stored.Key),
+			DocumentID: stored.DocumentID,
+			Key:        stored.Key,
+			Score:      1 - stored.Distance,
+			Attributes: stored.Attributes,
+		})
+	}
+	return finish(matches, query), nil
+}
+
+// Upsert writes one document per item, replacing any earlier version
+func (s *FirestoreStore) Upsert(ctx context.Context, items []Item) error {
+	now := time.Now().Unix()
+	for start := 0; start < len(items); start += firestoreBatchSize {
+		end := min(start+firestoreBatchSize, len(items))
+
+		batch := s.client.Batch()
+		for _, item := range items[start:end] {
+			data := map[string]interface{}{
+				"documentId":         item.DocumentID,
+				"key":                item.Key,
+				"attributes":         item.Attributes,
+				firestoreVectorField: firestore.Vector64(item.Vector),
+				"updatedAt":          now,
+			}
+			for name, value := range item.Attributes {
+				data[name] = value
+			}
+			batch.Set(s.client.Collection(s.collection).Doc(firestoreDocID(item.ID)), data)
+		}
+		if _, err := batch.Commit(ctx); err != nil {
+			return fmt.Errorf("failed to write vectors: %w", err)
+		}
+	}
+	return nil
+}
+
+// firestoreDocID makes an item ID safe as a document ID, which cannot hold '/'
+func firestoreDocID(id string) string {
+	documentID, key := SplitItemID(id)
+	return documentID + "__" + key
+}
@@ -0,0 +1,390 @@
+package vectorstore
+
+import (
+	"container/heap"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+
+	"interviewai.wkv.local/vectorsearch/internal/embeddings"
+)
+
+// exactSearchLimit is the most items a pre-filtered search compares one by one.
+// Graph search works poorly when few items pass the filter, and scanning that
+// few is cheap.
+const exactSearchLimit = 2000
+
+// HNSWParams tunes the graph
+type HNSWParams struct {
+	M              int // links per node on the upper layers; layer 0 keeps 2*M
+	EfConstruction int // candidates considered when linking a new node
+	EfSearch       int // candidates considered per search, at least k
+}
+
+// DefaultHNSWParams suits corpora up to a few hundred thousand vectors
+var DefaultHNSWParams = HNSWParams{M: 16, EfConstruction: 200, EfSearch: 100}
+
+// HNSW is a hierarchical navigable small world graph: an approximate
+// nearest-neighbour index held in memory. Vectors are normalised on insert so
+// similarity is a dot product. Replaced items stay in the graph as tombstones to
+// keep it navigable, and are never returned. Once tombstones are as many as the
+// live items, as after every item has been replaced, the graph is rebuilt from the
+// live ones.
+type HNSW struct {
+	mu         sync.RWMutex
+	dimensions int
+	params     HNSWParams
+	levelMult  float64
+	rng        *rand.Rand
+
+	nodes    []*hnswNode
+	ids      map[string]int32 // live node for each item ID
+	deleted  int              // tombstones among nodes
+	entry    int32            // -1 when empty
+	maxLevel int
+}
+
+type hnswNode struct {
+	Item    Item
+	Level   int
+	Links   [][]int32 // neighbours on each layer up to Level
+	Deleted bool
+}
+
+// NewHNSW creates an empty index for vectors of the given size
+func NewHNSW(dimensions int, params HNSWParams) *HNSW {
+	if params.M <= 1 {
+		params.M = DefaultHNSWParams.M
+	}
+	if params.EfConstruction <= 0 {
+		params.EfConstruction = DefaultHNSWParams.EfConstruction
+	}
+	if params.EfSearch <= 0 {
+		params.EfSearch = DefaultHNSWParams.EfSearch
+	}
+	return &HNSW{
+		dimensions: dimensions,
+		params:     params,
+		levelMult:  1 / math.Log(float64(params.M)),
+		rng:        rand.New(rand.NewSource(1)),
+		ids:        make(map[string]int32),
+		entry:      -1,
+	}
+}
+
+// Dimensions returns the vector size the index accepts
+func (h *HNSW) Dimensions() int { return h.dimensions }
+
+// Len returns the number of live items
+func (h *HNSW) Len() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.ids)
+}
+
+// Insert adds items, replacing any with the same ID
+func (h *HNSW) Insert(items ...Item) error {
+	for _, item := range items {
+		if len(item.Vector) != h.dimensions {
+			return fmt.Errorf("%w: item %s has %d, index has %d", ErrDimensions, item.ID, len(item.Vector), h.dimensions)
+		}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, item := range items {
+		h.insert(item)
+	}
+	h.compactIfSparse()
+	return nil
+}
+
+func (h *HNSW) insert(item Item) {
+	item.Vector = embeddings.Normalize(append([]float64(nil), item.Vector...))
+	if old, ok := h.ids[item.ID]; ok {
+		h.nodes[old].Deleted = true
+		h.deleted++
+	}
+
+	id := int32(len(h.nodes))
+	level := int(-math.Log(1-h.rng.Float64()) * h.levelMult)
+	node := &hnswNode{Item: item, Level: level, Links: make([][]int32, level+1)}
+	h.nodes = append(h.nodes, node)
+	h.ids[item.ID] = id
+
+	if h.entry < 0 {
+		h.entry, h.maxLevel = id, level
+		return
+	}
+
+	entries := []candidate{{node: h.entry, distance: h.distance(item.Vector, h.entry)}}
+	for layer := h.maxLevel; layer > level; layer-- {
+		entries = h.searchLayer(item.Vector, entries, 1, layer, nil)
+	}
+	for layer := min(level, h.maxLevel); layer >= 0; layer-- {
+		found := h.searchLayer(item.Vector, entries, h.params.EfConstruction, layer, nil)
+		for _, neighbour := range h.selectNeighbours(found, h.params.M) {
+			node.Links[layer] = append(node.Links[layer], neighbour.node)
+			h.link(neighbour.node, id, layer)
+		}
+		entries = found
+	}
+
+	if level > h.maxLevel {
+		h.entry, h.maxLevel = id, level
+	}
+}
+
+// compactIfSparse rebuilds the graph from its live items once tombstones are as
+// many as them, so replacing every item does not double the graph and its
+// snapshot. Items are inserted again in their original order.
+func (h *HNSW) compactIfSparse() {
+	if h.deleted == 0 || h.deleted < len(h.ids) {
+		return
+	}
+	live := make([]Item, 0, len(h.ids))
+	for _, node := range h.nodes {
+		if !node.Deleted {
+			live = append(live, node.Item)
+		}
+	}
+
+	h.nodes = make([]*hnswNode, 0, len(live))
+	h.ids = make(map[string]int32, len(live))
+	h.deleted = 0
+	h.entry, h.maxLevel = -1, 0
+	for _, item := range live {
+		h.insert(item)
+	}
+}
+
+// link adds a link from one node to another, pruning the node's links back to
+// the layer's limit
+func (h *HNSW) link(from, to int32, layer int) {
+	node := h.nodes[from]
+	node.Links[layer] = append(node.Links[layer], to)
+
+	limit := h.params.M
+	if layer == 0 {
+		limit *= 2
+	}
+	if len(node.Links[layer]) <= limit {
+		return
+	}
+
+	candidates := make([]candidate, len(node.Links[layer]))
+	for i, neighbour := range node.Links[layer] {
+		candidates[i] = candidate{node: neighbour, distance: h.distance(node.Item.Vector, neighbour)}
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].distance < candidates[j].distance })
+
+	kept := h.selectNeighbours(candidates, limit)
+	node.Links[layer] = node.Links[layer][:0]
+	for _, neighbour := range kept {
+		node.Links[layer] = append(node.Links[layer], neighbour.node)
+	}
+}
+
+// selectNeighbours picks up to m of the candidates, closest first, skipping any
+// closer to an already picked neighbour than to the node so links spread in
+// different directions. Skipped candidates fill any remaining places.
+func (h *HNSW) selectNeighbours(candidates []candidate, m int) []candidate {
+	selected := make([]candidate, 0, m)
+	var skipped []candidate
+	for _, c := range candidates {
+		if len(selected) >= m {
+			break
+		}
+		diverse := true
+		for _, s := range selected {
+			if h.distance(h.nodes[c.node].Item.Vector, s.node) < c.distance {
+				diverse = false
+				break
+			}
+		}
+		if diverse {
+			selected = append(selected, c)
+		} else {
+			skipped = append(skipped, c)
+		}
+	}
+	for _, c := range skipped {
+		if len(selected) >= m {
+			break
+		}
+		selected = append(selected, c)
+	}
+	return selected
+}
+
+// Search returns the query's nearest live items, closest first
+func (h *HNSW) Search(query Query) ([]Match, error) {
+	if len(query.Vector) != h.dimensions {
+		return nil, fmt.Errorf("%w: query has %d, index has %d", ErrDimensions, len(query.Vector), h.dimensions)
+	}
+	if query.K <= 0 {
+		return nil, nil
+	}
+	vector := embeddings.Normalize(append([]float64(nil), query.Vector...))
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if h.entry < 0 {
+		return nil, nil
+	}
+
+	prefilter := query.Mode != FilterPost && !query.Filter.Empty()
+	accept := func(node *hnswNode) bool {
+		return !node.Deleted && (!prefilter || query.Filter.Matches(node.Item.Attributes))
+	}
+
+	if prefilter {
+		if matching := h.matching(accept); len(matching) <= exactSearchLimit {
+			return finish(h.exactSearch(vector, matching), query), nil
+		}
+	}
+
+	entries := []candidate{{node: h.entry, distance: h.distance(vector, h.entry)}}
+	for layer := h.maxLevel; layer > 0; layer-- {
+		entries = h.searchLayer(vector, entries, 1, layer, nil)
+	}
+	found := h.searchLayer(vector, entries, max(h.params.EfSearch, query.fetchK()), 0, accept)
+	return finish(h.matches(found), query), nil
+}
+
+// matching returns the nodes accept keeps, stopping once there are too many to
+// scan
+func (h *HNSW) matching(accept func(*hnswNode) bool) []int32 {
+	var matching []int32
+	for _, id := range h.ids {
+		if accept(h.nodes[id]) {
+			matching = append(matching, id)
+			if len(matching) > exactSearchLimit {
+				break
+			}
+		}
+	}
+	return matching
+}
+
+// exactSearch compares the query with every given node
+func (h *HNSW) exactSearch(vector []float64, nodes []int32) []Match {
+	found := make([]candidate, len(nodes))
+	for i, id := range nodes {
+		found[i] = candidate{node: id, distance: h.distance(vector, id)}
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].distance < found[j].distance })
+	return h.matches(found)
+}
+
+func (h *HNSW) matches(found []candidate) []Match {
+	matches := make([]Match, len(found))
+	for i, c := range found {
+		item := h.nodes[c.node].Item
+		matches[i] = Match{
+			ID:         item.ID,
+			DocumentID: item.DocumentID,
+			Key:        item.Key,
+			Score:      1 - c.distance,
+			Attributes: item.Attributes,
+		}
+	}
+	return matches
+}
+
+// searchLayer walks one layer from the entry points and returns the ef closest
+// nodes accept keeps (all nodes when accept is nil), closest first. Rejected
+// nodes are still walked through, so the graph stays connected under a filter.
+func (h *HNSW) searchLayer(vector []float64, entries []candidate, ef, layer int, accept func(*hnswNode) bool) []candidate {
+	visited := make(map[int32]bool, ef*4)
+	toVisit := &nearestFirst{}
+	results := &farthestFirst{}
+	keep := func(c candidate) {
+		if accept == nil || accept(h.nodes[c.node]) {
+			heap.Push(results, c)
+			if results.Len() > ef {
+				heap.Pop(results)
+			}
+		}
+	}
+
+	for _, entry := range entries {
+		visited[entry.node] = true
+		heap.Push(toVisit, entry)
+		keep(entry)
+	}
+
+	for toVisit.Len() > 0 {
+		current := heap.Pop(toVisit).(candidate)
+		if results.Len() >= ef && current.distance > (*results)[0].distance {
+			break
+		}
+		links := h.nodes[current.node].Links
+		if layer >= len(links) {
+			continue
+		}
+		for _, neighbour := range links[layer] {
+			if visited[neighbour] {
+				continue
+			}
+			visited[neighbour] = true
+
+			c := candidate{node: neighbour, distance: h.distance(vector, neighbour)}
+			if results.Len() < ef || c.distance < (*results)[0].distance {
+				heap.Push(toVisit, c)
+				keep(c)
+			}
+		}
+	}
+
+	found := make([]candidate, results.Len())
+	for i := len(found) - 1; i >= 0; i-- {
+		found[i] = heap.Pop(results).(candidate)
+	}
+	return found
+}
+
+// distance is one minus the cosine similarity of a normalised vector to a node
+func (h *HNSW) distance(vector []float64, node int32) float64 {
+	other := h.nodes[node].Item.Vector
+	var dot float64
+	for i := range vector {
+		dot += vector[i] * other[i]
+	}
+	return 1 - dot
+}
+
+type candidate struct {
+	node     int32
+	distance float64
+}
+
+// nearestFirst is a min-heap of candidates by distance
+type nearestFirst []candidate
+
+func (q nearestFirst) Len() int            { return len(q) }
+func (q nearestFirst) Less(i, j int) bool  { return q[i].distance < q[j].distance }
+func (q nearestFirst) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *nearestFirst) Push(x interface{}) { *q = append(*q, x.(candidate)) }
+func (q *nearestFirst) Pop() interface{} {
+	old := *q
+	c := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return c
+}
+
+// farthestFirst is a max-heap of candidates by distance
+type farthestFirst []candidate
+
+func (q farthestFirst) Len() int            { return len(q) }
+func (q farthestFirst) Less(i, j int) bool  { return q[i].distance > q[j].distance }
+func (q farthestFirst) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *farthestFirst) Push(x interface{}) { *q = append(*q, x.(candidate)) }
+func (q *farthestFirst) Pop() interface{} {
+	old := *q
+	c := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return c
+}
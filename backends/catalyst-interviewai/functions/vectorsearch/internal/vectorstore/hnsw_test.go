@@ -0,0 +1,271 @@
+package vectorstore
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+
+	"interviewai.wkv.local/vectorsearch/internal/embeddings"
+)
+
+// randomItems returns n items with random vectors, spread over documents of
+// four items each and alternating between two interview types
+func randomItems(rng *rand.Rand, n, dimensions int) []Item {
+	items := make([]Item, n)
+	for i := range items {
+		documentID := fmt.Sprintf("doc%d", i/4)
+		key := fmt.Sprintf("chunk_%d", i%4)
+		interviewType := "coding"
+		if i%2 == 1 {
+			interviewType = "behavioral"
+		}
+		items[i] = Item{
+			ID:         ItemID(documentID, key),
+			DocumentID: documentID,
+			Key:        key,
+			Vector:     randomVector(rng, dimensions),
+			Attributes: map[string]string{"interviewType": interviewType},
+		}
+	}
+	return items
+}
+
+func randomVector(rng *rand.Rand, dimensions int) []float64 {
+	vector := make([]float64, dimensions)
+	for i := range vector {
+		vector[i] = rng.NormFloat64()
+	}
+	return vector
+}
+
+// exactTopK returns the IDs of the k items most similar to the vector that pass
+// the filter, compared one by one
+func exactTopK(items []Item, vector []float64, k int, filter Filter) []string {
+	type scored struct {
+		id    string
+		score float64
+	}
+	var all []scored
+	for _, item := range items {
+		if filter.Matches(item.Attributes) {
+			all = append(all, scored{item.ID, embeddings.Cosine(vector, item.Vector)})
+		}
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].score > all[j].score })
+	var ids []string
+	for i := 0; i < k && i < len(all); i++ {
+		ids = append(ids, all[i].id)
+	}
+	return ids
+}
+
+func TestHNSWRecall(t *testing.T) {
+	const (
+		dimensions = 32
+		queries    = 50
+	)
+	rng := rand.New(rand.NewSource(42))
+	// More items than exactSearchLimit, so both filters below go through the graph
+	items := randomItems(rng, 5000, dimensions)
+	h := NewHNSW(dimensions, DefaultHNSWParams)
+	if err := h.Insert(items...); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		k          int
+		filter     Filter
+		mode       FilterMode
+		wantRecall float64
+	}{
+		{name: "top 10", k: 10, wantRecall: 0.9},
+		{name: "top 50", k: 50, wantRecall: 0.9},
+		{name: "pre-filtered", k: 10, filter: Filter{"interviewType": "coding"}, wantRecall: 0.9},
+		{name: "post-filtered", k: 10, filter: Filter{"interviewType": "behavioral"}, mode: FilterPost, wantRecall: 0.9},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var found, total int
+			for q := 0; q < queries; q++ {
+				vector := randomVector(rng, dimensions)
+				matches, err := h.Search(Query{Vector: vector, K: tt.k, Filter: tt.filter, Mode: tt.mode})
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				want := make(map[string]bool)
+				for _, id := range exactTopK(items, vector, tt.k, tt.filter) {
+					want[id] = true
+				}
+				for i, match := range matches {
+					if want[match.ID] {
+						found++
+					}
+					if !tt.filter.Matches(match.Attributes) {
+						t.Errorf("match %s has attributes %v outside the filter", match.ID, match.Attributes)
+					}
+					if i > 0 && match.Score > matches[i-1].Score {
+						t.Errorf("match %d scored %v, above the earlier %v", i, match.Score, matches[i-1].Score)
+					}
+				}
+				total += len(want)
+			}
+
+			if recall := float64(found) / float64(total); recall < tt.wantRecall {
+				t.Errorf("recall %.3f against exact search, want at least %.2f", recall, tt.wantRecall)
+			}
+		})
+	}
+}
+
+func TestHNSWExactFilter(t *testing.T) {
+	const dimensions = 16
+	rng := rand.New(rand.NewSource(7))
+	items := randomItems(rng, 400, dimensions)
+	h := NewHNSW(dimensions, DefaultHNSWParams)
+	if err := h.Insert(items...); err != nil {
+		t.Fatal(err)
+	}
+
+	// Few enough items pass the filter that they are compared one by one, so the
+	// results must equal exact search
+	filter := Filter{"interviewType": "coding"}
+	for q := 0; q < 10; q++ {
+		vector := randomVector(rng, dimensions)
+		matches, err := h.Search(Query{Vector: vector, K: 10, Filter: filter})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, match := range matches {
+			got = append(got, match.ID)
+		}
+		if want := exactTopK(items, vector, 10, filter); !reflect.DeepEqual(got, want) {
+			t.Errorf("query %d: got %v, want %v", q, got, want)
+		}
+	}
+}
+
+func TestHNSWPerDocument(t *testing.T) {
+	const dimensions = 16
+	rng := rand.New(rand.NewSource(3))
+	items := randomItems(rng, 400, dimensions)
+	h := NewHNSW(dimensions, DefaultHNSWParams)
+	if err := h.Insert(items...); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		perDocument int
+	}{
+		{name: "one per document", perDocument: 1},
+		{name: "two per document", perDocument: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// A query equal to one item finds that item first
+			matches, err := h.Search(Query{Vector: items[0].Vector, K: 20, PerDocument: tt.perDocument})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(matches) != 20 {
+				t.Fatalf("got %d matches, want 20", len(matches))
+			}
+			if matches[0].ID != items[0].ID {
+				t.Errorf("first match is %s, want %s", matches[0].ID, items[0].ID)
+			}
+			perDocument := make(map[string]int)
+			for _, match := range matches {
+				perDocument[match.DocumentID]++
+				if perDocument[match.DocumentID] > tt.perDocument {
+					t.Errorf("document %s has more than %d matches", match.DocumentID, tt.perDocument)
+				}
+			}
+		})
+	}
+}
+
+func TestHNSWReplace(t *testing.T) {
+	const dimensions = 16
+	rng := rand.New(rand.NewSource(5))
+	items := randomItems(rng, 200, dimensions)
+
+	tests := []struct {
+		name        string
+		replaced    int // how many items get new vectors
+		wantNodes   int
+		wantDeleted int
+	}{
+		{name: "a few", replaced: 20, wantNodes: 220, wantDeleted: 20},
+		{name: "half", replaced: 100, wantNodes: 300, wantDeleted: 100},
+		{name: "all compacts", replaced: 200, wantNodes: 200, wantDeleted: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHNSW(dimensions, DefaultHNSWParams)
+			if err := h.Insert(items...); err != nil {
+				t.Fatal(err)
+			}
+			replacements := make([]Item, tt.replaced)
+			for i := range replacements {
+				replacements[i] = items[i]
+				replacements[i].Vector = randomVector(rng, dimensions)
+			}
+			if err := h.Insert(replacements...); err != nil {
+				t.Fatal(err)
+			}
+
+			if h.Len() != len(items) {
+				t.Errorf("Len() = %d, want %d", h.Len(), len(items))
+			}
+			if len(h.nodes) != tt.wantNodes || h.deleted != tt.wantDeleted {
+				t.Errorf("graph has %d nodes and %d tombstones, want %d and %d", len(h.nodes), h.deleted, tt.wantNodes, tt.wantDeleted)
+			}
+
+			// Searching for a replacement's new vector finds it, once
+			for _, item := range replacements[:min(5, len(replacements))] {
+				matches, err := h.Search(Query{Vector: item.Vector, K: 5})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(matches) == 0 || matches[0].ID != item.ID || matches[0].Score < 0.999 {
+					t.Errorf("search for %s's new vector returned %v", item.ID, matches)
+				}
+				for _, match := range matches[1:] {
+					if match.ID == item.ID {
+						t.Errorf("%s returned twice", item.ID)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestHNSWErrors(t *testing.T) {
+	h := NewHNSW(4, DefaultHNSWParams)
+
+	tests := []struct {
+		name    string
+		run     func() error
+		wantErr bool
+	}{
+		{name: "insert wrong size", run: func() error { return h.Insert(Item{ID: "a", Vector: []float64{1, 2}}) }, wantErr: true},
+		{name: "search wrong size", run: func() error { _, err := h.Search(Query{Vector: []float64{1}, K: 1}); return err }, wantErr: true},
+		{name: "search empty index", run: func() error { _, err := h.Search(Query{Vector: []float64{1, 0, 0, 0}, K: 1}); return err }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.run(); (err != nil) != tt.wantErr {
+				t.Errorf("got error %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,206 @@
+package vectorstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"time"
+)
+
+// saveAttempts is how many times Save rebuilds on another instance's snapshot
+// before giving up
+const saveAttempts = 3
+
+// HNSWStore serves searches from an in-process HNSW index. The index is loaded
+// from a snapshot at cold start and reloaded when another instance saves a newer
+// one.
+type HNSWStore struct {
+	mu        sync.RWMutex // guards index
+	index     *HNSW
+	snapshots Snapshots // nil keeps the index in memory only
+
+	// writeMu serialises changes to the index: upserts, saves and reloads
+	writeMu sync.Mutex
+	loaded  Revision // the snapshot the index was built on
+	pending []Item   // upserts not yet saved, applied again to a reloaded index
+
+	refreshEvery time.Duration
+	refreshMu    sync.Mutex // held by the one search checking for a newer snapshot
+	checkedAt    time.Time
+}
+
+// NewHNSWStore loads the index from snapshots, or starts an empty one when
+// nothing has been saved. A snapshot of a different vector size is an error: it
+// was built with another embedding model. refreshEvery limits how often searches
+// check for a newer snapshot; zero never checks.
+func NewHNSWStore(ctx context.Context, dimensions int, params HNSWParams, snapshots Snapshots, refreshEvery time.Duration) (*HNSWStore, error) {
+	s := &HNSWStore{
+		index:        NewHNSW(dimensions, params),
+		snapshots:    snapshots,
+		refreshEvery: refreshEvery,
+		checkedAt:    time.Now(),
+	}
+	if snapshots == nil {
+		return s, nil
+	}
+
+	index, revision, err := s.load(ctx)
+	if errors.Is(err, ErrNoSnapshot) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if index.Dimensions() != dimensions {
+		return nil, fmt.Errorf("%w: snapshot %s has %d, queries have %d", ErrDimensions, snapshots, index.Dimensions(), dimensions)
+	}
+	s.index, s.loaded = index, revision
+	return s, nil
+}
+
+// Name returns "hnsw"
+func (s *HNSWStore) Name() string { return "hnsw" }
+
+// Len returns the number of indexed items
+func (s *HNSWStore) Len() int {
+	return s.current().Len()
+}
+
+// Search finds the nearest items in the index
+func (s *HNSWStore) Search(ctx context.Context, query Query) ([]Match, error) {
+	s.refresh(ctx)
+	return s.current().Search(query)
+}
+
+// Upsert adds items to this instance's index. Call Save to share them.
+func (s *HNSWStore) Upsert(_ context.Context, items []Item) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	if err := s.current().Insert(items...); err != nil {
+		return err
+	}
+	if s.snapshots != nil {
+		s.pending = append(s.pending, items...)
+	}
+	return nil
+}
+
+// Save writes the index to the snapshot location. When another instance has saved
+// since this index was loaded, its snapshot is loaded, this instance's upserts are
+// applied to it again and the save is retried, so neither instance's upserts are
+// lost.
+func (s *HNSWStore) Save(ctx context.Context) error {
+	if s.snapshots == nil {
+		return nil
+	}
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	for attempt := 1; ; attempt++ {
+		revision, err := s.snapshots.Save(ctx, s.loaded, s.current().Encode)
+		if err == nil {
+			s.loaded, s.pending = revision, nil
+			return nil
+		}
+		if !errors.Is(err, ErrSnapshotChanged) || attempt == saveAttempts {
+			return fmt.Errorf("failed to save index to %s: %w", s.snapshots, err)
+		}
+
+		index, revision, err := s.load(ctx)
+		switch {
+		case errors.Is(err, ErrNoSnapshot):
+			// Deleted since; the next attempt creates it
+			s.loaded = Revision{}
+		case err != nil:
+			return fmt.Errorf("failed to reload index from %s: %w", s.snapshots, err)
+		default:
+			if err := s.adopt(index, revision); err != nil {
+				return err
+			}
+			log.Printf("HNSWStore: %s was saved by another instance; merged %d upserts into it", s.snapshots, len(s.pending))
+		}
+	}
+}
+
+func (s *HNSWStore) current() *HNSW {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.index
+}
+
+func (s *HNSWStore) load(ctx context.Context) (*HNSW, Revision, error) {
+	var index *HNSW
+	revision, err := s.snapshots.Load(ctx, func(r io.Reader) error {
+		var err error
+		index, err = ReadHNSW(r)
+		return err
+	})
+	if err != nil {
+		return nil, Revision{}, err
+	}
+	return index, revision, nil
+}
+
+// adopt swaps in an index loaded from revision, with the upserts not yet saved
+// applied to it. The caller holds writeMu.
+func (s *HNSWStore) adopt(index *HNSW, revision Revision) error {
+	if index.Dimensions() != s.current().Dimensions() {
+		return fmt.Errorf("%w: snapshot %s has %d, want %d", ErrDimensions, s.snapshots, index.Dimensions(), s.current().Dimensions())
+	}
+	if err := index.Insert(s.pending...); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.index = index
+	s.mu.Unlock()
+	s.loaded = revision
+	return nil
+}
+
+// refresh swaps in a newer snapshot if one has been saved since the last check.
+// Failures keep the current index, and so does an upsert or save in progress.
+// Searches arriving while another one downloads a snapshot use the current index.
+func (s *HNSWStore) refresh(ctx context.Context) {
+	if s.snapshots == nil || s.refreshEvery <= 0 {
+		return
+	}
+	if !s.refreshMu.TryLock() {
+		return
+	}
+	defer s.refreshMu.Unlock()
+	if time.Since(s.checkedAt) < s.refreshEvery {
+		return
+	}
+	s.checkedAt = time.Now()
+
+	if !s.writeMu.TryLock() {
+		return
+	}
+	defer s.writeMu.Unlock()
+
+	updated, err := s.snapshots.Updated(ctx)
+	if err != nil {
+		if !errors.Is(err, ErrNoSnapshot) {
+			log.Printf("HNSWStore: Failed to check snapshot %s: %v", s.snapshots, err)
+		}
+		return
+	}
+	if updated.Same(s.loaded) {
+		return
+	}
+
+	index, revision, err := s.load(ctx)
+	if err != nil {
+		log.Printf("HNSWStore: Failed to reload snapshot %s: %v", s.snapshots, err)
+		return
+	}
+	if err := s.adopt(index, revision); err != nil {
+		log.Printf("HNSWStore: Ignoring snapshot %s: %v", s.snapshots, err)
+		return
+	}
+	log.Printf("HNSWStore: Reloaded %d items from %s", index.Len(), s.snapshots)
+}
@@ -0,0 +1,101 @@
+package vectorstore
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+// slowSnapshots holds one snapshot in memory. Loads wait for release once the
+// snapshot has been saved, as a large download would.
+type slowSnapshots struct {
+	mu       sync.Mutex
+	data     []byte
+	revision Revision
+	loading  chan struct{} // receives when a load starts waiting
+	release  chan struct{}
+}
+
+func (s *slowSnapshots) String() string { return "memory" }
+
+func (s *slowSnapshots) Load(_ context.Context, read func(io.Reader) error) (Revision, error) {
+	s.mu.Lock()
+	data, revision := s.data, s.revision
+	s.mu.Unlock()
+	if data == nil {
+		return Revision{}, ErrNoSnapshot
+	}
+
+	s.loading <- struct{}{}
+	<-s.release
+	return revision, read(bytes.NewReader(data))
+}
+
+func (s *slowSnapshots) Save(context.Context, Revision, func(io.Writer) error) (Revision, error) {
+	panic("not used")
+}
+
+func (s *slowSnapshots) Updated(context.Context) (Revision, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.data == nil {
+		return Revision{}, ErrNoSnapshot
+	}
+	return s.revision, nil
+}
+
+func TestHNSWStoreSearchDuringReload(t *testing.T) {
+	ctx := context.Background()
+	snapshots := &slowSnapshots{loading: make(chan struct{}), release: make(chan struct{})}
+	store, err := NewHNSWStore(ctx, 3, DefaultHNSWParams, snapshots, time.Nanosecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Another instance saves an index with one item
+	saved := NewHNSW(3, DefaultHNSWParams)
+	if err := saved.Insert(Item{ID: ItemID("doc1", "document"), DocumentID: "doc1", Key: "document", Vector: []float64{1, 0, 0}}); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := saved.Encode(&buf); err != nil {
+		t.Fatal(err)
+	}
+	snapshots.mu.Lock()
+	snapshots.data, snapshots.revision = buf.Bytes(), Revision{Saved: time.Now()}
+	snapshots.mu.Unlock()
+
+	query := Query{Vector: []float64{1, 0, 0}, K: 1}
+	reloaded := make(chan []Match)
+	go func() {
+		matches, _ := store.Search(ctx, query)
+		reloaded <- matches
+	}()
+	<-snapshots.loading
+
+	// The download is in progress; other searches use the index already loaded
+	searched := make(chan []Match)
+	go func() {
+		matches, _ := store.Search(ctx, query)
+		searched <- matches
+	}()
+	select {
+	case matches := <-searched:
+		if len(matches) != 0 {
+			t.Errorf("search during the reload got %d matches from the old, empty index", len(matches))
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("search waited for the snapshot download")
+	}
+
+	close(snapshots.release)
+	if matches := <-reloaded; len(matches) != 1 {
+		t.Errorf("search that reloaded got %d matches, want 1 from the new snapshot", len(matches))
+	}
+	if store.Len() != 1 {
+		t.Errorf("Len() = %d after the reload, want 1", store.Len())
+	}
+}
@@ -0,0 +1,260 @@
+package vectorstore
+
+import (
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"google.golang.org/api/googleapi"
+)
+
+// snapshotVersion changes whenever the snapshot layout does
+const snapshotVersion = 1
+
+var (
+	// ErrNoSnapshot is returned when nothing has been saved yet
+	ErrNoSnapshot = errors.New("no snapshot")
+	// ErrSnapshotChanged is returned by Save when another instance has saved since
+	// the revision the index was built on
+	ErrSnapshotChanged = errors.New("snapshot was saved by another instance")
+)
+
+// Revision identifies one saved snapshot. The zero Revision stands for no snapshot.
+type Revision struct {
+	Saved      time.Time
+	Generation int64 // the Cloud Storage object generation; zero for files
+}
+
+// Same reports whether r and other are the same save
+func (r Revision) Same(other Revision) bool {
+	if r.Generation != 0 || other.Generation != 0 {
+		return r.Generation == other.Generation
+	}
+	return r.Saved.Equal(other.Saved)
+}
+
+// Snapshots is where a serialized index is kept between instances
+type Snapshots interface {
+	// Load passes the latest snapshot to read and returns its revision
+	Load(ctx context.Context, read func(io.Reader) error) (Revision, error)
+	// Save replaces the snapshot with what write produces if it is still base, and
+	// returns ErrSnapshotChanged if it is not. A failed write leaves the snapshot
+	// alone.
+	Save(ctx context.Context, base Revision, write func(io.Writer) error) (Revision, error)
+	// Updated returns the revision of the latest snapshot
+	Updated(ctx context.Context) (Revision, error)
+	String() string
+}
+
+// OpenSnapshots keeps snapshots at location: a gs://bucket/object URL, or a local
+// file path
+func OpenSnapshots(ctx context.Context, location string) (Snapshots, error) {
+	if path, ok := strings.CutPrefix(location, "gs://"); ok {
+		bucket, object, _ := strings.Cut(path, "/")
+		if bucket == "" || object == "" {
+			return nil, fmt.Errorf("invalid snapshot location %q, want gs://bucket/object", location)
+		}
+		client, err := storage.NewClient(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create storage client: %w", err)
+		}
+		return &gcsSnapshots{object: client.Bucket(bucket).Object(object), location: location}, nil
+	}
+	return fileSnapshots(location), nil
+}
+
+// fileSnapshots keeps the snapshot in a local file
+type fileSnapshots string
+
+func (f fileSnapshots) String() string { return string(f) }
+
+func (f fileSnapshots) Load(_ context.Context, read func(io.Reader) error) (Revision, error) {
+	file, err := os.Open(string(f))
+	if errors.Is(err, os.ErrNotExist) {
+		return Revision{}, ErrNoSnapshot
+	}
+	if err != nil {
+		return Revision{}, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return Revision{}, err
+	}
+	return Revision{Saved: info.ModTime()}, read(file)
+}
+
+// Save writes beside the snapshot and renames over it, so readers never see a
+// partial file. The revision is checked by modification time just before the
+// rename, which is enough for the processes of one machine.
+func (f fileSnapshots) Save(ctx context.Context, base Revision, write func(io.Writer) error) (Revision, error) {
+	dir := filepath.Dir(string(f))
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return Revision{}, err
+	}
+	temp, err := os.CreateTemp(dir, filepath.Base(string(f))+".*.tmp")
+	if err != nil {
+		return Revision{}, err
+	}
+	defer os.Remove(temp.Name())
+
+	if err := write(temp); err != nil {
+		temp.Close()
+		return Revision{}, err
+	}
+	if err := temp.Close(); err != nil {
+		return Revision{}, err
+	}
+
+	current, err := f.Updated(ctx)
+	switch {
+	case errors.Is(err, ErrNoSnapshot):
+		current = Revision{}
+	case err != nil:
+		return Revision{}, err
+	}
+	if !current.Same(base) {
+		return Revision{}, ErrSnapshotChanged
+	}
+	if err := os.Rename(temp.Name(), string(f)); err != nil {
+		return Revision{}, err
+	}
+	return f.Updated(ctx)
+}
+
+func (f fileSnapshots) Updated(_ context.Context) (Revision, error) {
+	info, err := os.Stat(string(f))
+	if errors.Is(err, os.ErrNotExist) {
+		return Revision{}, ErrNoSnapshot
+	}
+	if err != nil {
+		return Revision{}, err
+	}
+	return Revision{Saved: info.ModTime()}, nil
+}
+
+// gcsSnapshots keeps the snapshot in a Cloud Storage object
+type gcsSnapshots struct {
+	object   *storage.ObjectHandle
+	location string
+}
+
+func (g *gcsSnapshots) String() string { return g.location }
+
+func (g *gcsSnapshots) Load(ctx context.Context, read func(io.Reader) error) (Revision, error) {
+	reader, err := g.object.NewReader(ctx)
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		return Revision{}, ErrNoSnapshot
+	}
+	if err != nil {
+		return Revision{}, err
+	}
+	defer reader.Close()
+	return Revision{Saved: reader.Attrs.LastModified, Generation: reader.Attrs.Generation}, read(reader)
+}
+
+// Save uploads the snapshot on condition that the object is still at base's
+// generation, or does not exist for the zero Revision. Cancelling the upload when
+// write fails keeps the previous object.
+func (g *gcsSnapshots) Save(ctx context.Context, base Revision, write func(io.Writer) error) (Revision, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	conditions := storage.Conditions{DoesNotExist: true}
+	if base.Generation != 0 {
+		conditions = storage.Conditions{GenerationMatch: base.Generation}
+	}
+	writer := g.object.If(conditions).NewWriter(ctx)
+	writer.ContentType = "application/octet-stream"
+	if err := write(writer); err != nil {
+		cancel()
+		writer.Close()
+		return Revision{}, err
+	}
+	if err := writer.Close(); err != nil {
+		var apiErr *googleapi.Error
+		if errors.As(err, &apiErr) && apiErr.Code == http.StatusPreconditionFailed {
+			return Revision{}, ErrSnapshotChanged
+		}
+		return Revision{}, err
+	}
+	attrs := writer.Attrs()
+	return Revision{Saved: attrs.Updated, Generation: attrs.Generation}, nil
+}
+
+func (g *gcsSnapshots) Updated(ctx context.Context) (Revision, error) {
+	attrs, err := g.object.Attrs(ctx)
+	if errors.Is(err, storage.ErrObjectNotExist) {
+		return Revision{}, ErrNoSnapshot
+	}
+	if err != nil {
+		return Revision{}, err
+	}
+	return Revision{Saved: attrs.Updated, Generation: attrs.Generation}, nil
+}
+
+// hnswSnapshot is the gob-encoded form of an HNSW index
+type hnswSnapshot struct {
+	Version    int
+	Dimensions int
+	Params     HNSWParams
+	Nodes      []*hnswNode
+	Entry      int32
+	MaxLevel   int
+}
+
+// Encode writes the index for ReadHNSW
+func (h *HNSW) Encode(w io.Writer) error {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return gob.NewEncoder(w).Encode(hnswSnapshot{
+		Version:    snapshotVersion,
+		Dimensions: h.dimensions,
+		Params:     h.params,
+		Nodes:      h.nodes,
+		Entry:      h.entry,
+		MaxLevel:   h.maxLevel,
+	})
+}
+
+// ReadHNSW decodes an index written by Encode
+func ReadHNSW(r io.Reader) (*HNSW, error) {
+	var snapshot hnswSnapshot
+	if err := gob.NewDecoder(r).Decode(&snapshot); err != nil {
+		return nil, fmt.Errorf("failed to decode index: %w", err)
+	}
+	if snapshot.Version != snapshotVersion {
+		return nil, fmt.Errorf("unsupported index snapshot version %d", snapshot.Version)
+	}
+
+	h := NewHNSW(snapshot.Dimensions, snapshot.Params)
+	h.nodes = snapshot.Nodes
+	h.entry = snapshot.Entry
+	h.maxLevel = snapshot.MaxLevel
+	for i, node := range h.nodes {
+		// Pad short link lists so a damaged node cannot index out of range
+		for len(node.Links) <= node.Level {
+			node.Links = append(node.Links, nil)
+		}
+		if node.Deleted {
+			h.deleted++
+		} else {
+			h.ids[node.Item.ID] = int32(i)
+		}
+	}
+	if len(h.nodes) == 0 {
+		h.entry = -1
+	}
+	// Older snapshots may hold more tombstones than live items
+	h.compactIfSparse()
+	return h, nil
+}
@@ -0,0 +1,175 @@
+// Package vectorstore finds the stored vectors nearest to a query across the whole
+// corpus. A document contributes several vectors (its summary, title, questions and
+// transcript chunks), each stored as an Item under the document's ID and the key
+// the scraper gave it.
+package vectorstore
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+)
+
+// MaxK is the most matches one search returns
+const MaxK = 1000
+
+// PostFilterOverfetch is how many times k a post-filtered search retrieves, so
+// enough matches survive the filter
+const PostFilterOverfetch = 4
+
+// PerDocumentOverfetch is how many times k a search retrieves when it limits the
+// matches per document, so documents with many vectors do not crowd out the rest
+const PerDocumentOverfetch = 4
+
+// ErrDimensions is returned for vectors whose size does not match the store's
+var ErrDimensions = errors.New("vector has the wrong number of dimensions")
+
+// Item is one vector of a document
+type Item struct {
+	ID         string            // ItemID(DocumentID, Key)
+	DocumentID string            // the scraped_content document
+	Key        string            // which part of the document, e.g. "document" or "chunk_3"
+	Vector     []float64         // the embedding
+	Attributes map[string]string // metadata filters can match, e.g. interviewType
+}
+
+// Match is an item found by a search
+type Match struct {
+	ID         string
+	DocumentID string
+	Key        string
+	Score      float64 // cosine similarity to the query; higher is closer
+	Attributes map[string]string
+}
+
+// FilterMode says when a filter is applied
+type FilterMode string
+
+const (
+	// FilterPre restricts the search to matching items, so every result matches
+	// and the top k are the true top k among them
+	FilterPre FilterMode = "pre"
+	// FilterPost searches the whole corpus and then drops items that do not match.
+	// It needs no index on the filtered attributes but can return fewer than k.
+	FilterPost FilterMode = "post"
+)
+
+// Filter keeps items whose attributes equal every value given. Empty values are
+// ignored.
+type Filter map[string]string
+
+// Matches reports whether attributes satisfy the filter
+func (f Filter) Matches(attributes map[string]string) bool {
+	for name, value := range f {
+		if value != "" && attributes[name] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// Empty reports whether the filter keeps every item
+func (f Filter) Empty() bool {
+	for _, value := range f {
+		if value != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// names returns the filtered attribute names in a stable order
+func (f Filter) names() []string {
+	var names []string
+	for name, value := range f {
+		if value != "" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Query asks for the K items nearest to Vector that pass Filter
+type Query struct {
+	Vector []float64
+	K      int
+	Filter Filter
+	Mode   FilterMode // FilterPre when empty
+	// PerDocument is the most matches returned from one document, so one long
+	// transcript cannot fill the results; zero for no limit
+	PerDocument int
+}
+
+// fetchK returns how many items to retrieve before post-filtering and limiting
+// matches per document
+func (q Query) fetchK() int {
+	k := q.K
+	if q.Mode == FilterPost && !q.Filter.Empty() {
+		k *= PostFilterOverfetch
+	}
+	if q.PerDocument > 0 {
+		k *= PerDocumentOverfetch
+	}
+	if k > MaxK {
+		k = MaxK
+	}
+	return k
+}
+
+// Store is an index of item vectors
+type Store interface {
+	// Name identifies the backend in responses and logs
+	Name() string
+	// Search returns up to K matches, closest first
+	Search(ctx context.Context, query Query) ([]Match, error)
+	// Upsert adds items, replacing any stored under the same ID
+	Upsert(ctx context.Context, items []Item) error
+}
+
+// Persister is a Store that keeps its index in memory and must be saved for other
+// instances to see upserts
+type Persister interface {
+	Save(ctx context.Context) error
+}
+
+// ItemID names a document's vector. Keys never contain '#', so the document ID is
+// everything before the last one.
+func ItemID(documentID, key string) string {
+	return documentID + "#" + key
+}
+
+// SplitItemID reverses ItemID
+func SplitItemID(id string) (documentID, key string) {
+	i := strings.LastIndex(id, "#")
+	if i < 0 {
+		return id, ""
+	}
+	return id[:i], id[i+1:]
+}
+
+// finish drops matches the filter rejects when the query asked for
+// post-filtering, and matches past the per-document limit, then trims to k
+func finish(matches []Match, query Query) []Match {
+	postfilter := query.Mode == FilterPost && !query.Filter.Empty()
+	perDocument := make(map[string]int)
+	kept := matches[:0]
+	for _, match := range matches {
+		if postfilter && !query.Filter.Matches(match.Attributes) {
+			continue
+		}
+		if query.PerDocument > 0 {
+			if perDocument[match.DocumentID] == query.PerDocument {
+				continue
+			}
+			perDocument[match.DocumentID]++
+		}
+		kept = append(kept, match)
+	}
+	matches = kept
+	if len(matches) > query.K {
+		matches = matches[:query.K]
+	}
+	return matches
+}
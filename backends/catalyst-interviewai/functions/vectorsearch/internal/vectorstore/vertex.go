@@ -0,0 +1,140 @@
+package vectorstore
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/api/aiplatform/v1"
+)
+
+// vertexUpsertBatchSize is the most datapoints sent in one upsert request
+const vertexUpsertBatchSize = 1000
+
+// VertexStore searches an index deployed to a Vertex AI Vector Search endpoint.
+// Attributes are stored as restricts, one namespace per attribute, so the index
+// applies pre-filters itself. The index must compare unit vectors by dot product
+// or cosine, so the returned distance is the similarity.
+type VertexStore struct {
+	service         *aiplatform.Service
+	endpoint        string // projects/*/locations/*/indexEndpoints/*
+	deployedIndexID string
+	index           string // projects/*/locations/*/indexes/*, empty when upserts are not allowed
+}
+
+// NewVertexStore searches the deployed index on the endpoint. service must send
+// queries to the endpoint's own domain when it is public. indexID names the index
+// for streaming upserts and may be empty for indexes rebuilt in batch.
+func NewVertexStore(service *aiplatform.Service, projectID, location, endpointID, deployedIndexID, indexID string) *VertexStore {
+	s := &VertexStore{
+		service:         service,
+		endpoint:        fmt.Sprintf("projects/%s/locations/%s/indexEndpoints/%s", projectID, location, endpointID),
+		deployedIndexID: deployedIndexID,
+	}
+	if indexID != "" {
+		s.index = fmt.Sprintf("projects/%s/locations/%s/indexes/%s", projectID, location, indexID)
+	}
+	return s
+}
+
+// Name returns "vertex"
+func (s *VertexStore) Name() string { return "vertex" }
+
+// Search asks the deployed index for the nearest datapoints
+func (s *VertexStore) Search(ctx context.Context, query Query) ([]Match, error) {
+	if query.K <= 0 {
+		return nil, nil
+	}
+
+	datapoint := &aiplatform.GoogleCloudAiplatformV1IndexDatapoint{FeatureVector: query.Vector}
+	postfilter := query.Mode == FilterPost && !query.Filter.Empty()
+	if !postfilter {
+		datapoint.Restricts = restricts(query.Filter)
+	}
+
+	resp, err := s.service.Projects.Locations.IndexEndpoints.FindNeighbors(s.endpoint, &aiplatform.GoogleCloudAiplatformV1FindNeighborsRequest{
+		DeployedIndexId: s.deployedIndexID,
+		Queries: []*aiplatform.GoogleCloudAiplatformV1FindNeighborsRequestQuery{{
+			Datapoint:                         datapoint,
+			NeighborCount:                     int64(query.fetchK()),
+			PerCrowdingAttributeNeighborCount: int64(query.PerDocument),
+		}},
+		// Restricts come back only with the full datapoint
+		ReturnFullDatapoint: postfilter,
+	}).Context(ctx).Do()
+	if err != nil {
+		return nil, fmt.Errorf("vertex vector search failed: %w", err)
+	}
+	if len(resp.NearestNeighbors) == 0 {
+		return nil, nil
+	}
+
+	neighbors := resp.NearestNeighbors[0].Neighbors
+	matches := make([]Match, 0, len(neighbors))
+	for _, neighbor := range neighbors {
+		if neighbor.Datapoint == nil {
+			continue
+		}
+		documentID, key := SplitItemID(neighbor.Datapoint.DatapointId)
+		matches = append(matches, Match{
+			ID:         neighbor.Datapoint.DatapointId,
+			DocumentID: documentID,
+			Key:        key,
+			Score:      neighbor.Distance,
+			Attributes: attributes(neighbor.Datapoint.Restricts),
+		})
+	}
+	return finish(matches, query), nil
+}
+
+// Upsert streams datapoints into the index. Indexes built in batch take updates
+// from Cloud Storage instead, so they reject upserts.
+func (s *VertexStore) Upsert(ctx context.Context, items []Item) error {
+	if s.index == "" {
+		return fmt.Errorf("vertex index ID not configured; the index must be updated in batch")
+	}
+
+	for start := 0; start < len(items); start += vertexUpsertBatchSize {
+		end := min(start+vertexUpsertBatchSize, len(items))
+
+		datapoints := make([]*aiplatform.GoogleCloudAiplatformV1IndexDatapoint, 0, end-start)
+		for _, item := range items[start:end] {
+			datapoints = append(datapoints, &aiplatform.GoogleCloudAiplatformV1IndexDatapoint{
+				DatapointId:   item.ID,
+				FeatureVector: item.Vector,
+				Restricts:     restricts(Filter(item.Attributes)),
+				// Crowding by document lets queries cap the neighbours per document
+				CrowdingTag: &aiplatform.GoogleCloudAiplatformV1IndexDatapointCrowdingTag{CrowdingAttribute: item.DocumentID},
+			})
+		}
+
+		_, err := s.service.Projects.Locations.Indexes.UpsertDatapoints(s.index, &aiplatform.GoogleCloudAiplatformV1UpsertDatapointsRequest{
+			Datapoints: datapoints,
+		}).Context(ctx).Do()
+		if err != nil {
+			return fmt.Errorf("failed to upsert datapoints: %w", err)
+		}
+	}
+	return nil
+}
+
+// restricts turns attribute values into single-value allow lists
+func restricts(values Filter) []*aiplatform.GoogleCloudAiplatformV1IndexDatapointRestriction {
+	var restricts []*aiplatform.GoogleCloudAiplatformV1IndexDatapointRestriction
+	for _, name := range values.names() {
+		restricts = append(restricts, &aiplatform.GoogleCloudAiplatformV1IndexDatapointRestriction{
+			Namespace: name,
+			AllowList: []string{values[name]},
+		})
+	}
+	return restricts
+}
+
+func attributes(restricts []*aiplatform.GoogleCloudAiplatformV1IndexDatapointRestriction) map[string]string {
+	values := make(map[string]string, len(restricts))
+	for _, restrict := range restricts {
+		if len(restrict.AllowList) > 0 {
+			values[restrict.Namespace] = restrict.AllowList[0]
+		}
+	}
+	return values
+}
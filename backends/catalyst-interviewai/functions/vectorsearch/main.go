@@ -12,7 +12,13 @@ import (
 	"time"
 
 	"interviewai.wkv.local/vectorsearch/internal/auth"
+	"interviewai.wkv.local/vectorsearch/internal/embeddings"
+	"interviewai.wkv.local/vectorsearch/internal/health"
 	"interviewai.wkv.local/vectorsearch/internal/httputils"
+	"interviewai.wkv.local/vectorsearch/internal/lexical"
+	"interviewai.wkv.local/vectorsearch/internal/ratelimit"
+	"interviewai.wkv.local/vectorsearch/internal/rerank"
+	"interviewai.wkv.local/vectorsearch/internal/vectorstore"
 	"interviewai.wkv.local/vectorsearch/models"
 
 	"cloud.google.com/go/firestore"
@@ -25,9 +31,16 @@ var (
 	firebaseAppSingleton *firebase.App
 	firestoreClient      *firestore.Client
 	aiplatformService    *aiplatform.Service
+	queryEmbedder        embeddings.Embedder
+	vectorStore          vectorstore.Store
+	lexicalIndex         *lexical.Index
+	resultReranker       rerank.Reranker
 	gcpProjectIDEnv      string
 	locationEnv          string
 	indexEndpointIDEnv   string
+	adminClaimEnv        string
+	rateLimiter          *ratelimit.Limiter
+	healthChecker        *health.Checker
 )
 
 func init() {
@@ -35,6 +48,7 @@ func init() {
 	gcpProjectIDEnv = os.Getenv("GCP_PROJECT_ID")
 	locationEnv = os.Getenv("VERTEX_AI_LOCATION")
 	indexEndpointIDEnv = os.Getenv("VERTEX_AI_INDEX_ENDPOINT_ID")
+	adminClaimEnv = os.Getenv("ADMIN_CLAIM")
 
 	if gcpProjectIDEnv == "" {
 		log.Fatal("GCP_PROJECT_ID environment variable not set.")
@@ -42,6 +56,9 @@ func init() {
 	if locationEnv == "" {
 		locationEnv = "us-central1" // Default location
 	}
+	if adminClaimEnv == "" {
+		adminClaimEnv = "admin"
+	}
 
 	// Initialize Firebase App
 	var err error
@@ -67,14 +84,107 @@ func init() {
 		log.Fatalf("aiplatform.NewService in init: %v", err)
 	}
 
+	// Queries are embedded by the model that embedded the stored documents
+	queryEmbedder, err = newEmbedder()
+	if err != nil {
+		log.Fatalf("Failed to set up embeddings: %v", err)
+	}
+	log.Printf("VectorSearch: Embedding queries with %s (%d dimensions)", queryEmbedder.Model(), queryEmbedder.Dimensions())
+
+	// Searches rank the whole corpus through a nearest-neighbour index and a
+	// keyword index
+	vectorStore, err = newVectorStore(ctx)
+	if err != nil {
+		log.Fatalf("Failed to set up vector store: %v", err)
+	}
+	lexicalIndex = lexical.NewIndex(lexical.DefaultParams)
+	if lexicalRefresh.every, err = refreshInterval(); err != nil {
+		log.Fatalf("Failed to set up lexical index: %v", err)
+	}
+	warmIndexes(ctx)
+	log.Printf("VectorSearch: Searching the %s vector store", vectorStore.Name())
+
+	resultReranker, err = newReranker(ctx)
+	if err != nil {
+		log.Fatalf("Failed to set up reranker: %v", err)
+	}
+
+	// Per-user search limits; RATE_LIMIT_STORE=memory keeps counters in process for local runs
+	switch os.Getenv("RATE_LIMIT_STORE") {
+	case "memory":
+		rateLimiter = ratelimit.NewLimiter(ratelimit.NewMemoryStore(), nil)
+	case "", "firestore":
+		rateLimiter = ratelimit.NewLimiter(ratelimit.NewFirestoreStore(firestoreClient, "rateLimits"), nil)
+	default:
+		log.Fatalf("Unknown RATE_LIMIT_STORE %q", os.Getenv("RATE_LIMIT_STORE"))
+	}
+
+	healthChecker = newHealthChecker()
+
 	log.Println("VectorSearch: All services initialized successfully.")
 }
 
+// newEmbedder creates the query embedder named by EMBEDDING_PROVIDER: "vertex" (the
+// default) for the Vertex AI model in EMBEDDING_MODEL, or "local" for the offline
+// hashing embedder. EMBEDDING_DIMENSIONS sets the vector size for both.
+func newEmbedder() (embeddings.Embedder, error) {
+	dimensions := embeddings.DefaultDimensions
+	if value := os.Getenv("EMBEDDING_DIMENSIONS"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			return nil, fmt.Errorf("invalid EMBEDDING_DIMENSIONS %q", value)
+		}
+		dimensions = parsed
+	}
+
+	switch provider := os.Getenv("EMBEDDING_PROVIDER"); provider {
+	case "", "vertex":
+		return embeddings.NewVertexEmbedder(aiplatformService, gcpProjectIDEnv, locationEnv, os.Getenv("EMBEDDING_MODEL"), dimensions), nil
+	case "local":
+		return embeddings.NewLocalEmbedder(dimensions), nil
+	default:
+		return nil, fmt.Errorf("unknown EMBEDDING_PROVIDER %q", provider)
+	}
+}
+
+// minSimilarity is the lowest cosine similarity a search result may have
+const minSimilarity = 0.3
+
 // SearchRequest defines the request for semantic search
 type SearchRequest struct {
 	Query   string                 `json:"query"`
 	Filters map[string]interface{} `json:"filters,omitempty"`
 	Limit   int                    `json:"limit,omitempty"`
+	// FilterMode is "pre" (the default) to search only matching content, or "post"
+	// to search everything and drop what does not match
+	FilterMode vectorstore.FilterMode `json:"filterMode,omitempty"`
+	// Ranking tunes how vector and keyword matches are fused
+	Ranking *RankingOptions `json:"ranking,omitempty"`
+	// PassagesPerResult is how many passages each result carries, 0 for none.
+	// Unset means defaultPassages.
+	PassagesPerResult *int `json:"passagesPerResult,omitempty"`
+	// PerSource is how many results may share a source URL, 1 when unset
+	PerSource int `json:"perSource,omitempty"`
+	// Rerank rescores the candidates with the configured reranker
+	Rerank bool `json:"rerank,omitempty"`
+	// Diversity, when set, spreads the results over different content
+	Diversity *DiversityOptions `json:"diversity,omitempty"`
+}
+
+// passageCount returns how many passages each result carries
+func (r SearchRequest) passageCount() int {
+	if r.PassagesPerResult == nil {
+		return defaultPassages
+	}
+	return *r.PassagesPerResult
+}
+
+// perSource returns how many results may share a source
+func (r SearchRequest) perSource() int {
+	if r.PerSource <= 0 {
+		return 1
+	}
+	return r.PerSource
 }
 
 // UpsertRequest defines the request for upserting embeddings
@@ -93,12 +203,16 @@ func VectorSearchGCF(w http.ResponseWriter, r *http.Request) {
 	// Handle different endpoints based on path
 	path := r.URL.Path
 	switch {
+	case health.IsHealthPath(path):
+		healthChecker.ServeHTTP(w, r)
 	case strings.HasSuffix(path, "/search"):
 		handleSemanticSearch(w, r)
 	case strings.HasSuffix(path, "/upsert"):
 		handleUpsertEmbeddings(w, r)
 	case strings.HasSuffix(path, "/similar"):
 		handleFindSimilar(w, r)
+	case strings.HasSuffix(path, "/reindex"):
+		handleReindex(w, r)
 	default:
 		httputils.ErrorJSON(w, "Invalid endpoint", http.StatusNotFound)
 	}
@@ -134,6 +248,38 @@ func handleSemanticSearch(w http.ResponseWriter, r *http.Request) {
 	if req.Limit <= 0 {
 		req.Limit = 10
 	}
+	if req.Limit*candidatesPerResult > vectorstore.MaxK {
+		req.Limit = vectorstore.MaxK / candidatesPerResult
+	}
+	if n := req.PassagesPerResult; n != nil && (*n < 0 || *n > maxPassages) {
+		httputils.ErrorJSON(w, fmt.Sprintf("passagesPerResult must be between 0 and %d", maxPassages), http.StatusBadRequest)
+		return
+	}
+	if req.PerSource < 0 {
+		httputils.ErrorJSON(w, "perSource must not be negative", http.StatusBadRequest)
+		return
+	}
+	if req.Diversity != nil {
+		if err := rerank.ValidateLambda(req.Diversity.lambda()); err != nil {
+			httputils.ErrorJSON(w, "invalid diversity: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	switch req.FilterMode {
+	case "", vectorstore.FilterPre, vectorstore.FilterPost:
+	default:
+		httputils.ErrorJSON(w, "filterMode must be \"pre\" or \"post\"", http.StatusBadRequest)
+		return
+	}
+	if _, err := req.Ranking.options(); err != nil {
+		httputils.ErrorJSON(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !allowSearch(w, r, authedUser.UID) {
+		return
+	}
 
 	// Perform semantic search
 	results, err := performSemanticSearch(r.Context(), req, authedUser.UID)
@@ -145,9 +291,12 @@ func handleSemanticSearch(w http.ResponseWriter, r *http.Request) {
 
 	log.Printf("Semantic search completed for user %s, found %d results", authedUser.UID, len(results))
 	httputils.RespondJSON(w, map[string]interface{}{
-		"results": results,
-		"total":   len(results),
-		"query":   req.Query,
+		"results":  results,
+		"total":    len(results),
+		"query":    req.Query,
+		"model":    queryEmbedder.Model(),
+		"store":    vectorStore.Name(),
+		"reranker": resultReranker.Name(),
 	}, http.StatusOK)
 }
 
@@ -215,12 +364,21 @@ func handleFindSimilar(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !allowSearch(w, r, authedUser.UID) {
+		return
+	}
+
+	// The reference document is fetched too and dropped, so it takes one place
+	maxLimit := vectorstore.MaxK/candidatesPerResult - 1
 	limitStr := r.URL.Query().Get("limit")
 	limit := 5
 	if limitStr != "" {
-		if parsedLimit, err := strconv.Atoi(limitStr); err == nil {
-			limit = parsedLimit
+		parsedLimit, err := strconv.Atoi(limitStr)
+		if err != nil || parsedLimit < 1 || parsedLimit > maxLimit {
+			httputils.ErrorJSON(w, fmt.Sprintf("limit must be between 1 and %d", maxLimit), http.StatusBadRequest)
+			return
 		}
+		limit = parsedLimit
 	}
 
 	// Find similar documents
@@ -238,98 +396,117 @@ func handleFindSimilar(w http.ResponseWriter, r *http.Request) {
 	}, http.StatusOK)
 }
 
-// performSemanticSearch executes the actual semantic search
+// performSemanticSearch ranks the whole corpus by fusing the similarity of its
+// vectors to the query with the query terms it contains, keeps the best results
+// of each source, optionally reranks and diversifies them, and picks out the
+// passages that match
 func performSemanticSearch(ctx context.Context, req SearchRequest, userID string) ([]models.SearchResult, error) {
-	// For now, implement a hybrid approach using Firestore + vector similarity
-	// In production, you'd use Vertex AI Vector Search or Pinecone
-
-	// Step 1: Query Firestore for documents matching metadata filters
-	collection := firestoreClient.Collection("scraped_content")
-	query := collection.Query
+	opts, err := req.Ranking.options()
+	if err != nil {
+		return nil, err
+	}
 
-	// Apply filters
-	if req.Filters != nil {
-		if interviewType, ok := req.Filters["interviewType"].(string); ok && interviewType != "" {
-			query = query.Where("interviewType", "==", interviewType)
-		}
-		if targetLevel, ok := req.Filters["targetLevel"].(string); ok && targetLevel != "" {
-			query = query.Where("targetLevel", "==", targetLevel)
-		}
-		if company, ok := req.Filters["company"].(string); ok && company != "" {
-			query = query.Where("targetCompany", "==", company)
-		}
-		if contentType, ok := req.Filters["contentType"].(string); ok && contentType != "" {
-			query = query.Where("contentType", "==", contentType)
+	var queryEmbedding []float64
+	if opts.VectorWeight > 0 {
+		queryEmbedding, err = generateQueryEmbedding(ctx, req.Query)
+		if err != nil {
+			if opts.LexicalWeight <= 0 {
+				return nil, fmt.Errorf("failed to generate query embedding: %w", err)
+			}
+			log.Printf("VectorSearch: Failed to embed query, ranking by keywords only: %v", err)
+			queryEmbedding = nil
 		}
 	}
 
-	// Limit results for performance
-	query = query.Limit(req.Limit * 2) // Get more than needed, then filter by similarity
-
-	docs, err := query.Documents(ctx).GetAll()
+	best, err := rankDocuments(ctx, req, opts, queryEmbedding)
 	if err != nil {
-		return nil, fmt.Errorf("failed to query documents: %w", err)
+		return nil, err
+	}
+	if len(best) == 0 {
+		return nil, nil
 	}
 
-	// Step 2: Generate embedding for search query
-	queryEmbedding, err := generateQueryEmbedding(ctx, req.Query)
+	refs := make([]*firestore.DocumentRef, len(best))
+	for i, ranked := range best {
+		refs[i] = firestoreClient.Collection("scraped_content").Doc(ranked.ID)
+	}
+	docs, err := firestoreClient.GetAll(ctx, refs)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate query embedding: %w", err)
+		return nil, fmt.Errorf("failed to load documents: %w", err)
 	}
 
-	// Step 3: Calculate similarities and rank results
-	var results []models.SearchResult
-	for _, doc := range docs {
+	perSource := make(map[string]int)
+	candidates := make([]searchCandidate, 0, len(docs))
+	for i, doc := range docs {
+		// The index can lag behind deletions
+		if !doc.Exists() {
+			continue
+		}
 		var content models.ScrapedContent
 		if err := doc.DataTo(&content); err != nil {
 			log.Printf("Failed to parse document %s: %v", doc.Ref.ID, err)
 			continue
 		}
 
-		// Calculate similarity with different content parts
-		similarity := calculateDocumentSimilarity(queryEmbedding, &content)
-		if similarity > 0.3 { // Threshold for relevance
-			results = append(results, models.SearchResult{
+		// Keep one long video or post from filling the results
+		source := content.Source.URL
+		if source == "" {
+			source = doc.Ref.ID
+		}
+		if perSource[source] >= req.perSource() {
+			continue
+		}
+		perSource[source]++
+
+		metadata := map[string]interface{}{
+			"interviewType": content.InterviewType,
+			"targetLevel":   content.TargetLevel,
+			"targetCompany": content.TargetCompany,
+			"author":        content.Source.Author,
+			"createdAt":     content.CreatedAt,
+			"qualityScore":  content.QualityScore,
+		}
+		if best[i].match != nil {
+			metadata["matchedKey"] = best[i].match.Key
+		}
+
+		candidates = append(candidates, searchCandidate{
+			result: models.SearchResult{
 				ID:          doc.Ref.ID,
 				Content:     content.Content.Summary,
 				Source:      content.Source.URL,
 				Title:       content.Source.Title,
-				Score:       similarity,
+				Score:       best[i].Score,
 				ContentType: content.ContentType,
-				Metadata: map[string]interface{}{
-					"interviewType": content.InterviewType,
-					"targetLevel":   content.TargetLevel,
-					"targetCompany": content.TargetCompany,
-					"author":        content.Source.Author,
-					"createdAt":     content.CreatedAt,
-					"qualityScore":  content.QualityScore,
-				},
-			})
-		}
+				Metadata:    metadata,
+				Scores:      best[i].breakdown(opts.Method),
+			},
+			content: content,
+		})
 	}
 
-	// Sort by similarity score
-	for i := 0; i < len(results); i++ {
-		for j := i + 1; j < len(results); j++ {
-			if results[i].Score < results[j].Score {
-				results[i], results[j] = results[j], results[i]
-			}
-		}
+	// Candidates past the limit give reranking and diversification room to choose
+	if req.Rerank {
+		candidates = rerankCandidates(ctx, req.Query, candidates)
 	}
-
-	// Limit final results
-	if len(results) > req.Limit {
-		results = results[:req.Limit]
+	if req.Diversity != nil {
+		candidates = diversifyCandidates(candidates, req.Diversity.lambda(), req.Limit)
+	}
+	if len(candidates) > req.Limit {
+		candidates = candidates[:req.Limit]
 	}
 
+	terms := lexical.Terms(req.Query)
+	results := make([]models.SearchResult, len(candidates))
+	for i := range candidates {
+		candidates[i].result.Passages = topPassages(&candidates[i].content, queryEmbedding, terms, opts, req.passageCount())
+		results[i] = candidates[i].result
+	}
 	return results, nil
 }
 
 // upsertEmbeddings stores document embeddings for later retrieval
 func upsertEmbeddings(ctx context.Context, documents []models.IndexedDocument, userID string) error {
-	// In a production system, this would upsert to Vertex AI Vector Search
-	// For now, we'll store in Firestore with the embeddings
-
 	batch := firestoreClient.Batch()
 
 	for _, doc := range documents {
@@ -356,7 +533,15 @@ func upsertEmbeddings(ctx context.Context, documents []models.IndexedDocument, u
 		return fmt.Errorf("failed to commit batch: %w", err)
 	}
 
-	return nil
+	// Index the scraped documents' vectors under the keys the scraper stored
+	ids := make([]string, len(documents))
+	for i, doc := range documents {
+		ids[i] = doc.ID
+	}
+	if _, err := indexDocuments(ctx, ids); err != nil {
+		return fmt.Errorf("failed to index documents: %w", err)
+	}
+	return saveVectorStore(ctx)
 }
 
 // findSimilarDocuments finds documents similar to a given document
@@ -411,84 +596,40 @@ func findSimilarDocuments(ctx context.Context, documentID string, limit int, use
 
 // Helper functions
 
+// generateQueryEmbedding embeds the query for retrieval with queryEmbedder
 func generateQueryEmbedding(ctx context.Context, query string) ([]float64, error) {
-	// In production, use the same embedding service as content scraping
-	// For now, return a placeholder
-
-	// TODO: Integrate with actual embedding generation
-	// This should use the same embedding model as used in content processing
-	return make([]float64, 768), nil // Placeholder embedding
-}
-
-func calculateDocumentSimilarity(queryEmbedding []float64, content *models.ScrapedContent) float64 {
-	// Calculate similarity with document embeddings
-	if content.Embeddings == nil || len(content.Embeddings.Vectors) == 0 {
-		// Fallback to text-based similarity if no embeddings
-		return calculateTextSimilarity(content)
-	}
-
-	// Use the document-level embedding for comparison
-	if docEmbedding, exists := content.EmbeddingMetadata["document"]; exists {
-		if idx, ok := docEmbedding.(int); ok && idx < len(content.Embeddings.Vectors) {
-			similarity, err := calculateCosineSimilarity(queryEmbedding, content.Embeddings.Vectors[idx])
-			if err == nil {
-				return similarity
-			}
-		}
-	}
-
-	// Fallback to average of all embeddings
-	var totalSimilarity float64
-	count := 0
-	for _, embedding := range content.Embeddings.Vectors {
-		if sim, err := calculateCosineSimilarity(queryEmbedding, embedding); err == nil {
-			totalSimilarity += sim
-			count++
-		}
-	}
-
-	if count > 0 {
-		return totalSimilarity / float64(count)
-	}
-
-	return 0.0
+	return embeddings.EmbedOne(ctx, queryEmbedder, query, embeddings.TaskQuery)
 }
 
-func calculateTextSimilarity(content *models.ScrapedContent) float64 {
-	// Simple text-based similarity as fallback
-	// In production, you'd use more sophisticated text similarity
-
-	// Give higher scores to interview-specific content
-	score := content.QualityScore
-
-	// Boost based on content type
-	switch content.ContentType {
-	case "interview_experience":
-		score += 0.3
-	case "tips":
-		score += 0.2
-	case "tutorial":
-		score += 0.1
-	}
-
-	return score
+// vectorIndex reads a position in Embeddings.Vectors from EmbeddingMetadata.
+// Firestore hands integers back as int64, and JSON as float64.
+func vectorIndex(value interface{}) (int, bool) {
+	switch v := value.(type) {
+	case int:
+		return v, v >= 0
+	case int64:
+		return int(v), v >= 0
+	case float64:
+		return int(v), v >= 0
+	}
+	return 0, false
 }
 
-func calculateCosineSimilarity(a, b []float64) (float64, error) {
-	if len(a) != len(b) {
-		return 0, fmt.Errorf("vector dimensions don't match: %d vs %d", len(a), len(b))
-	}
-
-	var dotProduct, normA, normB float64
-	for i := 0; i < len(a); i++ {
-		dotProduct += a[i] * b[i]
-		normA += a[i] * a[i]
-		normB += b[i] * b[i]
+// allowSearch applies the user's search rate limit, writing a 429 when exceeded.
+// Search always runs on platform resources, so every user gets the default quota.
+func allowSearch(w http.ResponseWriter, r *http.Request, userID string) bool {
+	decision, err := rateLimiter.Allow(r.Context(), userID, ratelimit.ClassSearch, false)
+	if err != nil {
+		// Fail open so a counter store outage does not block search
+		log.Printf("Rate limit check failed for user %s: %v", userID, err)
+		return true
 	}
 
-	if normA == 0 || normB == 0 {
-		return 0, nil
+	decision.WriteHeaders(w)
+	if !decision.Allowed {
+		log.Printf("User %s denied search request (%s)", userID, decision.Reason)
+		httputils.ErrorJSON(w, decision.Message(), http.StatusTooManyRequests)
+		return false
 	}
-
-	return dotProduct / (normA * normB), nil
+	return true
 }
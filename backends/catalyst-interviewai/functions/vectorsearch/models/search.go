@@ -9,13 +9,57 @@ type SearchResult struct {
 	Score       float64                `json:"score"`
 	ContentType string                 `json:"contentType"`
 	Metadata    map[string]interface{} `json:"metadata"`
+	Scores      *ScoreBreakdown        `json:"scores,omitempty"`
+	Passages    []Passage              `json:"passages,omitempty"`
+}
+
+// Passage is a chunk of a document's full text that answers the query
+type Passage struct {
+	Key   string  `json:"key"`   // the chunk's embedding key, e.g. "chunk_3"
+	Text  string  `json:"text"`  // the whole chunk
+	Start int     `json:"start"` // character offsets of the chunk in the full text
+	End   int     `json:"end"`
+	Score float64 `json:"score"`
+	// Snippet is a short extract of the chunk around the query terms, which
+	// Highlights locate within it
+	Snippet    string `json:"snippet"`
+	Highlights []Span `json:"highlights,omitempty"`
+	// Timestamp estimates the second of a YouTube video where the chunk starts,
+	// and URL links to it
+	Timestamp *int   `json:"timestamp,omitempty"`
+	URL       string `json:"url,omitempty"`
+}
+
+// Span is a range of characters
+type Span struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// ScoreBreakdown shows how a hybrid search ranked a result. A retriever that did
+// not return the result has no component. Rerank is set when a reranker rescored
+// the result, and MMR when results were diversified.
+type ScoreBreakdown struct {
+	Method  string          `json:"method"`
+	Fused   float64         `json:"fused"`
+	Vector  *ComponentScore `json:"vector,omitempty"`
+	Lexical *ComponentScore `json:"lexical,omitempty"`
+	Rerank  *ComponentScore `json:"rerank,omitempty"`
+	MMR     *float64        `json:"mmr,omitempty"` // marginal relevance when the result was picked
+}
+
+// ComponentScore is one retriever's score and rank for a result
+type ComponentScore struct {
+	Score float64  `json:"score"`
+	Rank  int      `json:"rank"`
+	Terms []string `json:"terms,omitempty"` // query terms the lexical index matched
 }
 
 // IndexedDocument represents a document ready for vector indexing
 type IndexedDocument struct {
 	ID           string                 `json:"id"`
 	Content      string                 `json:"content"`
-	Embeddings   map[string][]float64   `json:"embeddings"`
+	Embeddings   *EmbeddingData         `json:"embeddings"`
 	Metadata     map[string]interface{} `json:"metadata"`
 	QualityScore float64                `json:"qualityScore"`
 }
@@ -44,13 +88,25 @@ type ContentSource struct {
 	Author      string `json:"author,omitempty" firestore:"author,omitempty"`
 	Domain      string `json:"domain" firestore:"domain"`
 	Type        string `json:"type" firestore:"type"`
+	Duration    string `json:"duration,omitempty" firestore:"duration,omitempty"` // ISO 8601, for videos
 }
 
 // ContentData represents the actual content data
 type ContentData struct {
-	Raw     string `json:"raw" firestore:"raw"`
-	Summary string `json:"summary" firestore:"summary"`
-	Title   string `json:"title" firestore:"title"`
+	Raw       string     `json:"raw" firestore:"raw"`
+	Summary   string     `json:"summary" firestore:"summary"`
+	Title     string     `json:"title" firestore:"title"`
+	Questions []Question `json:"questions,omitempty" firestore:"questions,omitempty"`
+	Tags      []string   `json:"tags,omitempty" firestore:"tags,omitempty"`
+	// FullTranscript is the whole transcript or article text the scraper embedded
+	// in chunks
+	FullTranscript string `json:"fullTranscript,omitempty" firestore:"fullTranscript,omitempty"`
+}
+
+// Question is an interview question the scraper extracted
+type Question struct {
+	QuestionText string `json:"questionText" firestore:"questionText"`
+	Context      string `json:"context,omitempty" firestore:"context,omitempty"`
 }
 
 // EmbeddingData represents embedding vectors and metadata
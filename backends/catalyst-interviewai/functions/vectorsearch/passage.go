@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+	"unicode/utf8"
+
+	"interviewai.wkv.local/vectorsearch/internal/embeddings"
+	"interviewai.wkv.local/vectorsearch/internal/fusion"
+	"interviewai.wkv.local/vectorsearch/internal/passages"
+	"interviewai.wkv.local/vectorsearch/models"
+)
+
+// defaultPassages is how many passages a result carries unless the request says
+// otherwise
+const defaultPassages = 3
+
+// maxPassages bounds passagesPerResult
+const maxPassages = 10
+
+// snippetLength is the size of a passage snippet in characters
+const snippetLength = 240
+
+// topPassages scores each chunk of a document's full text and returns the best
+// count, highest first. A chunk scores by the similarity of its chunk_N vector to
+// the query and the share of query terms it contains, weighted as the request
+// weighs vector and keyword ranking. Chunks with neither score are left out.
+func topPassages(content *models.ScrapedContent, queryEmbedding []float64, terms []string, opts fusion.Options, count int) []models.Passage {
+	text := content.Content.FullTranscript
+	if count <= 0 || text == "" {
+		return nil
+	}
+
+	type scored struct {
+		chunk passages.Chunk
+		spans []passages.Span
+		score float64
+	}
+	var candidates []scored
+	for _, chunk := range passages.Split(text, passages.ChunkWords) {
+		spans, matched := passages.Find(chunk.Text, terms)
+
+		var score, weight float64
+		if vector := contentVector(content, fmt.Sprintf("chunk_%d", chunk.Index)); queryEmbedding != nil && vector != nil {
+			score += opts.VectorWeight * embeddings.Cosine(queryEmbedding, vector)
+			weight += opts.VectorWeight
+		}
+		if len(terms) > 0 && opts.LexicalWeight > 0 {
+			score += opts.LexicalWeight * float64(len(matched)) / float64(len(terms))
+			weight += opts.LexicalWeight
+		}
+		if weight == 0 || score <= 0 {
+			continue
+		}
+		candidates = append(candidates, scored{chunk: chunk, spans: spans, score: score / weight})
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].score > candidates[j].score })
+	if len(candidates) > count {
+		candidates = candidates[:count]
+	}
+
+	duration, isVideo := videoDuration(content)
+	length := utf8.RuneCountInString(text)
+	results := make([]models.Passage, len(candidates))
+	for i, c := range candidates {
+		snippet, highlights := passages.Snippet(c.chunk.Text, c.spans, snippetLength)
+		passage := models.Passage{
+			Key:     fmt.Sprintf("chunk_%d", c.chunk.Index),
+			Text:    c.chunk.Text,
+			Start:   c.chunk.Start,
+			End:     c.chunk.End,
+			Score:   c.score,
+			Snippet: snippet,
+		}
+		for _, span := range highlights {
+			passage.Highlights = append(passage.Highlights, models.Span{Start: span.Start, End: span.End})
+		}
+		if isVideo {
+			seconds := passages.Timestamp(duration, c.chunk.Start, length)
+			passage.Timestamp = &seconds
+			passage.URL = passages.TimestampLink(content.Source.URL, seconds)
+		}
+		results[i] = passage
+	}
+	return results
+}
+
+// contentVector returns a document's vector for an embedding key, or nil when it
+// has none
+func contentVector(content *models.ScrapedContent, key string) []float64 {
+	if content.Embeddings == nil {
+		return nil
+	}
+	idx, ok := vectorIndex(content.EmbeddingMetadata[key])
+	if !ok || idx >= len(content.Embeddings.Vectors) {
+		return nil
+	}
+	return content.Embeddings.Vectors[idx]
+}
+
+// videoDuration returns the length of a YouTube video from its metadata
+func videoDuration(content *models.ScrapedContent) (time.Duration, bool) {
+	if content.Source.Type != "youtube" || content.Source.URL == "" {
+		return 0, false
+	}
+	return passages.ParseISODuration(content.Source.Duration)
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"interviewai.wkv.local/vectorsearch/internal/fusion"
+	"interviewai.wkv.local/vectorsearch/internal/lexical"
+	"interviewai.wkv.local/vectorsearch/internal/vectorstore"
+	"interviewai.wkv.local/vectorsearch/models"
+)
+
+// How much a term counts in each indexed field. Titles and tags are short and
+// name the topic, so a match there says more than one in a summary.
+const (
+	titleWeight    = 2.0
+	tagWeight      = 2.0
+	questionWeight = 1.5
+	summaryWeight  = 1.0
+)
+
+// candidatesPerResult is how many candidates each retriever contributes to fusion
+// per result wanted, so a document ranked low by one can still be lifted by the
+// other
+const candidatesPerResult = 2
+
+// lexicalFields are the scraped_content fields the lexical index reads
+var lexicalFields = []string{
+	"source.title", "content.summary", "content.questions", "content.tags",
+	"interviewType", "targetLevel", "targetCompany", "contentType",
+}
+
+// RankingOptions tunes how vector and keyword matches are fused. Unset fields keep
+// fusion.DefaultOptions; a zero weight turns that retriever off.
+type RankingOptions struct {
+	Method        fusion.Method `json:"method,omitempty"`
+	VectorWeight  *float64      `json:"vectorWeight,omitempty"`
+	LexicalWeight *float64      `json:"lexicalWeight,omitempty"`
+	RRFK          float64       `json:"rrfK,omitempty"`
+}
+
+// options fills in the defaults and validates the result
+func (o *RankingOptions) options() (fusion.Options, error) {
+	opts := fusion.DefaultOptions
+	if o != nil {
+		if o.Method != "" {
+			opts.Method = o.Method
+		}
+		if o.VectorWeight != nil {
+			opts.VectorWeight = *o.VectorWeight
+		}
+		if o.LexicalWeight != nil {
+			opts.LexicalWeight = *o.LexicalWeight
+		}
+		if o.RRFK != 0 {
+			opts.RRFK = o.RRFK
+		}
+	}
+	if err := opts.Validate(); err != nil {
+		return opts, fmt.Errorf("invalid ranking: %w", err)
+	}
+	return opts, nil
+}
+
+// rankedDocument is a document fused from the vector and lexical rankings
+type rankedDocument struct {
+	fusion.Result
+	match *vectorstore.Match // the document's closest vector, if vector search found it
+	hit   *lexical.Hit       // the document's keyword match, if lexical search found it
+}
+
+// breakdown reports the component scores for the response
+func (d rankedDocument) breakdown(method fusion.Method) *models.ScoreBreakdown {
+	scores := &models.ScoreBreakdown{Method: string(method), Fused: d.Score}
+	if d.Vector != nil {
+		scores.Vector = &models.ComponentScore{Score: d.Vector.Score, Rank: d.Vector.Rank}
+	}
+	if d.Lexical != nil {
+		scores.Lexical = &models.ComponentScore{Score: d.Lexical.Score, Rank: d.Lexical.Rank, Terms: d.hit.Matched}
+	}
+	return scores
+}
+
+// rankDocuments retrieves candidates from the vector store and the lexical index
+// and fuses them, returning more documents than the limit so results can still be
+// dropped per source. queryEmbedding is nil when vector ranking is off. When the
+// vector store fails, the documents are ranked by keywords alone unless those are
+// turned off too.
+func rankDocuments(ctx context.Context, req SearchRequest, opts fusion.Options, queryEmbedding []float64) ([]rankedDocument, error) {
+	depth := req.Limit * candidatesPerResult
+	if depth <= 0 {
+		return nil, nil
+	}
+	filter := searchFilter(req.Filters)
+
+	var vectorCandidates []fusion.Candidate
+	matches := make(map[string]*vectorstore.Match)
+	if queryEmbedding != nil {
+		// A document scores as its closest vector, so one match per document is enough
+		found, err := vectorStore.Search(ctx, vectorstore.Query{
+			Vector:      queryEmbedding,
+			K:           depth,
+			Filter:      filter,
+			Mode:        req.FilterMode,
+			PerDocument: 1,
+		})
+		if err != nil {
+			if opts.LexicalWeight <= 0 {
+				return nil, fmt.Errorf("vector search failed: %w", err)
+			}
+			log.Printf("VectorSearch: Vector search on %s failed, ranking by keywords only: %v", vectorStore.Name(), err)
+			found = nil
+		}
+
+		for i := range found {
+			match := &found[i]
+			if match.Score < minSimilarity {
+				continue
+			}
+			matches[match.DocumentID] = match
+			vectorCandidates = append(vectorCandidates, fusion.Candidate{ID: match.DocumentID, Score: match.Score})
+		}
+	}
+
+	var lexicalCandidates []fusion.Candidate
+	hits := make(map[string]*lexical.Hit)
+	if opts.LexicalWeight > 0 {
+		refreshLexicalIndex(ctx)
+		found := lexicalIndex.Search(req.Query, depth, filter.Matches)
+		for i := range found {
+			hits[found[i].ID] = &found[i]
+			lexicalCandidates = append(lexicalCandidates, fusion.Candidate{ID: found[i].ID, Score: found[i].Score})
+		}
+	}
+
+	fused := fusion.Fuse(vectorCandidates, lexicalCandidates, opts)
+	if len(fused) > depth {
+		fused = fused[:depth]
+	}
+
+	ranked := make([]rankedDocument, len(fused))
+	for i, result := range fused {
+		ranked[i] = rankedDocument{Result: result, match: matches[result.ID], hit: hits[result.ID]}
+	}
+	return ranked, nil
+}
+
+// contentDocument lists the text of a scraped document the lexical index searches
+func contentDocument(id string, content *models.ScrapedContent) lexical.Document {
+	fields := []lexical.Field{
+		{Text: content.Source.Title, Weight: titleWeight},
+		{Text: content.Content.Summary, Weight: summaryWeight},
+	}
+	for _, question := range content.Content.Questions {
+		fields = append(fields, lexical.Field{Text: question.QuestionText, Weight: questionWeight})
+	}
+	for _, tag := range content.Content.Tags {
+		fields = append(fields, lexical.Field{Text: tag, Weight: tagWeight})
+	}
+	return lexical.Document{ID: id, Fields: fields, Attributes: contentAttributes(content)}
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+
+	"google.golang.org/api/discoveryengine/v1"
+	"interviewai.wkv.local/vectorsearch/internal/embeddings"
+	"interviewai.wkv.local/vectorsearch/internal/lexical"
+	"interviewai.wkv.local/vectorsearch/internal/rerank"
+	"interviewai.wkv.local/vectorsearch/models"
+)
+
+// DiversityOptions asks for results spread over different content, chosen by
+// maximal marginal relevance
+type DiversityOptions struct {
+	// Lambda weighs relevance against variety, from 0 (only variety) to 1 (only
+	// relevance). Unset means rerank.DefaultLambda.
+	Lambda *float64 `json:"lambda,omitempty"`
+}
+
+// lambda returns the requested lambda or the default
+func (o *DiversityOptions) lambda() float64 {
+	if o.Lambda == nil {
+		return rerank.DefaultLambda
+	}
+	return *o.Lambda
+}
+
+// newReranker creates the reranker named by RERANKER: "none" (the default) keeps
+// the fused order, and "vertex" scores with the Vertex AI ranking model in
+// RERANK_MODEL
+func newReranker(ctx context.Context) (rerank.Reranker, error) {
+	switch name := os.Getenv("RERANKER"); name {
+	case "", "none":
+		return rerank.Noop{}, nil
+	case "vertex":
+		service, err := discoveryengine.NewService(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("discoveryengine.NewService: %w", err)
+		}
+		return rerank.NewVertexReranker(service, gcpProjectIDEnv, os.Getenv("RERANK_MODEL")), nil
+	default:
+		return nil, fmt.Errorf("unknown RERANKER %q", name)
+	}
+}
+
+// searchCandidate is a hydrated result that reranking and diversification may
+// still reorder or drop
+type searchCandidate struct {
+	result  models.SearchResult
+	content models.ScrapedContent
+}
+
+// rerankCandidates rescores candidates with resultReranker by title and summary.
+// The search can answer without it, so a failure keeps the fused order.
+func rerankCandidates(ctx context.Context, query string, candidates []searchCandidate) []searchCandidate {
+	if _, noop := resultReranker.(rerank.Noop); noop || len(candidates) == 0 {
+		return candidates
+	}
+
+	docs := make([]rerank.Document, len(candidates))
+	byID := make(map[string]searchCandidate, len(candidates))
+	for i, candidate := range candidates {
+		docs[i] = rerank.Document{
+			ID:    candidate.result.ID,
+			Title: candidate.result.Title,
+			Text:  candidate.result.Content,
+			Score: candidate.result.Score,
+		}
+		byID[candidate.result.ID] = candidate
+	}
+
+	ranked, err := resultReranker.Rerank(ctx, query, docs)
+	if err != nil {
+		log.Printf("VectorSearch: Reranking with %s failed, keeping fused order: %v", resultReranker.Name(), err)
+		return candidates
+	}
+
+	reranked := make([]searchCandidate, 0, len(ranked))
+	for i, doc := range ranked {
+		candidate, ok := byID[doc.ID]
+		if !ok {
+			continue
+		}
+		candidate.result.Score = doc.Score
+		candidate.result.Scores.Rerank = &models.ComponentScore{Score: doc.Score, Rank: i + 1}
+		reranked = append(reranked, candidate)
+	}
+	return reranked
+}
+
+// diversifyCandidates picks limit candidates by maximal marginal relevance.
+// Candidates are compared by their whole-document vectors, or by the terms of
+// their titles and summaries when either has none.
+func diversifyCandidates(candidates []searchCandidate, lambda float64, limit int) []searchCandidate {
+	relevance := make([]float64, len(candidates))
+	vectors := make([][]float64, len(candidates))
+	terms := make([]map[string]bool, len(candidates))
+	for i, candidate := range candidates {
+		relevance[i] = candidate.result.Score
+		vectors[i] = documentVector(&candidate.content)
+		terms[i] = make(map[string]bool)
+		for _, term := range lexical.Terms(candidate.result.Title + " " + candidate.result.Content) {
+			terms[i][term] = true
+		}
+	}
+
+	similarity := func(i, j int) float64 {
+		if vectors[i] != nil && vectors[j] != nil {
+			return max(0, embeddings.Cosine(vectors[i], vectors[j]))
+		}
+		return jaccard(terms[i], terms[j])
+	}
+
+	picks := rerank.MMR(relevance, similarity, lambda, limit)
+	diversified := make([]searchCandidate, len(picks))
+	for i, pick := range picks {
+		candidate := candidates[pick.Index]
+		score := pick.Score
+		candidate.result.Scores.MMR = &score
+		diversified[i] = candidate
+	}
+	return diversified
+}
+
+// documentVector returns the vector the scraper made of a whole document, falling
+// back to its title's
+func documentVector(content *models.ScrapedContent) []float64 {
+	if vector := contentVector(content, "document"); vector != nil {
+		return vector
+	}
+	return contentVector(content, "title")
+}
+
+// jaccard is the share of terms two sets have in common
+func jaccard(a, b map[string]bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	shared := 0
+	for term := range a {
+		if b[term] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
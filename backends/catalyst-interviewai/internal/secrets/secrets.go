@@ -8,8 +8,11 @@ import (
 
 	secretmanager "cloud.google.com/go/secretmanager/apiv1"
 	"cloud.google.com/go/secretmanager/apiv1/secretmanagerpb"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"interview-ai/catalyst-backend/internal/tracing"
 )
 
 // SecretNameForUser generates the Google Secret Manager secret name for a given user ID.
@@ -30,7 +33,7 @@ func GetUserAPIKey(ctx context.Context, client *secretmanager.Client, gcpProject
 		Name: secretVersionName,
 	}
 
-	result, err := client.AccessSecretVersion(ctx, accessRequest)
+	result, err := accessSecretVersion(ctx, client, secretID, accessRequest)
 	if err != nil {
 		st, ok := status.FromError(err)
 		if ok && (st.Code() == codes.NotFound || st.Code() == codes.PermissionDenied || st.Code() == codes.FailedPrecondition) {
@@ -41,3 +44,34 @@ func GetUserAPIKey(ctx context.Context, client *secretmanager.Client, gcpProject
 
 	return string(result.Payload.Data), nil
 }
+
+// GetSystemSecret accesses the latest version of a project-wide secret, such as a
+// key shared between services.
+func GetSystemSecret(ctx context.Context, client *secretmanager.Client, gcpProjectID string, secretID string) (string, error) {
+	if client == nil {
+		return "", fmt.Errorf("Secret Manager client not initialized")
+	}
+	secretVersionName := fmt.Sprintf("projects/%s/secrets/%s/versions/latest", gcpProjectID, secretID)
+
+	result, err := accessSecretVersion(ctx, client, secretID, &secretmanagerpb.AccessSecretVersionRequest{
+		Name: secretVersionName,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to access secret %s: %w", secretID, err)
+	}
+
+	return string(result.Payload.Data), nil
+}
+
+// accessSecretVersion reads a secret inside a client span, so slow Secret Manager
+// reads show up in the request's trace
+func accessSecretVersion(ctx context.Context, client *secretmanager.Client, secretID string, req *secretmanagerpb.AccessSecretVersionRequest) (*secretmanagerpb.AccessSecretVersionResponse, error) {
+	ctx, span := tracing.Start(ctx, "SecretManager.AccessSecretVersion", trace.SpanKindClient,
+		attribute.String("rpc.system", "grpc"),
+		attribute.String("rpc.service", "google.cloud.secretmanager.v1.SecretManagerService"),
+		attribute.String("secret.id", secretID),
+	)
+	result, err := client.AccessSecretVersion(ctx, req)
+	tracing.End(span, err)
+	return result, err
+}
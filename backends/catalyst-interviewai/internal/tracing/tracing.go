@@ -0,0 +1,169 @@
+package tracing
+
+// OpenTelemetry tracing for GCF handlers and their outbound calls
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Exporters selectable with TRACING_EXPORTER
+const (
+	// ExporterCloudTrace sends spans to Cloud Trace's OTLP endpoint with the function's credentials
+	ExporterCloudTrace = "cloudtrace"
+	// ExporterOTLP sends spans to the collector named by OTEL_EXPORTER_OTLP_ENDPOINT
+	ExporterOTLP = "otlp"
+	// ExporterStdout pretty-prints spans to stdout, for local development
+	ExporterStdout = "stdout"
+	// ExporterNone records nothing but still propagates incoming trace context
+	ExporterNone = "none"
+)
+
+const instrumentationName = "interviewai.wkv.local/tracing"
+
+// provider is nil until Setup installs an exporting provider
+var provider *sdktrace.TracerProvider
+
+// Config selects how spans are exported
+type Config struct {
+	ServiceName string
+	ProjectID   string
+	Exporter    string
+	// SampleRatio is the fraction of new traces recorded. Traces started upstream keep
+	// the caller's decision.
+	SampleRatio float64
+}
+
+// ConfigFromEnv reads TRACING_EXPORTER and TRACING_SAMPLE_RATIO. Without an explicit
+// exporter, deployed functions (K_SERVICE or FUNCTION_TARGET set) export to Cloud Trace,
+// local runs with OTEL_EXPORTER_OTLP_ENDPOINT export there, and anything else exports nothing.
+func ConfigFromEnv(serviceName, projectID string) Config {
+	config := Config{
+		ServiceName: serviceName,
+		ProjectID:   projectID,
+		Exporter:    strings.ToLower(strings.TrimSpace(os.Getenv("TRACING_EXPORTER"))),
+		SampleRatio: 1,
+	}
+
+	if config.Exporter == "" {
+		switch {
+		case os.Getenv("K_SERVICE") != "" || os.Getenv("FUNCTION_TARGET") != "":
+			config.Exporter = ExporterCloudTrace
+		case os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" || os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") != "":
+			config.Exporter = ExporterOTLP
+		default:
+			config.Exporter = ExporterNone
+		}
+	}
+
+	if value := os.Getenv("TRACING_SAMPLE_RATIO"); value != "" {
+		ratio, err := strconv.ParseFloat(value, 64)
+		if err != nil || ratio < 0 || ratio > 1 {
+			log.Printf("Warning: ignoring invalid TRACING_SAMPLE_RATIO %q", value)
+		} else {
+			config.SampleRatio = ratio
+		}
+	}
+	return config
+}
+
+// Setup installs the W3C trace context propagator and, unless the exporter is "none",
+// a tracer provider exporting with config. The returned function flushes and stops it.
+func Setup(ctx context.Context, config Config) (func(context.Context) error, error) {
+	// traceparent wins over X-Cloud-Trace-Context when a request carries both
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		cloudTraceContext{},
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	exporter, err := newExporter(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	if exporter == nil {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	attributes := []attribute.KeyValue{semconv.ServiceName(config.ServiceName)}
+	if config.ProjectID != "" {
+		attributes = append(attributes, attribute.String("gcp.project_id", config.ProjectID))
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL, attributes...))
+	if err != nil {
+		return nil, fmt.Errorf("building trace resource: %w", err)
+	}
+
+	provider = sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(config.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	log.Printf("Tracing: exporting %s spans with %s (sample ratio %.2f)", config.ServiceName, config.Exporter, config.SampleRatio)
+	return provider.Shutdown, nil
+}
+
+func newExporter(ctx context.Context, config Config) (sdktrace.SpanExporter, error) {
+	switch config.Exporter {
+	case ExporterNone:
+		return nil, nil
+	case ExporterStdout:
+		return stdouttrace.New(stdouttrace.WithPrettyPrint())
+	case ExporterOTLP:
+		// Endpoint, headers and TLS come from the standard OTEL_EXPORTER_OTLP_* variables
+		return otlptracehttp.New(ctx)
+	case ExporterCloudTrace:
+		if config.ProjectID == "" {
+			return nil, fmt.Errorf("the %s exporter needs a GCP project ID", ExporterCloudTrace)
+		}
+		client, err := newCloudTraceClient(ctx, config.ProjectID)
+		if err != nil {
+			return nil, err
+		}
+		return otlptrace.New(ctx, client)
+	default:
+		return nil, fmt.Errorf("unknown TRACING_EXPORTER %q", config.Exporter)
+	}
+}
+
+// Flush exports the spans recorded so far. Functions may lose CPU as soon as the
+// handler returns, so spans are flushed before it does.
+func Flush(ctx context.Context) {
+	if provider == nil {
+		return
+	}
+	if err := provider.ForceFlush(ctx); err != nil {
+		log.Printf("Tracing: failed to flush spans: %v", err)
+	}
+}
+
+// Start begins a span as a child of the one in ctx
+func Start(ctx context.Context, name string, kind trace.SpanKind, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, trace.WithSpanKind(kind), trace.WithAttributes(attributes...))
+}
+
+// End records err on span, if any, and ends it
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
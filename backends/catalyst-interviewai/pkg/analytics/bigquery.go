@@ -2,10 +2,13 @@ package analytics
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
+	"strings"
 	"sync"
 	"time"
+	"unicode"
 
 	"cloud.google.com/go/bigquery"
 )
@@ -62,6 +65,44 @@ type InterviewSession struct {
 	Metadata        map[string]interface{} `bigquery:"metadata"`
 }
 
+// Save implements bigquery.ValueSaver. Metadata is a JSON column, which the
+// streaming API only accepts as an encoded string.
+func (s *InterviewSession) Save() (map[string]bigquery.Value, string, error) {
+	row := map[string]bigquery.Value{
+		"session_id":     s.SessionID,
+		"user_id":        s.UserID,
+		"started_at":     s.StartedAt,
+		"status":         s.Status,
+		"interview_type": s.InterviewType,
+	}
+	if s.EndedAt != nil {
+		row["ended_at"] = *s.EndedAt
+	}
+	if s.TargetRole != nil {
+		row["target_role"] = *s.TargetRole
+	}
+	if s.Company != nil {
+		row["company"] = *s.Company
+	}
+	if s.DurationSeconds != nil {
+		row["duration_seconds"] = *s.DurationSeconds
+	}
+	if s.QuestionCount != nil {
+		row["question_count"] = *s.QuestionCount
+	}
+	if s.CompletionRate != nil {
+		row["completion_rate"] = *s.CompletionRate
+	}
+	if len(s.Metadata) > 0 {
+		metadata, err := json.Marshal(s.Metadata)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to encode session metadata: %w", err)
+		}
+		row["metadata"] = string(metadata)
+	}
+	return row, bigquery.NoDedupeID, nil
+}
+
 // UserResponse represents a row in the user_responses table
 type UserResponse struct {
 	ResponseID           string    `bigquery:"response_id"`
@@ -109,6 +150,14 @@ type AgentInteraction struct {
 	Metadata         map[string]interface{} `bigquery:"metadata"`
 }
 
+// Ping checks that the analytics dataset is reachable
+func (c *Client) Ping(ctx context.Context) error {
+	if _, err := c.bqClient.Dataset(c.datasetID).Metadata(ctx); err != nil {
+		return fmt.Errorf("failed to read dataset %s: %w", c.datasetID, err)
+	}
+	return nil
+}
+
 // InsertInterviewSession inserts a new interview session
 func (c *Client) InsertInterviewSession(ctx context.Context, session *InterviewSession) error {
 	table := c.bqClient.Dataset(c.datasetID).Table("interview_sessions")
@@ -142,7 +191,7 @@ func (c *Client) UpdateInterviewSession(ctx context.Context, sessionID string, u
 func (c *Client) InsertUserResponse(ctx context.Context, response *UserResponse) error {
 	// Calculate word count if not provided
 	if response.WordCount == nil {
-		words := len(response.ResponseText) / 5 // Rough estimate
+		words := WordCount(response.ResponseText)
 		response.WordCount = &words
 	}
 	
@@ -156,6 +205,18 @@ func (c *Client) InsertUserResponse(ctx context.Context, response *UserResponse)
 	return nil
 }
 
+// WordCount counts the words in text: whitespace-separated tokens with at least one
+// letter or digit, so dashes and bullets on their own are not counted
+func WordCount(text string) int {
+	count := 0
+	for _, token := range strings.Fields(text) {
+		if strings.IndexFunc(token, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }) >= 0 {
+			count++
+		}
+	}
+	return count
+}
+
 // InsertEvaluationScore inserts an evaluation score
 func (c *Client) InsertEvaluationScore(ctx context.Context, score *EvaluationScore) error {
 	table := c.bqClient.Dataset(c.datasetID).Table("evaluation_scores")
@@ -255,6 +316,11 @@ func IntPtr(i int) *int {
 	return &i
 }
 
+// Helper function to create a pointer to an int64
+func Int64Ptr(i int64) *int64 {
+	return &i
+}
+
 // Helper function to create a pointer to a float64
 func Float64Ptr(f float64) *float64 {
 	return &f
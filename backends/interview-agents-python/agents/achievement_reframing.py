@@ -10,6 +10,7 @@ import google.generativeai as genai
 from google.cloud import aiplatform
 
 from .base import BaseAgent, AgentMessage, MessageType, MessagePriority
+from common.api_keys import model_for_request
 from common.config import AgentName, settings
 from common.telemetry import SessionTracker, trace_agent_operation, trace_ai_operation
 
@@ -212,7 +213,7 @@ class AchievementReframingAgent(BaseAgent):
             with trace_ai_operation("achievement_reframing", settings.default_model, "reframing") as span:
                 prompt = self._create_reframing_prompt(star_story, target_company, job_description, target_role)
                 
-                response = await self.model.generate_content_async(prompt)
+                response = await model_for_request(self.model).generate_content_async(prompt)
                 result_json = json.loads(response.text)
                 
                 achievement = ReframedAchievement(
@@ -432,7 +433,7 @@ class AchievementReframingAgent(BaseAgent):
                 }}
                 """
                 
-                response = await self.model.generate_content_async(prompt)
+                response = await model_for_request(self.model).generate_content_async(prompt)
                 result = json.loads(response.text)
                 
                 return result
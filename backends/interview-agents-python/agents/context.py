@@ -13,6 +13,7 @@ from google.cloud import aiplatform
 import httpx
 
 from .base import BaseAgent, AgentMessage, MessageType, MessagePriority
+from common.api_keys import model_for_request
 from common.config import AgentName, settings
 from common.telemetry import SessionTracker, trace_agent_operation, trace_ai_operation
 from common.auth import firestore_client
@@ -284,7 +285,7 @@ class ContextAgent(BaseAgent):
             with trace_ai_operation("resume_extraction", settings.default_model, "context") as span:
                 prompt = self._create_resume_extraction_prompt(resume_text)
                 
-                response = await self.model.generate_content_async(prompt)
+                response = await model_for_request(self.model).generate_content_async(prompt)
                 
                 # Parse JSON response
                 extracted_data = json.loads(response.text)
@@ -458,7 +459,7 @@ class ContextAgent(BaseAgent):
             with trace_ai_operation("job_extraction", settings.default_model, "context") as span:
                 prompt = self._create_job_extraction_prompt(job_description)
                 
-                response = await self.model.generate_content_async(prompt)
+                response = await model_for_request(self.model).generate_content_async(prompt)
                 extracted_data = json.loads(response.text)
                 
                 job_context.company_name = extracted_data.get("company_name")
@@ -576,7 +577,7 @@ class ContextAgent(BaseAgent):
             with trace_ai_operation("response_analysis", settings.default_model, "context") as span:
                 prompt = self._create_response_analysis_prompt(user_response, response_time, current_state)
                 
-                response = await self.model.generate_content_async(prompt)
+                response = await model_for_request(self.model).generate_content_async(prompt)
                 analysis = json.loads(response.text)
                 
                 span.set_attribute("analysis_success", True)
@@ -641,7 +642,7 @@ class ContextAgent(BaseAgent):
             Make questions specific and probe deeper into the response.
             """
             
-            response = await self.model.generate_content_async(prompt)
+            response = await model_for_request(self.model).generate_content_async(prompt)
             suggestions = json.loads(response.text)
             
             return suggestions if isinstance(suggestions, list) else []
@@ -841,7 +842,7 @@ class ContextAgent(BaseAgent):
             Make recommendations specific and actionable.
             """
             
-            response = await self.model.generate_content_async(prompt)
+            response = await model_for_request(self.model).generate_content_async(prompt)
             recommendations = json.loads(response.text)
             
             return recommendations if isinstance(recommendations, list) else []
@@ -12,6 +12,7 @@ import google.generativeai as genai
 from google.cloud import aiplatform
 
 from .base import BaseAgent, AgentMessage, MessageType, MessagePriority
+from common.api_keys import model_for_request
 from common.config import AgentName, settings
 from common.telemetry import SessionTracker, trace_agent_operation, trace_ai_operation
 
@@ -191,7 +192,7 @@ class ContextAgentFocused(BaseAgent):
             with trace_ai_operation("resume_parsing", settings.default_model, "context") as span:
                 prompt = self._create_resume_parsing_prompt(resume_content)
                 
-                response = await self.model.generate_content_async(prompt)
+                response = await model_for_request(self.model).generate_content_async(prompt)
                 parsed_json = json.loads(response.text)
                 
                 # Create ParsedDocument from AI response
@@ -14,9 +14,9 @@ import google.generativeai as genai
 from google.cloud import aiplatform
 
 from .base import BaseAgent, AgentMessage, MessageType, MessagePriority
+from common.api_keys import model_for_request
 from common.config import AgentName, ComplexityLevel, settings
 from common.telemetry import SessionTracker, trace_agent_operation, trace_ai_operation
-from common.analytics import get_analytics_client
 
 logger = logging.getLogger(__name__)
 
@@ -440,31 +440,18 @@ class EvaluatorAgent(BaseAgent):
         # Generate immediate feedback
         feedback = await self._generate_immediate_feedback(evaluation)
         
-        # Log evaluation to BigQuery
-        analytics = get_analytics_client()
-        if analytics:
-            # Extract dimension scores
-            dim_scores = {dim.value: score.value for dim, score in evaluation.dimension_scores.items()}
-            
-            analytics.log_evaluation_score(
-                response_id=f"{session_id}_{len(session_eval.evaluations)}",
-                session_id=session_id,
-                overall_score=evaluation.overall_score * 100,  # Convert to 0-100 scale
-                evaluator_agent=self.agent_name.value,
-                clarity_score=dim_scores.get("communication_clarity", 0) * 100 if "communication_clarity" in dim_scores else None,
-                relevance_score=dim_scores.get("problem_understanding", 0) * 100 if "problem_understanding" in dim_scores else None,
-                depth_score=dim_scores.get("technical_depth", 0) * 100 if "technical_depth" in dim_scores else None,
-                structure_score=dim_scores.get("solution_approach", 0) * 100 if "solution_approach" in dim_scores else None,
-                strengths=evaluation.strengths[:5],  # Limit to 5 strengths
-                improvements=evaluation.growth_areas[:5],  # Limit to 5 improvements
-                feedback_text=feedback.get("message") if isinstance(feedback, dict) else str(feedback)
-            )
+        # Link the evaluation to the gateway's ID for the answer when one was sent.
+        # The gateway logs it to BigQuery from the orchestrator's respond payload.
+        response_id = request.get("response_id") or f"{session_id}_{len(session_eval.evaluations)}"
         
         return {
             "success": True,
             "evaluation_id": f"{session_id}_{len(session_eval.evaluations)}",
+            "response_id": response_id,
             "scores": {dim.value: score.value for dim, score in evaluation.dimension_scores.items()},
             "overall_score": evaluation.overall_score,
+            "score_scale": 1.0,
+            "evaluator_agent": self.agent_name.value,
             "confidence": evaluation.overall_confidence,
             "feedback": feedback,
             "strengths": evaluation.strengths,
@@ -520,7 +507,7 @@ class EvaluatorAgent(BaseAgent):
             with trace_ai_operation(f"evaluate_{dimension.value}", settings.default_model, evaluation.session_id) as span:
                 prompt = self._create_dimension_evaluation_prompt(evaluation, dimension)
                 
-                response = await self.model.generate_content_async(prompt)
+                response = await model_for_request(self.model).generate_content_async(prompt)
                 result = json.loads(response.text)
                 
                 score = Score(
@@ -676,7 +663,7 @@ class EvaluatorAgent(BaseAgent):
             Make questions specific and probing.
             """
             
-            response = await self.model.generate_content_async(prompt)
+            response = await model_for_request(self.model).generate_content_async(prompt)
             recommendations = json.loads(response.text)
             
             return recommendations if isinstance(recommendations, list) else []
@@ -807,6 +794,7 @@ class EvaluatorAgent(BaseAgent):
         return {
             "session_id": session_id,
             "total_evaluations": len(session_eval.evaluations),
+            "score_scale": 1.0,  # scores below run 0-1; reports rescale from this to 0-100
             "average_scores": {dim.value: score for dim, score in session_eval.average_scores.items()},
             "overall_average": statistics.mean(session_eval.average_scores.values()) if session_eval.average_scores else 0.0,
             "consistent_strengths": session_eval.consistent_strengths,
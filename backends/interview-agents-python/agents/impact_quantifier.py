@@ -12,6 +12,7 @@ import google.generativeai as genai
 from google.cloud import aiplatform
 
 from .base import BaseAgent, AgentMessage, MessageType, MessagePriority
+from common.api_keys import model_for_request
 from common.config import AgentName, settings
 from common.telemetry import SessionTracker, trace_agent_operation, trace_ai_operation
 
@@ -267,7 +268,7 @@ class ImpactQuantifierAgent(BaseAgent):
             with trace_ai_operation("quantification_analysis", settings.default_model, "impact_quantifier") as span:
                 prompt = self._create_quantification_prompt(result_text, existing_metrics)
                 
-                response = await self.model.generate_content_async(prompt)
+                response = await model_for_request(self.model).generate_content_async(prompt)
                 analysis = json.loads(response.text)
                 
                 span.set_attribute("quantified", analysis.get("quantified", False))
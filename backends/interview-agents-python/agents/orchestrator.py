@@ -12,6 +12,7 @@ import google.generativeai as genai
 from google.cloud import aiplatform
 
 from .base import BaseAgent, AgentMessage, MessageType, MessagePriority
+from common.api_keys import model_for_request
 from common.config import (
     AgentName, ComplexityLevel, ReasoningStrategy, 
     get_reasoning_strategy, settings
@@ -86,6 +87,8 @@ class SessionContext:
     last_user_response: Optional[str] = None
     last_response_timestamp: Optional[datetime] = None
     silence_duration: float = 0.0
+    questions_asked: int = 0
+    responses_received: int = 0
 
 
 class OrchestratorAgent(BaseAgent):
@@ -96,9 +99,17 @@ class OrchestratorAgent(BaseAgent):
     state transitions, and interventions based on user behavior and performance.
     """
     
-    def __init__(self, session_tracker: Optional[SessionTracker] = None):
+    def __init__(
+        self,
+        session_tracker: Optional[SessionTracker] = None,
+        evaluator: Optional[BaseAgent] = None
+    ):
         super().__init__(AgentName.ORCHESTRATOR, session_tracker)
         
+        # Answers are scored in-process when the evaluator runs alongside, so the
+        # respond payload can carry its evaluation
+        self.evaluator = evaluator
+        
         # Session management
         self.active_sessions: Dict[str, SessionContext] = {}
         
@@ -216,6 +227,7 @@ class OrchestratorAgent(BaseAgent):
         
         handlers = {
             "start_interview": self._start_interview,
+            "resume_interview": self._resume_interview,
             "user_response": self._handle_user_response,
             "state_transition": self._handle_state_transition,
             "get_status": self._get_session_status,
@@ -281,6 +293,8 @@ class OrchestratorAgent(BaseAgent):
         
         # Get initial question from Interviewer agent
         initial_question = await self._request_initial_question(context)
+        if initial_question:
+            context.questions_asked += 1
         
         return {
             "success": True,
@@ -292,6 +306,65 @@ class OrchestratorAgent(BaseAgent):
             "timestamp": datetime.now().isoformat()
         }
     
+    async def _resume_interview(
+        self,
+        context: SessionContext,
+        request: Dict[str, Any]
+    ) -> Dict[str, Any]:
+        """Restore a session from the gateway's transcript without asking a new question."""
+        transcript = request.get("transcript") or []
+        
+        complexity = await self._assess_complexity(context)
+        context.complexity = complexity
+        context.reasoning_strategy = get_reasoning_strategy(complexity)
+        
+        started_at = request.get("started_at")
+        if started_at:
+            try:
+                context.start_time = datetime.fromisoformat(started_at.replace("Z", "+00:00")).replace(tzinfo=None)
+            except ValueError:
+                logger.warning(f"Ignoring invalid started_at {started_at!r} for session {context.session_id}")
+        
+        await self._transition_state(context, InterviewState.SCOPING, "interview_resumed")
+        
+        # Pick up where the lost session was, if the gateway knows
+        resumed_state = request.get("state")
+        if resumed_state and resumed_state != context.current_state.value:
+            try:
+                target_state = InterviewState(resumed_state)
+            except ValueError:
+                logger.warning(f"Unknown state {resumed_state!r} for resumed session {context.session_id}")
+            else:
+                context.previous_state = context.current_state
+                context.current_state = target_state
+                context.state_transitions.append({
+                    "from_state": context.previous_state.value,
+                    "to_state": target_state.value,
+                    "trigger": "interview_resumed",
+                    "timestamp": datetime.now(),
+                    "duration_in_previous": 0.0
+                })
+        
+        context.questions_asked = sum(1 for turn in transcript if turn.get("question"))
+        context.responses_received = sum(1 for turn in transcript if turn.get("answer"))
+        answers = [turn["answer"] for turn in transcript if turn.get("answer")]
+        if answers:
+            context.last_user_response = answers[-1]
+            context.last_response_timestamp = datetime.now()
+        
+        await self._initialize_agents(context, transcript)
+        
+        return {
+            "success": True,
+            "session_id": context.session_id,
+            "resumed": True,
+            "resumed_turns": len(transcript),
+            "state": context.current_state.value,
+            "complexity": context.complexity.value,
+            "reasoning_strategy": context.reasoning_strategy.value,
+            "timestamp": datetime.now().isoformat()
+        }
+    
     async def _handle_user_response(
         self, 
         context: SessionContext, 
@@ -305,6 +378,7 @@ class OrchestratorAgent(BaseAgent):
         context.last_user_response = user_response
         context.last_response_timestamp = datetime.now()
         context.silence_duration = 0.0
+        context.responses_received += 1
         
         # Check for interventions
         interventions = await self._check_interventions(context, user_response)
@@ -342,20 +416,21 @@ class OrchestratorAgent(BaseAgent):
         ))
         
         # Send to Evaluator for real-time assessment
-        tasks.append(self.send_message(
-            AgentName.EVALUATOR,
-            MessageType.REQUEST,
-            {
-                "action": "evaluate_response",
-                "user_response": user_response,
-                "response_time": response_time,
-                "context": {
-                    "state": context.current_state.value,
-                    "complexity": context.complexity.value,
-                    "interview_type": context.interview_type
-                }
+        evaluation_request = {
+            "action": "evaluate_response",
+            "user_response": user_response,
+            "response_time": response_time,
+            "response_id": request.get("response_id"),
+            "context": {
+                "state": context.current_state.value,
+                "complexity": context.complexity.value,
+                "interview_type": context.interview_type
             }
-        ))
+        }
+        if self.evaluator:
+            tasks.append(self.evaluator.process_request({"session_id": context.session_id, **evaluation_request}))
+        else:
+            tasks.append(self.send_message(AgentName.EVALUATOR, MessageType.REQUEST, evaluation_request))
         
         # Send to Interviewer for next question
         tasks.append(self.send_message(
@@ -373,19 +448,31 @@ class OrchestratorAgent(BaseAgent):
         ))
         
         # Execute all tasks concurrently
-        await asyncio.gather(*tasks, return_exceptions=True)
+        results = await asyncio.gather(*tasks, return_exceptions=True)
+        if not isinstance(results[-1], Exception):
+            context.questions_asked += 1
         
         # Check if state transition is needed
         next_state = await self._determine_next_state(context, user_response)
         if next_state and next_state != context.current_state:
             await self._transition_state(context, next_state, "semantic")
         
-        return {
+        result = {
             "success": True,
             "state": context.current_state.value,
             "processing": "agents_notified",
             "timestamp": datetime.now().isoformat()
         }
+        
+        # The gateway logs the evaluation to BigQuery from here
+        evaluation = results[1]
+        if isinstance(evaluation, dict) and "error" not in evaluation:
+            context.scores.update(evaluation.get("scores", {}))
+            result["evaluation"] = evaluation
+        elif self.evaluator:
+            logger.warning(f"No evaluation for response in session {context.session_id}: {evaluation}")
+        
+        return result
     
     async def _assess_complexity(self, context: SessionContext) -> ComplexityLevel:
         """Assess interview complexity based on context."""
@@ -398,7 +485,7 @@ class OrchestratorAgent(BaseAgent):
             "interview_type": {
                 "behavioral": 1,
                 "technical": 2,
-                "system design": 4,
+                "system_design": 4,
                 "leadership": 3
             }
         }
@@ -438,7 +525,7 @@ class OrchestratorAgent(BaseAgent):
         type_multipliers = {
             "behavioral": 0.8,
             "technical": 1.0,
-            "system design": 1.4,
+            "system_design": 1.4,
             "leadership": 1.2
         }
         
@@ -522,7 +609,7 @@ class OrchestratorAgent(BaseAgent):
             with trace_ai_operation("state_analysis", settings.default_model, context.session_id) as span:
                 prompt = self._create_state_analysis_prompt(context, user_response)
                 
-                response = await self.model.generate_content_async(prompt)
+                response = await model_for_request(self.model).generate_content_async(prompt)
                 result = response.text.strip().lower()
                 
                 # Parse AI response
@@ -641,8 +728,12 @@ class OrchestratorAgent(BaseAgent):
         
         await asyncio.gather(*tasks, return_exceptions=True)
     
-    async def _initialize_agents(self, context: SessionContext):
-        """Initialize all other agents for the session."""
+    async def _initialize_agents(
+        self,
+        context: SessionContext,
+        transcript: Optional[List[Dict[str, Any]]] = None
+    ):
+        """Initialize all other agents for the session, with the turns so far when resuming."""
         init_payload = {
             "action": "initialize_session",
             "session_context": {
@@ -656,11 +747,12 @@ class OrchestratorAgent(BaseAgent):
                 "job_description": context.job_description
             }
         }
+        if transcript:
+            init_payload["session_context"]["transcript"] = transcript
         
         agents_to_init = [
             AgentName.CONTEXT,
             AgentName.INTERVIEWER,
-            AgentName.EVALUATOR,
             AgentName.SYNTHESIS
         ]
         
@@ -668,6 +760,10 @@ class OrchestratorAgent(BaseAgent):
             self.send_message(agent, MessageType.REQUEST, init_payload)
             for agent in agents_to_init
         ]
+        if self.evaluator:
+            tasks.append(self.evaluator.process_request({"session_id": context.session_id, **init_payload}))
+        else:
+            tasks.append(self.send_message(AgentName.EVALUATOR, MessageType.REQUEST, init_payload))
         
         await asyncio.gather(*tasks, return_exceptions=True)
     
@@ -721,6 +817,8 @@ class OrchestratorAgent(BaseAgent):
             "duration_seconds": (datetime.now() - context.start_time).total_seconds(),
             "state_transitions": len(context.state_transitions),
             "interventions": len(context.interventions),
+            "questions_asked": context.questions_asked,
+            "questions_answered": context.responses_received,
             "scores": context.scores,
             "last_activity": context.last_response_timestamp.isoformat() if context.last_response_timestamp else None
         }
@@ -758,6 +856,8 @@ class OrchestratorAgent(BaseAgent):
             "final_scores": context.scores,
             "interventions_count": len(context.interventions),
             "state_transitions_count": len(context.state_transitions),
+            "questions_asked": context.questions_asked,
+            "questions_answered": context.responses_received,
             "complexity": context.complexity.value,
             "reasoning_strategy": context.reasoning_strategy.value
         }
@@ -959,7 +1059,7 @@ class OrchestratorAgent(BaseAgent):
         type_complexity = {
             "behavioral": 0.3,
             "technical": 0.6,
-            "system design": 1.0,
+            "system_design": 1.0,
             "leadership": 0.8
         }
         
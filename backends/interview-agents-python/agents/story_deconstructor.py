@@ -12,6 +12,7 @@ import google.generativeai as genai
 from google.cloud import aiplatform
 
 from .base import BaseAgent, AgentMessage, MessageType, MessagePriority
+from common.api_keys import model_for_request
 from common.config import AgentName, settings
 from common.telemetry import SessionTracker, trace_agent_operation, trace_ai_operation
 
@@ -277,7 +278,7 @@ class StoryDeconstructorAgent(BaseAgent):
             with trace_ai_operation("star_extraction", settings.default_model, "story_deconstruction") as span:
                 prompt = self._create_star_extraction_prompt(story_text, initial_result)
                 
-                response = await self.model.generate_content_async(prompt)
+                response = await model_for_request(self.model).generate_content_async(prompt)
                 result_json = json.loads(response.text)
                 
                 # Create components from AI response
@@ -10,9 +10,25 @@ from fastapi import Request, Response, HTTPException
 from fastapi.security import HTTPBearer, HTTPAuthorizationCredentials
 from starlette.middleware.base import BaseHTTPMiddleware
 from starlette.types import ASGIApp
+from opentelemetry import propagate, trace
+from opentelemetry.trace import SpanKind, Status, StatusCode
 
+from ..common.api_keys import (
+    API_KEY_HEADER,
+    API_KEY_SOURCE_HEADER,
+    APIKeyError,
+    decrypt_api_key,
+    reset_request_api_key,
+    set_request_api_key,
+)
 from ..common.auth import FirebaseAuth
-from ..common.telemetry import trace_ai_operation
+from ..common.telemetry import trace_ai_operation, tracer
+from ..common.user_context import (
+    USER_CONTEXT_HEADER,
+    UserContextError,
+    signing_key,
+    verify_user_context,
+)
 
 logger = logging.getLogger(__name__)
 
@@ -23,6 +39,9 @@ class AuthenticationMiddleware(BaseHTTPMiddleware):
         super().__init__(app)
         self.firebase_auth = FirebaseAuth()
         self.exempt_paths = {"/health", "/docs", "/openapi.json", "/redoc"}
+        self.signing_key = signing_key()
+        if self.signing_key is None:
+            logger.error("USER_CONTEXT_SIGNING_KEY not set; all authenticated requests will be rejected")
     
     async def dispatch(self, request: Request, call_next: Callable) -> Response:
         """Process request with authentication."""
@@ -31,14 +50,33 @@ class AuthenticationMiddleware(BaseHTTPMiddleware):
         if request.url.path in self.exempt_paths:
             return await call_next(request)
         
-        # Get user ID from Go gateway header
-        user_id = request.headers.get("X-User-ID")
-        request_source = request.headers.get("X-Request-Source")
+        # The user ID is only trusted when the gateway signed it
+        signed_context = request.headers.get(USER_CONTEXT_HEADER)
+        if not signed_context:
+            logger.error("Signed user context not found in request headers")
+            raise HTTPException(
+                status_code=401,
+                detail="Authentication required - signed user context not found"
+            )
+        if self.signing_key is None:
+            logger.error("USER_CONTEXT_SIGNING_KEY is not configured; rejecting request")
+            raise HTTPException(
+                status_code=401,
+                detail="Authentication required - gateway signing is not configured"
+            )
+        try:
+            claims = verify_user_context(
+                signed_context, self.signing_key, request.method, request.url.path
+            )
+        except UserContextError as e:
+            logger.warning(f"Rejected user context for {request.method} {request.url.path}: {e}")
+            raise HTTPException(status_code=401, detail=f"Authentication failed - {e}")
         
-        # Validate request source
-        if request_source != "go-gateway":
-            logger.warning(f"Request from unknown source: {request_source}")
-            # Allow for now, but could be more strict in production
+        user_id = claims["uid"]
+        header_user_id = request.headers.get("X-User-ID")
+        if header_user_id and header_user_id != user_id:
+            logger.warning("X-User-ID does not match the signed user context")
+            raise HTTPException(status_code=401, detail="Authentication failed - user mismatch")
         
         if not user_id:
             logger.error("User ID not found in request headers")
@@ -54,7 +92,22 @@ class AuthenticationMiddleware(BaseHTTPMiddleware):
         
         logger.debug(f"Authenticated request for user {user_id}")
         
-        response = await call_next(request)
+        # Gemini calls for this request run on the key the gateway chose for the user
+        api_key = None
+        sealed_key = request.headers.get(API_KEY_HEADER)
+        if sealed_key:
+            try:
+                api_key = decrypt_api_key(sealed_key, self.signing_key, user_id)
+            except APIKeyError as e:
+                logger.warning(f"Rejected API key for {request.method} {request.url.path}: {e}")
+                raise HTTPException(status_code=401, detail=f"Authentication failed - {e}")
+            request.state.api_key_source = request.headers.get(API_KEY_SOURCE_HEADER, "user")
+        
+        token = set_request_api_key(api_key)
+        try:
+            response = await call_next(request)
+        finally:
+            reset_request_api_key(token)
         return response
 
 
@@ -72,35 +125,57 @@ class TelemetryMiddleware(BaseHTTPMiddleware):
         
         logger.info(f"Request: {method} {path} - User: {user_id}")
         
-        try:
-            response = await call_next(request)
-            
-            # Calculate duration
-            duration = (datetime.now() - start_time).total_seconds() * 1000  # ms
-            
-            # Log successful request
-            logger.info(
-                f"Response: {method} {path} - Status: {response.status_code} - "
-                f"Duration: {duration:.2f}ms - User: {user_id}"
-            )
-            
-            # Add response headers
-            response.headers["X-Response-Time"] = f"{duration:.2f}ms"
-            response.headers["X-Request-ID"] = getattr(request.state, "request_id", "unknown")
-            
-            return response
-            
-        except Exception as e:
-            # Calculate duration
-            duration = (datetime.now() - start_time).total_seconds() * 1000  # ms
-            
-            # Log error
-            logger.error(
-                f"Error: {method} {path} - Duration: {duration:.2f}ms - "
-                f"User: {user_id} - Error: {str(e)}"
-            )
-            
-            raise
+        # Continue the trace the Go gateway started (W3C traceparent header), so a
+        # slow interview turn shows up as one trace from gateway to agent
+        parent = propagate.extract(dict(request.headers))
+        with tracer.start_as_current_span(
+            f"{method} {path}",
+            context=parent,
+            kind=SpanKind.SERVER,
+            attributes={
+                "http.method": method,
+                "http.target": path,
+                "enduser.id": user_id,
+            },
+        ) as span:
+            try:
+                response = await call_next(request)
+                
+                # Calculate duration
+                duration = (datetime.now() - start_time).total_seconds() * 1000  # ms
+                
+                # Log successful request
+                logger.info(
+                    f"Response: {method} {path} - Status: {response.status_code} - "
+                    f"Duration: {duration:.2f}ms - User: {user_id}"
+                )
+                
+                span.set_attribute("http.status_code", response.status_code)
+                if response.status_code >= 500:
+                    span.set_status(Status(StatusCode.ERROR))
+                
+                # Add response headers
+                response.headers["X-Response-Time"] = f"{duration:.2f}ms"
+                response.headers["X-Request-ID"] = getattr(request.state, "request_id", "unknown")
+                trace_id = span.get_span_context().trace_id
+                if trace_id:
+                    response.headers["X-Trace-ID"] = trace.format_trace_id(trace_id)
+                
+                return response
+                
+            except Exception as e:
+                # Calculate duration
+                duration = (datetime.now() - start_time).total_seconds() * 1000  # ms
+                
+                # Log error
+                logger.error(
+                    f"Error: {method} {path} - Duration: {duration:.2f}ms - "
+                    f"User: {user_id} - Error: {str(e)}"
+                )
+                
+                span.record_exception(e)
+                span.set_status(Status(StatusCode.ERROR, str(e)))
+                raise
 
 
 class CORSMiddleware(BaseHTTPMiddleware):
@@ -133,7 +208,7 @@ class CORSMiddleware(BaseHTTPMiddleware):
         
         response.headers["Access-Control-Allow-Methods"] = "GET, POST, PUT, DELETE, OPTIONS"
         response.headers["Access-Control-Allow-Headers"] = (
-            "Content-Type, Authorization, X-Requested-With, X-User-ID, X-Request-Source"
+            "Content-Type, Authorization, X-Requested-With, X-User-ID, X-Request-Source, X-User-Context, X-Agent-API-Key, X-Agent-API-Key-Source"
         )
         response.headers["Access-Control-Allow-Credentials"] = "true"
         response.headers["Access-Control-Max-Age"] = "86400"
@@ -7,7 +7,7 @@ import asyncio
 import logging
 import os
 from datetime import datetime
-from typing import Dict, Any, Optional
+from typing import Dict, Any, List, Optional
 from contextlib import asynccontextmanager
 
 from fastapi import FastAPI, HTTPException, Request, Depends
@@ -50,9 +50,9 @@ async def lifespan(app: FastAPI):
     
     # Initialize agents
     try:
-        agents[AgentName.ORCHESTRATOR] = OrchestratorAgent()
         agents[AgentName.CONTEXT] = ContextAgent()
         agents[AgentName.EVALUATOR] = EvaluatorAgent()
+        agents[AgentName.ORCHESTRATOR] = OrchestratorAgent(evaluator=agents[AgentName.EVALUATOR])
         agents[AgentName.SYNTHESIS] = SynthesisAgent()
         
         logger.info("All agents initialized successfully")
@@ -107,10 +107,32 @@ class InterviewResponseRequest(BaseModel):
     """Request model for interview responses."""
     response: str = Field(..., description="User's interview response")
     responseTime: Optional[float] = Field(None, description="Response time in seconds")
+    response_id: Optional[str] = Field(None, description="Gateway-generated ID of this answer, echoed in evaluations")
     session_id: str = Field(..., description="Session ID")
     user_id: str = Field(..., description="User ID")
     type: str = Field(default="user_response")
 
+class TranscriptTurn(BaseModel):
+    """One turn of a transcript kept by the gateway."""
+    index: int = Field(..., description="Position of the turn in the interview")
+    question: Optional[str] = Field(None, description="Question the interviewer asked")
+    answer: Optional[str] = Field(None, description="Candidate's answer")
+    reply: Optional[str] = Field(None, description="Interviewer's reply to the answer")
+    state: Optional[str] = Field(None, description="Interview state after the reply")
+
+class ResumeInterviewRequest(BaseModel):
+    """Request model for rebuilding a session this instance no longer holds."""
+    interviewType: str = Field(..., description="Type of interview")
+    faangLevel: str = Field(..., description="Target FAANG level")
+    resume: Optional[Dict[str, Any]] = Field(None, description="Parsed resume data")
+    jobDescription: Optional[str] = Field(None, description="Job description")
+    targetCompany: Optional[str] = Field(None, description="Target company")
+    user_id: str = Field(..., description="User ID from authentication")
+    state: Optional[str] = Field(None, description="Interview state the session was last in")
+    started_at: Optional[str] = Field(None, description="When the session was first started (ISO 8601)")
+    transcript: List[TranscriptTurn] = Field(default_factory=list, description="Turns so far, in order")
+    type: str = Field(default="resume_interview")
+
 # Use the middleware-based authentication
 def get_user_id(request: Request) -> str:
     """Extract user ID from request state (set by middleware)."""
@@ -227,10 +249,14 @@ async def submit_interview_response(
             "session_id": session_id,
             "user_id": user_id,
             "response": request.response,
-            "response_time": request.responseTime
+            "response_time": request.responseTime,
+            "response_id": request.response_id
         })
         
         if "error" in result:
+            # The gateway resumes sessions this instance does not know
+            if "Invalid or missing session_id" in result["error"]:
+                raise HTTPException(status_code=404, detail="Session not found")
             raise HTTPException(status_code=500, detail=result["error"])
         
         logger.info(f"Response processed successfully for session {session_id}")
@@ -242,6 +268,58 @@ async def submit_interview_response(
         logger.error(f"Failed to process response for session {session_id}: {e}")
         raise HTTPException(status_code=500, detail=f"Failed to process response: {str(e)}")
 
+@app.post("/interview/{session_id}/resume")
+async def resume_interview(
+    session_id: str,
+    request: ResumeInterviewRequest,
+    user_id: str = Depends(get_user_id)
+):
+    """Rebuild a session from the gateway's transcript, e.g. after an instance restart."""
+    try:
+        orchestrator = agents[AgentName.ORCHESTRATOR]
+        
+        # Resuming is retried by the gateway, so a session already held is left alone
+        if session_id in orchestrator.active_sessions:
+            logger.info(f"Session {session_id} is already active; nothing to resume")
+            return {
+                "success": True,
+                "session_id": session_id,
+                "resumed": False,
+                "state": orchestrator.active_sessions[session_id].current_state.value
+            }
+        
+        logger.info(f"Resuming session {session_id} for user {user_id} from {len(request.transcript)} turns")
+        
+        await orchestrator.initialize(
+            session_id=session_id,
+            user_id=user_id,
+            interview_type=request.interviewType,
+            faang_level=request.faangLevel,
+            resume=request.resume,
+            job_description=request.jobDescription,
+            target_company=request.targetCompany
+        )
+        
+        result = await orchestrator.process_request({
+            "type": "resume_interview",
+            "session_id": session_id,
+            "user_id": user_id,
+            "state": request.state,
+            "started_at": request.started_at,
+            "transcript": [turn.dict() for turn in request.transcript]
+        })
+        
+        if "error" in result:
+            raise HTTPException(status_code=500, detail=result["error"])
+        
+        return result
+        
+    except HTTPException:
+        raise
+    except Exception as e:
+        logger.error(f"Failed to resume session {session_id}: {e}")
+        raise HTTPException(status_code=500, detail=f"Failed to resume interview: {str(e)}")
+
 @app.get("/interview/{session_id}/status")
 async def get_interview_status(
     session_id: str,
@@ -40,9 +40,9 @@ async def lifespan(app: FastAPI):
     init_telemetry()
     
     # Initialize agents
-    agents[AgentName.ORCHESTRATOR] = OrchestratorAgent()
     agents[AgentName.CONTEXT] = ContextAgent()
     agents[AgentName.EVALUATOR] = EvaluatorAgent()
+    agents[AgentName.ORCHESTRATOR] = OrchestratorAgent(evaluator=agents[AgentName.EVALUATOR])
     
     # Initialize Narrative Refinement Module agents
     agents["story_deconstructor"] = StoryDeconstructorAgent()
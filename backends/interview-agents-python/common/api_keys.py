@@ -0,0 +1,102 @@
+"""The user's Gemini API key, forwarded by the Go gateway.
+
+The gateway resolves which key pays for a session: the user's own key (BYOK) or
+the platform default. It sends the key in ``X-Agent-API-Key`` as
+``v1.<nonce>.<ciphertext>`` (base64url), encrypted with AES-256-GCM under a key
+derived from the user context signing key. The user ID is the associated data,
+so a key cannot be replayed for another user.
+"""
+
+import base64
+import copy
+import hashlib
+import hmac
+from contextvars import ContextVar, Token
+from typing import Any, Optional
+
+from cachetools import TTLCache
+from cryptography.exceptions import InvalidTag
+from cryptography.hazmat.primitives.ciphers.aead import AESGCM
+from google.ai import generativelanguage as glm
+from google.api_core.client_options import ClientOptions
+
+API_KEY_HEADER = "X-Agent-API-Key"
+API_KEY_SOURCE_HEADER = "X-Agent-API-Key-Source"
+API_KEY_VERSION = "v1"
+
+# Must match apiKeyDerivationLabel in the gateway
+_DERIVATION_LABEL = b"agent-api-key"
+
+_request_api_key: ContextVar[Optional[str]] = ContextVar("request_api_key", default=None)
+
+# Models bound to a key are reused across requests from the same user
+_bound_models: TTLCache = TTLCache(maxsize=256, ttl=3600)
+
+
+class APIKeyError(Exception):
+    """Raised when a forwarded API key cannot be decrypted."""
+
+
+def _b64decode(value: str) -> bytes:
+    return base64.urlsafe_b64decode(value + "=" * (-len(value) % 4))
+
+
+def decrypt_api_key(sealed: str, signing_key: bytes, user_id: str) -> str:
+    """Decrypt a key sealed by the gateway for ``user_id``."""
+    parts = sealed.split(".")
+    if len(parts) != 3 or parts[0] != API_KEY_VERSION:
+        raise APIKeyError("malformed API key header")
+
+    try:
+        nonce = _b64decode(parts[1])
+        ciphertext = _b64decode(parts[2])
+    except ValueError as e:
+        raise APIKeyError("malformed API key header") from e
+
+    key = hmac.new(signing_key, _DERIVATION_LABEL, hashlib.sha256).digest()
+    aad = f"{API_KEY_VERSION}.{user_id}".encode("utf-8")
+    try:
+        return AESGCM(key).decrypt(nonce, ciphertext, aad).decode("utf-8")
+    except (InvalidTag, ValueError) as e:
+        raise APIKeyError("API key was not sealed for this user") from e
+
+
+def set_request_api_key(api_key: Optional[str]) -> Token:
+    """Use ``api_key`` for Gemini calls made while handling the current request."""
+    return _request_api_key.set(api_key)
+
+
+def reset_request_api_key(token: Token) -> None:
+    """Restore the key that was in use before ``set_request_api_key``."""
+    _request_api_key.reset(token)
+
+
+def model_for_request(model: Any) -> Any:
+    """Return ``model`` bound to the current request's API key.
+
+    Without a forwarded key the shared model, which runs on the service's own
+    credentials, is returned unchanged.
+    """
+    api_key = _request_api_key.get()
+    if model is None or not api_key:
+        return model
+
+    cache_key = (id(model), hashlib.sha256(api_key.encode("utf-8")).hexdigest())
+    bound = _bound_models.get(cache_key)
+    if bound is None:
+        bound = _bind_model(model, api_key)
+        _bound_models[cache_key] = bound
+    return bound
+
+
+def _bind_model(model: Any, api_key: str) -> Any:
+    """Copy a GenerativeModel with clients that authenticate with ``api_key``.
+
+    genai.configure() is process-wide, so per-user keys need clients of their
+    own; the model creates its default clients lazily, so ours are used instead.
+    """
+    options = ClientOptions(api_key=api_key)
+    bound = copy.copy(model)
+    bound._client = glm.GenerativeServiceClient(client_options=options)
+    bound._async_client = glm.GenerativeServiceAsyncClient(client_options=options)
+    return bound
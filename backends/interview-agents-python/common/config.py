@@ -69,6 +69,12 @@ class Settings(BaseSettings):
         description="Path to Firebase service account credentials"
     )
     
+    # Gateway authentication
+    user_context_signing_key: Optional[str] = Field(
+        default=None,
+        description="Shared key for verifying the gateway's signed X-User-Context header"
+    )
+    
     # AI Models
     vertex_ai_location: str = Field(
         default="us-central1",
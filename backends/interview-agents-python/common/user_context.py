@@ -0,0 +1,85 @@
+"""Verification of the signed user context sent by the Go gateway.
+
+The gateway signs every request with a shared key so the agents can trust which
+user a request is for. The header value is ``v1.<payload>.<signature>`` where
+payload is base64url JSON and signature is the base64url HMAC-SHA256 of
+``v1.<payload>``.
+"""
+
+import base64
+import hashlib
+import hmac
+import json
+import time
+from typing import Any, Dict, Optional
+
+from .config import Environment, settings
+
+USER_CONTEXT_HEADER = "X-User-Context"
+USER_CONTEXT_VERSION = "v1"
+
+# Matches DevSigningKey in the gateway; only accepted in development
+DEV_SIGNING_KEY = "interview-ai-local-dev-user-context-key"
+
+# Allowance for clock drift between the gateway and this service
+CLOCK_SKEW_SECONDS = 30
+
+
+class UserContextError(Exception):
+    """Raised when a signed user context is missing, malformed or invalid."""
+
+
+def signing_key() -> Optional[bytes]:
+    """Return the key used to verify user contexts, if one is configured."""
+    if settings.user_context_signing_key:
+        return settings.user_context_signing_key.encode("utf-8")
+    if settings.environment == Environment.DEVELOPMENT:
+        return DEV_SIGNING_KEY.encode("utf-8")
+    return None
+
+
+def _b64decode(value: str) -> bytes:
+    return base64.urlsafe_b64decode(value + "=" * (-len(value) % 4))
+
+
+def verify_user_context(
+    signed: str,
+    key: bytes,
+    method: str,
+    path: str,
+    now: Optional[float] = None,
+) -> Dict[str, Any]:
+    """Verify a signed user context and return its claims.
+
+    The context must be signed with ``key``, unexpired, and issued for this
+    request's method and path.
+    """
+    parts = signed.split(".")
+    if len(parts) != 3 or parts[0] != USER_CONTEXT_VERSION:
+        raise UserContextError("malformed user context")
+
+    try:
+        signature = _b64decode(parts[2])
+    except ValueError as e:
+        raise UserContextError("malformed user context signature") from e
+
+    expected = hmac.new(key, f"{parts[0]}.{parts[1]}".encode("ascii"), hashlib.sha256).digest()
+    if not hmac.compare_digest(signature, expected):
+        raise UserContextError("invalid user context signature")
+
+    try:
+        claims = json.loads(_b64decode(parts[1]))
+    except ValueError as e:
+        raise UserContextError("malformed user context payload") from e
+
+    now = time.time() if now is None else now
+    if now > claims.get("exp", 0) + CLOCK_SKEW_SECONDS:
+        raise UserContextError("user context expired")
+    if claims.get("iat", 0) > now + CLOCK_SKEW_SECONDS:
+        raise UserContextError("user context issued in the future")
+    if claims.get("mth") != method or claims.get("pth") != path:
+        raise UserContextError("user context was issued for a different request")
+    if not claims.get("uid"):
+        raise UserContextError("user context has no user")
+
+    return claims
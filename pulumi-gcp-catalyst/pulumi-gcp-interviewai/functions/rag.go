@@ -104,6 +104,8 @@ func DeployRAGInfrastructure(ctx *pulumi.Context, cfg *config.CatalystConfig, so
 			"VERTEX_AI_LOCATION":          pulumi.String(cfg.GcpRegion),
 			"VERTEX_AI_INDEX_ENDPOINT_ID": pulumi.String(""), // To be configured later
 			"GCP_PROJECT_ID":              pulumi.String(cfg.GcpProject),
+			"VECTOR_STORE":                pulumi.String("hnsw"),
+			"HNSW_SNAPSHOT":               pulumi.Sprintf("gs://%s/vectorsearch/hnsw-index.gob", sourceBucket.Name),
 		},
 	})
 	if err != nil {
@@ -191,8 +193,11 @@ To complete the Vertex AI Vector Search setup:
      --index=INDEX_ID \
      --region=`+cfg.GcpRegion+`
 
-4. Update the VectorSearch function environment variable:
+4. Switch the VectorSearch function to the endpoint:
+   VECTOR_STORE=vertex
    VERTEX_AI_INDEX_ENDPOINT_ID=<your-endpoint-id>
+   VERTEX_AI_DEPLOYED_INDEX_ID=interview-content-deployed
+   VERTEX_AI_INDEX_ID=<your-index-id> (streaming indexes only)
 `))
 
 	return nil